@@ -0,0 +1,303 @@
+package rtp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/searKing/rtp/codecs/pcm"
+	"github.com/searKing/rtp/format"
+)
+
+// FmtpUnmarshaler is a Payloader or a Depacketizer configured from the parameters of an SDP fmtp line
+type FmtpUnmarshaler interface {
+	UnmarshalFmtp(fmtp string) error
+}
+
+// Codec describes a RTP payload format, registered by MIME type
+type Codec struct {
+	// MimeType is the media type and the encoding name of the payload format, such as "audio/opus";
+	// encoding names are case-insensitive
+	MimeType string
+	// ClockRate is the RTP clock rate; 0 if it depends on the stream, such as MPEG4-GENERIC's sample rate
+	ClockRate uint32
+	// Channels is the number of audio channels, 0 for video
+	Channels int
+	// PayloadType is the static payload type of rfc3551#section-6 if Static, a dynamic one is negotiated otherwise
+	PayloadType uint8
+	Static      bool
+
+	// NewPayloader and NewDepacketizer return a Payloader and a Depacketizer of the payload format,
+	// nil if none is available
+	NewPayloader    func() Payloader
+	NewDepacketizer func() Depacketizer
+}
+
+// EncodingName returns the encoding name of the rtpmap attribute, the subtype of the MIME type
+func (c Codec) EncodingName() string {
+	if i := strings.IndexByte(c.MimeType, '/'); i >= 0 {
+		return c.MimeType[i+1:]
+	}
+	return c.MimeType
+}
+
+// Payloader returns a Payloader of the codec, configured with the parameters of an SDP fmtp line if not empty
+func (c Codec) Payloader(fmtp string) (Payloader, error) {
+	if c.NewPayloader == nil {
+		return nil, fmt.Errorf("no payloader for %s", c.MimeType)
+	}
+	payloader := c.NewPayloader()
+	if err := c.unmarshalFmtp(payloader, fmtp); err != nil {
+		return nil, err
+	}
+	return payloader, nil
+}
+
+// Depacketizer returns a Depacketizer of the codec, configured with the parameters of an SDP fmtp line if not empty
+func (c Codec) Depacketizer(fmtp string) (Depacketizer, error) {
+	if c.NewDepacketizer == nil {
+		return nil, fmt.Errorf("no depacketizer for %s", c.MimeType)
+	}
+	depacketizer := c.NewDepacketizer()
+	if err := c.unmarshalFmtp(depacketizer, fmtp); err != nil {
+		return nil, err
+	}
+	return depacketizer, nil
+}
+
+// unmarshalFmtp configures a payloader or a depacketizer by the parameters of an SDP fmtp line,
+// failing on parameters it does not take
+func (c Codec) unmarshalFmtp(v interface{}, fmtp string) error {
+	if strings.TrimSpace(fmtp) == "" {
+		return nil
+	}
+	u, ok := v.(FmtpUnmarshaler)
+	if !ok {
+		return fmt.Errorf("unsupported fmtp %q for %s", fmtp, c.MimeType)
+	}
+	return u.UnmarshalFmtp(fmtp)
+}
+
+// payloaderClockRate returns the RTP clock rate of the codec, or else of a payloader knowing it, such as
+// a MPEG4GenericPayloader configured by its fmtp
+func payloaderClockRate(c Codec, payloader Payloader) uint32 {
+	if c.ClockRate != 0 {
+		return c.ClockRate
+	}
+	if p, ok := payloader.(interface{ ClockRate() uint32 }); ok {
+		return p.ClockRate()
+	}
+	return 0
+}
+
+var codecs = struct {
+	sync.RWMutex
+	byName        map[string]Codec
+	byPayloadType map[uint8]Codec
+}{
+	byName:        make(map[string]Codec),
+	byPayloadType: make(map[uint8]Codec),
+}
+
+// RegisterCodec registers a codec by its MIME type and encoding name, and by its payload type if static,
+// replacing any codec registered with them
+func RegisterCodec(c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.byName[strings.ToLower(c.MimeType)] = c
+	codecs.byName[strings.ToLower(c.EncodingName())] = c
+	if c.Static {
+		codecs.byPayloadType[c.PayloadType] = c
+	}
+}
+
+// LookupCodec returns the codec registered by a MIME type, such as "video/H264", or an encoding name, such as "H264";
+// names are case-insensitive
+func LookupCodec(name string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.byName[strings.ToLower(name)]
+	return c, ok
+}
+
+// LookupCodecByPayloadType returns the codec of a static payload type of rfc3551#section-6
+func LookupCodecByPayloadType(pt uint8) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	c, ok := codecs.byPayloadType[pt]
+	return c, ok
+}
+
+// lookupStaticCodec returns the codec of a static payload type of an encoding name, clock rate and number of
+// channels, such as L16/44100/2 for the payload type 10
+func lookupStaticCodec(name string, clockRate uint32, channels int) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	for _, c := range codecs.byPayloadType {
+		if strings.EqualFold(c.EncodingName(), name) && c.ClockRate == clockRate && c.Channels == channels {
+			return c, true
+		}
+	}
+	return Codec{}, false
+}
+
+// configureRtpmap applies the clock rate and number of channels of a rtpmap attribute to a payloader
+// of a codec taking them, failing if it does not
+func configureRtpmap(c Codec, payloader Payloader, clockRate uint32, channels int) error {
+	if p, ok := payloader.(*format.LPCMPayloader); ok {
+		p.SampleRate, p.Channels = int(clockRate), channels
+		return nil
+	}
+	if c.ClockRate != 0 && c.ClockRate != clockRate {
+		return fmt.Errorf("unsupported clock rate %d for %s", clockRate, c.MimeType)
+	}
+	if c.Channels != 0 && c.Channels != channels {
+		return fmt.Errorf("unsupported %d channels for %s", channels, c.MimeType)
+	}
+	return nil
+}
+
+// NewCodecPacketizer returns a new instance of a Packetizer for a codec registered by its name, with
+// a payloader configured by the parameters of an SDP fmtp line.
+// clockRate and channels are those of the rtpmap attribute, 0 for the ones of the codec, or else of its fmtp.
+// The static payload type of rfc3551#section-6 they match replaces pt, such as 10 for L16/44100/2,
+// while pt is kept for any other, such as L16/48000/2
+func NewCodecPacketizer(mtu int, name string, pt uint8, clockRate uint32, channels int, ssrc uint32,
+	fmtp string, sequencer Sequencer) (Packetizer, error) {
+	c, ok := LookupCodec(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %s", name)
+	}
+	if clockRate == 0 {
+		clockRate = c.ClockRate
+	}
+	if channels == 0 {
+		channels = c.Channels
+	}
+	s, static := lookupStaticCodec(c.EncodingName(), clockRate, channels)
+	if static {
+		c, pt = s, s.PayloadType
+	}
+	payloader, err := c.Payloader(fmtp)
+	if err != nil {
+		return nil, err
+	}
+	if clockRate == 0 {
+		clockRate = payloaderClockRate(c, payloader)
+	}
+	if clockRate == 0 {
+		return nil, fmt.Errorf("unknown clock rate of %s", c.MimeType)
+	}
+	if !static {
+		if err := configureRtpmap(c, payloader, clockRate, channels); err != nil {
+			return nil, err
+		}
+	}
+	return NewPacketizer(mtu, pt, ssrc, payloader, sequencer, clockRate), nil
+}
+
+func init() {
+	for _, c := range []Codec{
+		// rfc3551#section-6, Table 4: Payload types (PT) for audio encodings
+		{MimeType: "audio/PCMU", ClockRate: 8000, Channels: 1, PayloadType: 0, Static: true,
+			NewPayloader: func() Payloader { return &format.G711Payloader{} }},
+		{MimeType: "audio/GSM", ClockRate: 8000, Channels: 1, PayloadType: 3, Static: true},
+		{MimeType: "audio/G723", ClockRate: 8000, Channels: 1, PayloadType: 4, Static: true},
+		{MimeType: "audio/DVI4", ClockRate: 8000, Channels: 1, PayloadType: 5, Static: true},
+		{MimeType: "audio/LPC", ClockRate: 8000, Channels: 1, PayloadType: 7, Static: true},
+		{MimeType: "audio/PCMA", ClockRate: 8000, Channels: 1, PayloadType: 8, Static: true,
+			NewPayloader: func() Payloader { return &format.G711Payloader{} }},
+		// the RTP clock rate of G.722 is 8 kHz, though it samples at 16 kHz
+		{MimeType: "audio/G722", ClockRate: 8000, Channels: 1, PayloadType: 9, Static: true,
+			NewPayloader: func() Payloader { return &format.G722Payloader{} }},
+		{MimeType: "audio/L16", ClockRate: 44100, Channels: 1, PayloadType: 11, Static: true,
+			NewPayloader: func() Payloader {
+				return &format.LPCMPayloader{SampleSize: pcm.L16SampleSize, SampleRate: 44100, Channels: 1}
+			},
+			NewDepacketizer: func() Depacketizer { return &format.LPCMPacket{SampleSize: pcm.L16SampleSize, Channels: 1} }},
+		{MimeType: "audio/QCELP", ClockRate: 8000, Channels: 1, PayloadType: 12, Static: true},
+		{MimeType: "audio/CN", ClockRate: 8000, Channels: 1, PayloadType: 13, Static: true},
+		{MimeType: "audio/MPA", ClockRate: 90000, PayloadType: 14, Static: true,
+			NewPayloader:    func() Payloader { return &format.MPAPayloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.MPAPacket{} }},
+		{MimeType: "audio/G728", ClockRate: 8000, Channels: 1, PayloadType: 15, Static: true},
+		{MimeType: "audio/G729", ClockRate: 8000, Channels: 1, PayloadType: 18, Static: true},
+
+		// rfc3551#section-6, Table 5: Payload types (PT) for video and combined encodings
+		{MimeType: "video/CelB", ClockRate: 90000, PayloadType: 25, Static: true},
+		{MimeType: "video/JPEG", ClockRate: 90000, PayloadType: 26, Static: true,
+			NewPayloader:    func() Payloader { return &format.JPEGPayloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.JPEGPacket{} }},
+		{MimeType: "video/nv", ClockRate: 90000, PayloadType: 28, Static: true},
+		{MimeType: "video/H261", ClockRate: 90000, PayloadType: 31, Static: true},
+		{MimeType: "video/MPV", ClockRate: 90000, PayloadType: 32, Static: true},
+		{MimeType: "video/MP2T", ClockRate: 90000, PayloadType: 33, Static: true,
+			NewPayloader:    func() Payloader { return &format.MP2TPayloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.MP2TPacket{} }},
+		{MimeType: "video/H263", ClockRate: 90000, PayloadType: 34, Static: true},
+
+		// dynamic payload types
+		{MimeType: "audio/L24", ClockRate: 48000, Channels: 1,
+			NewPayloader: func() Payloader {
+				return &format.LPCMPayloader{SampleSize: pcm.L24SampleSize, SampleRate: 48000, Channels: 1}
+			},
+			NewDepacketizer: func() Depacketizer { return &format.LPCMPacket{SampleSize: pcm.L24SampleSize, Channels: 1} }},
+		{MimeType: "audio/opus", ClockRate: 48000, Channels: 2,
+			NewPayloader:    func() Payloader { return &format.OpusPayloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.OpusPacket{} }},
+		{MimeType: "audio/AMR", ClockRate: 8000, Channels: 1,
+			NewPayloader:    func() Payloader { return &format.AMRPayloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.AMRPacket{} }},
+		{MimeType: "audio/AMR-WB", ClockRate: 16000, Channels: 1,
+			NewPayloader:    func() Payloader { return &format.AMRPayloader{WideBand: true} },
+			NewDepacketizer: func() Depacketizer { return &format.AMRPacket{WideBand: true} }},
+		{MimeType: "audio/MPA-ROBUST", ClockRate: 90000,
+			NewPayloader:    func() Payloader { return &format.MP3ADUPayloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.MP3ADUPacket{} }},
+		{MimeType: "audio/MPEG4-GENERIC",
+			NewPayloader:    func() Payloader { return &format.MPEG4GenericPayloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.MPEG4GenericPacket{} }},
+		{MimeType: "audio/MP4A-LATM",
+			NewPayloader:    func() Payloader { return &format.LATMPayloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.LATMPacket{} }},
+		{MimeType: "video/H263-1998", ClockRate: 90000,
+			NewPayloader:    func() Payloader { return &format.H263Payloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.H263Packet{} }},
+		{MimeType: "video/H263-2000", ClockRate: 90000,
+			NewPayloader:    func() Payloader { return &format.H263Payloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.H263Packet{} }},
+		{MimeType: "video/H264", ClockRate: 90000,
+			NewPayloader:    func() Payloader { return &format.H264Payloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.H264Packet{} }},
+		{MimeType: "video/H265", ClockRate: 90000,
+			NewPayloader: func() Payloader { return &format.H265Payloader{} }},
+		{MimeType: "video/VP8", ClockRate: 90000,
+			NewPayloader:    func() Payloader { return &format.VP8Payloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.VP8Packet{} }},
+		{MimeType: "video/VP9", ClockRate: 90000,
+			NewPayloader:    func() Payloader { return &format.VP9Payloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.VP9Packet{} }},
+		{MimeType: "video/AV1", ClockRate: 90000,
+			NewPayloader:    func() Payloader { return &format.AV1Payloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.AV1Packet{} }},
+		{MimeType: "video/raw", ClockRate: 90000,
+			NewPayloader:    func() Payloader { return &format.RawVideoPayloader{} },
+			NewDepacketizer: func() Depacketizer { return &format.RawVideoPacket{} }},
+	} {
+		RegisterCodec(c)
+	}
+	// rfc3551#section-6: L16 and DVI4 have a payload type per number of channels or clock rate,
+	// the ones above are registered by name
+	for _, c := range []Codec{
+		{MimeType: "audio/L16", ClockRate: 44100, Channels: 2, PayloadType: 10, Static: true,
+			NewPayloader: func() Payloader {
+				return &format.LPCMPayloader{SampleSize: pcm.L16SampleSize, SampleRate: 44100, Channels: 2}
+			},
+			NewDepacketizer: func() Depacketizer { return &format.LPCMPacket{SampleSize: pcm.L16SampleSize, Channels: 2} }},
+		{MimeType: "audio/DVI4", ClockRate: 16000, Channels: 1, PayloadType: 6, Static: true},
+		{MimeType: "audio/DVI4", ClockRate: 11025, Channels: 1, PayloadType: 16, Static: true},
+		{MimeType: "audio/DVI4", ClockRate: 22050, Channels: 1, PayloadType: 17, Static: true},
+	} {
+		codecs.byPayloadType[c.PayloadType] = c
+	}
+}
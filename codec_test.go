@@ -0,0 +1,157 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/searKing/rtp/codecs/pcm"
+	"github.com/searKing/rtp/format"
+)
+
+func TestLookupCodec(t *testing.T) {
+	for _, name := range []string{"video/H264", "H264", "h264", "VIDEO/h264"} {
+		c, ok := LookupCodec(name)
+		if !ok || c.MimeType != "video/H264" || c.ClockRate != 90000 || c.Static {
+			t.Fatalf("LookupCodec(%q) should return H264, got %v", name, c.MimeType)
+		}
+	}
+	if c, ok := LookupCodec("audio/opus"); !ok || c.ClockRate != 48000 || c.Channels != 2 {
+		t.Fatal("LookupCodec should return opus at 48 kHz in stereo")
+	}
+	if _, ok := LookupCodec("audio/unknown"); ok {
+		t.Fatal("LookupCodec should not find an unknown codec")
+	}
+
+	tests := []struct {
+		pt        uint8
+		name      string
+		clockRate uint32
+		channels  int
+	}{
+		{0, "audio/PCMU", 8000, 1},
+		{8, "audio/PCMA", 8000, 1},
+		{9, "audio/G722", 8000, 1},
+		{10, "audio/L16", 44100, 2},
+		{11, "audio/L16", 44100, 1},
+		{6, "audio/DVI4", 16000, 1},
+		{14, "audio/MPA", 90000, 0},
+		{26, "video/JPEG", 90000, 0},
+		{33, "video/MP2T", 90000, 0},
+	}
+	for _, tt := range tests {
+		c, ok := LookupCodecByPayloadType(tt.pt)
+		if !ok || c.MimeType != tt.name || c.ClockRate != tt.clockRate || c.Channels != tt.channels || c.PayloadType != tt.pt {
+			t.Errorf("LookupCodecByPayloadType(%d) = %v, want %s/%d/%d", tt.pt, c.MimeType, tt.name, tt.clockRate, tt.channels)
+		}
+	}
+	if _, ok := LookupCodecByPayloadType(96); ok {
+		t.Fatal("LookupCodecByPayloadType should not find a dynamic payload type")
+	}
+}
+
+func TestCodec_Payloader(t *testing.T) {
+	c, _ := LookupCodec("H264")
+	payloader, err := c.Payloader("packetization-mode=0;sprop-parameter-sets=Z0IAHw==,aM48gA==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h264, ok := payloader.(*format.H264Payloader); !ok || !h264.SingleNALUnit {
+		t.Fatal("Payloader should be configured by the fmtp")
+	}
+	if _, err := c.Payloader("packetization-mode=5"); err == nil {
+		t.Fatal("Payloader should fail on an invalid fmtp")
+	}
+	depacketizer, err := c.Depacketizer("sprop-interleaving-depth=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h264, ok := depacketizer.(*format.H264Packet); !ok || h264.InterleavingDepth != 3 {
+		t.Fatal("Depacketizer should be configured by the fmtp")
+	}
+	amr, _ := LookupCodec("AMR")
+	payloader, err = amr.Payloader("octet-align=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amr, ok := payloader.(*format.AMRPayloader); !ok || !amr.OctetAligned {
+		t.Fatal("Payloader should be configured by the fmtp")
+	}
+	if g722, _ := LookupCodec("G722"); g722.NewPayloader == nil {
+		t.Fatal("G722 should have a payloader")
+	} else if _, err := g722.Payloader("bitrate=64000"); err == nil {
+		t.Fatal("Payloader should fail on a fmtp it cannot parse")
+	}
+	if c, _ := LookupCodec("GSM"); c.NewPayloader != nil {
+		t.Fatal("GSM should have no payloader")
+	} else if _, err := c.Payloader(""); err == nil {
+		t.Fatal("Payloader should fail without payloader")
+	}
+}
+
+func TestNewCodecPacketizer(t *testing.T) {
+	// G.722 samples at 16 kHz with a 8 kHz clock rate
+	g722, err := NewCodecPacketizer(1500, "audio/G722", 96, 0, 0, 0x1234ABCD, "", NewRandomSequencer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := g722.Packetize(make([]byte, 160), 0)
+	second := g722.Packetize(make([]byte, 160), 0)
+	if first[0].Header.PayloadType != 9 {
+		t.Fatalf("Packetizer should use the static payload type 9, got %d", first[0].Header.PayloadType)
+	}
+	if diff := second[0].Header.Timestamp - first[0].Header.Timestamp; diff != 160 {
+		t.Fatalf("Timestamp should advance by 160, got %d", diff)
+	}
+
+	// the clock rate of MPEG4-GENERIC is the sample rate of its config
+	aac, err := NewCodecPacketizer(1500, "mpeg4-generic", 97, 0, 0, 0x1234ABCD,
+		"streamtype=5;profile-level-id=15;mode=AAC-hbr;config=1210;sizelength=13;indexlength=3;indexdeltalength=3",
+		NewRandomSequencer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := aac.(*packetizer); p.ClockRate != 44100 || p.PayloadType != 97 {
+		t.Fatalf("Packetizer should run at 44.1 kHz, got %d", p.ClockRate)
+	}
+	if _, err := NewCodecPacketizer(1500, "mpeg4-generic", 97, 0, 0, 0x1234ABCD, "", NewRandomSequencer()); err == nil {
+		t.Fatal("NewCodecPacketizer should fail without the clock rate of MPEG4-GENERIC")
+	}
+	if _, err := NewCodecPacketizer(1500, "G722", 96, 16000, 1, 0x1234ABCD, "", NewRandomSequencer()); err == nil {
+		t.Fatal("NewCodecPacketizer should fail on a clock rate G.722 does not run at")
+	}
+
+	// L16 takes the static payload type of its clock rate and channels, a dynamic one otherwise, as L24 does
+	tests := []struct {
+		name        string
+		clockRate   uint32
+		channels    int
+		payloadType uint8
+		sampleSize  int
+	}{
+		{"L16", 0, 0, 11, pcm.L16SampleSize},
+		{"L16", 44100, 1, 11, pcm.L16SampleSize},
+		{"L16", 44100, 2, 10, pcm.L16SampleSize},
+		{"L16", 48000, 2, 96, pcm.L16SampleSize},
+		{"L16", 8000, 1, 96, pcm.L16SampleSize},
+		{"L24", 0, 0, 96, pcm.L24SampleSize},
+		{"L24", 48000, 2, 96, pcm.L24SampleSize},
+		{"L24", 96000, 1, 96, pcm.L24SampleSize},
+	}
+	for _, tt := range tests {
+		lpcm, err := NewCodecPacketizer(1500, tt.name, 96, tt.clockRate, tt.channels, 0x1234ABCD, "", NewRandomSequencer())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := lpcm.(*packetizer)
+		payloader, ok := p.Payloader.(*format.LPCMPayloader)
+		if !ok || p.PayloadType != tt.payloadType || payloader.SampleSize != tt.sampleSize {
+			t.Fatalf("%s/%d/%d should use the payload type %d, got %d", tt.name, tt.clockRate, tt.channels, tt.payloadType, p.PayloadType)
+		}
+		if tt.clockRate != 0 && (p.ClockRate != tt.clockRate || payloader.SampleRate != int(tt.clockRate) || payloader.Channels != tt.channels) {
+			t.Fatalf("%s/%d/%d should configure the payloader, got %d Hz and %d channels",
+				tt.name, tt.clockRate, tt.channels, payloader.SampleRate, payloader.Channels)
+		}
+	}
+	if _, err := NewCodecPacketizer(1500, "audio/unknown", 97, 0, 0, 0x1234ABCD, "", NewRandomSequencer()); err == nil {
+		t.Fatal("NewCodecPacketizer should fail on an unknown codec")
+	}
+}
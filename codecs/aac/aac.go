@@ -0,0 +1,103 @@
+package aac
+
+import "fmt"
+
+// ObjectType is the MPEG-4 Audio Object Type, Table 1.1 in ISO/IEC 14496-3
+type ObjectType uint8
+
+const (
+	ObjectTypeNull          ObjectType = 0
+	ObjectTypeAACMain       ObjectType = 1
+	ObjectTypeAACLC         ObjectType = 2
+	ObjectTypeAACSSR        ObjectType = 3
+	ObjectTypeAACLTP        ObjectType = 4
+	ObjectTypeSBR           ObjectType = 5
+	ObjectTypeAACScalable   ObjectType = 6
+	ObjectTypeTwinVQ        ObjectType = 7
+	ObjectTypeERAACLC       ObjectType = 17
+	ObjectTypeERAACLTP      ObjectType = 19
+	ObjectTypeERAACScalable ObjectType = 20
+	ObjectTypeERTwinVQ      ObjectType = 21
+	ObjectTypeERBSAC        ObjectType = 22
+	ObjectTypeERAACLD       ObjectType = 23
+	ObjectTypePS            ObjectType = 29
+	ObjectTypeEscape        ObjectType = 31
+	ObjectTypeLayer1        ObjectType = 32
+	ObjectTypeLayer2        ObjectType = 33
+	ObjectTypeLayer3        ObjectType = 34
+	ObjectTypeERAACELD      ObjectType = 39
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjectTypeNull:
+		return "Null"
+	case ObjectTypeAACMain:
+		return "AAC Main"
+	case ObjectTypeAACLC:
+		return "AAC LC"
+	case ObjectTypeAACSSR:
+		return "AAC SSR"
+	case ObjectTypeAACLTP:
+		return "AAC LTP"
+	case ObjectTypeSBR:
+		return "SBR"
+	case ObjectTypeAACScalable:
+		return "AAC Scalable"
+	case ObjectTypeTwinVQ:
+		return "TwinVQ"
+	case ObjectTypeERAACLC:
+		return "ER AAC LC"
+	case ObjectTypeERAACLTP:
+		return "ER AAC LTP"
+	case ObjectTypeERAACScalable:
+		return "ER AAC Scalable"
+	case ObjectTypeERTwinVQ:
+		return "ER TwinVQ"
+	case ObjectTypeERBSAC:
+		return "ER BSAC"
+	case ObjectTypeERAACLD:
+		return "ER AAC LD"
+	case ObjectTypePS:
+		return "PS"
+	case ObjectTypeLayer1:
+		return "Layer-1"
+	case ObjectTypeLayer2:
+		return "Layer-2"
+	case ObjectTypeLayer3:
+		return "Layer-3"
+	case ObjectTypeERAACELD:
+		return "ER AAC ELD"
+	default:
+		return fmt.Sprintf("object type %d", uint8(t))
+	}
+}
+
+// SampleRates is the sampling frequency table indexed by samplingFrequencyIndex,
+// Table 1.18 in ISO/IEC 14496-3
+var SampleRates = [...]int{
+	96000, 88200, 64000, 48000, 44100, 32000,
+	24000, 22050, 16000, 12000, 11025, 8000, 7350,
+}
+
+// SampleRateIndex returns the samplingFrequencyIndex of a sample rate,
+// or 0xf if the sample rate must be coded explicitly
+func SampleRateIndex(sampleRate int) uint8 {
+	for i, rate := range SampleRates {
+		if rate == sampleRate {
+			return uint8(i)
+		}
+	}
+	return 0xf
+}
+
+// ChannelCounts is the number of channels indexed by channelConfiguration,
+// Table 1.19 in ISO/IEC 14496-3; 0 means the channels are defined in a
+// program_config_element
+var ChannelCounts = [...]int{0, 1, 2, 3, 4, 5, 6, 8}
+
+const (
+	// 4.5.2.1: an access unit holds 1024 or 960 samples per channel
+	SamplesPerFrame      = 1024
+	SamplesPerShortFrame = 960
+)
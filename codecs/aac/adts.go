@@ -0,0 +1,151 @@
+package aac
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+const (
+	// 1.A.2.2.1: the ADTS fixed and variable headers, plus a CRC if protection_absent is 0
+	ADTSHeaderSize    = 7
+	ADTSHeaderCRCSize = 9
+
+	adtsSyncWord = 0xfff
+)
+
+// ADTSHeader is the header of an Audio Data Transport Stream frame, 1.A.2.2 in ISO/IEC 14496-3
+type ADTSHeader struct {
+	MPEG2                bool // ID, 1 for MPEG-2 AAC, 0 for MPEG-4
+	ProtectionAbsent     bool
+	ObjectType           ObjectType
+	SampleRate           int
+	ChannelConfiguration uint8
+	// FrameLength is the length of the frame, header included
+	FrameLength    int
+	BufferFullness uint16
+	RawDataBlocks  int
+}
+
+// HeaderSize returns the size of the header, CRC included
+func (h ADTSHeader) HeaderSize() int {
+	if h.ProtectionAbsent {
+		return ADTSHeaderSize
+	}
+	return ADTSHeaderCRCSize
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the ADTSHeader this method is called upon
+func (h *ADTSHeader) Unmarshal(buf []byte) error {
+	if len(buf) < ADTSHeaderSize {
+		return fmt.Errorf("buf is not large enough to container ADTS header")
+	}
+	r := bitstream.NewReader(buf)
+	if r.ReadBits(12) != adtsSyncWord {
+		return fmt.Errorf("invalid ADTS syncword")
+	}
+	h.MPEG2 = r.ReadFlag()
+	if layer := r.ReadBits(2); layer != 0 {
+		return fmt.Errorf("invalid ADTS layer %d", layer)
+	}
+	h.ProtectionAbsent = r.ReadFlag()
+	h.ObjectType = ObjectType(r.ReadUint8(2) + 1)
+	index := r.ReadUint8(4)
+	if int(index) >= len(SampleRates) {
+		return fmt.Errorf("invalid ADTS sampling frequency index %d", index)
+	}
+	h.SampleRate = SampleRates[index]
+	r.SkipBits(1) // private_bit
+	h.ChannelConfiguration = r.ReadUint8(3)
+	r.SkipBits(4) // original_copy, home, copyright_identification_bit, copyright_identification_start
+	h.FrameLength = int(r.ReadBits(13))
+	h.BufferFullness = r.ReadUint16(11)
+	h.RawDataBlocks = int(r.ReadBits(2)) + 1
+	if h.FrameLength < h.HeaderSize() {
+		return fmt.Errorf("invalid ADTS frame length %d", h.FrameLength)
+	}
+	return nil
+}
+
+// Marshal serializes the header into bytes.
+func (h ADTSHeader) Marshal() ([]byte, error) {
+	index := SampleRateIndex(h.SampleRate)
+	if index == 0xf {
+		return nil, fmt.Errorf("sample rate %d cannot be coded in ADTS", h.SampleRate)
+	}
+	if h.ObjectType < 1 || h.ObjectType > 4 {
+		return nil, fmt.Errorf("object type %s cannot be coded in ADTS", h.ObjectType)
+	}
+	w := bitstream.NewWriter()
+	w.WriteBits(adtsSyncWord, 12)
+	w.WriteFlag(h.MPEG2)
+	w.WriteBits(0, 2)
+	w.WriteFlag(h.ProtectionAbsent)
+	w.WriteBits(uint64(h.ObjectType-1), 2)
+	w.WriteBits(uint64(index), 4)
+	w.WriteBits(0, 1)
+	w.WriteBits(uint64(h.ChannelConfiguration), 3)
+	w.WriteBits(0, 4)
+	w.WriteBits(uint64(h.FrameLength), 13)
+	w.WriteBits(uint64(h.BufferFullness), 11)
+	w.WriteBits(uint64(h.RawDataBlocks-1), 2)
+	if !h.ProtectionAbsent {
+		w.WriteBits(0, 16)
+	}
+	return w.Bytes(), nil
+}
+
+// Config returns the AudioSpecificConfig equivalent to the header
+func (h ADTSHeader) Config() AudioSpecificConfig {
+	return AudioSpecificConfig{
+		ObjectType:           h.ObjectType,
+		SampleRate:           h.SampleRate,
+		ChannelConfiguration: h.ChannelConfiguration,
+	}
+}
+
+// String helps with debugging by printing ADTS header information in a readable way
+func (h ADTSHeader) String() string {
+	out := "AAC ADTSHeader:\n"
+
+	out += fmt.Sprintf("\tObjectType: %s\n", h.ObjectType)
+	out += fmt.Sprintf("\tSampleRate: %d\n", h.SampleRate)
+	out += fmt.Sprintf("\tChannelConfiguration: %d\n", h.ChannelConfiguration)
+	out += fmt.Sprintf("\tFrameLength: %d\n", h.FrameLength)
+	out += fmt.Sprintf("\tRawDataBlocks: %d\n", h.RawDataBlocks)
+
+	return out
+}
+
+func ParseADTSHeader(buf []byte) (ADTSHeader, error) {
+	var h ADTSHeader
+	err := (&h).Unmarshal(buf)
+	return h, err
+}
+
+// IsADTS reports whether buf starts with an ADTS syncword
+func IsADTS(buf []byte) bool {
+	return len(buf) >= 2 && buf[0] == 0xff && buf[1]&0xf6 == 0xf0
+}
+
+// SplitADTS splits a stream of ADTS frames into raw access units, stripping the headers
+func SplitADTS(buf []byte) (aus [][]byte, header ADTSHeader, err error) {
+	for len(buf) > 0 {
+		h, err := ParseADTSHeader(buf)
+		if err != nil {
+			return nil, header, err
+		}
+		if h.FrameLength > len(buf) {
+			return nil, header, fmt.Errorf("ADTS frame length %d exceeds remaining %d bytes", h.FrameLength, len(buf))
+		}
+		if h.RawDataBlocks != 1 {
+			return nil, header, fmt.Errorf("ADTS frames with %d raw data blocks are not supported", h.RawDataBlocks)
+		}
+		if len(aus) == 0 {
+			header = h
+		}
+		aus = append(aus, buf[h.HeaderSize():h.FrameLength])
+		buf = buf[h.FrameLength:]
+	}
+	return aus, header, nil
+}
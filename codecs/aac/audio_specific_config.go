@@ -0,0 +1,282 @@
+package aac
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+const (
+	// 1.6.5.1: syncExtensionType of the backward compatible SBR signaling
+	syncExtensionTypeSBR = 0x2b7
+	syncExtensionTypePS  = 0x548
+)
+
+// AudioSpecificConfig carries the decoder configuration of an MPEG-4 audio stream,
+// 1.6.2.1 in ISO/IEC 14496-3.  It is the "config" parameter of the SDP fmtp line of
+// mpeg4-generic streams, see rfc3640#section-4.1, hex encoded
+type AudioSpecificConfig struct {
+	ObjectType           ObjectType
+	SampleRate           int
+	ChannelConfiguration uint8
+
+	// SBR and PS signal HE-AAC v1 and v2; ExtensionSampleRate is the output sample rate with SBR
+	SBR                 bool
+	PS                  bool
+	ExtensionSampleRate int
+
+	// GASpecificConfig
+	FrameLengthFlag    bool // 960 instead of 1024 samples per frame
+	DependsOnCoreCoder bool
+	CoreCoderDelay     uint16
+	ExtensionFlag      bool
+}
+
+// Channels returns the number of channels, 0 if they are defined in a program_config_element
+func (c AudioSpecificConfig) Channels() int {
+	if int(c.ChannelConfiguration) < len(ChannelCounts) {
+		return ChannelCounts[c.ChannelConfiguration]
+	}
+	return 0
+}
+
+// SamplesPerFrame returns the number of samples per channel of each access unit, at SampleRate
+func (c AudioSpecificConfig) SamplesPerFrame() int {
+	if c.FrameLengthFlag {
+		return SamplesPerShortFrame
+	}
+	return SamplesPerFrame
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the AudioSpecificConfig this method is called upon
+func (c *AudioSpecificConfig) Unmarshal(buf []byte) error {
+	if buf == nil {
+		return fmt.Errorf("invalid nil AudioSpecificConfig")
+	}
+	r := bitstream.NewReader(buf)
+	if err := c.Decode(r); err != nil {
+		return err
+	}
+	c.decodeSyncExtension(r)
+	return nil
+}
+
+// UnmarshalText parses a hex encoded AudioSpecificConfig, such as the config= fmtp parameter
+func (c *AudioSpecificConfig) UnmarshalText(text []byte) error {
+	buf, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid AudioSpecificConfig %q: %v", text, err)
+	}
+	return c.Unmarshal(buf)
+}
+
+// Decode reads an AudioSpecificConfig from a bit reader, as it may be embedded in other structures
+func (c *AudioSpecificConfig) Decode(r *bitstream.Reader) error {
+	*c = AudioSpecificConfig{}
+	c.ObjectType = readObjectType(r)
+	c.SampleRate = readSampleRate(r)
+	c.ChannelConfiguration = r.ReadUint8(4)
+
+	if c.ObjectType == ObjectTypeSBR || c.ObjectType == ObjectTypePS {
+		// explicit hierarchical signaling of SBR and PS
+		c.SBR = true
+		c.PS = c.ObjectType == ObjectTypePS
+		c.ExtensionSampleRate = readSampleRate(r)
+		c.ObjectType = readObjectType(r)
+		if c.ObjectType == ObjectTypeERBSAC {
+			r.SkipBits(4) // extensionChannelConfiguration
+		}
+	}
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("AudioSpecificConfig is not large enough: %v", err)
+	}
+
+	switch c.ObjectType {
+	case ObjectTypeAACMain, ObjectTypeAACLC, ObjectTypeAACSSR, ObjectTypeAACLTP, ObjectTypeAACScalable, ObjectTypeTwinVQ,
+		ObjectTypeERAACLC, ObjectTypeERAACLTP, ObjectTypeERAACScalable, ObjectTypeERTwinVQ, ObjectTypeERBSAC, ObjectTypeERAACLD:
+		if err := c.decodeGASpecificConfig(r); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported audio object type %s", c.ObjectType)
+	}
+	return nil
+}
+
+// decodeSyncExtension reads the backward compatible signaling of SBR and PS,
+// which may only follow an AudioSpecificConfig whose length is known
+func (c *AudioSpecificConfig) decodeSyncExtension(r *bitstream.Reader) {
+	if c.SBR || r.BitsLeft() < 16 {
+		return
+	}
+	if r.ReadBits(11) != syncExtensionTypeSBR || readObjectType(r) != ObjectTypeSBR {
+		return
+	}
+	sbr := r.ReadFlag()
+	if !sbr {
+		return
+	}
+	rate := readSampleRate(r)
+	ps := false
+	if r.BitsLeft() >= 12 && r.ReadBits(11) == syncExtensionTypePS {
+		ps = r.ReadFlag()
+	}
+	if r.Err() != nil {
+		// only trailing bits
+		return
+	}
+	c.SBR, c.PS, c.ExtensionSampleRate = sbr, ps, rate
+}
+
+func (c *AudioSpecificConfig) decodeGASpecificConfig(r *bitstream.Reader) error {
+	c.FrameLengthFlag = r.ReadFlag()
+	if c.DependsOnCoreCoder = r.ReadFlag(); c.DependsOnCoreCoder {
+		c.CoreCoderDelay = r.ReadUint16(14)
+	}
+	c.ExtensionFlag = r.ReadFlag()
+	if c.ChannelConfiguration == 0 {
+		return fmt.Errorf("program_config_element is not supported")
+	}
+	if c.ObjectType == ObjectTypeAACScalable || c.ObjectType == ObjectTypeERAACScalable {
+		r.SkipBits(3) // layerNr
+	}
+	if c.ExtensionFlag {
+		if c.ObjectType == ObjectTypeERBSAC {
+			r.SkipBits(5 + 11) // numOfSubFrame, layer_length
+		}
+		switch c.ObjectType {
+		case ObjectTypeERAACLC, ObjectTypeERAACLTP, ObjectTypeERAACScalable, ObjectTypeERAACLD:
+			r.SkipBits(3) // aacSectionDataResilienceFlag, aacScalefactorDataResilienceFlag, aacSpectralDataResilienceFlag
+		}
+		r.SkipBits(1) // extensionFlag3
+	}
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("GASpecificConfig is not large enough: %v", err)
+	}
+	return nil
+}
+
+// Marshal serializes the AudioSpecificConfig into bytes.
+func (c AudioSpecificConfig) Marshal() ([]byte, error) {
+	w := bitstream.NewWriter()
+	if err := c.Encode(w); err != nil {
+		return nil, err
+	}
+	w.ByteAlign()
+	return w.Bytes(), nil
+}
+
+// MarshalText serializes the AudioSpecificConfig as hex, for the config= fmtp parameter
+func (c AudioSpecificConfig) MarshalText() ([]byte, error) {
+	b, err := c.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(hex.EncodeToString(b)), nil
+}
+
+// Encode writes the AudioSpecificConfig into a bit writer, with explicit signaling of SBR and PS
+func (c AudioSpecificConfig) Encode(w *bitstream.Writer) error {
+	if c.ChannelConfiguration == 0 || int(c.ChannelConfiguration) >= 16 {
+		return fmt.Errorf("unsupported channel configuration %d", c.ChannelConfiguration)
+	}
+	switch {
+	case c.PS:
+		writeObjectType(w, ObjectTypePS)
+	case c.SBR:
+		writeObjectType(w, ObjectTypeSBR)
+	default:
+		writeObjectType(w, c.ObjectType)
+	}
+	writeSampleRate(w, c.SampleRate)
+	w.WriteBits(uint64(c.ChannelConfiguration), 4)
+	if c.SBR || c.PS {
+		writeSampleRate(w, c.ExtensionSampleRate)
+		writeObjectType(w, c.ObjectType)
+	}
+	w.WriteFlag(c.FrameLengthFlag)
+	w.WriteFlag(c.DependsOnCoreCoder)
+	if c.DependsOnCoreCoder {
+		w.WriteBits(uint64(c.CoreCoderDelay), 14)
+	}
+	w.WriteFlag(c.ExtensionFlag)
+	if c.ObjectType == ObjectTypeAACScalable || c.ObjectType == ObjectTypeERAACScalable {
+		w.WriteBits(0, 3)
+	}
+	if c.ExtensionFlag {
+		if c.ObjectType == ObjectTypeERBSAC {
+			w.WriteBits(0, 5+11)
+		}
+		switch c.ObjectType {
+		case ObjectTypeERAACLC, ObjectTypeERAACLTP, ObjectTypeERAACScalable, ObjectTypeERAACLD:
+			w.WriteBits(0, 3)
+		}
+		w.WriteBits(0, 1)
+	}
+	return nil
+}
+
+// String helps with debugging by printing AudioSpecificConfig information in a readable way
+func (c AudioSpecificConfig) String() string {
+	out := "AAC AudioSpecificConfig:\n"
+
+	out += fmt.Sprintf("\tObjectType: %s\n", c.ObjectType)
+	out += fmt.Sprintf("\tSampleRate: %d\n", c.SampleRate)
+	out += fmt.Sprintf("\tChannelConfiguration: %d\n", c.ChannelConfiguration)
+	out += fmt.Sprintf("\tSBR: %v\n", c.SBR)
+	out += fmt.Sprintf("\tPS: %v\n", c.PS)
+	out += fmt.Sprintf("\tExtensionSampleRate: %d\n", c.ExtensionSampleRate)
+	out += fmt.Sprintf("\tFrameLengthFlag: %v\n", c.FrameLengthFlag)
+
+	return out
+}
+
+func readObjectType(r *bitstream.Reader) ObjectType {
+	t := ObjectType(r.ReadUint8(5))
+	if t == ObjectTypeEscape {
+		t = ObjectTypeLayer1 + ObjectType(r.ReadUint8(6))
+	}
+	return t
+}
+
+func writeObjectType(w *bitstream.Writer, t ObjectType) {
+	if t >= ObjectTypeEscape {
+		w.WriteBits(uint64(ObjectTypeEscape), 5)
+		w.WriteBits(uint64(t-ObjectTypeLayer1), 6)
+		return
+	}
+	w.WriteBits(uint64(t), 5)
+}
+
+func readSampleRate(r *bitstream.Reader) int {
+	index := r.ReadUint8(4)
+	if index == 0xf {
+		return int(r.ReadBits(24))
+	}
+	if int(index) < len(SampleRates) {
+		return SampleRates[index]
+	}
+	return 0
+}
+
+func writeSampleRate(w *bitstream.Writer, sampleRate int) {
+	index := SampleRateIndex(sampleRate)
+	w.WriteBits(uint64(index), 4)
+	if index == 0xf {
+		w.WriteBits(uint64(sampleRate), 24)
+	}
+}
+
+func ParseAudioSpecificConfig(buf []byte) (AudioSpecificConfig, error) {
+	var c AudioSpecificConfig
+	err := (&c).Unmarshal(buf)
+	return c, err
+}
+
+// ParseConfig parses the hex encoded config= parameter of an SDP fmtp line
+func ParseConfig(config string) (AudioSpecificConfig, error) {
+	var c AudioSpecificConfig
+	err := (&c).UnmarshalText([]byte(config))
+	return c, err
+}
@@ -0,0 +1,81 @@
+package aac
+
+import (
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		config     string
+		objectType ObjectType
+		sampleRate int
+		channels   int
+		sbr        bool
+		extRate    int
+	}{
+		{"1210", ObjectTypeAACLC, 44100, 2, false, 0},
+		{"1190", ObjectTypeAACLC, 48000, 2, false, 0},
+		{"1408", ObjectTypeAACLC, 16000, 1, false, 0},
+		// explicit SBR, 24 kHz core and 48 kHz output
+		{"2b118800", ObjectTypeAACLC, 24000, 2, true, 48000},
+		// backward compatible SBR
+		{"139056e5a0", ObjectTypeAACLC, 22050, 2, true, 44100},
+	}
+	for _, test := range tests {
+		c, err := ParseConfig(test.config)
+		if err != nil {
+			t.Fatalf("%s: %v", test.config, err)
+		}
+		if c.ObjectType != test.objectType || c.SampleRate != test.sampleRate || c.Channels() != test.channels ||
+			c.SBR != test.sbr || c.ExtensionSampleRate != test.extRate {
+			t.Fatalf("%s: got %s", test.config, c)
+		}
+		if test.sbr {
+			continue
+		}
+		text, err := c.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(text) != test.config {
+			t.Fatalf("got %s, want %s", text, test.config)
+		}
+	}
+
+	if _, err := ParseConfig("12"); err == nil {
+		t.Fatal("Truncated config should fail")
+	}
+	if _, err := ParseConfig("zz"); err == nil {
+		t.Fatal("Invalid hex should fail")
+	}
+}
+
+func TestSplitADTS(t *testing.T) {
+	h := ADTSHeader{ProtectionAbsent: true, ObjectType: ObjectTypeAACLC, SampleRate: 48000, ChannelConfiguration: 2, BufferFullness: 0x7ff, RawDataBlocks: 1}
+	var stream []byte
+	for _, size := range []int{10, 20} {
+		h.FrameLength = ADTSHeaderSize + size
+		raw, err := h.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream = append(stream, raw...)
+		stream = append(stream, make([]byte, size)...)
+	}
+	if !IsADTS(stream) {
+		t.Fatal("Stream should start with an ADTS header")
+	}
+	aus, header, err := SplitADTS(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aus) != 2 || len(aus[0]) != 10 || len(aus[1]) != 20 {
+		t.Fatal("Access units are split incorrectly")
+	}
+	if header.Config().SampleRate != 48000 || header.Config().Channels() != 2 {
+		t.Fatalf("got %s", header)
+	}
+	if _, _, err = SplitADTS(stream[:len(stream)-1]); err == nil {
+		t.Fatal("Truncated stream should fail")
+	}
+}
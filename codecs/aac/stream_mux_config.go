@@ -0,0 +1,237 @@
+package aac
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// StreamMuxConfig is the LATM multiplex configuration, 1.7.3.1 in ISO/IEC 14496-3.
+// With cpresent=0 it is the "config" parameter of the SDP fmtp line of MP4A-LATM streams,
+// see rfc6416#section-7.3, hex encoded.
+//
+// Only a single program with a single layer is supported, which is what every
+// LATM source carrying a single AAC stream sends.
+type StreamMuxConfig struct {
+	AudioMuxVersion           uint8
+	AllStreamsSameTimeFraming bool
+	// NumSubFrames is the number of extra payloads in each AudioMuxElement,
+	// numSubFrames + 1 access units are multiplexed in each of them
+	NumSubFrames uint8
+
+	Config AudioSpecificConfig
+
+	// FrameLengthType 0 is the only type supported: access units of variable length,
+	// each preceded by its PayloadLengthInfo
+	FrameLengthType    uint8
+	LatmBufferFullness uint8
+
+	OtherDataLenBits uint32
+	CRCCheckPresent  bool
+	CRCCheckSum      uint8
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the StreamMuxConfig this method is called upon
+func (c *StreamMuxConfig) Unmarshal(buf []byte) error {
+	if buf == nil {
+		return fmt.Errorf("invalid nil StreamMuxConfig")
+	}
+	return c.Decode(bitstream.NewReader(buf))
+}
+
+// UnmarshalText parses a hex encoded StreamMuxConfig, such as the config= fmtp parameter
+func (c *StreamMuxConfig) UnmarshalText(text []byte) error {
+	buf, err := hex.DecodeString(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid StreamMuxConfig %q: %v", text, err)
+	}
+	return c.Unmarshal(buf)
+}
+
+// Decode reads a StreamMuxConfig from a bit reader, as it may be embedded in an AudioMuxElement
+func (c *StreamMuxConfig) Decode(r *bitstream.Reader) error {
+	*c = StreamMuxConfig{}
+	c.AudioMuxVersion = r.ReadBit()
+	if c.AudioMuxVersion == 1 {
+		if audioMuxVersionA := r.ReadBit(); audioMuxVersionA != 0 {
+			return fmt.Errorf("audioMuxVersionA %d is not supported", audioMuxVersionA)
+		}
+		readLatmValue(r) // taraBufferFullness
+	}
+	c.AllStreamsSameTimeFraming = r.ReadFlag()
+	c.NumSubFrames = r.ReadUint8(6)
+	if numProgram := r.ReadBits(4); numProgram != 0 {
+		return fmt.Errorf("%d programs are not supported", numProgram+1)
+	}
+	if numLayer := r.ReadBits(3); numLayer != 0 {
+		return fmt.Errorf("%d layers are not supported", numLayer+1)
+	}
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("StreamMuxConfig is not large enough: %v", err)
+	}
+
+	if c.AudioMuxVersion == 0 {
+		if err := c.Config.Decode(r); err != nil {
+			return err
+		}
+	} else {
+		ascLen := int(readLatmValue(r))
+		start := r.Pos()
+		if err := c.Config.Decode(r); err != nil {
+			return err
+		}
+		if fill := ascLen - (r.Pos() - start); fill > 0 {
+			r.SkipBits(fill)
+		}
+	}
+
+	c.FrameLengthType = r.ReadUint8(3)
+	if c.FrameLengthType != 0 {
+		return fmt.Errorf("frameLengthType %d is not supported", c.FrameLengthType)
+	}
+	c.LatmBufferFullness = r.ReadUint8(8)
+
+	if otherDataPresent := r.ReadFlag(); otherDataPresent {
+		if c.AudioMuxVersion == 1 {
+			c.OtherDataLenBits = readLatmValue(r)
+		} else {
+			for {
+				esc := r.ReadFlag()
+				c.OtherDataLenBits = c.OtherDataLenBits<<8 + r.ReadUint32(8)
+				if !esc || r.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+	if c.CRCCheckPresent = r.ReadFlag(); c.CRCCheckPresent {
+		c.CRCCheckSum = r.ReadUint8(8)
+	}
+	if err := r.Err(); err != nil {
+		return fmt.Errorf("StreamMuxConfig is not large enough: %v", err)
+	}
+	return nil
+}
+
+// Marshal serializes the StreamMuxConfig into bytes.
+func (c StreamMuxConfig) Marshal() ([]byte, error) {
+	w := bitstream.NewWriter()
+	if err := c.Encode(w); err != nil {
+		return nil, err
+	}
+	w.ByteAlign()
+	return w.Bytes(), nil
+}
+
+// MarshalText serializes the StreamMuxConfig as hex, for the config= fmtp parameter
+func (c StreamMuxConfig) MarshalText() ([]byte, error) {
+	b, err := c.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(hex.EncodeToString(b)), nil
+}
+
+// Encode writes the StreamMuxConfig into a bit writer
+func (c StreamMuxConfig) Encode(w *bitstream.Writer) error {
+	if c.AudioMuxVersion != 0 {
+		return fmt.Errorf("audioMuxVersion %d is not supported", c.AudioMuxVersion)
+	}
+	if c.FrameLengthType != 0 {
+		return fmt.Errorf("frameLengthType %d is not supported", c.FrameLengthType)
+	}
+	w.WriteBit(c.AudioMuxVersion)
+	w.WriteFlag(c.AllStreamsSameTimeFraming)
+	w.WriteBits(uint64(c.NumSubFrames), 6)
+	w.WriteBits(0, 4) // numProgram
+	w.WriteBits(0, 3) // numLayer
+	if err := c.Config.Encode(w); err != nil {
+		return err
+	}
+	w.WriteBits(uint64(c.FrameLengthType), 3)
+	w.WriteBits(uint64(c.LatmBufferFullness), 8)
+	w.WriteFlag(c.OtherDataLenBits > 0)
+	if c.OtherDataLenBits > 0 {
+		var lens []uint8
+		for v := c.OtherDataLenBits; v > 0; v >>= 8 {
+			lens = append([]uint8{uint8(v)}, lens...)
+		}
+		for i, v := range lens {
+			w.WriteFlag(i < len(lens)-1) // otherDataLenEsc
+			w.WriteBits(uint64(v), 8)
+		}
+	}
+	w.WriteFlag(c.CRCCheckPresent)
+	if c.CRCCheckPresent {
+		w.WriteBits(uint64(c.CRCCheckSum), 8)
+	}
+	return nil
+}
+
+// String helps with debugging by printing StreamMuxConfig information in a readable way
+func (c StreamMuxConfig) String() string {
+	out := "AAC StreamMuxConfig:\n"
+
+	out += fmt.Sprintf("\tAudioMuxVersion: %d\n", c.AudioMuxVersion)
+	out += fmt.Sprintf("\tAllStreamsSameTimeFraming: %v\n", c.AllStreamsSameTimeFraming)
+	out += fmt.Sprintf("\tNumSubFrames: %d\n", c.NumSubFrames)
+	out += fmt.Sprintf("\t%s\n", c.Config)
+	out += fmt.Sprintf("\tFrameLengthType: %d\n", c.FrameLengthType)
+
+	return out
+}
+
+// readLatmValue reads a LatmGetValue() field
+func readLatmValue(r *bitstream.Reader) uint32 {
+	bytesForValue := int(r.ReadBits(2))
+	var v uint32
+	for i := 0; i <= bytesForValue; i++ {
+		v = v<<8 | r.ReadUint32(8)
+	}
+	return v
+}
+
+// NewStreamMuxConfig returns the StreamMuxConfig of a single AAC stream, one access unit per AudioMuxElement
+func NewStreamMuxConfig(config AudioSpecificConfig) StreamMuxConfig {
+	return StreamMuxConfig{
+		AllStreamsSameTimeFraming: true,
+		Config:                    config,
+		LatmBufferFullness:        0xff,
+	}
+}
+
+func ParseStreamMuxConfig(buf []byte) (StreamMuxConfig, error) {
+	var c StreamMuxConfig
+	err := (&c).Unmarshal(buf)
+	return c, err
+}
+
+// ParseLATMConfig parses the hex encoded config= parameter of an MP4A-LATM SDP fmtp line
+func ParseLATMConfig(config string) (StreamMuxConfig, error) {
+	var c StreamMuxConfig
+	err := (&c).UnmarshalText([]byte(config))
+	return c, err
+}
+
+// PayloadLengthInfo encodes the length of an access unit, for frameLengthType 0
+func PayloadLengthInfo(size int) []byte {
+	out := make([]byte, 0, size/255+1)
+	for ; size >= 255; size -= 255 {
+		out = append(out, 255)
+	}
+	return append(out, byte(size))
+}
+
+// ParsePayloadLengthInfo decodes the length of an access unit, for frameLengthType 0,
+// returning the length and the number of bytes consumed
+func ParsePayloadLengthInfo(buf []byte) (size int, n int, err error) {
+	for _, b := range buf {
+		n++
+		size += int(b)
+		if b != 255 {
+			return size, n, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("buf is not large enough to container PayloadLengthInfo")
+}
@@ -0,0 +1,48 @@
+package aac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseLATMConfig(t *testing.T) {
+	c, err := ParseLATMConfig("400023203fc0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NumSubFrames != 0 || !c.AllStreamsSameTimeFraming || c.LatmBufferFullness != 0xff {
+		t.Fatalf("got %s", c)
+	}
+	if c.Config.ObjectType != ObjectTypeAACLC || c.Config.SampleRate != 48000 || c.Config.Channels() != 2 {
+		t.Fatalf("got %s", c.Config)
+	}
+
+	text, err := NewStreamMuxConfig(c.Config).MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "400023203fc0" {
+		t.Fatalf("got %s, want 400023203fc0", text)
+	}
+
+	// 2 programs
+	if _, err = ParseLATMConfig("40102320"); err == nil {
+		t.Fatal("Multiple programs should fail")
+	}
+}
+
+func TestPayloadLengthInfo(t *testing.T) {
+	for _, size := range []int{0, 1, 254, 255, 256, 510, 1000} {
+		info := PayloadLengthInfo(size)
+		got, n, err := ParsePayloadLengthInfo(append(info, 0xaa))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != size || n != len(info) {
+			t.Fatalf("length %d decoded as %d", size, got)
+		}
+	}
+	if _, _, err := ParsePayloadLengthInfo(bytes.Repeat([]byte{255}, 3)); err == nil {
+		t.Fatal("Unterminated PayloadLengthInfo should fail")
+	}
+}
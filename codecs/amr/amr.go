@@ -0,0 +1,64 @@
+package amr
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sample rates and frame duration of AMR and AMR-WB, rfc4867#section-3.1
+const (
+	SampleRate   = 8000
+	WBSampleRate = 16000
+
+	FrameDuration = 20 * time.Millisecond
+)
+
+// Magic numbers of the storage format, rfc4867#section-5
+const (
+	Magic   = "#!AMR\n"
+	WBMagic = "#!AMR-WB\n"
+)
+
+// Frame types, Table 1a of 3GPP TS 26.101 and Table 1a of 3GPP TS 26.201
+const (
+	FrameTypeSID   = 8 // AMR comfort noise
+	WBFrameTypeSID = 9 // AMR-WB comfort noise
+
+	WBFrameTypeSpeechLost = 14
+	FrameTypeNoData       = 15
+)
+
+// frameBits holds the number of speech bits of each frame type of AMR, 3GPP TS 26.101
+var frameBits = [16]int{95, 103, 118, 134, 148, 159, 204, 244, 39, -1, -1, -1, -1, -1, -1, 0}
+
+// wbFrameBits holds the number of speech bits of each frame type of AMR-WB, 3GPP TS 26.201
+var wbFrameBits = [16]int{132, 177, 253, 285, 317, 365, 397, 461, 477, 40, -1, -1, -1, -1, 0, 0}
+
+// FrameBits returns the number of speech bits of a frame type
+func FrameBits(wideband bool, ft uint8) (int, error) {
+	bits := -1
+	if int(ft) < len(frameBits) {
+		bits = frameBits[ft]
+		if wideband {
+			bits = wbFrameBits[ft]
+		}
+	}
+	if bits < 0 {
+		return 0, fmt.Errorf("reserved frame type %d", ft)
+	}
+	return bits, nil
+}
+
+// FrameSize returns the number of bytes of the speech bits of a frame type, padded to an octet boundary
+func FrameSize(wideband bool, ft uint8) (int, error) {
+	bits, err := FrameBits(wideband, ft)
+	return (bits + 7) / 8, err
+}
+
+// SamplesPerFrame returns the number of samples of a 20 ms frame
+func SamplesPerFrame(wideband bool) int {
+	if wideband {
+		return WBSampleRate / 50
+	}
+	return SampleRate / 50
+}
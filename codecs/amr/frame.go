@@ -0,0 +1,96 @@
+package amr
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// The frame header of the storage format, rfc4867#section-5.3
+//
+//	0 1 2 3 4 5 6 7
+//	+-+-+-+-+-+-+-+-+
+//	|P|  FT   |Q|P|P|
+//	+-+-+-+-+-+-+-+-+
+const (
+	FrameHeaderSize = 1
+
+	FrameHeaderFTMask  = 0x78
+	FrameHeaderFTShift = 3
+	FrameHeaderQMask   = 0x04
+)
+
+// Frame represents a speech frame, as in the storage format
+type Frame struct {
+	// FT is the frame type, the codec mode of speech frames
+	FT uint8
+	// Q is unset when the frame is damaged
+	Q bool
+	// Data holds the speech bits, in the order of the storage format, padded to an octet boundary
+	Data []byte
+}
+
+// Marshal serializes the frame into the storage format
+func (f Frame) Marshal(wideband bool) ([]byte, error) {
+	size, err := FrameSize(wideband, f.FT)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Data) != size {
+		return nil, fmt.Errorf("frame type %d has %d bytes, want %d", f.FT, len(f.Data), size)
+	}
+	header := f.FT << FrameHeaderFTShift & FrameHeaderFTMask
+	if f.Q {
+		header |= FrameHeaderQMask
+	}
+	return append([]byte{header}, f.Data...), nil
+}
+
+// Unmarshal parses a frame of the storage format and stores the result in the Frame this method is called upon,
+// it returns the number of bytes read
+func (f *Frame) Unmarshal(wideband bool, buf []byte) (int, error) {
+	if len(buf) < FrameHeaderSize {
+		return 0, fmt.Errorf("buf is not large enough to container frame header")
+	}
+	f.FT = (buf[0] & FrameHeaderFTMask) >> FrameHeaderFTShift
+	f.Q = buf[0]&FrameHeaderQMask != 0
+	size, err := FrameSize(wideband, f.FT)
+	if err != nil {
+		return 0, err
+	}
+	if len(buf) < FrameHeaderSize+size {
+		return 0, fmt.Errorf("buf is not large enough to container frame of type %d", f.FT)
+	}
+	f.Data = buf[FrameHeaderSize : FrameHeaderSize+size]
+	return FrameHeaderSize + size, nil
+}
+
+// String helps with debugging by printing Frame information in a readable way
+func (f Frame) String() string {
+	out := "AMR Frame:\n"
+
+	out += fmt.Sprintf("\tFT: %d\n", f.FT)
+	out += fmt.Sprintf("\tQ: %v\n", f.Q)
+	out += fmt.Sprintf("\tData: %d bytes\n", len(f.Data))
+
+	return out
+}
+
+// ParseFrames parses the concatenated frames of the storage format, after the magic number if any
+func ParseFrames(wideband bool, buf []byte) ([]Frame, error) {
+	magic := Magic
+	if wideband {
+		magic = WBMagic
+	}
+	buf = bytes.TrimPrefix(buf, []byte(magic))
+	var frames []Frame
+	for len(buf) > 0 {
+		var f Frame
+		n, err := (&f).Unmarshal(wideband, buf)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, f)
+		buf = buf[n:]
+	}
+	return frames, nil
+}
@@ -0,0 +1,40 @@
+package amr
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseFrames(t *testing.T) {
+	// AMR 12.2 kbit/s, 244 bits in 31 bytes, then NO_DATA
+	speech := make([]byte, 31)
+	speech[0] = 0xaa
+	buf := append(append([]byte(Magic), 0x3c), speech...)
+	buf = append(buf, 0x7c)
+
+	frames, err := ParseFrames(false, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 || frames[0].FT != 7 || !frames[0].Q || !bytes.Equal(frames[0].Data, speech) {
+		t.Fatalf("Unexpected frames %v", frames)
+	}
+	if frames[1].FT != FrameTypeNoData || len(frames[1].Data) != 0 {
+		t.Fatalf("Unexpected NO_DATA frame %v", frames[1])
+	}
+	raw, err := frames[0].Marshal(false)
+	if err != nil || !bytes.Equal(raw, buf[len(Magic):len(Magic)+32]) {
+		t.Fatalf("Marshal should rebuild the frame, got %x %v", raw, err)
+	}
+
+	// AMR-WB 23.85 kbit/s, 477 bits in 60 bytes
+	if size, _ := FrameSize(true, 8); size != 60 {
+		t.Fatalf("FrameSize should be 60 bytes, got %d", size)
+	}
+	if _, err = ParseFrames(true, []byte{0x44, 0x00}); err == nil {
+		t.Fatal("ParseFrames should reject a truncated frame")
+	}
+	if _, err = ParseFrames(false, []byte{0x4c}); err == nil {
+		t.Fatal("ParseFrames should reject a reserved frame type")
+	}
+}
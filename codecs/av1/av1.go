@@ -0,0 +1,8 @@
+package av1
+
+const (
+	// 4.10.5: leb128() values are at most 8 bytes long
+	MaxLEB128Size = 8
+	// 4.10.5: leb128() values are less than 2^32
+	MaxLEB128Value = 1<<32 - 1
+)
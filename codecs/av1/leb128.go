@@ -0,0 +1,41 @@
+package av1
+
+import "fmt"
+
+// ReadLEB128 reads an unsigned integer in the leb128() format, 4.10.5,
+// and returns it with the number of bytes read
+func ReadLEB128(buf []byte) (uint64, int, error) {
+	var v uint64
+	for i := 0; i < MaxLEB128Size; i++ {
+		if i >= len(buf) {
+			return 0, 0, fmt.Errorf("buf is not large enough to container leb128")
+		}
+		v |= uint64(buf[i]&0x7f) << uint(7*i)
+		if buf[i]&0x80 == 0 {
+			if v > MaxLEB128Value {
+				return 0, 0, fmt.Errorf("leb128 value %d exceeds %d", v, uint64(MaxLEB128Value))
+			}
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("leb128 is longer than %d bytes", MaxLEB128Size)
+}
+
+// AppendLEB128 appends the shortest leb128() encoding of v to buf
+func AppendLEB128(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+// LEB128Size returns the size of the shortest leb128() encoding of v
+func LEB128Size(v uint64) int {
+	size := 1
+	for v >= 0x80 {
+		v >>= 7
+		size++
+	}
+	return size
+}
@@ -0,0 +1,73 @@
+package av1
+
+import (
+	"fmt"
+)
+
+// Obu represents an open bitstream unit, 5.3.1
+type Obu struct {
+	Header  ObuHeader
+	Payload []byte
+}
+
+// Unmarshal parses the OBU at the start of the passed byte slice and stores the result in the Obu this method is called upon
+// An OBU without obu_size extends to the end of buf.  It returns the size of the OBU
+func (o *Obu) Unmarshal(buf []byte) (int, error) {
+	if err := (&o.Header).Unmarshal(buf); err != nil {
+		return 0, err
+	}
+	index := o.Header.MarshalSize()
+	if !o.Header.HasSizeField {
+		o.Payload = buf[index:]
+		return len(buf), nil
+	}
+	size, n, err := ReadLEB128(buf[index:])
+	if err != nil {
+		return 0, err
+	}
+	index += n
+	if uint64(len(buf)-index) < size {
+		return 0, fmt.Errorf("obu_size %d exceeds remaining %d bytes", size, len(buf)-index)
+	}
+	o.Payload = buf[index : index+int(size)]
+	return index + int(size), nil
+}
+
+// Marshal serializes the OBU into bytes, with obu_size if Header.HasSizeField is set
+func (o Obu) Marshal() ([]byte, error) {
+	header, err := o.Header.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, o.MarshalSize())
+	buf = append(buf, header...)
+	if o.Header.HasSizeField {
+		buf = AppendLEB128(buf, uint64(len(o.Payload)))
+	}
+	return append(buf, o.Payload...), nil
+}
+
+// MarshalSize returns the size of the OBU once marshaled.
+func (o Obu) MarshalSize() int {
+	size := o.Header.MarshalSize() + len(o.Payload)
+	if o.Header.HasSizeField {
+		size += LEB128Size(uint64(len(o.Payload)))
+	}
+	return size
+}
+
+// ParseObus splits a sequence of OBUs in the low overhead bitstream format, Annex B excluded,
+// where each OBU but the last one shall have obu_size
+func ParseObus(buf []byte) ([]Obu, error) {
+	var obus []Obu
+	for len(buf) > 0 {
+		var o Obu
+		n, err := (&o).Unmarshal(buf)
+		if err != nil {
+			return nil, err
+		}
+		obus = append(obus, o)
+		buf = buf[n:]
+	}
+	return obus, nil
+}
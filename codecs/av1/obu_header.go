@@ -0,0 +1,107 @@
+package av1
+
+import (
+	"fmt"
+)
+
+// The OBU header, 5.3.2 and 5.3.3 in the AV1 Bitstream & Decoding Process Specification
+//
+//	+---------------+
+//	|0|1|2|3|4|5|6|7|
+//	+-+-+-+-+-+-+-+-+
+//	|F| type  |X|S|-| (REQUIRED)
+//	+-+-+-+-+-+-+-+-+
+//	| T | S | R |  (X)
+//	+-+-+-+-+-+-+-+-+
+//
+// where the extension is |tid(3)|sid(2)|reserved(3)|
+const (
+	ObuHeaderByteIndex = 0
+
+	ObuForbiddenBitMask   = 0x80
+	ObuTypeMask           = 0x78
+	ObuTypeOffset         = 3
+	ObuExtensionFlagMask  = 0x04
+	ObuHasSizeFieldMask   = 0x02
+	ObuTemporalIDMask     = 0xe0
+	ObuTemporalIDOffset   = 5
+	ObuSpatialIDMask      = 0x18
+	ObuSpatialIDOffset    = 3
+	ObuExtensionByteIndex = 1
+)
+
+// ObuHeader represents the OBU header
+type ObuHeader struct {
+	Type          ObuType
+	ExtensionFlag bool
+	HasSizeField  bool
+	// TemporalID and SpatialID are only present with the extension
+	TemporalID uint8
+	SpatialID  uint8
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the ObuHeader this method is called upon
+func (h *ObuHeader) Unmarshal(buf []byte) error {
+	if len(buf) < 1 {
+		return fmt.Errorf("buf is not large enough to container header")
+	}
+	if buf[0]&ObuForbiddenBitMask != 0 {
+		return fmt.Errorf("invalid obu_forbidden_bit")
+	}
+	*h = ObuHeader{
+		Type:          ObuType((buf[0] & ObuTypeMask) >> ObuTypeOffset),
+		ExtensionFlag: buf[0]&ObuExtensionFlagMask != 0,
+		HasSizeField:  buf[0]&ObuHasSizeFieldMask != 0,
+	}
+	if h.ExtensionFlag {
+		if len(buf) < 2 {
+			return fmt.Errorf("buf is not large enough to container extension header")
+		}
+		h.TemporalID = (buf[ObuExtensionByteIndex] & ObuTemporalIDMask) >> ObuTemporalIDOffset
+		h.SpatialID = (buf[ObuExtensionByteIndex] & ObuSpatialIDMask) >> ObuSpatialIDOffset
+	}
+	return nil
+}
+
+// Marshal serializes the header into bytes.
+func (h ObuHeader) Marshal() ([]byte, error) {
+	buf := make([]byte, h.MarshalSize())
+	buf[0] = byte(h.Type<<ObuTypeOffset) & ObuTypeMask
+	if h.HasSizeField {
+		buf[0] |= ObuHasSizeFieldMask
+	}
+	if h.ExtensionFlag {
+		buf[0] |= ObuExtensionFlagMask
+		buf[ObuExtensionByteIndex] = h.TemporalID<<ObuTemporalIDOffset&ObuTemporalIDMask |
+			h.SpatialID<<ObuSpatialIDOffset&ObuSpatialIDMask
+	}
+	return buf, nil
+}
+
+// MarshalSize returns the size of the header once marshaled.
+func (h ObuHeader) MarshalSize() int {
+	if h.ExtensionFlag {
+		return 2
+	}
+	return 1
+}
+
+// String helps with debugging by printing ObuHeader information in a readable way
+func (h ObuHeader) String() string {
+	out := "AV1 ObuHeader:\n"
+
+	out += fmt.Sprintf("\tType: %s\n", h.Type)
+	out += fmt.Sprintf("\tHasSizeField: %v\n", h.HasSizeField)
+	if h.ExtensionFlag {
+		out += fmt.Sprintf("\tTemporalID: %d\n", h.TemporalID)
+		out += fmt.Sprintf("\tSpatialID: %d\n", h.SpatialID)
+	}
+
+	return out
+}
+
+func ParseObuHeader(obu []byte) (ObuHeader, error) {
+	var h ObuHeader
+	err := (&h).Unmarshal(obu[ObuHeaderByteIndex:])
+	return h, err
+}
@@ -0,0 +1,74 @@
+package av1
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLEB128(t *testing.T) {
+	for _, v := range []uint64{0, 1, 0x7f, 0x80, 0x3fff, 0x4000, MaxLEB128Value} {
+		raw := AppendLEB128(nil, v)
+		if len(raw) != LEB128Size(v) {
+			t.Fatalf("%d: LEB128Size %d, AppendLEB128 %d", v, LEB128Size(v), len(raw))
+		}
+		got, n, err := ReadLEB128(raw)
+		if err != nil {
+			t.Fatalf("%d: ReadLEB128 failed: %v", v, err)
+		}
+		if got != v || n != len(raw) {
+			t.Fatalf("%d: got %d in %d bytes", v, got, n)
+		}
+	}
+
+	// non-minimal encoding
+	if v, n, err := ReadLEB128([]byte{0x81, 0x80, 0x00}); err != nil || v != 1 || n != 3 {
+		t.Fatal("ReadLEB128 should accept a padded encoding")
+	}
+	if _, _, err := ReadLEB128([]byte{0x80}); err == nil {
+		t.Fatal("ReadLEB128 accepted a truncated value")
+	}
+	if _, _, err := ReadLEB128(bytes.Repeat([]byte{0x80}, 9)); err == nil {
+		t.Fatal("ReadLEB128 accepted more than 8 bytes")
+	}
+}
+
+func TestParseObus(t *testing.T) {
+	obus := []Obu{
+		{Header: ObuHeader{Type: ObuTypeTemporalDelimiter, HasSizeField: true}},
+		{Header: ObuHeader{Type: ObuTypeSequenceHeader, HasSizeField: true}, Payload: []byte{0x00, 0x00, 0x00}},
+		{Header: ObuHeader{Type: ObuTypeFrame, ExtensionFlag: true, TemporalID: 2, SpatialID: 1, HasSizeField: true}, Payload: bytes.Repeat([]byte{0x42}, 200)},
+		// the last OBU may omit obu_size
+		{Header: ObuHeader{Type: ObuTypeMetadata}, Payload: []byte{0x01, 0x02}},
+	}
+	var stream []byte
+	for _, o := range obus {
+		raw, err := o.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(raw) != o.MarshalSize() {
+			t.Fatalf("MarshalSize %d, Marshal %d", o.MarshalSize(), len(raw))
+		}
+		stream = append(stream, raw...)
+	}
+
+	got, err := ParseObus(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(obus) {
+		t.Fatalf("ParseObus should return %d OBUs, got %d", len(obus), len(got))
+	}
+	for i := range obus {
+		if got[i].Header != obus[i].Header || !bytes.Equal(got[i].Payload, obus[i].Payload) {
+			t.Fatalf("#%d: got %s, want %s", i, got[i].Header, obus[i].Header)
+		}
+	}
+
+	if _, err = ParseObus(stream[:10]); err == nil {
+		t.Fatal("ParseObus accepted a truncated OBU")
+	}
+	if _, err = ParseObus([]byte{0x80}); err == nil {
+		t.Fatal("ParseObus accepted the forbidden bit")
+	}
+}
@@ -0,0 +1,44 @@
+package av1
+
+import "fmt"
+
+// ObuType is the obu_type of an OBU header, 6.2.2 in the AV1 Bitstream & Decoding Process Specification
+type ObuType uint8
+
+const (
+	ObuTypeReserved0            ObuType = iota // 0
+	ObuTypeSequenceHeader                      // 1
+	ObuTypeTemporalDelimiter                   // 2
+	ObuTypeFrameHeader                         // 3
+	ObuTypeTileGroup                           // 4
+	ObuTypeMetadata                            // 5
+	ObuTypeFrame                               // 6
+	ObuTypeRedundantFrameHeader                // 7
+	ObuTypeTileList                            // 8
+	ObuTypePadding              ObuType = 15   // 15
+)
+
+func (t ObuType) String() string {
+	switch t {
+	case ObuTypeSequenceHeader:
+		return "OBU_SEQUENCE_HEADER"
+	case ObuTypeTemporalDelimiter:
+		return "OBU_TEMPORAL_DELIMITER"
+	case ObuTypeFrameHeader:
+		return "OBU_FRAME_HEADER"
+	case ObuTypeTileGroup:
+		return "OBU_TILE_GROUP"
+	case ObuTypeMetadata:
+		return "OBU_METADATA"
+	case ObuTypeFrame:
+		return "OBU_FRAME"
+	case ObuTypeRedundantFrameHeader:
+		return "OBU_REDUNDANT_FRAME_HEADER"
+	case ObuTypeTileList:
+		return "OBU_TILE_LIST"
+	case ObuTypePadding:
+		return "OBU_PADDING"
+	default:
+		return fmt.Sprintf("OBU_RESERVED_%d", uint8(t))
+	}
+}
@@ -0,0 +1,92 @@
+package bitstream
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestReaderWriter(t *testing.T) {
+	values := []struct {
+		v uint64
+		n int
+	}{
+		{1, 1}, {0x5, 3}, {0x1abc, 13}, {0, 0}, {0xdeadbeefcafe, 48}, {0x3, 2}, {0x7f, 7},
+	}
+
+	w := NewWriter()
+	for _, value := range values {
+		w.WriteBits(value.v, value.n)
+	}
+	w.ByteAlign()
+	w.WriteBytes([]byte{0x12, 0x34})
+	if w.Len()%8 != 0 {
+		t.Fatal("Writer should be byte aligned")
+	}
+
+	r := NewReader(w.Bytes())
+	for i, value := range values {
+		if v := r.ReadBits(value.n); v != value.v {
+			t.Fatalf("#%d: got %x, want %x", i, v, value.v)
+		}
+	}
+	r.ByteAlign()
+	if b := r.ReadBytes(2); !bytes.Equal(b, []byte{0x12, 0x34}) {
+		t.Fatalf("got %x, want 1234", b)
+	}
+	if r.Err() != nil || r.BitsLeft() != 0 {
+		t.Fatal("Reader should be at the end without error")
+	}
+
+	if r.ReadFlag() || r.Err() != io.ErrUnexpectedEOF {
+		t.Fatal("Reading past the end should fail")
+	}
+}
+
+func TestExpGolomb(t *testing.T) {
+	w := NewWriter()
+	for _, v := range []uint32{0, 1, 2, 3, 7, 255, 0xfffffffe} {
+		w.WriteUE(v)
+	}
+	for _, v := range []int32{0, 1, -1, 2, -2, 1000, -1000} {
+		w.WriteSE(v)
+	}
+	w.WriteTrailingBits()
+
+	// 0 is 1, 1 is 010, 2 is 011, 3 is 00100, 7 is 0001000
+	if !bytes.Equal(w.Bytes()[:2], []byte{0xa6, 0x41}) {
+		t.Fatalf("Writer should write ue(v), got %x", w.Bytes()[:2])
+	}
+	r := NewReader(w.Bytes())
+	for _, v := range []uint32{0, 1, 2, 3, 7, 255, 0xfffffffe} {
+		if got := r.ReadUE(); got != v {
+			t.Fatalf("ue(v): got %d, want %d", got, v)
+		}
+	}
+	for _, v := range []int32{0, 1, -1, 2, -2, 1000, -1000} {
+		if got := r.ReadSE(); got != v {
+			t.Fatalf("se(v): got %d, want %d", got, v)
+		}
+	}
+	if r.MoreRBSPData() || r.Err() != nil {
+		t.Fatal("Reader should be at the trailing bits")
+	}
+	if r := NewReader([]byte{0x00, 0x00, 0x00, 0x00, 0x00}); r.ReadUE() != 0 || r.Err() == nil {
+		t.Fatal("Overlong ue(v) should fail")
+	}
+}
+
+func TestEmulationPrevention(t *testing.T) {
+	rbsp := []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00}
+	ebsp := AddEmulationPrevention(rbsp)
+	if !bytes.Equal(ebsp, []byte{0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x03, 0x00, 0x00}) {
+		t.Fatalf("AddEmulationPrevention should escape the start code emulations, got %x", ebsp)
+	}
+	if got := RemoveEmulationPrevention(ebsp); !bytes.Equal(got, rbsp) {
+		t.Fatalf("RemoveEmulationPrevention should restore the RBSP, got %x", got)
+	}
+	plain := []byte{0x67, 0x42, 0x00, 0x1e}
+	if got := RemoveEmulationPrevention(plain); !bytes.Equal(got, plain) {
+		t.Fatal("RemoveEmulationPrevention should keep data without emulation prevention bytes")
+	}
+}
@@ -0,0 +1,50 @@
+package bitstream
+
+// Emulation prevention, 7.4.1 in T-REC-H.264 and T-REC-H.265: within a NAL unit, the sequences 0x000000 to 0x000003
+// are escaped by inserting an emulation_prevention_three_byte 0x03 after two zero bytes
+
+// RemoveEmulationPrevention returns the RBSP of a NAL unit payload, without its emulation prevention bytes
+func RemoveEmulationPrevention(ebsp []byte) []byte {
+	var rbsp []byte
+	zeros := 0
+	for i, b := range ebsp {
+		if zeros >= 2 && b == 0x03 {
+			if rbsp == nil {
+				rbsp = append(make([]byte, 0, len(ebsp)), ebsp[:i]...)
+			}
+			zeros = 0
+			continue
+		}
+		if rbsp != nil {
+			rbsp = append(rbsp, b)
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	if rbsp == nil {
+		return ebsp
+	}
+	return rbsp
+}
+
+// AddEmulationPrevention returns the payload of a NAL unit of an RBSP, with the emulation prevention bytes it needs
+func AddEmulationPrevention(rbsp []byte) []byte {
+	ebsp := make([]byte, 0, len(rbsp)+len(rbsp)/64)
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 0x03 {
+			ebsp = append(ebsp, 0x03)
+			zeros = 0
+		}
+		ebsp = append(ebsp, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return ebsp
+}
@@ -0,0 +1,70 @@
+package bitstream
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Exp-Golomb codes, 9.1 in T-REC-H.264 and T-REC-H.265
+
+// ReadUE reads an unsigned integer Exp-Golomb-coded, ue(v)
+func (r *Reader) ReadUE() uint32 {
+	leadingZeroBits := 0
+	for !r.ReadFlag() {
+		if r.err != nil {
+			return 0
+		}
+		leadingZeroBits++
+		if leadingZeroBits > 31 {
+			r.err = fmt.Errorf("exp-Golomb code of more than 32 bits")
+			return 0
+		}
+	}
+	return uint32(1<<uint(leadingZeroBits) - 1 + r.ReadBits(leadingZeroBits))
+}
+
+// ReadSE reads a signed integer Exp-Golomb-coded, se(v)
+func (r *Reader) ReadSE() int32 {
+	k := r.ReadUE()
+	if k%2 == 0 {
+		return -int32(k / 2)
+	}
+	return int32(k/2 + 1)
+}
+
+// MoreRBSPData reports whether data remains before the rbsp_trailing_bits, the last bit set and the zero bits after it,
+// more_rbsp_data() in 7.2 of T-REC-H.264
+func (r *Reader) MoreRBSPData() bool {
+	if r.err != nil {
+		return false
+	}
+	for i := len(r.buf) - 1; i >= 0; i-- {
+		if r.buf[i] != 0 {
+			last := i*8 + 7 - bits.TrailingZeros8(r.buf[i])
+			return r.pos < last
+		}
+	}
+	return false
+}
+
+// WriteUE writes an unsigned integer Exp-Golomb-coded, ue(v)
+func (w *Writer) WriteUE(v uint32) {
+	n := bits.Len64(uint64(v) + 1)
+	w.WriteBits(0, n-1)
+	w.WriteBits(uint64(v)+1, n)
+}
+
+// WriteSE writes a signed integer Exp-Golomb-coded, se(v)
+func (w *Writer) WriteSE(v int32) {
+	if v > 0 {
+		w.WriteUE(uint32(2*int64(v) - 1))
+		return
+	}
+	w.WriteUE(uint32(-2 * int64(v)))
+}
+
+// WriteTrailingBits writes the rbsp_trailing_bits: a bit set, then zero bits up to the next byte boundary
+func (w *Writer) WriteTrailingBits() {
+	w.WriteBit(1)
+	w.ByteAlign()
+}
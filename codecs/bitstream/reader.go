@@ -0,0 +1,136 @@
+package bitstream
+
+import "io"
+
+// Reader reads a byte slice bit by bit, most significant bit first.
+//
+// Errors are sticky: once a read runs past the end of the data, every
+// following read returns 0 and Err reports io.ErrUnexpectedEOF, so that
+// a syntax structure can be parsed entirely before checking for errors.
+type Reader struct {
+	buf []byte
+	pos int // in bits
+	err error
+}
+
+// NewReader returns a new Reader reading from buf
+func NewReader(buf []byte) *Reader {
+	return &Reader{buf: buf}
+}
+
+// Err returns the first error met while reading
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Pos returns the number of bits read so far
+func (r *Reader) Pos() int {
+	return r.pos
+}
+
+// BitsLeft returns the number of bits not read yet
+func (r *Reader) BitsLeft() int {
+	return len(r.buf)*8 - r.pos
+}
+
+// ByteAligned reports whether the next bit to read is the first bit of a byte
+func (r *Reader) ByteAligned() bool {
+	return r.pos%8 == 0
+}
+
+// ReadBits reads n bits, n in [0, 64], as an unsigned integer
+func (r *Reader) ReadBits(n int) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if n < 0 || n > 64 || n > r.BitsLeft() {
+		r.err = io.ErrUnexpectedEOF
+		r.pos = len(r.buf) * 8
+		return 0
+	}
+	var v uint64
+	for n > 0 {
+		byteIndex := r.pos / 8
+		bitOffset := r.pos % 8
+		// read as many bits as possible from the current byte
+		take := 8 - bitOffset
+		if take > n {
+			take = n
+		}
+		b := uint64(r.buf[byteIndex]>>uint(8-bitOffset-take)) & (1<<uint(take) - 1)
+		v = v<<uint(take) | b
+		r.pos += take
+		n -= take
+	}
+	return v
+}
+
+// ReadBit reads a single bit
+func (r *Reader) ReadBit() uint8 {
+	return uint8(r.ReadBits(1))
+}
+
+// ReadFlag reads a single bit as a boolean
+func (r *Reader) ReadFlag() bool {
+	return r.ReadBits(1) != 0
+}
+
+// ReadUint8 reads n bits, n in [0, 8]
+func (r *Reader) ReadUint8(n int) uint8 {
+	return uint8(r.ReadBits(n))
+}
+
+// ReadUint16 reads n bits, n in [0, 16]
+func (r *Reader) ReadUint16(n int) uint16 {
+	return uint16(r.ReadBits(n))
+}
+
+// ReadUint32 reads n bits, n in [0, 32]
+func (r *Reader) ReadUint32(n int) uint32 {
+	return uint32(r.ReadBits(n))
+}
+
+// SkipBits skips n bits
+func (r *Reader) SkipBits(n int) {
+	if r.err != nil {
+		return
+	}
+	if n < 0 || n > r.BitsLeft() {
+		r.err = io.ErrUnexpectedEOF
+		r.pos = len(r.buf) * 8
+		return
+	}
+	r.pos += n
+}
+
+// ByteAlign skips the bits up to the next byte boundary
+func (r *Reader) ByteAlign() {
+	if r.pos%8 != 0 {
+		r.SkipBits(8 - r.pos%8)
+	}
+}
+
+// ReadBytes reads n bytes, the reader must be byte aligned
+func (r *Reader) ReadBytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if r.pos%8 != 0 {
+		out := make([]byte, 0, n)
+		for i := 0; i < n; i++ {
+			out = append(out, r.ReadUint8(8))
+		}
+		if r.err != nil {
+			return nil
+		}
+		return out
+	}
+	if n < 0 || n*8 > r.BitsLeft() {
+		r.err = io.ErrUnexpectedEOF
+		r.pos = len(r.buf) * 8
+		return nil
+	}
+	out := r.buf[r.pos/8 : r.pos/8+n]
+	r.pos += n * 8
+	return out
+}
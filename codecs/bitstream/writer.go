@@ -0,0 +1,79 @@
+package bitstream
+
+// Writer writes bits into a growing byte slice, most significant bit first
+type Writer struct {
+	buf []byte
+	pos int // in bits
+}
+
+// NewWriter returns a new Writer
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Len returns the number of bits written so far
+func (w *Writer) Len() int {
+	return w.pos
+}
+
+// ByteAligned reports whether the next bit to write is the first bit of a byte
+func (w *Writer) ByteAligned() bool {
+	return w.pos%8 == 0
+}
+
+// WriteBits writes the n least significant bits of v, n in [0, 64]
+func (w *Writer) WriteBits(v uint64, n int) {
+	for n > 0 {
+		if w.pos%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		bitOffset := w.pos % 8
+		// write as many bits as possible into the current byte
+		take := 8 - bitOffset
+		if take > n {
+			take = n
+		}
+		b := byte(v>>uint(n-take)) & (1<<uint(take) - 1)
+		w.buf[len(w.buf)-1] |= b << uint(8-bitOffset-take)
+		w.pos += take
+		n -= take
+	}
+}
+
+// WriteBit writes a single bit
+func (w *Writer) WriteBit(b uint8) {
+	w.WriteBits(uint64(b), 1)
+}
+
+// WriteFlag writes a boolean as a single bit
+func (w *Writer) WriteFlag(f bool) {
+	if f {
+		w.WriteBits(1, 1)
+		return
+	}
+	w.WriteBits(0, 1)
+}
+
+// WriteBytes writes whole bytes
+func (w *Writer) WriteBytes(b []byte) {
+	if w.pos%8 == 0 {
+		w.buf = append(w.buf, b...)
+		w.pos += len(b) * 8
+		return
+	}
+	for _, v := range b {
+		w.WriteBits(uint64(v), 8)
+	}
+}
+
+// ByteAlign writes zero bits up to the next byte boundary
+func (w *Writer) ByteAlign() {
+	if w.pos%8 != 0 {
+		w.WriteBits(0, 8-w.pos%8)
+	}
+}
+
+// Bytes returns the bytes written so far, the last byte being padded with zero bits
+func (w *Writer) Bytes() []byte {
+	return w.buf
+}
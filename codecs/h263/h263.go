@@ -0,0 +1,101 @@
+package h263
+
+import "fmt"
+
+// Start codes, 5.1.1 and 5.2.2 in ITU-T H.263: the picture start code is 22 bits, 0000 0000 0000 0000 1000 00,
+// the GOB start code 17 bits, 0000 0000 0000 0000 1, followed by a non-zero group number
+const (
+	PictureStartCode     = 0x20
+	PictureStartCodeBits = 22
+	StartCodePrefixSize  = 2
+)
+
+// IsPictureStartCode reports whether buf starts with a byte-aligned picture start code
+func IsPictureStartCode(buf []byte) bool {
+	return len(buf) >= 3 && buf[0] == 0 && buf[1] == 0 && buf[2]>>2 == PictureStartCode
+}
+
+// IsStartCode reports whether buf starts with a byte-aligned picture, GOB, or slice start code
+func IsStartCode(buf []byte) bool {
+	return len(buf) >= 3 && buf[0] == 0 && buf[1] == 0 && buf[2]&0x80 != 0
+}
+
+// SourceFormat is the picture format, bits 6-8 of PTYPE or 1-3 of OPPTYPE in ITU-T H.263
+type SourceFormat uint8
+
+const (
+	SourceFormatSubQCIF  SourceFormat = 1
+	SourceFormatQCIF     SourceFormat = 2
+	SourceFormatCIF      SourceFormat = 3
+	SourceFormat4CIF     SourceFormat = 4
+	SourceFormat16CIF    SourceFormat = 5
+	SourceFormatCustom   SourceFormat = 6
+	SourceFormatExtended SourceFormat = 7 // PTYPE only: PLUSPTYPE follows
+)
+
+// Dimensions returns the width and height of the standardized formats
+func (f SourceFormat) Dimensions() (width, height int) {
+	switch f {
+	case SourceFormatSubQCIF:
+		return 128, 96
+	case SourceFormatQCIF:
+		return 176, 144
+	case SourceFormatCIF:
+		return 352, 288
+	case SourceFormat4CIF:
+		return 704, 576
+	case SourceFormat16CIF:
+		return 1408, 1152
+	}
+	return 0, 0
+}
+
+func (f SourceFormat) String() string {
+	switch f {
+	case SourceFormatSubQCIF:
+		return "sub-QCIF"
+	case SourceFormatQCIF:
+		return "QCIF"
+	case SourceFormatCIF:
+		return "CIF"
+	case SourceFormat4CIF:
+		return "4CIF"
+	case SourceFormat16CIF:
+		return "16CIF"
+	case SourceFormatCustom:
+		return "custom"
+	case SourceFormatExtended:
+		return "extended PTYPE"
+	}
+	return fmt.Sprintf("reserved source format %d", uint8(f))
+}
+
+// PictureType is the picture coding type, bit 9 of PTYPE, or the picture type code of MPPTYPE in ITU-T H.263
+type PictureType uint8
+
+const (
+	PictureTypeI          PictureType = 0
+	PictureTypeP          PictureType = 1
+	PictureTypeImprovedPB PictureType = 2
+	PictureTypeB          PictureType = 3
+	PictureTypeEI         PictureType = 4
+	PictureTypeEP         PictureType = 5
+)
+
+func (t PictureType) String() string {
+	switch t {
+	case PictureTypeI:
+		return "I"
+	case PictureTypeP:
+		return "P"
+	case PictureTypeImprovedPB:
+		return "improved PB"
+	case PictureTypeB:
+		return "B"
+	case PictureTypeEI:
+		return "EI"
+	case PictureTypeEP:
+		return "EP"
+	}
+	return fmt.Sprintf("reserved picture type %d", uint8(t))
+}
@@ -0,0 +1,120 @@
+package h263
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// PictureHeader represents the start of the picture layer, 5.1 in ITU-T H.263,
+// up to the picture format: PSC, TR, PTYPE, and PLUSPTYPE, CPM, PSBI and CPFMT of H.263 version 2
+type PictureHeader struct {
+	// TR is the temporal reference, the 8 least significant bits
+	TR uint8
+	// SourceFormat is the picture format, SourceFormatCustom with the dimensions of CPFMT
+	SourceFormat SourceFormat
+	PictureType  PictureType
+	// PlusType is set when the picture uses PLUSPTYPE, H.263 version 2
+	PlusType bool
+	// UFEP is the update full extended PTYPE of PLUSPTYPE, 1 when OPPTYPE, with the source format, is present
+	UFEP uint8
+	// Width and Height are the dimensions of the picture, 0 when a PLUSPTYPE picture does not repeat them
+	Width  int
+	Height int
+}
+
+// Decode reads the picture header, from the picture start code
+func (h *PictureHeader) Decode(r *bitstream.Reader) error {
+	*h = PictureHeader{}
+	if r.ReadBits(PictureStartCodeBits) != PictureStartCode {
+		return fmt.Errorf("missing picture start code")
+	}
+	h.TR = r.ReadUint8(8)
+	// PTYPE: the first bit is 1 to avoid start code emulation, the second 0 to distinguish from H.261
+	if r.ReadBit() != 1 || r.ReadBit() != 0 {
+		if r.Err() != nil {
+			return r.Err()
+		}
+		return fmt.Errorf("invalid PTYPE marker bits")
+	}
+	r.SkipBits(3) // split screen, document camera, freeze picture release
+	h.SourceFormat = SourceFormat(r.ReadUint8(3))
+	switch h.SourceFormat {
+	case SourceFormatExtended:
+		if err := h.decodePlusType(r); err != nil {
+			return err
+		}
+	case SourceFormatSubQCIF, SourceFormatQCIF, SourceFormatCIF, SourceFormat4CIF, SourceFormat16CIF:
+		h.PictureType = PictureType(r.ReadBit())
+		h.Width, h.Height = h.SourceFormat.Dimensions()
+	default:
+		return fmt.Errorf("invalid %s", h.SourceFormat)
+	}
+	return r.Err()
+}
+
+// decodePlusType reads PLUSPTYPE, CPM, PSBI and CPFMT, 5.1.4 to 5.1.6 in ITU-T H.263
+func (h *PictureHeader) decodePlusType(r *bitstream.Reader) error {
+	h.PlusType = true
+	h.UFEP = r.ReadUint8(3)
+	h.SourceFormat = 0
+	switch h.UFEP {
+	case 0:
+	case 1:
+		// OPPTYPE: the source format, then the optional modes and 1 0 0 0
+		h.SourceFormat = SourceFormat(r.ReadUint8(3))
+		r.SkipBits(15)
+		if h.SourceFormat == 0 || h.SourceFormat == SourceFormatExtended {
+			return fmt.Errorf("invalid %s in OPPTYPE", h.SourceFormat)
+		}
+	default:
+		return fmt.Errorf("invalid UFEP %d", h.UFEP)
+	}
+	// MPPTYPE: the picture type code, RPR, RRU, rounding type, then 0 0 1
+	h.PictureType = PictureType(r.ReadUint8(3))
+	r.SkipBits(6)
+	if r.ReadFlag() { // CPM
+		r.SkipBits(2) // PSBI
+	}
+	if h.SourceFormat == SourceFormatCustom {
+		// CPFMT: pixel aspect ratio, picture width indication, 1, picture height indication
+		r.SkipBits(4)
+		h.Width = (int(r.ReadUint16(9)) + 1) * 4
+		r.SkipBits(1)
+		h.Height = int(r.ReadUint16(9)) * 4
+	} else {
+		h.Width, h.Height = h.SourceFormat.Dimensions()
+	}
+	return r.Err()
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the PictureHeader this method is called upon
+func (h *PictureHeader) Unmarshal(buf []byte) error {
+	return h.Decode(bitstream.NewReader(buf))
+}
+
+// IsIntra reports whether the picture is coded without reference to other pictures
+func (h PictureHeader) IsIntra() bool {
+	return h.PictureType == PictureTypeI || h.PictureType == PictureTypeEI
+}
+
+// String helps with debugging by printing PictureHeader information in a readable way
+func (h PictureHeader) String() string {
+	out := "H263 PictureHeader:\n"
+
+	out += fmt.Sprintf("\tTR: %d\n", h.TR)
+	out += fmt.Sprintf("\tSourceFormat: %s\n", h.SourceFormat)
+	out += fmt.Sprintf("\tPictureType: %s\n", h.PictureType)
+	out += fmt.Sprintf("\tPlusType: %v\n", h.PlusType)
+	out += fmt.Sprintf("\tUFEP: %d\n", h.UFEP)
+	out += fmt.Sprintf("\tWidth: %d\n", h.Width)
+	out += fmt.Sprintf("\tHeight: %d\n", h.Height)
+
+	return out
+}
+
+func ParsePictureHeader(buf []byte) (PictureHeader, error) {
+	var h PictureHeader
+	err := (&h).Unmarshal(buf)
+	return h, err
+}
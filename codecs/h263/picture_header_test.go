@@ -0,0 +1,66 @@
+package h263
+
+import (
+	"testing"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+func TestPictureHeader_Unmarshal(t *testing.T) {
+	// baseline QCIF P picture
+	w := bitstream.NewWriter()
+	w.WriteBits(PictureStartCode, PictureStartCodeBits)
+	w.WriteBits(5, 8) // TR
+	w.WriteBits(2, 2) // 1 0
+	w.WriteBits(0, 3) // split screen, document camera, freeze picture release
+	w.WriteBits(2, 3) // QCIF
+	w.WriteBits(1, 1) // INTER
+	w.WriteBits(0, 4) // UMV, SAC, AP, PB
+	w.WriteBits(8, 5) // PQUANT
+	w.WriteBits(0, 2) // CPM, PEI
+	w.ByteAlign()
+	buf := w.Bytes()
+	if !IsPictureStartCode(buf) || !IsStartCode(buf) {
+		t.Fatal("Picture should start with a picture start code")
+	}
+
+	h, err := ParsePictureHeader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.TR != 5 || h.SourceFormat != SourceFormatQCIF || h.PictureType != PictureTypeP || h.PlusType || h.Width != 176 || h.Height != 144 {
+		t.Fatalf("Unexpected picture header %s", h)
+	}
+
+	// PLUSPTYPE I picture, custom 640x480
+	w = bitstream.NewWriter()
+	w.WriteBits(PictureStartCode, PictureStartCodeBits)
+	w.WriteBits(9, 8)
+	w.WriteBits(2, 2)
+	w.WriteBits(0, 3)
+	w.WriteBits(7, 3)  // extended PTYPE
+	w.WriteBits(1, 3)  // UFEP
+	w.WriteBits(6, 3)  // custom
+	w.WriteBits(8, 15) // options, then 1 0 0 0
+	w.WriteBits(0, 3)  // I
+	w.WriteBits(1, 6)  // RPR, RRU, RTYPE, 0 0 1
+	w.WriteBits(0, 1)  // CPM
+	w.WriteBits(2, 4)  // PAR
+	w.WriteBits(640/4-1, 9)
+	w.WriteBits(1, 1)
+	w.WriteBits(480/4, 9)
+	w.ByteAlign()
+	if h, err = ParsePictureHeader(w.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !h.PlusType || h.UFEP != 1 || h.SourceFormat != SourceFormatCustom || !h.IsIntra() || h.Width != 640 || h.Height != 480 {
+		t.Fatalf("Unexpected picture header %s", h)
+	}
+
+	if _, err = ParsePictureHeader([]byte{0x00, 0x00, 0x84}); err == nil {
+		t.Fatal("Truncated picture header should be rejected")
+	}
+	if _, err = ParsePictureHeader([]byte{0x00, 0x00, 0x90, 0x00, 0x00}); err == nil {
+		t.Fatal("GOB start code should be rejected")
+	}
+}
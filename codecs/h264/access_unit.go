@@ -0,0 +1,95 @@
+package h264
+
+// AccessUnit represents the NAL units of an access unit, 7.4.1.2.3 of T-REC-H.264
+type AccessUnit struct {
+	NALUs [][]byte
+	// KeyFrame is set when the primary coded picture is an IDR picture
+	KeyFrame bool
+}
+
+// AnnexB returns the access unit as a byte stream in the format of Annex B, as H264Payloader expects it
+func (au AccessUnit) AnnexB() []byte {
+	return JoinAnnexB(au.NALUs)
+}
+
+// AccessUnitSplitter splits a byte stream in the format of Annex B, received in chunks, into access units, 7.4.1.2.3
+// The parameter sets of the stream are kept to parse the slice headers; a slice whose header cannot be parsed starts
+// a new picture when its first_mb_in_slice is 0
+type AccessUnitSplitter struct {
+	ParameterSets ParameterSets
+
+	scanner AnnexBScanner
+	au      AccessUnit
+	// hasVCL is set once au holds a slice, and prev is the header of the last one
+	hasVCL bool
+	prev   SliceHeader
+}
+
+// Write appends a chunk of the stream, and returns the access units it completes
+func (s *AccessUnitSplitter) Write(chunk []byte) []AccessUnit {
+	var aus []AccessUnit
+	for _, nalu := range s.scanner.Write(chunk) {
+		if au := s.push(nalu); au != nil {
+			aus = append(aus, *au)
+		}
+	}
+	return aus
+}
+
+// Flush returns the access units buffered, at the end of the stream
+func (s *AccessUnitSplitter) Flush() []AccessUnit {
+	var aus []AccessUnit
+	if nalu := s.scanner.Flush(); nalu != nil {
+		if au := s.push(nalu); au != nil {
+			aus = append(aus, *au)
+		}
+	}
+	if len(s.au.NALUs) > 0 {
+		aus = append(aus, s.au)
+	}
+	s.au = AccessUnit{}
+	s.hasVCL = false
+	return aus
+}
+
+// push appends a NAL unit to the access unit, and returns the previous access unit if the NAL unit starts a new one
+func (s *AccessUnitSplitter) push(nalu []byte) *AccessUnit {
+	typ := ParseNalHeader(nalu).NalUnitType
+	newAU := false
+	switch typ {
+	case NalUnitTypeAud, NalUnitTypeSps, NalUnitTypePps, NalUnitTypeSei, NalUnitTypePrefix, NalUnitTypeSubSps,
+		NalUnitTypeDps, NalUnitTypeReserved17, NalUnitTypeReserved18:
+		newAU = s.hasVCL
+		_ = s.ParameterSets.Update(nalu)
+	case NalUnitTypeSlice, NalUnitTypeDpa, NalUnitTypeIdrSlice:
+		h, err := ParseSliceHeader(nalu, &s.ParameterSets)
+		if err != nil {
+			newAU = s.hasVCL && h.FirstMbInSlice == 0
+		} else {
+			newAU = s.hasVCL && h.FirstOfNewPicture(s.prev)
+		}
+		s.prev = h
+	}
+
+	var out *AccessUnit
+	if newAU {
+		au := s.au
+		out = &au
+		s.au = AccessUnit{}
+		s.hasVCL = false
+	}
+	s.au.NALUs = append(s.au.NALUs, nalu)
+	switch typ {
+	case NalUnitTypeIdrSlice:
+		s.au.KeyFrame = true
+		fallthrough
+	case NalUnitTypeSlice, NalUnitTypeDpa, NalUnitTypeDpb, NalUnitTypeDpc:
+		s.hasVCL = true
+	}
+	return out
+}
+
+func SplitAccessUnits(stream []byte) []AccessUnit {
+	var s AccessUnitSplitter
+	return append(s.Write(stream), s.Flush()...)
+}
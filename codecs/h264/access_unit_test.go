@@ -0,0 +1,97 @@
+package h264
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+var (
+	testSPS = []byte{0x67, 0x64, 0x00, 0x28, 0xac, 0xd9, 0x40, 0x78, 0x02, 0x27, 0xe5, 0xc0, 0x44, 0x00, 0x00, 0x03,
+		0x00, 0x04, 0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc6, 0x58}
+	testPPS = []byte{0x68, 0xeb, 0xe3, 0xcb, 0x22, 0xc0}
+)
+
+// testSlice returns a slice NAL unit of testSPS and testPPS, of nal_ref_idc 3 and some slice data
+func testSlice(t *testing.T, typ NalUnitType, firstMb, frameNum, poc uint32) []byte {
+	sps, err := ParseSPS(testSPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bitstream.NewWriter()
+	w.WriteUE(firstMb)
+	w.WriteUE(uint32(SliceTypeI))
+	w.WriteUE(0)
+	w.WriteBits(uint64(frameNum), int(sps.Log2MaxFrameNum))
+	if typ == NalUnitTypeIdrSlice {
+		w.WriteUE(0)
+	}
+	w.WriteBits(uint64(poc), int(sps.Log2MaxPicOrderCntLsb))
+	w.WriteTrailingBits()
+	w.WriteBytes([]byte{0xaa, 0xbb})
+	h := NalHeader{NalRefIdc: 3, NalUnitType: typ}
+	return append([]byte{h.Byte()}, w.Bytes()...)
+}
+
+func TestSliceHeader(t *testing.T) {
+	var ps ParameterSets
+	for _, nalu := range [][]byte{testSPS, testPPS} {
+		if err := ps.Update(nalu); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := ParseSliceHeader(testSlice(t, NalUnitTypeIdrSlice, 10, 0, 4), &ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.FirstMbInSlice != 10 || !h.SliceType.IsIntra() || !h.IdrPic() || h.PicOrderCntLsb != 4 {
+		t.Errorf("unexpected %s", h)
+	}
+	if _, err := ParseSliceHeader(testSlice(t, NalUnitTypeSlice, 0, 1, 2), &ParameterSets{}); err == nil {
+		t.Error("slice header parsed without its PPS")
+	}
+}
+
+func TestAccessUnitSplitter(t *testing.T) {
+	aud := []byte{0x09, 0xf0}
+	idr0, idr1 := testSlice(t, NalUnitTypeIdrSlice, 0, 0, 0), testSlice(t, NalUnitTypeIdrSlice, 120, 0, 0)
+	p0, p1 := testSlice(t, NalUnitTypeSlice, 0, 1, 2), testSlice(t, NalUnitTypeSlice, 120, 1, 2)
+	p2 := testSlice(t, NalUnitTypeSlice, 0, 2, 4)
+	stream := JoinAnnexB([][]byte{aud, testSPS, testPPS, idr0, idr1, p0, p1, p2})
+	// a three bytes start code, and trailing zero bytes
+	stream = append(stream, 0x00, 0x00, 0x01, 0x0c, 0xff, 0x80, 0x00, 0x00)
+
+	want := []AccessUnit{
+		{NALUs: [][]byte{aud, testSPS, testPPS, idr0, idr1}, KeyFrame: true},
+		{NALUs: [][]byte{p0, p1}},
+		{NALUs: [][]byte{p2, {0x0c, 0xff, 0x80}}},
+	}
+
+	// fed in small chunks
+	var s AccessUnitSplitter
+	var aus []AccessUnit
+	for i := 0; i < len(stream); i += 5 {
+		aus = append(aus, s.Write(stream[i:min(i+5, len(stream))])...)
+	}
+	aus = append(aus, s.Flush()...)
+	if len(aus) != len(want) {
+		t.Fatalf("%d access units, want %d", len(aus), len(want))
+	}
+	for i := range want {
+		if aus[i].KeyFrame != want[i].KeyFrame || !bytes.Equal(aus[i].AnnexB(), want[i].AnnexB()) {
+			t.Errorf("access unit %d: %v, want %v", i, aus[i], want[i])
+		}
+	}
+
+	if aus := SplitAccessUnits(stream); len(aus) != len(want) {
+		t.Errorf("%d access units, want %d", len(aus), len(want))
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
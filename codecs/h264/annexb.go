@@ -0,0 +1,89 @@
+package h264
+
+import "bytes"
+
+// SplitAnnexB splits a byte stream in the format of Annex B of T-REC-H.264 into its NAL units, without their start codes
+// The bytes before the first start code are dropped, the trailing zero bytes of the NAL units too
+func SplitAnnexB(stream []byte) [][]byte {
+	var s AnnexBScanner
+	nalus := s.Write(stream)
+	if nalu := s.Flush(); nalu != nil {
+		nalus = append(nalus, nalu)
+	}
+	return nalus
+}
+
+// AnnexBScanner splits a byte stream in the format of Annex B, received in chunks, into its NAL units
+// A NAL unit is complete once the start code of the next one is received; Flush returns the last one
+// The scanner also serves the byte streams of T-REC-H.265, which share the format
+type AnnexBScanner struct {
+	buf []byte
+	// scanned is the count of bytes of buf searched for a start code
+	scanned int
+	// started is set once a start code was found
+	started bool
+}
+
+// Write appends a chunk of the stream, and returns the NAL units it completes
+func (s *AnnexBScanner) Write(chunk []byte) [][]byte {
+	var nalus [][]byte
+	s.buf = append(s.buf, chunk...)
+	for {
+		// a start code is 0x000001, preceded by any number of zero bytes
+		from := 0
+		if s.scanned > 2 {
+			from = s.scanned - 2
+		}
+		i := bytes.Index(s.buf[from:], []byte{0, 0, 1})
+		if i < 0 {
+			s.scanned = len(s.buf)
+			break
+		}
+		i += from
+		s.scanned = 0
+		if s.started {
+			if nalu := bytes.TrimRight(s.buf[:i], "\x00"); len(nalu) > 0 {
+				nalus = append(nalus, append([]byte(nil), nalu...))
+			}
+		}
+		s.started = true
+		s.buf = s.buf[i+3:]
+	}
+	if !s.started {
+		// keep the bytes which may start a start code
+		if len(s.buf) > 2 {
+			s.buf = s.buf[len(s.buf)-2:]
+			s.scanned = 2
+		}
+	}
+	return nalus
+}
+
+// Flush returns the last NAL unit received, nil if none
+func (s *AnnexBScanner) Flush() []byte {
+	var nalu []byte
+	if s.started {
+		nalu = bytes.TrimRight(s.buf, "\x00")
+	}
+	s.buf = nil
+	s.scanned = 0
+	s.started = false
+	if len(nalu) == 0 {
+		return nil
+	}
+	return append([]byte(nil), nalu...)
+}
+
+// JoinAnnexB joins NAL units into a byte stream in the format of Annex B, each one prefixed with StartSequence
+func JoinAnnexB(nalus [][]byte) []byte {
+	size := 0
+	for _, nalu := range nalus {
+		size += len(StartSequence) + len(nalu)
+	}
+	stream := make([]byte, 0, size)
+	for _, nalu := range nalus {
+		stream = append(stream, StartSequence...)
+		stream = append(stream, nalu...)
+	}
+	return stream
+}
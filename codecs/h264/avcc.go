@@ -0,0 +1,69 @@
+package h264
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// NAL units in the AVCC format of ISO/IEC 14496-15, as MP4 and Matroska store them, are prefixed with their length
+// in big-endian on LengthSizeMinusOne + 1 bytes of their decoder configuration record, 1, 2 or 4
+
+// SplitAVCC splits the NAL units prefixed with their length on lengthSize bytes
+func SplitAVCC(buf []byte, lengthSize int) ([][]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, fmt.Errorf("invalid NAL unit length size %d", lengthSize)
+	}
+	var nalus [][]byte
+	for len(buf) > 0 {
+		if len(buf) < lengthSize {
+			return nil, fmt.Errorf("buf is not large enough to container NAL unit length")
+		}
+		var size uint64
+		for _, b := range buf[:lengthSize] {
+			size = size<<8 | uint64(b)
+		}
+		buf = buf[lengthSize:]
+		if uint64(len(buf)) < size {
+			return nil, fmt.Errorf("buf is not large enough to container NAL unit of %d bytes", size)
+		}
+		nalus = append(nalus, buf[:size:size])
+		buf = buf[size:]
+	}
+	return nalus, nil
+}
+
+// JoinAVCC joins NAL units, each one prefixed with its length on lengthSize bytes
+func JoinAVCC(nalus [][]byte, lengthSize int) ([]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, fmt.Errorf("invalid NAL unit length size %d", lengthSize)
+	}
+	size := 0
+	for _, nalu := range nalus {
+		if uint64(len(nalu)) >= 1<<uint(8*lengthSize) {
+			return nil, fmt.Errorf("NAL unit of %d bytes exceeds length size %d", len(nalu), lengthSize)
+		}
+		size += lengthSize + len(nalu)
+	}
+	buf := make([]byte, 0, size)
+	var length [4]byte
+	for _, nalu := range nalus {
+		binary.BigEndian.PutUint32(length[:], uint32(len(nalu)))
+		buf = append(buf, length[4-lengthSize:]...)
+		buf = append(buf, nalu...)
+	}
+	return buf, nil
+}
+
+// AnnexBToAVCC converts a byte stream in the format of Annex B into NAL units prefixed with their length on lengthSize bytes
+func AnnexBToAVCC(stream []byte, lengthSize int) ([]byte, error) {
+	return JoinAVCC(SplitAnnexB(stream), lengthSize)
+}
+
+// AVCCToAnnexB converts NAL units prefixed with their length on lengthSize bytes into a byte stream in the format of Annex B
+func AVCCToAnnexB(buf []byte, lengthSize int) ([]byte, error) {
+	nalus, err := SplitAVCC(buf, lengthSize)
+	if err != nil {
+		return nil, err
+	}
+	return JoinAnnexB(nalus), nil
+}
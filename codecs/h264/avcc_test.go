@@ -0,0 +1,65 @@
+package h264
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestAVCC(t *testing.T) {
+	nalus := [][]byte{testSPS, testPPS, {0x65, 0x88, 0x84, 0x21}}
+	stream := JoinAnnexB(nalus)
+	for _, lengthSize := range []int{1, 2, 4} {
+		avcc, err := AnnexBToAVCC(stream, lengthSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(avcc) != len(testSPS)+len(testPPS)+4+3*lengthSize {
+			t.Errorf("length size %d: unexpected size %d", lengthSize, len(avcc))
+		}
+		annexB, err := AVCCToAnnexB(avcc, lengthSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(annexB, stream) {
+			t.Errorf("length size %d: % x, want % x", lengthSize, annexB, stream)
+		}
+	}
+	if _, err := SplitAVCC([]byte{0x00, 0x05, 0x65}, 2); err == nil {
+		t.Error("truncated NAL unit split")
+	}
+	if _, err := JoinAVCC([][]byte{make([]byte, 256)}, 1); err == nil {
+		t.Error("NAL unit larger than its length size joined")
+	}
+}
+
+func TestAVCDecoderConfigurationRecord(t *testing.T) {
+	r, err := NewAVCDecoderConfigurationRecord([][]byte{testSPS}, [][]byte{testPPS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x01, 0x64, 0x00, 0x28, 0xff, 0xe1, 0x00, byte(len(testSPS))}
+	if !bytes.HasPrefix(raw, want) || !bytes.HasSuffix(raw, []byte{0xfd, 0xf8, 0xf8, 0x00}) {
+		t.Errorf("unexpected record % x", raw)
+	}
+	got, err := ParseAVCDecoderConfigurationRecord(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, r) || got.LengthSize() != 4 {
+		t.Errorf("record %v, want %v", got, r)
+	}
+
+	// the fields of the High profiles may be missing
+	got, err = ParseAVCDecoderConfigurationRecord(raw[:len(raw)-4])
+	if err != nil || len(got.PPS) != 1 {
+		t.Errorf("record without the fields of the High profiles: %v", err)
+	}
+	if _, err := ParseAVCDecoderConfigurationRecord(raw[:10]); err == nil {
+		t.Error("truncated record parsed")
+	}
+}
@@ -0,0 +1,175 @@
+package h264
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// AVCDecoderConfigurationRecord represents the avcC box of MP4 and the CodecPrivate of Matroska, 5.3.3.1 of ISO/IEC 14496-15
+type AVCDecoderConfigurationRecord struct {
+	ConfigurationVersion uint8
+	AVCProfileIndication uint8
+	ProfileCompatibility uint8
+	AVCLevelIndication   uint8
+	LengthSizeMinusOne   uint8
+	SPS                  [][]byte
+	PPS                  [][]byte
+
+	// the fields of the High profiles, marshaled for them only
+	ChromaFormat         uint8
+	BitDepthLumaMinus8   uint8
+	BitDepthChromaMinus8 uint8
+	SPSExt               [][]byte
+}
+
+// NewAVCDecoderConfigurationRecord returns the record of the parameter sets of a stream, in AVCC with 4 bytes lengths
+func NewAVCDecoderConfigurationRecord(sps, pps [][]byte) (AVCDecoderConfigurationRecord, error) {
+	if len(sps) == 0 {
+		return AVCDecoderConfigurationRecord{}, fmt.Errorf("AVCDecoderConfigurationRecord requires a SPS")
+	}
+	s, err := ParseSPS(sps[0])
+	if err != nil {
+		return AVCDecoderConfigurationRecord{}, err
+	}
+	return AVCDecoderConfigurationRecord{
+		ConfigurationVersion: 1,
+		AVCProfileIndication: s.ProfileIdc,
+		ProfileCompatibility: s.ConstraintFlags,
+		AVCLevelIndication:   s.LevelIdc,
+		LengthSizeMinusOne:   3,
+		SPS:                  sps,
+		PPS:                  pps,
+		ChromaFormat:         uint8(s.ChromaFormatIdc),
+		BitDepthLumaMinus8:   uint8(s.BitDepthLuma - 8),
+		BitDepthChromaMinus8: uint8(s.BitDepthChroma - 8),
+	}, nil
+}
+
+// LengthSize returns the size of the lengths of the NAL units of the samples
+func (r AVCDecoderConfigurationRecord) LengthSize() int {
+	return int(r.LengthSizeMinusOne) + 1
+}
+
+// hasHighProfileFields reports whether the profile requires the fields of the High profiles
+func (r AVCDecoderConfigurationRecord) hasHighProfileFields() bool {
+	switch r.AVCProfileIndication {
+	case 100, 110, 122, 144:
+		return true
+	}
+	return false
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the AVCDecoderConfigurationRecord this method is called upon
+// The fields of the High profiles are optional, as many muxers omit them
+func (r *AVCDecoderConfigurationRecord) Unmarshal(buf []byte) error {
+	if buf == nil {
+		return fmt.Errorf("invalid nil AVCDecoderConfigurationRecord")
+	}
+	if len(buf) < 7 {
+		return fmt.Errorf("buf is not large enough to container AVCDecoderConfigurationRecord")
+	}
+	*r = AVCDecoderConfigurationRecord{
+		ConfigurationVersion: buf[0],
+		AVCProfileIndication: buf[1],
+		ProfileCompatibility: buf[2],
+		AVCLevelIndication:   buf[3],
+		LengthSizeMinusOne:   buf[4] & 0x03,
+	}
+	if r.ConfigurationVersion != 1 {
+		return fmt.Errorf("unsupported configurationVersion %d", r.ConfigurationVersion)
+	}
+	var err error
+	if r.SPS, buf, err = readParameterSets(buf[6:], int(buf[5]&0x1f)); err != nil {
+		return err
+	}
+	if len(buf) < 1 {
+		return fmt.Errorf("buf is not large enough to container numOfPictureParameterSets")
+	}
+	if r.PPS, buf, err = readParameterSets(buf[1:], int(buf[0])); err != nil {
+		return err
+	}
+	if r.hasHighProfileFields() && len(buf) >= 4 {
+		r.ChromaFormat = buf[0] & 0x03
+		r.BitDepthLumaMinus8 = buf[1] & 0x07
+		r.BitDepthChromaMinus8 = buf[2] & 0x07
+		if r.SPSExt, _, err = readParameterSets(buf[4:], int(buf[3])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Marshal serializes the record into bytes.
+func (r AVCDecoderConfigurationRecord) Marshal() ([]byte, error) {
+	if len(r.SPS) > 0x1f {
+		return nil, fmt.Errorf("%d SPS exceed %d", len(r.SPS), 0x1f)
+	}
+	if len(r.PPS) > 0xff || len(r.SPSExt) > 0xff {
+		return nil, fmt.Errorf("too many parameter sets")
+	}
+	out := []byte{r.ConfigurationVersion, r.AVCProfileIndication, r.ProfileCompatibility, r.AVCLevelIndication,
+		0xfc | r.LengthSizeMinusOne&0x03, 0xe0 | byte(len(r.SPS))}
+	var err error
+	if out, err = appendParameterSets(out, r.SPS); err != nil {
+		return nil, err
+	}
+	out = append(out, byte(len(r.PPS)))
+	if out, err = appendParameterSets(out, r.PPS); err != nil {
+		return nil, err
+	}
+	if r.hasHighProfileFields() {
+		out = append(out, 0xfc|r.ChromaFormat&0x03, 0xf8|r.BitDepthLumaMinus8&0x07, 0xf8|r.BitDepthChromaMinus8&0x07,
+			byte(len(r.SPSExt)))
+		if out, err = appendParameterSets(out, r.SPSExt); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// String helps with debugging by printing AVCDecoderConfigurationRecord information in a readable way
+func (r AVCDecoderConfigurationRecord) String() string {
+	out := "AVCDecoderConfigurationRecord:\n"
+
+	out += fmt.Sprintf("\tProfileLevelID: %02x%02x%02x\n", r.AVCProfileIndication, r.ProfileCompatibility, r.AVCLevelIndication)
+	out += fmt.Sprintf("\tLengthSize: %v\n", r.LengthSize())
+	out += fmt.Sprintf("\tSPS: %v\n", len(r.SPS))
+	out += fmt.Sprintf("\tPPS: %v\n", len(r.PPS))
+
+	return out
+}
+
+func ParseAVCDecoderConfigurationRecord(buf []byte) (AVCDecoderConfigurationRecord, error) {
+	var r AVCDecoderConfigurationRecord
+	err := (&r).Unmarshal(buf)
+	return r, err
+}
+
+// readParameterSets reads n parameter sets, each one prefixed with its length on 16 bits, and returns the bytes after them
+func readParameterSets(buf []byte, n int) ([][]byte, []byte, error) {
+	var sets [][]byte
+	for i := 0; i < n; i++ {
+		if len(buf) < 2 {
+			return nil, nil, fmt.Errorf("buf is not large enough to container parameter set length")
+		}
+		size := int(binary.BigEndian.Uint16(buf))
+		if len(buf) < 2+size {
+			return nil, nil, fmt.Errorf("buf is not large enough to container parameter set of %d bytes", size)
+		}
+		sets = append(sets, buf[2:2+size:2+size])
+		buf = buf[2+size:]
+	}
+	return sets, buf, nil
+}
+
+// appendParameterSets appends parameter sets, each one prefixed with its length on 16 bits
+func appendParameterSets(out []byte, sets [][]byte) ([]byte, error) {
+	for _, set := range sets {
+		if len(set) > 0xffff {
+			return nil, fmt.Errorf("parameter set of %d bytes exceeds %d", len(set), 0xffff)
+		}
+		out = append(out, byte(len(set)>>8), byte(len(set)))
+		out = append(out, set...)
+	}
+	return out, nil
+}
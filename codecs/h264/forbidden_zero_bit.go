@@ -14,9 +14,9 @@ func (f ForbiddenZeroBit) Byte() byte {
 }
 func (f ForbiddenZeroBit) Marshal() ([]byte, error) {
 	if f {
-		return []byte{0}, nil
+		return []byte{ForbiddenZeroBitMask}, nil
 	}
-	return []byte{ForbiddenZeroBitMask}, nil
+	return []byte{0}, nil
 }
 
 func (f *ForbiddenZeroBit) Unmarshal(buf []byte) error {
@@ -0,0 +1,29 @@
+package h264
+
+import (
+	"testing"
+)
+
+func TestForbiddenZeroBit_Marshal(t *testing.T) {
+	tests := []struct {
+		f    ForbiddenZeroBit
+		byte byte
+	}{
+		{false, 0x00},
+		{true, ForbiddenZeroBitMask},
+	}
+
+	for i, test := range tests {
+		raw, err := test.f.Marshal()
+		if err != nil {
+			t.Fatalf("#%d: Marshal failed: %v", i, err)
+		}
+		if len(raw) != 1 || raw[0] != test.byte || test.f.Byte() != test.byte {
+			t.Fatalf("#%d: Marshal %x, want %x", i, raw, test.byte)
+		}
+		// the other bits of the NAL unit header are ignored
+		if got := ParseForbiddenZeroBit([]byte{raw[0] | 0x67}); got != test.f {
+			t.Fatalf("#%d: got %v, want %v", i, got, test.f)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package h264
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// ParameterSets holds the SPS and PPS of a stream by ID, as they are received
+type ParameterSets struct {
+	SPS [MaxSpsCount]*SPS
+	PPS [MaxPpsCount]*PPS
+}
+
+// Update parses a SPS or PPS NAL unit and stores it, replacing the one of the same ID; other NAL units are ignored
+func (ps *ParameterSets) Update(nalu []byte) error {
+	if len(nalu) == 0 {
+		return fmt.Errorf("invalid empty nalu")
+	}
+	switch ParseNalHeader(nalu).NalUnitType {
+	case NalUnitTypeSps:
+		sps, err := ParseSPS(nalu)
+		if err != nil {
+			return err
+		}
+		ps.SPS[sps.ID] = &sps
+	case NalUnitTypePps:
+		// the SPS referred to is needed to parse the PPS
+		r := bitstream.NewReader(bitstream.RemoveEmulationPrevention(nalu[1:]))
+		r.ReadUE()
+		spsID := r.ReadUE()
+		if r.Err() != nil || spsID >= MaxSpsCount {
+			return fmt.Errorf("invalid PPS")
+		}
+		pps, err := ParsePPS(nalu, ps.SPS[spsID])
+		if err != nil {
+			return err
+		}
+		ps.PPS[pps.ID] = &pps
+	}
+	return nil
+}
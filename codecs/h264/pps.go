@@ -0,0 +1,227 @@
+package h264
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// PPS represents a picture parameter set, pic_parameter_set_rbsp() in 7.3.2.2 of T-REC-H.264
+type PPS struct {
+	NalRefIdc NalRefIdc
+
+	ID    uint32
+	SPSID uint32
+	// EntropyCodingMode is set for CABAC, unset for CAVLC
+	EntropyCodingMode                 bool
+	BottomFieldPicOrderInFramePresent bool
+
+	// NumSliceGroups is num_slice_groups_minus1 + 1, the other slice group fields are set when it exceeds 1
+	NumSliceGroups             uint32
+	SliceGroupMapType          uint32
+	RunLengthMinus1            []uint32
+	TopLeft                    []uint32
+	BottomRight                []uint32
+	SliceGroupChangeDirection  bool
+	SliceGroupChangeRateMinus1 uint32
+	PicSizeInMapUnits          uint32
+	SliceGroupID               []uint32
+
+	NumRefIdxL0DefaultActive       uint32
+	NumRefIdxL1DefaultActive       uint32
+	WeightedPred                   bool
+	WeightedBipredIdc              uint8
+	PicInitQp                      int32
+	PicInitQs                      int32
+	ChromaQpIndexOffset            int32
+	DeblockingFilterControlPresent bool
+	ConstrainedIntraPred           bool
+	RedundantPicCntPresent         bool
+
+	// the optional fields of the High profiles, set when MoreData is
+	MoreData                bool
+	Transform8x8Mode        bool
+	PicScalingMatrixPresent bool
+	// ScalingLists holds the delta_scale values of the scaling lists coded, nil for those not present
+	ScalingLists              [12][]int32
+	SecondChromaQpIndexOffset int32
+}
+
+// Decode reads the PPS RBSP, without its NAL header
+// sps is the SPS the PPS refers to, needed for the scaling lists of 4:4:4 sequences; nil is taken as not 4:4:4
+func (p *PPS) Decode(r *bitstream.Reader, sps *SPS) error {
+	*p = PPS{NalRefIdc: p.NalRefIdc}
+	p.ID = r.ReadUE()
+	if p.ID >= MaxPpsCount {
+		return fmt.Errorf("pic_parameter_set_id %d exceeds %d", p.ID, MaxPpsCount-1)
+	}
+	p.SPSID = r.ReadUE()
+	if p.SPSID >= MaxSpsCount {
+		return fmt.Errorf("seq_parameter_set_id %d exceeds %d", p.SPSID, MaxSpsCount-1)
+	}
+	p.EntropyCodingMode = r.ReadFlag()
+	p.BottomFieldPicOrderInFramePresent = r.ReadFlag()
+	p.NumSliceGroups = r.ReadUE() + 1
+	if p.NumSliceGroups > MaxSliceGroups {
+		return fmt.Errorf("num_slice_groups_minus1 %d exceeds %d", p.NumSliceGroups-1, MaxSliceGroups-1)
+	}
+	if p.NumSliceGroups > 1 {
+		p.SliceGroupMapType = r.ReadUE()
+		switch p.SliceGroupMapType {
+		case 0:
+			p.RunLengthMinus1 = make([]uint32, p.NumSliceGroups)
+			for i := range p.RunLengthMinus1 {
+				p.RunLengthMinus1[i] = r.ReadUE()
+			}
+		case 2:
+			p.TopLeft = make([]uint32, p.NumSliceGroups-1)
+			p.BottomRight = make([]uint32, p.NumSliceGroups-1)
+			for i := range p.TopLeft {
+				p.TopLeft[i] = r.ReadUE()
+				p.BottomRight[i] = r.ReadUE()
+			}
+		case 3, 4, 5:
+			p.SliceGroupChangeDirection = r.ReadFlag()
+			p.SliceGroupChangeRateMinus1 = r.ReadUE()
+		case 6:
+			p.PicSizeInMapUnits = r.ReadUE() + 1
+			if p.PicSizeInMapUnits > MaxMbPicSize {
+				return fmt.Errorf("pic_size_in_map_units_minus1 %d exceeds %d", p.PicSizeInMapUnits-1, MaxMbPicSize-1)
+			}
+			n := bits.Len32(p.NumSliceGroups - 1)
+			p.SliceGroupID = make([]uint32, p.PicSizeInMapUnits)
+			for i := range p.SliceGroupID {
+				p.SliceGroupID[i] = r.ReadUint32(n)
+			}
+		}
+	}
+	p.NumRefIdxL0DefaultActive = r.ReadUE() + 1
+	p.NumRefIdxL1DefaultActive = r.ReadUE() + 1
+	if p.NumRefIdxL0DefaultActive > 32 || p.NumRefIdxL1DefaultActive > 32 {
+		return fmt.Errorf("invalid num_ref_idx_default_active %d/%d", p.NumRefIdxL0DefaultActive, p.NumRefIdxL1DefaultActive)
+	}
+	p.WeightedPred = r.ReadFlag()
+	p.WeightedBipredIdc = r.ReadUint8(2)
+	p.PicInitQp = r.ReadSE() + 26
+	p.PicInitQs = r.ReadSE() + 26
+	p.ChromaQpIndexOffset = r.ReadSE()
+	p.DeblockingFilterControlPresent = r.ReadFlag()
+	p.ConstrainedIntraPred = r.ReadFlag()
+	p.RedundantPicCntPresent = r.ReadFlag()
+	p.SecondChromaQpIndexOffset = p.ChromaQpIndexOffset
+
+	if p.MoreData = r.MoreRBSPData(); p.MoreData {
+		p.Transform8x8Mode = r.ReadFlag()
+		if p.PicScalingMatrixPresent = r.ReadFlag(); p.PicScalingMatrixPresent {
+			decodeScalingLists(r, p.ScalingLists[:p.scalingListCount(sps)])
+		}
+		p.SecondChromaQpIndexOffset = r.ReadSE()
+	}
+	return r.Err()
+}
+
+// scalingListCount returns the number of scaling lists of the picture scaling matrix
+func (p PPS) scalingListCount(sps *SPS) int {
+	if !p.Transform8x8Mode {
+		return 6
+	}
+	if sps != nil && sps.ChromaFormatIdc == 3 {
+		return 12
+	}
+	return 8
+}
+
+// Encode writes the PPS RBSP, without its NAL header, up to its trailing bits
+func (p PPS) Encode(w *bitstream.Writer, sps *SPS) {
+	w.WriteUE(p.ID)
+	w.WriteUE(p.SPSID)
+	w.WriteFlag(p.EntropyCodingMode)
+	w.WriteFlag(p.BottomFieldPicOrderInFramePresent)
+	w.WriteUE(p.NumSliceGroups - 1)
+	if p.NumSliceGroups > 1 {
+		w.WriteUE(p.SliceGroupMapType)
+		switch p.SliceGroupMapType {
+		case 0:
+			for _, v := range p.RunLengthMinus1 {
+				w.WriteUE(v)
+			}
+		case 2:
+			for i := range p.TopLeft {
+				w.WriteUE(p.TopLeft[i])
+				w.WriteUE(p.BottomRight[i])
+			}
+		case 3, 4, 5:
+			w.WriteFlag(p.SliceGroupChangeDirection)
+			w.WriteUE(p.SliceGroupChangeRateMinus1)
+		case 6:
+			w.WriteUE(p.PicSizeInMapUnits - 1)
+			n := bits.Len32(p.NumSliceGroups - 1)
+			for _, v := range p.SliceGroupID {
+				w.WriteBits(uint64(v), n)
+			}
+		}
+	}
+	w.WriteUE(p.NumRefIdxL0DefaultActive - 1)
+	w.WriteUE(p.NumRefIdxL1DefaultActive - 1)
+	w.WriteFlag(p.WeightedPred)
+	w.WriteBits(uint64(p.WeightedBipredIdc), 2)
+	w.WriteSE(p.PicInitQp - 26)
+	w.WriteSE(p.PicInitQs - 26)
+	w.WriteSE(p.ChromaQpIndexOffset)
+	w.WriteFlag(p.DeblockingFilterControlPresent)
+	w.WriteFlag(p.ConstrainedIntraPred)
+	w.WriteFlag(p.RedundantPicCntPresent)
+	if p.MoreData {
+		w.WriteFlag(p.Transform8x8Mode)
+		if w.WriteFlag(p.PicScalingMatrixPresent); p.PicScalingMatrixPresent {
+			encodeScalingLists(w, p.ScalingLists[:p.scalingListCount(sps)])
+		}
+		w.WriteSE(p.SecondChromaQpIndexOffset)
+	}
+	w.WriteTrailingBits()
+}
+
+// Unmarshal parses the passed PPS NAL unit, its header included, and stores the result in the PPS this method is called upon
+// sps is the SPS the PPS refers to, nil if unknown
+func (p *PPS) Unmarshal(nalu []byte, sps *SPS) error {
+	if nalu == nil {
+		return fmt.Errorf("invalid nil nalu")
+	}
+	if len(nalu) < 2 {
+		return fmt.Errorf("buf is not large enough to container PPS")
+	}
+	h := ParseNalHeader(nalu)
+	if h.NalUnitType != NalUnitTypePps {
+		return fmt.Errorf("NAL unit type %s is not PPS", h.NalUnitType)
+	}
+	p.NalRefIdc = h.NalRefIdc
+	return p.Decode(bitstream.NewReader(bitstream.RemoveEmulationPrevention(nalu[1:])), sps)
+}
+
+// Marshal serializes the PPS into a NAL unit, its header included
+func (p PPS) Marshal(sps *SPS) ([]byte, error) {
+	w := bitstream.NewWriter()
+	p.Encode(w, sps)
+	h := NalHeader{NalRefIdc: p.NalRefIdc, NalUnitType: NalUnitTypePps}
+	return append([]byte{h.Byte()}, bitstream.AddEmulationPrevention(w.Bytes())...), nil
+}
+
+// String helps with debugging by printing PPS information in a readable way
+func (p PPS) String() string {
+	out := "H264 PPS:\n"
+
+	out += fmt.Sprintf("\tID: %v\n", p.ID)
+	out += fmt.Sprintf("\tSPSID: %v\n", p.SPSID)
+	out += fmt.Sprintf("\tEntropyCodingMode: %v\n", p.EntropyCodingMode)
+	out += fmt.Sprintf("\tNumSliceGroups: %v\n", p.NumSliceGroups)
+	out += fmt.Sprintf("\tTransform8x8Mode: %v\n", p.Transform8x8Mode)
+
+	return out
+}
+
+func ParsePPS(nalu []byte, sps *SPS) (PPS, error) {
+	var p PPS
+	err := (&p).Unmarshal(nalu, sps)
+	return p, err
+}
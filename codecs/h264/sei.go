@@ -0,0 +1,141 @@
+package h264
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// SEI payload types, D.1 of T-REC-H.264, shared by T-REC-H.265
+const (
+	SEIPayloadTypeBufferingPeriod           = 0
+	SEIPayloadTypePicTiming                 = 1
+	SEIPayloadTypeUserDataRegisteredITUTT35 = 4
+	SEIPayloadTypeUserDataUnregistered      = 5
+	SEIPayloadTypeRecoveryPoint             = 6
+)
+
+// SEIMessage represents a sei_message(), 7.3.2.3.1 of T-REC-H.264, its payload left coded
+type SEIMessage struct {
+	PayloadType uint32
+	Payload     []byte
+}
+
+// SEI represents the messages of a SEI NAL unit, sei_rbsp() in 7.3.2.3
+type SEI struct {
+	NalRefIdc NalRefIdc
+	Messages  []SEIMessage
+}
+
+// Unmarshal parses the passed SEI NAL unit, its header included, and stores the result in the SEI this method is called upon
+func (s *SEI) Unmarshal(nalu []byte) error {
+	if nalu == nil {
+		return fmt.Errorf("invalid nil nalu")
+	}
+	if len(nalu) < 2 {
+		return fmt.Errorf("buf is not large enough to container SEI")
+	}
+	h := ParseNalHeader(nalu)
+	if h.NalUnitType != NalUnitTypeSei {
+		return fmt.Errorf("NAL unit type %s is not SEI", h.NalUnitType)
+	}
+	messages, err := UnmarshalSEIMessages(bitstream.RemoveEmulationPrevention(nalu[1:]))
+	if err != nil {
+		return err
+	}
+	s.NalRefIdc = h.NalRefIdc
+	s.Messages = messages
+	return nil
+}
+
+// Marshal serializes the SEI into a NAL unit, its header included
+func (s SEI) Marshal() ([]byte, error) {
+	if len(s.Messages) == 0 {
+		return nil, fmt.Errorf("SEI requires a message")
+	}
+	h := NalHeader{NalRefIdc: s.NalRefIdc, NalUnitType: NalUnitTypeSei}
+	return append([]byte{h.Byte()}, bitstream.AddEmulationPrevention(MarshalSEIMessages(s.Messages))...), nil
+}
+
+// String helps with debugging by printing SEI information in a readable way
+func (s SEI) String() string {
+	out := "H264 SEI:\n"
+
+	for _, m := range s.Messages {
+		out += fmt.Sprintf("\tPayloadType: %v, PayloadSize: %v\n", m.PayloadType, len(m.Payload))
+	}
+
+	return out
+}
+
+func ParseSEI(nalu []byte) (SEI, error) {
+	var s SEI
+	err := (&s).Unmarshal(nalu)
+	return s, err
+}
+
+// UnmarshalSEIMessages parses the messages of a SEI RBSP, up to its rbsp_trailing_bits
+func UnmarshalSEIMessages(rbsp []byte) ([]SEIMessage, error) {
+	var messages []SEIMessage
+	// ff_byte coded values, 7.3.2.3.1
+	readValue := func() (uint32, error) {
+		var v uint32
+		for len(rbsp) > 0 {
+			b := rbsp[0]
+			rbsp = rbsp[1:]
+			v += uint32(b)
+			if b != 0xff {
+				return v, nil
+			}
+		}
+		return 0, fmt.Errorf("buf is not large enough to container SEI message")
+	}
+	// more_rbsp_data(): the messages are byte aligned, the trailing bits are a single 0x80 byte
+	for len(rbsp) > 0 && !(len(rbsp) == 1 && rbsp[0] == 0x80) {
+		payloadType, err := readValue()
+		if err != nil {
+			return nil, err
+		}
+		payloadSize, err := readValue()
+		if err != nil {
+			return nil, err
+		}
+		if uint32(len(rbsp)) < payloadSize {
+			return nil, fmt.Errorf("buf is not large enough to container SEI payload of %d bytes", payloadSize)
+		}
+		messages = append(messages, SEIMessage{PayloadType: payloadType, Payload: rbsp[:payloadSize:payloadSize]})
+		rbsp = rbsp[payloadSize:]
+	}
+	return messages, nil
+}
+
+// MarshalSEIMessages serializes messages into a SEI RBSP, rbsp_trailing_bits included
+func MarshalSEIMessages(messages []SEIMessage) []byte {
+	var rbsp []byte
+	writeValue := func(v uint32) {
+		for ; v >= 0xff; v -= 0xff {
+			rbsp = append(rbsp, 0xff)
+		}
+		rbsp = append(rbsp, byte(v))
+	}
+	for _, m := range messages {
+		writeValue(m.PayloadType)
+		writeValue(uint32(len(m.Payload)))
+		rbsp = append(rbsp, m.Payload...)
+	}
+	return append(rbsp, 0x80)
+}
+
+// InsertSEI inserts a SEI NAL unit into an access unit in the format of Annex B, before its first slice, 7.4.1.2.3
+// The access unit can then be passed to H264Payloader
+func InsertSEI(accessUnit []byte, sei []byte) []byte {
+	nalus := SplitAnnexB(accessUnit)
+	i := 0
+	for ; i < len(nalus); i++ {
+		if typ := ParseNalHeader(nalus[i]).NalUnitType; typ >= NalUnitTypeSlice && typ <= NalUnitTypeIdrSlice {
+			break
+		}
+	}
+	nalus = append(nalus[:i], append([][]byte{sei}, nalus[i:]...)...)
+	return JoinAnnexB(nalus)
+}
@@ -0,0 +1,313 @@
+package h264
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// UserDataUnregistered represents a user_data_unregistered() SEI payload, D.1.7 of T-REC-H.264
+type UserDataUnregistered struct {
+	UUID [16]byte
+	Data []byte
+}
+
+// Unmarshal parses the passed SEI payload
+func (u *UserDataUnregistered) Unmarshal(payload []byte) error {
+	if len(payload) < len(u.UUID) {
+		return fmt.Errorf("Payload is not large enough to container uuid_iso_iec_11578")
+	}
+	copy(u.UUID[:], payload)
+	u.Data = payload[len(u.UUID):]
+	return nil
+}
+
+// Marshal serializes the SEI payload
+func (u UserDataUnregistered) Marshal() ([]byte, error) {
+	return append(append(make([]byte, 0, len(u.UUID)+len(u.Data)), u.UUID[:]...), u.Data...), nil
+}
+
+// ITU-T T.35 codes of the closed captions of ATSC A/53
+const (
+	CountryCodeUnitedStates = 0xb5
+	ProviderCodeATSC        = 0x0031
+	UserDataTypeCodeCC      = 0x03
+)
+
+// ATSCUserIdentifier is the user_identifier of the ATSC A/53 user data
+var ATSCUserIdentifier = []byte("GA94")
+
+// UserDataRegistered represents a user_data_registered_itu_t_t35() SEI payload, D.1.6 of T-REC-H.264
+type UserDataRegistered struct {
+	CountryCode uint8
+	// CountryCodeExtension is set with CountryCode 0xff
+	CountryCodeExtension uint8
+	// Data holds the bytes after the country code, starting with the provider code
+	Data []byte
+}
+
+// Unmarshal parses the passed SEI payload
+func (u *UserDataRegistered) Unmarshal(payload []byte) error {
+	if len(payload) < 1 {
+		return fmt.Errorf("Payload is not large enough to container itu_t_t35_country_code")
+	}
+	u.CountryCode = payload[0]
+	payload = payload[1:]
+	if u.CountryCode == 0xff {
+		if len(payload) < 1 {
+			return fmt.Errorf("Payload is not large enough to container itu_t_t35_country_code_extension_byte")
+		}
+		u.CountryCodeExtension = payload[0]
+		payload = payload[1:]
+	}
+	u.Data = payload
+	return nil
+}
+
+// Marshal serializes the SEI payload
+func (u UserDataRegistered) Marshal() ([]byte, error) {
+	out := []byte{u.CountryCode}
+	if u.CountryCode == 0xff {
+		out = append(out, u.CountryCodeExtension)
+	}
+	return append(out, u.Data...), nil
+}
+
+// CCData represents a closed caption construct of cc_data(), 4.3 of CEA-708
+// Types 0 and 1 carry CEA-608 byte pairs of the fields 1 and 2, types 2 and 3 CEA-708 DTVCC packets
+type CCData struct {
+	Valid bool
+	Type  uint8
+	Data  [2]byte
+}
+
+// Captions returns the closed captions the user data carries, as ATSC A/53 specifies them; false if it carries none
+func (u UserDataRegistered) Captions() ([]CCData, bool) {
+	d := u.Data
+	if u.CountryCode != CountryCodeUnitedStates || len(d) < 9 || uint16(d[0])<<8|uint16(d[1]) != ProviderCodeATSC ||
+		!bytes.Equal(d[2:6], ATSCUserIdentifier) || d[6] != UserDataTypeCodeCC {
+		return nil, false
+	}
+	// process_em_data_flag, process_cc_data_flag, additional_data_flag, cc_count, em_data
+	d = d[7:]
+	if d[0]&0x40 == 0 {
+		return nil, false
+	}
+	count := int(d[0] & 0x1f)
+	d = d[2:]
+	if len(d) < 3*count {
+		return nil, false
+	}
+	cc := make([]CCData, count)
+	for i := range cc {
+		cc[i] = CCData{Valid: d[0]&0x04 != 0, Type: d[0] & 0x03, Data: [2]byte{d[1], d[2]}}
+		d = d[3:]
+	}
+	return cc, true
+}
+
+// NewCaptionsUserData returns the user data carrying closed captions, as ATSC A/53 specifies them
+func NewCaptionsUserData(cc []CCData) UserDataRegistered {
+	data := []byte{byte(ProviderCodeATSC >> 8), byte(ProviderCodeATSC & 0xff)}
+	data = append(data, ATSCUserIdentifier...)
+	// process_cc_data_flag, cc_count, em_data
+	data = append(data, UserDataTypeCodeCC, 0x40|byte(len(cc)&0x1f), 0xff)
+	for _, c := range cc {
+		b := 0xf8 | c.Type&0x03
+		if c.Valid {
+			b |= 0x04
+		}
+		data = append(data, b, c.Data[0], c.Data[1])
+	}
+	// marker_bits
+	data = append(data, 0xff)
+	return UserDataRegistered{CountryCode: CountryCodeUnitedStates, Data: data}
+}
+
+// RecoveryPoint represents a recovery_point() SEI payload, D.1.8 of T-REC-H.264
+type RecoveryPoint struct {
+	RecoveryFrameCnt      uint32
+	ExactMatch            bool
+	BrokenLink            bool
+	ChangingSliceGroupIdc uint8
+}
+
+// Unmarshal parses the passed SEI payload
+func (p *RecoveryPoint) Unmarshal(payload []byte) error {
+	r := bitstream.NewReader(payload)
+	p.RecoveryFrameCnt = r.ReadUE()
+	p.ExactMatch = r.ReadFlag()
+	p.BrokenLink = r.ReadFlag()
+	p.ChangingSliceGroupIdc = r.ReadUint8(2)
+	return r.Err()
+}
+
+// Marshal serializes the SEI payload
+func (p RecoveryPoint) Marshal() ([]byte, error) {
+	w := bitstream.NewWriter()
+	w.WriteUE(p.RecoveryFrameCnt)
+	w.WriteFlag(p.ExactMatch)
+	w.WriteFlag(p.BrokenLink)
+	w.WriteBits(uint64(p.ChangingSliceGroupIdc), 2)
+	writePayloadAlignment(w)
+	return w.Bytes(), nil
+}
+
+// writePayloadAlignment writes the bit_equal_to_one and bit_equal_to_zero of a payload not byte aligned, D.1.1
+func writePayloadAlignment(w *bitstream.Writer) {
+	if !w.ByteAligned() {
+		w.WriteTrailingBits()
+	}
+}
+
+// ClockTimestamp represents a clock timestamp, a timecode, of a picture timing SEI payload
+// The seconds, minutes and hours are coded when FullTimestamp or their flag is set
+type ClockTimestamp struct {
+	CtType         uint8
+	NuitFieldBased bool
+	CountingType   uint8
+	FullTimestamp  bool
+	Discontinuity  bool
+	CntDropped     bool
+	NFrames        uint8
+	SecondsFlag    bool
+	Seconds        uint8
+	MinutesFlag    bool
+	Minutes        uint8
+	HoursFlag      bool
+	Hours          uint8
+	TimeOffset     int32
+}
+
+// String returns the timecode as hh:mm:ss:ff, or hh:mm:ss;ff when frames are dropped
+func (c ClockTimestamp) String() string {
+	sep := ":"
+	if c.CntDropped {
+		sep = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", c.Hours, c.Minutes, c.Seconds, sep, c.NFrames)
+}
+
+// numClockTS returns NumClockTS of pic_struct, Table D-1
+var numClockTS = [...]int{1, 1, 1, 2, 2, 3, 3, 2, 3}
+
+// PicTiming represents a pic_timing() SEI payload, D.1.3 of T-REC-H.264
+// Its syntax depends on the HRD and pic_struct_present_flag of the VUI of the active SPS
+type PicTiming struct {
+	// CpbRemovalDelay and DpbOutputDelay are set when the VUI has HRD parameters
+	CpbRemovalDelay uint32
+	DpbOutputDelay  uint32
+	// PicStruct and ClockTimestamps are set when the VUI has pic_struct_present_flag, a nil timestamp being absent
+	PicStruct       uint8
+	ClockTimestamps []*ClockTimestamp
+}
+
+// picTimingHrd returns the HRD parameters which size the delays, nil if absent, and whether pic_struct is present
+func picTimingHrd(sps *SPS) (*HRD, bool) {
+	if sps == nil || sps.VUI == nil {
+		return nil, false
+	}
+	hrd := sps.VUI.NalHrd
+	if hrd == nil {
+		hrd = sps.VUI.VclHrd
+	}
+	return hrd, sps.VUI.PicStructPresent
+}
+
+// Unmarshal parses the passed SEI payload, sps is the active SPS
+func (p *PicTiming) Unmarshal(payload []byte, sps *SPS) error {
+	*p = PicTiming{}
+	hrd, picStructPresent := picTimingHrd(sps)
+	r := bitstream.NewReader(payload)
+	if hrd != nil {
+		p.CpbRemovalDelay = r.ReadUint32(int(hrd.CpbRemovalDelayLength))
+		p.DpbOutputDelay = r.ReadUint32(int(hrd.DpbOutputDelayLength))
+	}
+	if !picStructPresent {
+		return r.Err()
+	}
+	p.PicStruct = r.ReadUint8(4)
+	if int(p.PicStruct) >= len(numClockTS) {
+		return fmt.Errorf("reserved pic_struct %d", p.PicStruct)
+	}
+	p.ClockTimestamps = make([]*ClockTimestamp, numClockTS[p.PicStruct])
+	for i := range p.ClockTimestamps {
+		if !r.ReadFlag() {
+			continue
+		}
+		c := &ClockTimestamp{}
+		c.CtType = r.ReadUint8(2)
+		c.NuitFieldBased = r.ReadFlag()
+		c.CountingType = r.ReadUint8(5)
+		c.FullTimestamp = r.ReadFlag()
+		c.Discontinuity = r.ReadFlag()
+		c.CntDropped = r.ReadFlag()
+		c.NFrames = r.ReadUint8(8)
+		if c.FullTimestamp {
+			c.Seconds = r.ReadUint8(6)
+			c.Minutes = r.ReadUint8(6)
+			c.Hours = r.ReadUint8(5)
+		} else if c.SecondsFlag = r.ReadFlag(); c.SecondsFlag {
+			c.Seconds = r.ReadUint8(6)
+			if c.MinutesFlag = r.ReadFlag(); c.MinutesFlag {
+				c.Minutes = r.ReadUint8(6)
+				if c.HoursFlag = r.ReadFlag(); c.HoursFlag {
+					c.Hours = r.ReadUint8(5)
+				}
+			}
+		}
+		if hrd != nil && hrd.TimeOffsetLength > 0 {
+			n := uint(hrd.TimeOffsetLength)
+			c.TimeOffset = int32(r.ReadUint32(int(n))<<(32-n)) >> (32 - n)
+		}
+		p.ClockTimestamps[i] = c
+	}
+	return r.Err()
+}
+
+// Marshal serializes the SEI payload, sps is the active SPS
+func (p PicTiming) Marshal(sps *SPS) ([]byte, error) {
+	hrd, picStructPresent := picTimingHrd(sps)
+	w := bitstream.NewWriter()
+	if hrd != nil {
+		w.WriteBits(uint64(p.CpbRemovalDelay), int(hrd.CpbRemovalDelayLength))
+		w.WriteBits(uint64(p.DpbOutputDelay), int(hrd.DpbOutputDelayLength))
+	}
+	if picStructPresent {
+		if int(p.PicStruct) >= len(numClockTS) || len(p.ClockTimestamps) != numClockTS[p.PicStruct] {
+			return nil, fmt.Errorf("pic_struct %d requires %d clock timestamps", p.PicStruct, len(p.ClockTimestamps))
+		}
+		w.WriteBits(uint64(p.PicStruct), 4)
+		for _, c := range p.ClockTimestamps {
+			if w.WriteFlag(c != nil); c == nil {
+				continue
+			}
+			w.WriteBits(uint64(c.CtType), 2)
+			w.WriteFlag(c.NuitFieldBased)
+			w.WriteBits(uint64(c.CountingType), 5)
+			w.WriteFlag(c.FullTimestamp)
+			w.WriteFlag(c.Discontinuity)
+			w.WriteFlag(c.CntDropped)
+			w.WriteBits(uint64(c.NFrames), 8)
+			if c.FullTimestamp {
+				w.WriteBits(uint64(c.Seconds), 6)
+				w.WriteBits(uint64(c.Minutes), 6)
+				w.WriteBits(uint64(c.Hours), 5)
+			} else if w.WriteFlag(c.SecondsFlag); c.SecondsFlag {
+				w.WriteBits(uint64(c.Seconds), 6)
+				if w.WriteFlag(c.MinutesFlag); c.MinutesFlag {
+					w.WriteBits(uint64(c.Minutes), 6)
+					if w.WriteFlag(c.HoursFlag); c.HoursFlag {
+						w.WriteBits(uint64(c.Hours), 5)
+					}
+				}
+			}
+			if hrd != nil && hrd.TimeOffsetLength > 0 {
+				w.WriteBits(uint64(uint32(c.TimeOffset)), int(hrd.TimeOffsetLength))
+			}
+		}
+	}
+	writePayloadAlignment(w)
+	return w.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package h264
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSEI(t *testing.T) {
+	unregistered := UserDataUnregistered{
+		UUID: [16]byte{0xdc, 0x45, 0xe9, 0xbd, 0xe6, 0xd9, 0x48, 0xb7, 0x96, 0x2c, 0xd8, 0x20, 0xd9, 0x23, 0xee, 0xef},
+		Data: bytes.Repeat([]byte{0x00}, 300),
+	}
+	cc := []CCData{{Valid: true, Type: 0, Data: [2]byte{0x94, 0x2c}}, {Valid: false, Type: 1, Data: [2]byte{0x80, 0x80}}}
+	recovery := RecoveryPoint{RecoveryFrameCnt: 5, ExactMatch: true}
+
+	p0, _ := unregistered.Marshal()
+	p1, _ := NewCaptionsUserData(cc).Marshal()
+	p2, _ := recovery.Marshal()
+	sei := SEI{Messages: []SEIMessage{
+		{PayloadType: SEIPayloadTypeUserDataUnregistered, Payload: p0},
+		{PayloadType: SEIPayloadTypeUserDataRegisteredITUTT35, Payload: p1},
+		{PayloadType: SEIPayloadTypeRecoveryPoint, Payload: p2},
+	}}
+	nalu, err := sei.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the payload size of 316 bytes is coded 0xff 0x3d, the zero bytes are escaped
+	if !bytes.Contains(nalu, []byte{0x05, 0xff, 0x3d, 0xdc}) || !bytes.Contains(nalu, []byte{0x00, 0x00, 0x03}) {
+		t.Errorf("unexpected SEI NAL unit % x", nalu[:8])
+	}
+
+	got, err := ParseSEI(nalu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages) != 3 {
+		t.Fatalf("%d messages, want 3", len(got.Messages))
+	}
+	var u UserDataUnregistered
+	if err := u.Unmarshal(got.Messages[0].Payload); err != nil || !reflect.DeepEqual(u, unregistered) {
+		t.Errorf("user data unregistered %v, want %v", u, unregistered)
+	}
+	var r UserDataRegistered
+	if err := r.Unmarshal(got.Messages[1].Payload); err != nil {
+		t.Fatal(err)
+	}
+	if captions, ok := r.Captions(); !ok || !reflect.DeepEqual(captions, cc) {
+		t.Errorf("captions %v, want %v", captions, cc)
+	}
+	var rp RecoveryPoint
+	if err := rp.Unmarshal(got.Messages[2].Payload); err != nil || rp != recovery {
+		t.Errorf("recovery point %v, want %v", rp, recovery)
+	}
+}
+
+func TestPicTiming(t *testing.T) {
+	sps := &SPS{VUI: &VUI{
+		NalHrd:           &HRD{CpbRemovalDelayLength: 24, DpbOutputDelayLength: 24, TimeOffsetLength: 0},
+		PicStructPresent: true,
+	}}
+	timing := PicTiming{
+		CpbRemovalDelay: 2,
+		DpbOutputDelay:  4,
+		PicStruct:       0,
+		ClockTimestamps: []*ClockTimestamp{{CountingType: 4, FullTimestamp: true, CntDropped: true, NFrames: 12, Seconds: 34, Minutes: 56, Hours: 10}},
+	}
+	payload, err := timing.Marshal(sps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got PicTiming
+	if err := got.Unmarshal(payload, sps); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, timing) {
+		t.Errorf("pic timing %v, want %v", got, timing)
+	}
+	if tc := got.ClockTimestamps[0].String(); tc != "10:56:34;12" {
+		t.Errorf("timecode %s, want 10:56:34;12", tc)
+	}
+}
+
+func TestInsertSEI(t *testing.T) {
+	aud, idr := []byte{0x09, 0xf0}, []byte{0x65, 0x88, 0x84}
+	sei := []byte{0x06, 0x06, 0x01, 0xc4, 0x80}
+	au := InsertSEI(JoinAnnexB([][]byte{aud, testSPS, testPPS, idr}), sei)
+	if want := JoinAnnexB([][]byte{aud, testSPS, testPPS, sei, idr}); !bytes.Equal(au, want) {
+		t.Errorf("access unit % x, want % x", au, want)
+	}
+}
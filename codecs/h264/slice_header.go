@@ -0,0 +1,192 @@
+package h264
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// SliceType is slice_type, Table 7-6 in T-REC-H.264
+// The values 5 to 9 also tell the other slices of the picture are of the same type
+type SliceType uint32
+
+const (
+	SliceTypeP SliceType = iota
+	SliceTypeB
+	SliceTypeI
+	SliceTypeSP
+	SliceTypeSI
+)
+
+// Type returns the type of the slice, without the indication about the other slices of the picture
+func (t SliceType) Type() SliceType {
+	return t % 5
+}
+
+// IsIntra reports whether the slice is intra coded, I or SI
+func (t SliceType) IsIntra() bool {
+	return t.Type() == SliceTypeI || t.Type() == SliceTypeSI
+}
+
+func (t SliceType) String() string {
+	switch t.Type() {
+	case SliceTypeP:
+		return "P"
+	case SliceTypeB:
+		return "B"
+	case SliceTypeI:
+		return "I"
+	case SliceTypeSP:
+		return "SP"
+	case SliceTypeSI:
+		return "SI"
+	}
+	return fmt.Sprintf("%d", uint32(t))
+}
+
+// SliceHeader represents the start of a slice_header(), 7.3.3 of T-REC-H.264, up to redundant_pic_cnt
+// It holds the fields which tell the first VCL NAL unit of a primary coded picture, 7.4.1.2.4
+type SliceHeader struct {
+	NalRefIdc   NalRefIdc
+	NalUnitType NalUnitType
+
+	FirstMbInSlice uint32
+	SliceType      SliceType
+	PPSID          uint32
+	ColourPlaneID  uint8
+	FrameNum       uint32
+	FieldPic       bool
+	BottomField    bool
+	// IdrPicID is set in the slices of IDR pictures
+	IdrPicID uint32
+	// PicOrderCntLsb and DeltaPicOrderCntBottom are set with pic_order_cnt_type 0
+	PicOrderCntLsb         uint32
+	DeltaPicOrderCntBottom int32
+	// DeltaPicOrderCnt is set with pic_order_cnt_type 1
+	DeltaPicOrderCnt [2]int32
+	RedundantPicCnt  uint32
+}
+
+// IdrPic reports whether the slice belongs to an IDR picture, IdrPicFlag
+func (h SliceHeader) IdrPic() bool {
+	return h.NalUnitType == NalUnitTypeIdrSlice
+}
+
+// Decode reads the slice header of a slice of the NAL unit described by nalHeader
+// The fields up to pic_parameter_set_id are read even if the PPS or its SPS is missing from ps
+func (h *SliceHeader) Decode(r *bitstream.Reader, nalHeader NalHeader, ps *ParameterSets) error {
+	*h = SliceHeader{NalRefIdc: nalHeader.NalRefIdc, NalUnitType: nalHeader.NalUnitType}
+	h.FirstMbInSlice = r.ReadUE()
+	h.SliceType = SliceType(r.ReadUE())
+	h.PPSID = r.ReadUE()
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if h.SliceType > 9 {
+		return fmt.Errorf("invalid slice_type %d", h.SliceType)
+	}
+	if h.PPSID >= MaxPpsCount {
+		return fmt.Errorf("pic_parameter_set_id %d exceeds %d", h.PPSID, MaxPpsCount-1)
+	}
+	pps := ps.PPS[h.PPSID]
+	if pps == nil {
+		return fmt.Errorf("missing PPS %d", h.PPSID)
+	}
+	sps := ps.SPS[pps.SPSID]
+	if sps == nil {
+		return fmt.Errorf("missing SPS %d", pps.SPSID)
+	}
+
+	if sps.SeparateColourPlane {
+		h.ColourPlaneID = r.ReadUint8(2)
+	}
+	h.FrameNum = r.ReadUint32(int(sps.Log2MaxFrameNum))
+	if !sps.FrameMbsOnly {
+		if h.FieldPic = r.ReadFlag(); h.FieldPic {
+			h.BottomField = r.ReadFlag()
+		}
+	}
+	if h.IdrPic() {
+		h.IdrPicID = r.ReadUE()
+	}
+	switch {
+	case sps.PicOrderCntType == 0:
+		h.PicOrderCntLsb = r.ReadUint32(int(sps.Log2MaxPicOrderCntLsb))
+		if pps.BottomFieldPicOrderInFramePresent && !h.FieldPic {
+			h.DeltaPicOrderCntBottom = r.ReadSE()
+		}
+	case sps.PicOrderCntType == 1 && !sps.DeltaPicOrderAlwaysZero:
+		h.DeltaPicOrderCnt[0] = r.ReadSE()
+		if pps.BottomFieldPicOrderInFramePresent && !h.FieldPic {
+			h.DeltaPicOrderCnt[1] = r.ReadSE()
+		}
+	}
+	if pps.RedundantPicCntPresent {
+		h.RedundantPicCnt = r.ReadUE()
+	}
+	return r.Err()
+}
+
+// Unmarshal parses the slice header of the passed slice NAL unit, its header included, and stores the result in the
+// SliceHeader this method is called upon; ps holds the parameter sets received
+func (h *SliceHeader) Unmarshal(nalu []byte, ps *ParameterSets) error {
+	if nalu == nil {
+		return fmt.Errorf("invalid nil nalu")
+	}
+	if len(nalu) < 2 {
+		return fmt.Errorf("buf is not large enough to container slice header")
+	}
+	nalHeader := ParseNalHeader(nalu)
+	switch nalHeader.NalUnitType {
+	case NalUnitTypeSlice, NalUnitTypeDpa, NalUnitTypeIdrSlice:
+	default:
+		return fmt.Errorf("NAL unit type %s has no slice header", nalHeader.NalUnitType)
+	}
+	// the slice header is short, only its first bytes are unescaped
+	ebsp := nalu[1:]
+	if len(ebsp) > 64 {
+		ebsp = ebsp[:64]
+	}
+	return h.Decode(bitstream.NewReader(bitstream.RemoveEmulationPrevention(ebsp)), nalHeader, ps)
+}
+
+// FirstOfNewPicture reports whether the slice starts a new primary coded picture after the slice prev, 7.4.1.2.4
+func (h SliceHeader) FirstOfNewPicture(prev SliceHeader) bool {
+	switch {
+	case h.RedundantPicCnt > 0:
+		// redundant coded pictures follow their primary coded picture
+		return false
+	case h.FrameNum != prev.FrameNum,
+		h.PPSID != prev.PPSID,
+		h.FieldPic != prev.FieldPic,
+		h.FieldPic && h.BottomField != prev.BottomField,
+		(h.NalRefIdc == 0) != (prev.NalRefIdc == 0),
+		h.PicOrderCntLsb != prev.PicOrderCntLsb,
+		h.DeltaPicOrderCntBottom != prev.DeltaPicOrderCntBottom,
+		h.DeltaPicOrderCnt != prev.DeltaPicOrderCnt,
+		h.IdrPic() != prev.IdrPic(),
+		h.IdrPic() && prev.IdrPic() && h.IdrPicID != prev.IdrPicID:
+		return true
+	}
+	return false
+}
+
+// String helps with debugging by printing SliceHeader information in a readable way
+func (h SliceHeader) String() string {
+	out := "H264 SliceHeader:\n"
+
+	out += fmt.Sprintf("\tNalUnitType: %s\n", h.NalUnitType)
+	out += fmt.Sprintf("\tFirstMbInSlice: %v\n", h.FirstMbInSlice)
+	out += fmt.Sprintf("\tSliceType: %s\n", h.SliceType)
+	out += fmt.Sprintf("\tPPSID: %v\n", h.PPSID)
+	out += fmt.Sprintf("\tFrameNum: %v\n", h.FrameNum)
+	out += fmt.Sprintf("\tPicOrderCntLsb: %v\n", h.PicOrderCntLsb)
+
+	return out
+}
+
+func ParseSliceHeader(nalu []byte, ps *ParameterSets) (SliceHeader, error) {
+	var h SliceHeader
+	err := (&h).Unmarshal(nalu, ps)
+	return h, err
+}
@@ -0,0 +1,361 @@
+package h264
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// SPS represents a sequence parameter set, seq_parameter_set_data() in 7.3.2.1.1 of T-REC-H.264
+type SPS struct {
+	NalRefIdc NalRefIdc
+
+	ProfileIdc uint8
+	// ConstraintFlags holds constraint_set0_flag to constraint_set5_flag in its upper bits, and the reserved zero bits
+	ConstraintFlags uint8
+	LevelIdc        uint8
+	ID              uint32
+
+	// ChromaFormatIdc is 0 for monochrome, 1 for 4:2:0, 2 for 4:2:2 and 3 for 4:4:4
+	ChromaFormatIdc             uint32
+	SeparateColourPlane         bool
+	BitDepthLuma                uint32
+	BitDepthChroma              uint32
+	QpprimeYZeroTransformBypass bool
+	SeqScalingMatrixPresent     bool
+	// ScalingLists holds the delta_scale values of the scaling lists coded, nil for those not present
+	ScalingLists [12][]int32
+
+	Log2MaxFrameNum uint32
+	PicOrderCntType uint32
+	// Log2MaxPicOrderCntLsb is set with PicOrderCntType 0
+	Log2MaxPicOrderCntLsb uint32
+	// the fields of PicOrderCntType 1
+	DeltaPicOrderAlwaysZero   bool
+	OffsetForNonRefPic        int32
+	OffsetForTopToBottomField int32
+	OffsetForRefFrame         []int32
+
+	MaxNumRefFrames       uint32
+	GapsInFrameNumAllowed bool
+	PicWidthInMbs         uint32
+	PicHeightInMapUnits   uint32
+	FrameMbsOnly          bool
+	MbAdaptiveFrameField  bool
+	Direct8x8Inference    bool
+
+	FrameCropping   bool
+	FrameCropLeft   uint32
+	FrameCropRight  uint32
+	FrameCropTop    uint32
+	FrameCropBottom uint32
+
+	// VUI is set when the VUI parameters are present
+	VUI *VUI
+}
+
+// HasChromaFormat reports whether the profile codes the chroma format, bit depths and scaling matrices
+func (s SPS) HasChromaFormat() bool {
+	switch s.ProfileIdc {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		return true
+	}
+	return false
+}
+
+// ChromaArrayType returns ChromaArrayType, 0 when the colour planes are coded separately
+func (s SPS) ChromaArrayType() uint32 {
+	if s.SeparateColourPlane {
+		return 0
+	}
+	return s.ChromaFormatIdc
+}
+
+// PicHeightInMbs returns the height of a frame in macroblocks, FrameHeightInMbs
+func (s SPS) PicHeightInMbs() uint32 {
+	if s.FrameMbsOnly {
+		return s.PicHeightInMapUnits
+	}
+	return s.PicHeightInMapUnits * 2
+}
+
+// cropUnits returns CropUnitX and CropUnitY, 7.4.2.1.1
+func (s SPS) cropUnits() (x, y uint32) {
+	x, y = 1, 1
+	switch s.ChromaArrayType() {
+	case 1:
+		x, y = 2, 2
+	case 2:
+		x = 2
+	}
+	if !s.FrameMbsOnly {
+		y *= 2
+	}
+	return x, y
+}
+
+// Width returns the width in pixels of the frames, once cropped
+func (s SPS) Width() int {
+	x, _ := s.cropUnits()
+	return int(s.PicWidthInMbs*16) - int(x*(s.FrameCropLeft+s.FrameCropRight))
+}
+
+// Height returns the height in pixels of the frames, once cropped
+func (s SPS) Height() int {
+	_, y := s.cropUnits()
+	return int(s.PicHeightInMbs()*16) - int(y*(s.FrameCropTop+s.FrameCropBottom))
+}
+
+// FrameRate returns the frame rate of the timing information, 0 when absent
+// A frame lasts two ticks, E.2.1
+func (s SPS) FrameRate() float64 {
+	if s.VUI == nil || !s.VUI.TimingInfoPresent || s.VUI.NumUnitsInTick == 0 {
+		return 0
+	}
+	return float64(s.VUI.TimeScale) / float64(2*s.VUI.NumUnitsInTick)
+}
+
+// ProfileLevelID returns the profile-level-id of the SDP fmtp, see rfc6184
+func (s SPS) ProfileLevelID() string {
+	return fmt.Sprintf("%02x%02x%02x", s.ProfileIdc, s.ConstraintFlags, s.LevelIdc)
+}
+
+// CodecString returns the codecs parameter of the sequence, avc1.PPCCLL, see rfc6381
+func (s SPS) CodecString() string {
+	return "avc1." + s.ProfileLevelID()
+}
+
+// Decode reads the SPS RBSP, without its NAL header
+func (s *SPS) Decode(r *bitstream.Reader) error {
+	*s = SPS{NalRefIdc: s.NalRefIdc}
+	s.ProfileIdc = r.ReadUint8(8)
+	s.ConstraintFlags = r.ReadUint8(8)
+	s.LevelIdc = r.ReadUint8(8)
+	s.ID = r.ReadUE()
+	if s.ID >= MaxSpsCount {
+		return fmt.Errorf("seq_parameter_set_id %d exceeds %d", s.ID, MaxSpsCount-1)
+	}
+
+	s.ChromaFormatIdc = 1
+	s.BitDepthLuma = 8
+	s.BitDepthChroma = 8
+	if s.HasChromaFormat() {
+		s.ChromaFormatIdc = r.ReadUE()
+		if s.ChromaFormatIdc > 3 {
+			return fmt.Errorf("invalid chroma_format_idc %d", s.ChromaFormatIdc)
+		}
+		if s.ChromaFormatIdc == 3 {
+			s.SeparateColourPlane = r.ReadFlag()
+		}
+		s.BitDepthLuma = r.ReadUE() + 8
+		s.BitDepthChroma = r.ReadUE() + 8
+		if s.BitDepthLuma > 14 || s.BitDepthChroma > 14 {
+			return fmt.Errorf("invalid bit depth %d/%d", s.BitDepthLuma, s.BitDepthChroma)
+		}
+		s.QpprimeYZeroTransformBypass = r.ReadFlag()
+		if s.SeqScalingMatrixPresent = r.ReadFlag(); s.SeqScalingMatrixPresent {
+			n := 8
+			if s.ChromaFormatIdc == 3 {
+				n = 12
+			}
+			decodeScalingLists(r, s.ScalingLists[:n])
+		}
+	}
+
+	s.Log2MaxFrameNum = r.ReadUE() + 4
+	if s.Log2MaxFrameNum > 16 {
+		return fmt.Errorf("invalid log2_max_frame_num_minus4 %d", s.Log2MaxFrameNum-4)
+	}
+	s.PicOrderCntType = r.ReadUE()
+	switch s.PicOrderCntType {
+	case 0:
+		s.Log2MaxPicOrderCntLsb = r.ReadUE() + 4
+		if s.Log2MaxPicOrderCntLsb > 16 {
+			return fmt.Errorf("invalid log2_max_pic_order_cnt_lsb_minus4 %d", s.Log2MaxPicOrderCntLsb-4)
+		}
+	case 1:
+		s.DeltaPicOrderAlwaysZero = r.ReadFlag()
+		s.OffsetForNonRefPic = r.ReadSE()
+		s.OffsetForTopToBottomField = r.ReadSE()
+		n := r.ReadUE()
+		if n > 255 {
+			return fmt.Errorf("invalid num_ref_frames_in_pic_order_cnt_cycle %d", n)
+		}
+		s.OffsetForRefFrame = make([]int32, n)
+		for i := range s.OffsetForRefFrame {
+			s.OffsetForRefFrame[i] = r.ReadSE()
+		}
+	case 2:
+	default:
+		return fmt.Errorf("invalid pic_order_cnt_type %d", s.PicOrderCntType)
+	}
+
+	s.MaxNumRefFrames = r.ReadUE()
+	if s.MaxNumRefFrames > MaxDpbFrames {
+		return fmt.Errorf("max_num_ref_frames %d exceeds %d", s.MaxNumRefFrames, MaxDpbFrames)
+	}
+	s.GapsInFrameNumAllowed = r.ReadFlag()
+	s.PicWidthInMbs = r.ReadUE() + 1
+	s.PicHeightInMapUnits = r.ReadUE() + 1
+	s.FrameMbsOnly = r.ReadFlag()
+	if !s.FrameMbsOnly {
+		s.MbAdaptiveFrameField = r.ReadFlag()
+	}
+	if s.PicWidthInMbs > MaxMbWidth || s.PicHeightInMbs() > MaxMbHeight {
+		return fmt.Errorf("dimensions %dx%d macroblocks exceed %dx%d", s.PicWidthInMbs, s.PicHeightInMbs(), MaxMbWidth, MaxMbHeight)
+	}
+	s.Direct8x8Inference = r.ReadFlag()
+	if s.FrameCropping = r.ReadFlag(); s.FrameCropping {
+		s.FrameCropLeft = r.ReadUE()
+		s.FrameCropRight = r.ReadUE()
+		s.FrameCropTop = r.ReadUE()
+		s.FrameCropBottom = r.ReadUE()
+		if s.Width() <= 0 || s.Height() <= 0 {
+			return fmt.Errorf("cropping exceeds the frame")
+		}
+	}
+	if r.ReadFlag() {
+		s.VUI = &VUI{}
+		if err := s.VUI.Decode(r); err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// Encode writes the SPS RBSP, without its NAL header, up to its trailing bits
+func (s SPS) Encode(w *bitstream.Writer) {
+	w.WriteBits(uint64(s.ProfileIdc), 8)
+	w.WriteBits(uint64(s.ConstraintFlags), 8)
+	w.WriteBits(uint64(s.LevelIdc), 8)
+	w.WriteUE(s.ID)
+	if s.HasChromaFormat() {
+		w.WriteUE(s.ChromaFormatIdc)
+		if s.ChromaFormatIdc == 3 {
+			w.WriteFlag(s.SeparateColourPlane)
+		}
+		w.WriteUE(s.BitDepthLuma - 8)
+		w.WriteUE(s.BitDepthChroma - 8)
+		w.WriteFlag(s.QpprimeYZeroTransformBypass)
+		if w.WriteFlag(s.SeqScalingMatrixPresent); s.SeqScalingMatrixPresent {
+			n := 8
+			if s.ChromaFormatIdc == 3 {
+				n = 12
+			}
+			encodeScalingLists(w, s.ScalingLists[:n])
+		}
+	}
+	w.WriteUE(s.Log2MaxFrameNum - 4)
+	w.WriteUE(s.PicOrderCntType)
+	switch s.PicOrderCntType {
+	case 0:
+		w.WriteUE(s.Log2MaxPicOrderCntLsb - 4)
+	case 1:
+		w.WriteFlag(s.DeltaPicOrderAlwaysZero)
+		w.WriteSE(s.OffsetForNonRefPic)
+		w.WriteSE(s.OffsetForTopToBottomField)
+		w.WriteUE(uint32(len(s.OffsetForRefFrame)))
+		for _, offset := range s.OffsetForRefFrame {
+			w.WriteSE(offset)
+		}
+	}
+	w.WriteUE(s.MaxNumRefFrames)
+	w.WriteFlag(s.GapsInFrameNumAllowed)
+	w.WriteUE(s.PicWidthInMbs - 1)
+	w.WriteUE(s.PicHeightInMapUnits - 1)
+	if w.WriteFlag(s.FrameMbsOnly); !s.FrameMbsOnly {
+		w.WriteFlag(s.MbAdaptiveFrameField)
+	}
+	w.WriteFlag(s.Direct8x8Inference)
+	if w.WriteFlag(s.FrameCropping); s.FrameCropping {
+		w.WriteUE(s.FrameCropLeft)
+		w.WriteUE(s.FrameCropRight)
+		w.WriteUE(s.FrameCropTop)
+		w.WriteUE(s.FrameCropBottom)
+	}
+	if w.WriteFlag(s.VUI != nil); s.VUI != nil {
+		s.VUI.Encode(w)
+	}
+	w.WriteTrailingBits()
+}
+
+// Unmarshal parses the passed SPS NAL unit, its header included, and stores the result in the SPS this method is called upon
+func (s *SPS) Unmarshal(nalu []byte) error {
+	if nalu == nil {
+		return fmt.Errorf("invalid nil nalu")
+	}
+	if len(nalu) < 4 {
+		return fmt.Errorf("buf is not large enough to container SPS")
+	}
+	h := ParseNalHeader(nalu)
+	if h.NalUnitType != NalUnitTypeSps {
+		return fmt.Errorf("NAL unit type %s is not SPS", h.NalUnitType)
+	}
+	s.NalRefIdc = h.NalRefIdc
+	return s.Decode(bitstream.NewReader(bitstream.RemoveEmulationPrevention(nalu[1:])))
+}
+
+// Marshal serializes the SPS into a NAL unit, its header included
+func (s SPS) Marshal() ([]byte, error) {
+	w := bitstream.NewWriter()
+	s.Encode(w)
+	h := NalHeader{NalRefIdc: s.NalRefIdc, NalUnitType: NalUnitTypeSps}
+	return append([]byte{h.Byte()}, bitstream.AddEmulationPrevention(w.Bytes())...), nil
+}
+
+// String helps with debugging by printing SPS information in a readable way
+func (s SPS) String() string {
+	out := "H264 SPS:\n"
+
+	out += fmt.Sprintf("\tID: %v\n", s.ID)
+	out += fmt.Sprintf("\tProfileLevelID: %v\n", s.ProfileLevelID())
+	out += fmt.Sprintf("\tChromaFormatIdc: %v\n", s.ChromaFormatIdc)
+	out += fmt.Sprintf("\tBitDepth: %v/%v\n", s.BitDepthLuma, s.BitDepthChroma)
+	out += fmt.Sprintf("\tResolution: %vx%v\n", s.Width(), s.Height())
+	out += fmt.Sprintf("\tFrameMbsOnly: %v\n", s.FrameMbsOnly)
+	out += fmt.Sprintf("\tFrameRate: %v\n", s.FrameRate())
+
+	return out
+}
+
+func ParseSPS(nalu []byte) (SPS, error) {
+	var s SPS
+	err := (&s).Unmarshal(nalu)
+	return s, err
+}
+
+// decodeScalingLists reads the scaling lists of a scaling matrix, the 4x4 lists first then the 8x8 ones, 7.3.2.1.1.1
+// The delta_scale values are kept as coded: a list stops early, with nextScale 0, to use its default
+func decodeScalingLists(r *bitstream.Reader, lists [][]int32) {
+	for i := range lists {
+		lists[i] = nil
+		if !r.ReadFlag() {
+			continue
+		}
+		size := 16
+		if i >= 6 {
+			size = 64
+		}
+		lists[i] = []int32{}
+		for j, lastScale, nextScale := 0, int32(8), int32(8); j < size && nextScale != 0 && r.Err() == nil; j++ {
+			delta := r.ReadSE()
+			lists[i] = append(lists[i], delta)
+			nextScale = (lastScale + delta + 256) % 256
+			if nextScale != 0 {
+				lastScale = nextScale
+			}
+		}
+	}
+}
+
+// encodeScalingLists writes the scaling lists of a scaling matrix
+func encodeScalingLists(w *bitstream.Writer, lists [][]int32) {
+	for _, list := range lists {
+		if w.WriteFlag(list != nil); list == nil {
+			continue
+		}
+		for _, delta := range list {
+			w.WriteSE(delta)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package h264
+
+import "fmt"
+
+// SPSRewriter rewrites SPS NAL units, as a SFU does so that decoders behave, see webrtc/common_video/h264/sps_vui_rewriter.cc
+type SPSRewriter struct {
+	// LevelIdc replaces level_idc when not 0, such as 31 for level 3.1
+	LevelIdc uint8
+	// SkipVUI leaves the VUI as is; otherwise the VUI gets a bitstream restriction with max_num_reorder_frames 0
+	// and max_dec_frame_buffering as low as max_num_ref_frames allows, 1 usually,
+	// so that decoders output each frame as soon as it is decoded
+	SkipVUI bool
+}
+
+// Rewrite returns the SPS NAL unit rewritten, with its emulation prevention bytes; the SPS is returned as is
+// if it doesn't need to be rewritten
+func (rw SPSRewriter) Rewrite(nalu []byte) ([]byte, error) {
+	s, err := ParseSPS(nalu)
+	if err != nil {
+		return nil, err
+	}
+	var rewritten bool
+	if rw.LevelIdc != 0 && s.LevelIdc != rw.LevelIdc {
+		s.LevelIdc = rw.LevelIdc
+		rewritten = true
+	}
+	if !rw.SkipVUI && rewriteVUI(&s) {
+		rewritten = true
+	}
+	if !rewritten {
+		return nalu, nil
+	}
+	raw, err := s.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("malformed SPS rewritten: %s", err)
+	}
+	return raw, nil
+}
+
+// rewriteVUI sets the bitstream restriction of the VUI of the SPS for no frame reordering nor buffering,
+// reporting whether the SPS is modified
+func rewriteVUI(s *SPS) bool {
+	maxDecFrameBuffering := s.MaxNumRefFrames
+	if maxDecFrameBuffering < 1 {
+		maxDecFrameBuffering = 1
+	}
+	if s.VUI == nil {
+		s.VUI = &VUI{}
+	}
+	v := s.VUI
+	if v.BitstreamRestriction {
+		if v.MaxNumReorderFrames == 0 && v.MaxDecFrameBuffering <= maxDecFrameBuffering {
+			return false
+		}
+	} else {
+		// the values inferred in the absence of bitstream_restriction_flag, E.2.1
+		v.BitstreamRestriction = true
+		v.MotionVectorsOverPicBoundaries = true
+		v.MaxBytesPerPicDenom = 2
+		v.MaxBitsPerMbDenom = 1
+		v.Log2MaxMvLengthHorizontal = 16
+		v.Log2MaxMvLengthVertical = 16
+	}
+	v.MaxNumReorderFrames = 0
+	v.MaxDecFrameBuffering = maxDecFrameBuffering
+	return true
+}
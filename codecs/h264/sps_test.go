@@ -0,0 +1,136 @@
+package h264
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSPS(t *testing.T) {
+	tests := []struct {
+		name          string
+		nalu          []byte
+		width, height int
+		frameRate     float64
+		codec         string
+		sarWidth      uint16
+	}{
+		{
+			name: "constrained baseline",
+			nalu: []byte{0x67, 0x42, 0xc0, 0x28, 0xd9, 0x00, 0x78, 0x02, 0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+				0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc9, 0x20},
+			width: 1920, height: 1080, frameRate: 30, codec: "avc1.42c028",
+		},
+		{
+			name: "high",
+			nalu: []byte{0x67, 0x64, 0x00, 0x28, 0xac, 0xd9, 0x40, 0x78, 0x02, 0x27, 0xe5, 0xc0, 0x44, 0x00, 0x00, 0x03,
+				0x00, 0x04, 0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc6, 0x58},
+			width: 1920, height: 1080, frameRate: 30, codec: "avc1.640028", sarWidth: 1,
+		},
+	}
+	for _, tt := range tests {
+		s, err := ParseSPS(tt.nalu)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if s.Width() != tt.width || s.Height() != tt.height {
+			t.Errorf("%s: resolution %dx%d, want %dx%d", tt.name, s.Width(), s.Height(), tt.width, tt.height)
+		}
+		if s.FrameRate() != tt.frameRate {
+			t.Errorf("%s: frame rate %v, want %v", tt.name, s.FrameRate(), tt.frameRate)
+		}
+		if s.CodecString() != tt.codec {
+			t.Errorf("%s: codec %s, want %s", tt.name, s.CodecString(), tt.codec)
+		}
+		if s.ChromaFormatIdc != 1 || s.BitDepthLuma != 8 || !s.FrameMbsOnly {
+			t.Errorf("%s: unexpected format %s", tt.name, s)
+		}
+		if w, _ := s.VUI.SampleAspectRatio(); w != tt.sarWidth {
+			t.Errorf("%s: sample aspect ratio width %d, want %d", tt.name, w, tt.sarWidth)
+		}
+		raw, _ := s.Marshal()
+		if !bytes.Equal(raw, tt.nalu) {
+			t.Errorf("%s: marshal % x, want % x", tt.name, raw, tt.nalu)
+		}
+	}
+
+	if _, err := ParseSPS([]byte{0x68, 0xeb, 0xe3, 0xcb, 0x22, 0xc0}); err == nil {
+		t.Error("PPS parsed as SPS")
+	}
+	if _, err := ParseSPS([]byte{0x67, 0x64, 0x00, 0x28, 0xac}); err == nil {
+		t.Error("truncated SPS parsed")
+	}
+}
+
+func TestPPS(t *testing.T) {
+	nalu := []byte{0x68, 0xeb, 0xe3, 0xcb, 0x22, 0xc0}
+	p, err := ParsePPS(nalu, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.EntropyCodingMode || !p.Transform8x8Mode || p.PicInitQp != 23 || p.NumSliceGroups != 1 {
+		t.Errorf("unexpected %s", p)
+	}
+	raw, _ := p.Marshal(nil)
+	if !bytes.Equal(raw, nalu) {
+		t.Errorf("marshal % x, want % x", raw, nalu)
+	}
+}
+
+func TestSPSRewriter(t *testing.T) {
+	orig, err := ParseSPS([]byte{0x67, 0x42, 0xc0, 0x28, 0xd9, 0x00, 0x78, 0x02, 0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+		0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc9, 0x20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a single reference frame, without bitstream restriction
+	orig.MaxNumRefFrames = 1
+	orig.VUI.BitstreamRestriction = false
+	nalu, _ := orig.Marshal()
+
+	raw, err := SPSRewriter{LevelIdc: 31}.Rewrite(nalu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw[1:], []byte{0x00, 0x00, 0x00}) || bytes.Contains(raw[1:], []byte{0x00, 0x00, 0x01}) {
+		t.Fatalf("Rewrite should insert emulation prevention bytes: % x", raw)
+	}
+	s, err := ParseSPS(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.LevelIdc != 31 || s.Width() != orig.Width() || s.FrameRate() != orig.FrameRate() {
+		t.Fatalf("Rewrite should only change the level and VUI: %s", s)
+	}
+	v := s.VUI
+	if !v.BitstreamRestriction || v.MaxNumReorderFrames != 0 || v.MaxDecFrameBuffering != 1 ||
+		!v.MotionVectorsOverPicBoundaries || v.Log2MaxMvLengthHorizontal != 16 || !v.TimingInfoPresent {
+		t.Fatalf("Rewrite should add the bitstream restriction to the VUI: %+v", v)
+	}
+
+	// already rewritten
+	if again, err := (SPSRewriter{}).Rewrite(raw); err != nil || !bytes.Equal(again, raw) {
+		t.Fatal("Rewrite should leave a rewritten SPS as is")
+	}
+	if raw, err := (SPSRewriter{SkipVUI: true}).Rewrite(nalu); err != nil || !bytes.Equal(raw, nalu) {
+		t.Fatal("Rewrite should leave the SPS as is with nothing to rewrite")
+	}
+
+	// no VUI
+	orig.VUI = nil
+	nalu, _ = orig.Marshal()
+	if s, err = ParseSPS(mustRewrite(t, nalu)); err != nil || s.VUI == nil || s.VUI.MaxDecFrameBuffering != 1 || s.VUI.TimingInfoPresent {
+		t.Fatal("Rewrite should add a VUI with the bitstream restriction only")
+	}
+
+	if _, err := (SPSRewriter{}).Rewrite([]byte{0x68, 0xeb, 0xe3, 0xcb, 0x22, 0xc0}); err == nil {
+		t.Fatal("Rewrite should fail on a PPS")
+	}
+}
+
+func mustRewrite(t *testing.T, nalu []byte) []byte {
+	raw, err := SPSRewriter{}.Rewrite(nalu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
@@ -0,0 +1,236 @@
+package h264
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// E.2.1: aspect_ratio_idc of an explicit sample aspect ratio
+const AspectRatioIdcExtendedSAR = 255
+
+// sampleAspectRatios holds the sample aspect ratios of aspect_ratio_idc 1 to 16, Table E-1
+var sampleAspectRatios = [...][2]uint16{
+	{0, 0}, {1, 1}, {12, 11}, {10, 11}, {16, 11}, {40, 33}, {24, 11}, {20, 11}, {32, 11},
+	{80, 33}, {18, 11}, {15, 11}, {64, 33}, {160, 99}, {4, 3}, {3, 2}, {2, 1},
+}
+
+// HRD represents the hypothetical reference decoder parameters, hrd_parameters() in E.1.2
+type HRD struct {
+	// CpbCnt is cpb_cnt_minus1 + 1
+	CpbCnt       uint32
+	BitRateScale uint8
+	CpbSizeScale uint8
+	// BitRateValueMinus1, CpbSizeValueMinus1 and CbrFlag hold a value per CPB
+	BitRateValueMinus1 []uint32
+	CpbSizeValueMinus1 []uint32
+	CbrFlag            []bool
+	// the lengths in bits of the fields of the buffering period and picture timing SEI messages
+	InitialCpbRemovalDelayLength uint8
+	CpbRemovalDelayLength        uint8
+	DpbOutputDelayLength         uint8
+	TimeOffsetLength             uint8
+}
+
+// Decode reads the HRD parameters
+func (h *HRD) Decode(r *bitstream.Reader) error {
+	h.CpbCnt = r.ReadUE() + 1
+	if h.CpbCnt > MaxCpbCnt {
+		return fmt.Errorf("cpb_cnt_minus1 %d exceeds %d", h.CpbCnt-1, MaxCpbCnt-1)
+	}
+	h.BitRateScale = r.ReadUint8(4)
+	h.CpbSizeScale = r.ReadUint8(4)
+	h.BitRateValueMinus1 = make([]uint32, h.CpbCnt)
+	h.CpbSizeValueMinus1 = make([]uint32, h.CpbCnt)
+	h.CbrFlag = make([]bool, h.CpbCnt)
+	for i := range h.CbrFlag {
+		h.BitRateValueMinus1[i] = r.ReadUE()
+		h.CpbSizeValueMinus1[i] = r.ReadUE()
+		h.CbrFlag[i] = r.ReadFlag()
+	}
+	h.InitialCpbRemovalDelayLength = r.ReadUint8(5) + 1
+	h.CpbRemovalDelayLength = r.ReadUint8(5) + 1
+	h.DpbOutputDelayLength = r.ReadUint8(5) + 1
+	h.TimeOffsetLength = r.ReadUint8(5)
+	return r.Err()
+}
+
+// Encode writes the HRD parameters
+func (h HRD) Encode(w *bitstream.Writer) {
+	w.WriteUE(h.CpbCnt - 1)
+	w.WriteBits(uint64(h.BitRateScale), 4)
+	w.WriteBits(uint64(h.CpbSizeScale), 4)
+	for i := 0; i < int(h.CpbCnt); i++ {
+		w.WriteUE(h.BitRateValueMinus1[i])
+		w.WriteUE(h.CpbSizeValueMinus1[i])
+		w.WriteFlag(h.CbrFlag[i])
+	}
+	w.WriteBits(uint64(h.InitialCpbRemovalDelayLength-1), 5)
+	w.WriteBits(uint64(h.CpbRemovalDelayLength-1), 5)
+	w.WriteBits(uint64(h.DpbOutputDelayLength-1), 5)
+	w.WriteBits(uint64(h.TimeOffsetLength), 5)
+}
+
+// VUI represents the video usability information, vui_parameters() in E.1.1
+type VUI struct {
+	AspectRatioInfoPresent bool
+	AspectRatioIdc         uint8
+	// SarWidth and SarHeight are set with AspectRatioIdcExtendedSAR
+	SarWidth  uint16
+	SarHeight uint16
+
+	OverscanInfoPresent bool
+	OverscanAppropriate bool
+
+	VideoSignalTypePresent   bool
+	VideoFormat              uint8
+	VideoFullRange           bool
+	ColourDescriptionPresent bool
+	ColourPrimaries          uint8
+	TransferCharacteristics  uint8
+	MatrixCoefficients       uint8
+
+	ChromaLocInfoPresent           bool
+	ChromaSampleLocTypeTopField    uint32
+	ChromaSampleLocTypeBottomField uint32
+
+	TimingInfoPresent bool
+	NumUnitsInTick    uint32
+	TimeScale         uint32
+	FixedFrameRate    bool
+
+	// NalHrd and VclHrd are set when the parameters are present
+	NalHrd      *HRD
+	VclHrd      *HRD
+	LowDelayHrd bool
+
+	PicStructPresent bool
+
+	BitstreamRestriction           bool
+	MotionVectorsOverPicBoundaries bool
+	MaxBytesPerPicDenom            uint32
+	MaxBitsPerMbDenom              uint32
+	Log2MaxMvLengthHorizontal      uint32
+	Log2MaxMvLengthVertical        uint32
+	MaxNumReorderFrames            uint32
+	MaxDecFrameBuffering           uint32
+}
+
+// SampleAspectRatio returns the sample aspect ratio, 0:0 when unspecified
+func (v VUI) SampleAspectRatio() (width, height uint16) {
+	switch {
+	case !v.AspectRatioInfoPresent:
+		return 0, 0
+	case v.AspectRatioIdc == AspectRatioIdcExtendedSAR:
+		return v.SarWidth, v.SarHeight
+	case int(v.AspectRatioIdc) < len(sampleAspectRatios):
+		sar := sampleAspectRatios[v.AspectRatioIdc]
+		return sar[0], sar[1]
+	}
+	return 0, 0
+}
+
+// Decode reads the VUI parameters
+func (v *VUI) Decode(r *bitstream.Reader) error {
+	*v = VUI{}
+	if v.AspectRatioInfoPresent = r.ReadFlag(); v.AspectRatioInfoPresent {
+		v.AspectRatioIdc = r.ReadUint8(8)
+		if v.AspectRatioIdc == AspectRatioIdcExtendedSAR {
+			v.SarWidth = r.ReadUint16(16)
+			v.SarHeight = r.ReadUint16(16)
+		}
+	}
+	if v.OverscanInfoPresent = r.ReadFlag(); v.OverscanInfoPresent {
+		v.OverscanAppropriate = r.ReadFlag()
+	}
+	if v.VideoSignalTypePresent = r.ReadFlag(); v.VideoSignalTypePresent {
+		v.VideoFormat = r.ReadUint8(3)
+		v.VideoFullRange = r.ReadFlag()
+		if v.ColourDescriptionPresent = r.ReadFlag(); v.ColourDescriptionPresent {
+			v.ColourPrimaries = r.ReadUint8(8)
+			v.TransferCharacteristics = r.ReadUint8(8)
+			v.MatrixCoefficients = r.ReadUint8(8)
+		}
+	}
+	if v.ChromaLocInfoPresent = r.ReadFlag(); v.ChromaLocInfoPresent {
+		v.ChromaSampleLocTypeTopField = r.ReadUE()
+		v.ChromaSampleLocTypeBottomField = r.ReadUE()
+	}
+	if v.TimingInfoPresent = r.ReadFlag(); v.TimingInfoPresent {
+		v.NumUnitsInTick = r.ReadUint32(32)
+		v.TimeScale = r.ReadUint32(32)
+		v.FixedFrameRate = r.ReadFlag()
+	}
+	for _, hrd := range []**HRD{&v.NalHrd, &v.VclHrd} {
+		if r.ReadFlag() {
+			*hrd = &HRD{}
+			if err := (*hrd).Decode(r); err != nil {
+				return err
+			}
+		}
+	}
+	if v.NalHrd != nil || v.VclHrd != nil {
+		v.LowDelayHrd = r.ReadFlag()
+	}
+	v.PicStructPresent = r.ReadFlag()
+	if v.BitstreamRestriction = r.ReadFlag(); v.BitstreamRestriction {
+		v.MotionVectorsOverPicBoundaries = r.ReadFlag()
+		v.MaxBytesPerPicDenom = r.ReadUE()
+		v.MaxBitsPerMbDenom = r.ReadUE()
+		v.Log2MaxMvLengthHorizontal = r.ReadUE()
+		v.Log2MaxMvLengthVertical = r.ReadUE()
+		v.MaxNumReorderFrames = r.ReadUE()
+		v.MaxDecFrameBuffering = r.ReadUE()
+	}
+	return r.Err()
+}
+
+// Encode writes the VUI parameters
+func (v VUI) Encode(w *bitstream.Writer) {
+	if w.WriteFlag(v.AspectRatioInfoPresent); v.AspectRatioInfoPresent {
+		w.WriteBits(uint64(v.AspectRatioIdc), 8)
+		if v.AspectRatioIdc == AspectRatioIdcExtendedSAR {
+			w.WriteBits(uint64(v.SarWidth), 16)
+			w.WriteBits(uint64(v.SarHeight), 16)
+		}
+	}
+	if w.WriteFlag(v.OverscanInfoPresent); v.OverscanInfoPresent {
+		w.WriteFlag(v.OverscanAppropriate)
+	}
+	if w.WriteFlag(v.VideoSignalTypePresent); v.VideoSignalTypePresent {
+		w.WriteBits(uint64(v.VideoFormat), 3)
+		w.WriteFlag(v.VideoFullRange)
+		if w.WriteFlag(v.ColourDescriptionPresent); v.ColourDescriptionPresent {
+			w.WriteBits(uint64(v.ColourPrimaries), 8)
+			w.WriteBits(uint64(v.TransferCharacteristics), 8)
+			w.WriteBits(uint64(v.MatrixCoefficients), 8)
+		}
+	}
+	if w.WriteFlag(v.ChromaLocInfoPresent); v.ChromaLocInfoPresent {
+		w.WriteUE(v.ChromaSampleLocTypeTopField)
+		w.WriteUE(v.ChromaSampleLocTypeBottomField)
+	}
+	if w.WriteFlag(v.TimingInfoPresent); v.TimingInfoPresent {
+		w.WriteBits(uint64(v.NumUnitsInTick), 32)
+		w.WriteBits(uint64(v.TimeScale), 32)
+		w.WriteFlag(v.FixedFrameRate)
+	}
+	for _, hrd := range []*HRD{v.NalHrd, v.VclHrd} {
+		if w.WriteFlag(hrd != nil); hrd != nil {
+			hrd.Encode(w)
+		}
+	}
+	if v.NalHrd != nil || v.VclHrd != nil {
+		w.WriteFlag(v.LowDelayHrd)
+	}
+	w.WriteFlag(v.PicStructPresent)
+	if w.WriteFlag(v.BitstreamRestriction); v.BitstreamRestriction {
+		w.WriteFlag(v.MotionVectorsOverPicBoundaries)
+		w.WriteUE(v.MaxBytesPerPicDenom)
+		w.WriteUE(v.MaxBitsPerMbDenom)
+		w.WriteUE(v.Log2MaxMvLengthHorizontal)
+		w.WriteUE(v.Log2MaxMvLengthVertical)
+		w.WriteUE(v.MaxNumReorderFrames)
+		w.WriteUE(v.MaxDecFrameBuffering)
+	}
+}
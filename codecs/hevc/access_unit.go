@@ -0,0 +1,99 @@
+package hevc
+
+import "github.com/searKing/rtp/codecs/h264"
+
+// AccessUnit represents the NAL units of an access unit, 7.4.2.4.4 of T-REC-H.265
+type AccessUnit struct {
+	NALUs [][]byte
+	// KeyFrame is set when the picture is an IRAP picture
+	KeyFrame bool
+}
+
+// AnnexB returns the access unit as a byte stream in the format of Annex B
+func (au AccessUnit) AnnexB() []byte {
+	return h264.JoinAnnexB(au.NALUs)
+}
+
+// AccessUnitSplitter splits a byte stream in the format of Annex B, received in chunks, into access units, 7.4.2.4.4
+// Only the NAL units of the base layer, of nuh_layer_id 0, may start an access unit
+type AccessUnitSplitter struct {
+	// ParameterSets holds the parameter sets of the stream, kept for the callers
+	ParameterSets ParameterSets
+
+	scanner h264.AnnexBScanner
+	au      AccessUnit
+	// hasVCL is set once au holds a slice segment
+	hasVCL bool
+}
+
+// Write appends a chunk of the stream, and returns the access units it completes
+func (s *AccessUnitSplitter) Write(chunk []byte) []AccessUnit {
+	var aus []AccessUnit
+	for _, nalu := range s.scanner.Write(chunk) {
+		if au := s.push(nalu); au != nil {
+			aus = append(aus, *au)
+		}
+	}
+	return aus
+}
+
+// Flush returns the access units buffered, at the end of the stream
+func (s *AccessUnitSplitter) Flush() []AccessUnit {
+	var aus []AccessUnit
+	if nalu := s.scanner.Flush(); nalu != nil {
+		if au := s.push(nalu); au != nil {
+			aus = append(aus, *au)
+		}
+	}
+	if len(s.au.NALUs) > 0 {
+		aus = append(aus, s.au)
+	}
+	s.au = AccessUnit{}
+	s.hasVCL = false
+	return aus
+}
+
+// push appends a NAL unit to the access unit, and returns the previous access unit if the NAL unit starts a new one
+func (s *AccessUnitSplitter) push(nalu []byte) *AccessUnit {
+	if len(nalu) < 2 {
+		return nil
+	}
+	h := ParseNalHeader(nalu)
+	typ := h.NalUnitType
+	newAU := false
+	if h.NalLayerId == 0 {
+		switch {
+		case typ == NalUnitTypeVpsNut, typ == NalUnitTypeSpsNut, typ == NalUnitTypePpsNut:
+			newAU = s.hasVCL
+			_ = s.ParameterSets.Update(nalu)
+		case typ == NalUnitTypeAudNut, typ == NalUnitTypePrefixSeiNut,
+			typ >= NalUnitTypeRsvNvcl41 && typ <= NalUnitTypeRsvNvcl44,
+			typ >= NalUnitTypeUnspec48 && typ <= NalUnitTypeUnspec55:
+			newAU = s.hasVCL
+		case typ.Vcl():
+			// first_slice_segment_in_pic_flag
+			newAU = s.hasVCL && len(nalu) > 2 && nalu[2]&0x80 != 0
+		}
+	}
+
+	var out *AccessUnit
+	if newAU {
+		au := s.au
+		out = &au
+		s.au = AccessUnit{}
+		s.hasVCL = false
+	}
+	s.au.NALUs = append(s.au.NALUs, nalu)
+	if typ.Vcl() {
+		s.hasVCL = true
+		if typ.Irap() && h.NalLayerId == 0 {
+			s.au.KeyFrame = true
+		}
+	}
+	return out
+}
+
+func SplitAccessUnits(stream []byte) []AccessUnit {
+	var s AccessUnitSplitter
+	return append(s.Write(stream), s.Flush()...)
+}
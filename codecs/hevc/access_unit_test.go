@@ -0,0 +1,68 @@
+package hevc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+	"github.com/searKing/rtp/codecs/h264"
+)
+
+// testSliceSegment returns a slice segment NAL unit of testSPS and testPPS, with some slice data
+func testSliceSegment(typ NalUnitType, address uint32, sliceType SliceType) []byte {
+	w := bitstream.NewWriter()
+	w.WriteFlag(address == 0)
+	if typ.Irap() {
+		w.WriteFlag(false)
+	}
+	w.WriteUE(0)
+	if address != 0 {
+		// Ceil(Log2(PicSizeInCtbsY)) bits, 510 CTBs
+		w.WriteBits(uint64(address), 9)
+	}
+	w.WriteUE(uint32(sliceType))
+	w.WriteTrailingBits()
+	w.WriteBytes([]byte{0xaa, 0xbb})
+	return append(NalHeader{NalUnitType: typ, NalTemporalId: 1}.Bytes(), w.Bytes()...)
+}
+
+func TestSliceSegmentHeader(t *testing.T) {
+	var ps ParameterSets
+	for _, nalu := range [][]byte{testVPS, testSPS, testPPS} {
+		if err := ps.Update(nalu); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := ParseSliceSegmentHeader(testSliceSegment(NalUnitTypeIdrWRadl, 300, SliceTypeI), &ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.FirstSliceSegmentInPic || h.SliceSegmentAddress != 300 || h.SliceType != SliceTypeI {
+		t.Errorf("unexpected %s", h)
+	}
+	if _, err := ParseSliceSegmentHeader(testSliceSegment(NalUnitTypeTrailR, 0, SliceTypeP), &ParameterSets{}); err == nil {
+		t.Error("slice segment header parsed without its PPS")
+	}
+}
+
+func TestAccessUnitSplitter(t *testing.T) {
+	aud := []byte{0x46, 0x01, 0x50}
+	idr0, idr1 := testSliceSegment(NalUnitTypeIdrWRadl, 0, SliceTypeI), testSliceSegment(NalUnitTypeIdrWRadl, 255, SliceTypeI)
+	suffixSei := []byte{0x50, 0x01, 0x84, 0x01, 0x00, 0x80}
+	p0, p1 := testSliceSegment(NalUnitTypeTrailR, 0, SliceTypeP), testSliceSegment(NalUnitTypeTrailR, 255, SliceTypeP)
+	stream := h264.JoinAnnexB([][]byte{aud, testVPS, testSPS, testPPS, idr0, idr1, suffixSei, p0, p1})
+
+	want := []AccessUnit{
+		{NALUs: [][]byte{aud, testVPS, testSPS, testPPS, idr0, idr1, suffixSei}, KeyFrame: true},
+		{NALUs: [][]byte{p0, p1}},
+	}
+	aus := SplitAccessUnits(stream)
+	if len(aus) != len(want) {
+		t.Fatalf("%d access units, want %d", len(aus), len(want))
+	}
+	for i := range want {
+		if aus[i].KeyFrame != want[i].KeyFrame || !bytes.Equal(aus[i].AnnexB(), want[i].AnnexB()) {
+			t.Errorf("access unit %d: %v, want %v", i, aus[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package hevc
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// NALUArray represents an array of NAL units of the same type of a HEVCDecoderConfigurationRecord
+type NALUArray struct {
+	// ArrayCompleteness is set when all the NAL units of the type are in the array, none in the samples
+	ArrayCompleteness bool
+	NalUnitType       NalUnitType
+	NALUs             [][]byte
+}
+
+// HEVCDecoderConfigurationRecord represents the hvcC box of MP4 and the CodecPrivate of Matroska, 8.3.3.1 of ISO/IEC 14496-15
+type HEVCDecoderConfigurationRecord struct {
+	ConfigurationVersion      uint8
+	General                   Profile
+	LevelIdc                  uint8
+	MinSpatialSegmentationIdc uint16
+	ParallelismType           uint8
+	ChromaFormat              uint8
+	BitDepthLumaMinus8        uint8
+	BitDepthChromaMinus8      uint8
+	// AvgFrameRate is in frames per 256 seconds, 0 when unspecified
+	AvgFrameRate       uint16
+	ConstantFrameRate  uint8
+	NumTemporalLayers  uint8
+	TemporalIDNested   bool
+	LengthSizeMinusOne uint8
+	Arrays             []NALUArray
+}
+
+// HEVCDecoderConfigurationRecordSize is the size of the record without its arrays
+const HEVCDecoderConfigurationRecordSize = 23
+
+// NewHEVCDecoderConfigurationRecord returns the complete record of the parameter sets of a stream, in AVCC with 4 bytes lengths
+func NewHEVCDecoderConfigurationRecord(vps, sps, pps [][]byte) (HEVCDecoderConfigurationRecord, error) {
+	if len(sps) == 0 {
+		return HEVCDecoderConfigurationRecord{}, fmt.Errorf("HEVCDecoderConfigurationRecord requires a SPS")
+	}
+	s, err := ParseSPS(sps[0])
+	if err != nil {
+		return HEVCDecoderConfigurationRecord{}, err
+	}
+	r := HEVCDecoderConfigurationRecord{
+		ConfigurationVersion: 1,
+		General:              s.ProfileTierLevel.General,
+		LevelIdc:             s.ProfileTierLevel.LevelIdc,
+		ChromaFormat:         uint8(s.ChromaFormatIdc),
+		BitDepthLumaMinus8:   uint8(s.BitDepthLuma - 8),
+		BitDepthChromaMinus8: uint8(s.BitDepthChroma - 8),
+		NumTemporalLayers:    s.MaxSubLayersMinus1 + 1,
+		TemporalIDNested:     s.TemporalIDNesting,
+		LengthSizeMinusOne:   3,
+	}
+	if s.VUI != nil && s.VUI.BitstreamRestriction {
+		r.MinSpatialSegmentationIdc = uint16(s.VUI.MinSpatialSegmentationIdc)
+	}
+	for _, array := range []NALUArray{
+		{ArrayCompleteness: true, NalUnitType: NalUnitTypeVpsNut, NALUs: vps},
+		{ArrayCompleteness: true, NalUnitType: NalUnitTypeSpsNut, NALUs: sps},
+		{ArrayCompleteness: true, NalUnitType: NalUnitTypePpsNut, NALUs: pps},
+	} {
+		if len(array.NALUs) > 0 {
+			r.Arrays = append(r.Arrays, array)
+		}
+	}
+	return r, nil
+}
+
+// LengthSize returns the size of the lengths of the NAL units of the samples
+func (r HEVCDecoderConfigurationRecord) LengthSize() int {
+	return int(r.LengthSizeMinusOne) + 1
+}
+
+// NALUs returns the NAL units of the arrays of type typ
+func (r HEVCDecoderConfigurationRecord) NALUs(typ NalUnitType) [][]byte {
+	var nalus [][]byte
+	for _, array := range r.Arrays {
+		if array.NalUnitType == typ {
+			nalus = append(nalus, array.NALUs...)
+		}
+	}
+	return nalus
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the HEVCDecoderConfigurationRecord this method is called upon
+func (r *HEVCDecoderConfigurationRecord) Unmarshal(buf []byte) error {
+	if buf == nil {
+		return fmt.Errorf("invalid nil HEVCDecoderConfigurationRecord")
+	}
+	if len(buf) < HEVCDecoderConfigurationRecordSize {
+		return fmt.Errorf("buf is not large enough to container HEVCDecoderConfigurationRecord")
+	}
+	*r = HEVCDecoderConfigurationRecord{
+		ConfigurationVersion: buf[0],
+		General: Profile{
+			ProfileSpace:             buf[1] >> 6,
+			Tier:                     buf[1]&0x20 != 0,
+			ProfileIdc:               buf[1] & 0x1f,
+			CompatibilityFlags:       binary.BigEndian.Uint32(buf[2:]),
+			ConstraintIndicatorFlags: binary.BigEndian.Uint64(buf[4:]) & (1<<48 - 1),
+		},
+		LevelIdc:                  buf[12],
+		MinSpatialSegmentationIdc: binary.BigEndian.Uint16(buf[13:]) & 0x0fff,
+		ParallelismType:           buf[15] & 0x03,
+		ChromaFormat:              buf[16] & 0x03,
+		BitDepthLumaMinus8:        buf[17] & 0x07,
+		BitDepthChromaMinus8:      buf[18] & 0x07,
+		AvgFrameRate:              binary.BigEndian.Uint16(buf[19:]),
+		ConstantFrameRate:         buf[21] >> 6,
+		NumTemporalLayers:         buf[21] >> 3 & 0x07,
+		TemporalIDNested:          buf[21]&0x04 != 0,
+		LengthSizeMinusOne:        buf[21] & 0x03,
+	}
+	if r.ConfigurationVersion != 1 {
+		return fmt.Errorf("unsupported configurationVersion %d", r.ConfigurationVersion)
+	}
+	numOfArrays := int(buf[22])
+	buf = buf[HEVCDecoderConfigurationRecordSize:]
+	for i := 0; i < numOfArrays; i++ {
+		if len(buf) < 3 {
+			return fmt.Errorf("buf is not large enough to container NAL unit array")
+		}
+		array := NALUArray{ArrayCompleteness: buf[0]&0x80 != 0, NalUnitType: NalUnitType(buf[0] & 0x3f)}
+		numNalus := int(binary.BigEndian.Uint16(buf[1:]))
+		buf = buf[3:]
+		for j := 0; j < numNalus; j++ {
+			if len(buf) < 2 {
+				return fmt.Errorf("buf is not large enough to container NAL unit length")
+			}
+			size := int(binary.BigEndian.Uint16(buf))
+			if len(buf) < 2+size {
+				return fmt.Errorf("buf is not large enough to container NAL unit of %d bytes", size)
+			}
+			array.NALUs = append(array.NALUs, buf[2:2+size:2+size])
+			buf = buf[2+size:]
+		}
+		r.Arrays = append(r.Arrays, array)
+	}
+	return nil
+}
+
+// Marshal serializes the record into bytes.
+func (r HEVCDecoderConfigurationRecord) Marshal() ([]byte, error) {
+	if len(r.Arrays) > 0xff {
+		return nil, fmt.Errorf("%d NAL unit arrays exceed %d", len(r.Arrays), 0xff)
+	}
+	out := make([]byte, HEVCDecoderConfigurationRecordSize)
+	out[0] = r.ConfigurationVersion
+	out[1] = r.General.ProfileSpace<<6 | r.General.ProfileIdc&0x1f
+	if r.General.Tier {
+		out[1] |= 0x20
+	}
+	binary.BigEndian.PutUint32(out[2:], r.General.CompatibilityFlags)
+	var constraints [8]byte
+	binary.BigEndian.PutUint64(constraints[:], r.General.ConstraintIndicatorFlags)
+	copy(out[6:12], constraints[2:])
+	out[12] = r.LevelIdc
+	binary.BigEndian.PutUint16(out[13:], 0xf000|r.MinSpatialSegmentationIdc&0x0fff)
+	out[15] = 0xfc | r.ParallelismType&0x03
+	out[16] = 0xfc | r.ChromaFormat&0x03
+	out[17] = 0xf8 | r.BitDepthLumaMinus8&0x07
+	out[18] = 0xf8 | r.BitDepthChromaMinus8&0x07
+	binary.BigEndian.PutUint16(out[19:], r.AvgFrameRate)
+	out[21] = r.ConstantFrameRate<<6 | (r.NumTemporalLayers&0x07)<<3 | r.LengthSizeMinusOne&0x03
+	if r.TemporalIDNested {
+		out[21] |= 0x04
+	}
+	out[22] = byte(len(r.Arrays))
+	for _, array := range r.Arrays {
+		if len(array.NALUs) > 0xffff {
+			return nil, fmt.Errorf("%d NAL units exceed %d", len(array.NALUs), 0xffff)
+		}
+		b := byte(array.NalUnitType & 0x3f)
+		if array.ArrayCompleteness {
+			b |= 0x80
+		}
+		out = append(out, b, byte(len(array.NALUs)>>8), byte(len(array.NALUs)))
+		for _, nalu := range array.NALUs {
+			if len(nalu) > 0xffff {
+				return nil, fmt.Errorf("NAL unit of %d bytes exceeds %d", len(nalu), 0xffff)
+			}
+			out = append(out, byte(len(nalu)>>8), byte(len(nalu)))
+			out = append(out, nalu...)
+		}
+	}
+	return out, nil
+}
+
+// String helps with debugging by printing HEVCDecoderConfigurationRecord information in a readable way
+func (r HEVCDecoderConfigurationRecord) String() string {
+	out := "HEVCDecoderConfigurationRecord:\n"
+
+	out += fmt.Sprintf("\tCodec: %v\n", ProfileTierLevel{General: r.General, LevelIdc: r.LevelIdc}.CodecString())
+	out += fmt.Sprintf("\tLengthSize: %v\n", r.LengthSize())
+	for _, array := range r.Arrays {
+		out += fmt.Sprintf("\t%s: %v\n", array.NalUnitType, len(array.NALUs))
+	}
+
+	return out
+}
+
+func ParseHEVCDecoderConfigurationRecord(buf []byte) (HEVCDecoderConfigurationRecord, error) {
+	var r HEVCDecoderConfigurationRecord
+	err := (&r).Unmarshal(buf)
+	return r, err
+}
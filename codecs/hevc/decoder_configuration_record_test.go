@@ -0,0 +1,36 @@
+package hevc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestHEVCDecoderConfigurationRecord(t *testing.T) {
+	r, err := NewHEVCDecoderConfigurationRecord([][]byte{testVPS}, [][]byte{testSPS}, [][]byte{testPPS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x01, 0x01, 0x60, 0x00, 0x00, 0x00, 0x90, 0x00, 0x00, 0x00, 0x00, 0x00, 0x5d, 0xf0, 0x00, 0xfc,
+		0xfd, 0xf8, 0xf8, 0x00, 0x00, 0x0f, 0x03, 0xa0, 0x00, 0x01, 0x00, byte(len(testVPS))}
+	if !bytes.HasPrefix(raw, want) {
+		t.Errorf("record % x, want % x", raw[:len(want)], want)
+	}
+	got, err := ParseHEVCDecoderConfigurationRecord(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("record %v, want %v", got, r)
+	}
+	if sps := got.NALUs(NalUnitTypeSpsNut); len(sps) != 1 || !bytes.Equal(sps[0], testSPS) {
+		t.Errorf("unexpected SPS %v", sps)
+	}
+	if _, err := ParseHEVCDecoderConfigurationRecord(raw[:30]); err == nil {
+		t.Error("truncated record parsed")
+	}
+}
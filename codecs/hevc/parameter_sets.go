@@ -0,0 +1,38 @@
+package hevc
+
+import "fmt"
+
+// ParameterSets holds the VPS, SPS and PPS of a stream by ID, as they are received
+type ParameterSets struct {
+	VPS [MaxVpsCount]*VPS
+	SPS [MaxSpsCount]*SPS
+	PPS [MaxPpsCount]*PPS
+}
+
+// Update parses a VPS, SPS or PPS NAL unit and stores it, replacing the one of the same ID; other NAL units are ignored
+func (ps *ParameterSets) Update(nalu []byte) error {
+	if len(nalu) < 2 {
+		return fmt.Errorf("invalid nalu")
+	}
+	switch ParseNalHeader(nalu).NalUnitType {
+	case NalUnitTypeVpsNut:
+		vps, err := ParseVPS(nalu)
+		if err != nil {
+			return err
+		}
+		ps.VPS[vps.ID] = &vps
+	case NalUnitTypeSpsNut:
+		sps, err := ParseSPS(nalu)
+		if err != nil {
+			return err
+		}
+		ps.SPS[sps.ID] = &sps
+	case NalUnitTypePpsNut:
+		pps, err := ParsePPS(nalu)
+		if err != nil {
+			return err
+		}
+		ps.PPS[pps.ID] = &pps
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package hevc
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// PPS represents a picture parameter set, pic_parameter_set_rbsp() in 7.3.2.3 of T-REC-H.265
+// The fields are decoded up to slice_segment_header_extension_present_flag, the extensions are skipped
+type PPS struct {
+	ID                            uint32
+	SPSID                         uint32
+	DependentSliceSegmentsEnabled bool
+	OutputFlagPresent             bool
+	NumExtraSliceHeaderBits       uint8
+	SignDataHidingEnabled         bool
+	CabacInitPresent              bool
+	NumRefIdxL0DefaultActive      uint32
+	NumRefIdxL1DefaultActive      uint32
+	InitQp                        int32
+	ConstrainedIntraPred          bool
+	TransformSkipEnabled          bool
+	CuQpDeltaEnabled              bool
+	DiffCuQpDeltaDepth            uint32
+	CbQpOffset                    int32
+	CrQpOffset                    int32
+	SliceChromaQpOffsetsPresent   bool
+	WeightedPred                  bool
+	WeightedBipred                bool
+	TransquantBypassEnabled       bool
+
+	TilesEnabled             bool
+	EntropyCodingSyncEnabled bool
+	// NumTileColumns and NumTileRows are set with TilesEnabled, the column widths and row heights unless spaced uniformly
+	NumTileColumns               uint32
+	NumTileRows                  uint32
+	UniformSpacing               bool
+	ColumnWidthMinus1            []uint32
+	RowHeightMinus1              []uint32
+	LoopFilterAcrossTilesEnabled bool
+
+	LoopFilterAcrossSlicesEnabled      bool
+	DeblockingFilterControlPresent     bool
+	DeblockingFilterOverrideEnabled    bool
+	DeblockingFilterDisabled           bool
+	BetaOffsetDiv2                     int32
+	TcOffsetDiv2                       int32
+	ScalingListDataPresent             bool
+	ListsModificationPresent           bool
+	Log2ParallelMergeLevel             uint32
+	SliceSegmentHeaderExtensionPresent bool
+}
+
+// Decode reads the PPS RBSP, without its NAL header
+func (p *PPS) Decode(r *bitstream.Reader) error {
+	*p = PPS{}
+	p.ID = r.ReadUE()
+	if p.ID >= MaxPpsCount {
+		return fmt.Errorf("pps_pic_parameter_set_id %d exceeds %d", p.ID, MaxPpsCount-1)
+	}
+	p.SPSID = r.ReadUE()
+	if p.SPSID >= MaxSpsCount {
+		return fmt.Errorf("pps_seq_parameter_set_id %d exceeds %d", p.SPSID, MaxSpsCount-1)
+	}
+	p.DependentSliceSegmentsEnabled = r.ReadFlag()
+	p.OutputFlagPresent = r.ReadFlag()
+	p.NumExtraSliceHeaderBits = r.ReadUint8(3)
+	p.SignDataHidingEnabled = r.ReadFlag()
+	p.CabacInitPresent = r.ReadFlag()
+	p.NumRefIdxL0DefaultActive = r.ReadUE() + 1
+	p.NumRefIdxL1DefaultActive = r.ReadUE() + 1
+	if p.NumRefIdxL0DefaultActive > 15 || p.NumRefIdxL1DefaultActive > 15 {
+		return fmt.Errorf("invalid num_ref_idx_default_active %d/%d", p.NumRefIdxL0DefaultActive, p.NumRefIdxL1DefaultActive)
+	}
+	p.InitQp = r.ReadSE() + 26
+	p.ConstrainedIntraPred = r.ReadFlag()
+	p.TransformSkipEnabled = r.ReadFlag()
+	if p.CuQpDeltaEnabled = r.ReadFlag(); p.CuQpDeltaEnabled {
+		p.DiffCuQpDeltaDepth = r.ReadUE()
+	}
+	p.CbQpOffset = r.ReadSE()
+	p.CrQpOffset = r.ReadSE()
+	p.SliceChromaQpOffsetsPresent = r.ReadFlag()
+	p.WeightedPred = r.ReadFlag()
+	p.WeightedBipred = r.ReadFlag()
+	p.TransquantBypassEnabled = r.ReadFlag()
+	p.TilesEnabled = r.ReadFlag()
+	p.EntropyCodingSyncEnabled = r.ReadFlag()
+	if p.TilesEnabled {
+		p.NumTileColumns = r.ReadUE() + 1
+		p.NumTileRows = r.ReadUE() + 1
+		if p.NumTileColumns > MaxTileColumns || p.NumTileRows > MaxTileRows {
+			return fmt.Errorf("%dx%d tiles exceed %dx%d", p.NumTileColumns, p.NumTileRows, MaxTileColumns, MaxTileRows)
+		}
+		if p.UniformSpacing = r.ReadFlag(); !p.UniformSpacing {
+			p.ColumnWidthMinus1 = make([]uint32, p.NumTileColumns-1)
+			for i := range p.ColumnWidthMinus1 {
+				p.ColumnWidthMinus1[i] = r.ReadUE()
+			}
+			p.RowHeightMinus1 = make([]uint32, p.NumTileRows-1)
+			for i := range p.RowHeightMinus1 {
+				p.RowHeightMinus1[i] = r.ReadUE()
+			}
+		}
+		p.LoopFilterAcrossTilesEnabled = r.ReadFlag()
+	}
+	p.LoopFilterAcrossSlicesEnabled = r.ReadFlag()
+	if p.DeblockingFilterControlPresent = r.ReadFlag(); p.DeblockingFilterControlPresent {
+		p.DeblockingFilterOverrideEnabled = r.ReadFlag()
+		if p.DeblockingFilterDisabled = r.ReadFlag(); !p.DeblockingFilterDisabled {
+			p.BetaOffsetDiv2 = r.ReadSE()
+			p.TcOffsetDiv2 = r.ReadSE()
+		}
+	}
+	if p.ScalingListDataPresent = r.ReadFlag(); p.ScalingListDataPresent {
+		if err := skipScalingListData(r); err != nil {
+			return err
+		}
+	}
+	p.ListsModificationPresent = r.ReadFlag()
+	p.Log2ParallelMergeLevel = r.ReadUE() + 2
+	p.SliceSegmentHeaderExtensionPresent = r.ReadFlag()
+	return r.Err()
+}
+
+// Unmarshal parses the passed PPS NAL unit, its header included, and stores the result in the PPS this method is called upon
+func (p *PPS) Unmarshal(nalu []byte) error {
+	rbsp, err := parameterSetRBSP(nalu, NalUnitTypePpsNut)
+	if err != nil {
+		return err
+	}
+	return p.Decode(bitstream.NewReader(rbsp))
+}
+
+// String helps with debugging by printing PPS information in a readable way
+func (p PPS) String() string {
+	out := "HEVC PPS:\n"
+
+	out += fmt.Sprintf("\tID: %v\n", p.ID)
+	out += fmt.Sprintf("\tSPSID: %v\n", p.SPSID)
+	out += fmt.Sprintf("\tDependentSliceSegmentsEnabled: %v\n", p.DependentSliceSegmentsEnabled)
+	out += fmt.Sprintf("\tTilesEnabled: %v\n", p.TilesEnabled)
+	out += fmt.Sprintf("\tEntropyCodingSyncEnabled: %v\n", p.EntropyCodingSyncEnabled)
+
+	return out
+}
+
+func ParsePPS(nalu []byte) (PPS, error) {
+	var p PPS
+	err := (&p).Unmarshal(nalu)
+	return p, err
+}
@@ -0,0 +1,120 @@
+package hevc
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// Profile represents the profile fields of a profile_tier_level(), general or of a sub-layer, 7.3.3 of T-REC-H.265
+type Profile struct {
+	ProfileSpace uint8
+	// Tier is set for the High tier, unset for the Main tier
+	Tier       bool
+	ProfileIdc uint8
+	// CompatibilityFlags holds general_profile_compatibility_flag[0] in its most significant bit
+	CompatibilityFlags uint32
+	// ConstraintIndicatorFlags holds the 48 bits from general_progressive_source_flag on, the first one in bit 47
+	ConstraintIndicatorFlags uint64
+}
+
+// decode reads the 88 bits of a profile
+func (p *Profile) decode(r *bitstream.Reader) {
+	p.ProfileSpace = r.ReadUint8(2)
+	p.Tier = r.ReadFlag()
+	p.ProfileIdc = r.ReadUint8(5)
+	p.CompatibilityFlags = r.ReadUint32(32)
+	p.ConstraintIndicatorFlags = r.ReadBits(48)
+}
+
+// SubLayer represents the profile and level of a sub-layer, set when present
+type SubLayer struct {
+	ProfilePresent bool
+	Profile        Profile
+	LevelPresent   bool
+	LevelIdc       uint8
+}
+
+// ProfileTierLevel represents profile_tier_level(), 7.3.3 of T-REC-H.265
+type ProfileTierLevel struct {
+	// General is the profile of the stream, unset when decoded with profilePresent unset
+	General Profile
+	// LevelIdc is general_level_idc, 30 times the level number
+	LevelIdc  uint8
+	SubLayers []SubLayer
+}
+
+// Decode reads a profile_tier_level(profilePresent, maxSubLayersMinus1)
+func (p *ProfileTierLevel) Decode(r *bitstream.Reader, profilePresent bool, maxSubLayersMinus1 int) error {
+	*p = ProfileTierLevel{}
+	if profilePresent {
+		p.General.decode(r)
+	}
+	p.LevelIdc = r.ReadUint8(8)
+	p.SubLayers = make([]SubLayer, maxSubLayersMinus1)
+	for i := range p.SubLayers {
+		p.SubLayers[i].ProfilePresent = r.ReadFlag()
+		p.SubLayers[i].LevelPresent = r.ReadFlag()
+	}
+	if maxSubLayersMinus1 > 0 {
+		// reserved_zero_2bits
+		r.SkipBits(2 * (8 - maxSubLayersMinus1))
+	}
+	for i := range p.SubLayers {
+		if p.SubLayers[i].ProfilePresent {
+			p.SubLayers[i].Profile.decode(r)
+		}
+		if p.SubLayers[i].LevelPresent {
+			p.SubLayers[i].LevelIdc = r.ReadUint8(8)
+		}
+	}
+	return r.Err()
+}
+
+// CodecString returns the codecs parameter of the stream, such as hvc1.1.6.L93.B0, see E.3 of ISO/IEC 14496-15
+func (p ProfileTierLevel) CodecString() string {
+	out := "hvc1."
+	if p.General.ProfileSpace > 0 {
+		out += string(rune('A' + p.General.ProfileSpace - 1))
+	}
+	out += fmt.Sprintf("%d.%X.", p.General.ProfileIdc, bits.Reverse32(p.General.CompatibilityFlags))
+	if p.General.Tier {
+		out += "H"
+	} else {
+		out += "L"
+	}
+	out += fmt.Sprintf("%d", p.LevelIdc)
+
+	// the constraint bytes, the trailing zero ones omitted
+	constraints := make([]string, 0, 6)
+	for i := 5; i >= 0; i-- {
+		constraints = append(constraints, fmt.Sprintf("%X", uint8(p.General.ConstraintIndicatorFlags>>uint(8*i))))
+	}
+	for len(constraints) > 0 && constraints[len(constraints)-1] == "0" {
+		constraints = constraints[:len(constraints)-1]
+	}
+	if len(constraints) > 0 {
+		out += "." + strings.Join(constraints, ".")
+	}
+	return out
+}
+
+// Fmtp returns the profile-id, tier-flag and level-id parameters of an SDP fmtp line, see rfc7798
+// profile-space is added when not 0, its default
+func (p ProfileTierLevel) Fmtp() string {
+	var params []string
+	if p.General.ProfileSpace > 0 {
+		params = append(params, fmt.Sprintf("profile-space=%d", p.General.ProfileSpace))
+	}
+	tier := 0
+	if p.General.Tier {
+		tier = 1
+	}
+	params = append(params,
+		fmt.Sprintf("profile-id=%d", p.General.ProfileIdc),
+		fmt.Sprintf("tier-flag=%d", tier),
+		fmt.Sprintf("level-id=%d", p.LevelIdc))
+	return strings.Join(params, ";")
+}
@@ -0,0 +1,237 @@
+package hevc
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+	"github.com/searKing/rtp/codecs/h264"
+)
+
+// SEI payload types of T-REC-H.265 not shared with T-REC-H.264, see h264.SEIPayloadTypeUserDataUnregistered and others
+const (
+	SEIPayloadTypeTimeCode = 136
+)
+
+// SEI represents the messages of a prefix or suffix SEI NAL unit, sei_rbsp() in 7.3.2.4 of T-REC-H.265
+// The messages are coded as in T-REC-H.264, and the user data payloads are h264.UserDataUnregistered and
+// h264.UserDataRegistered
+type SEI struct {
+	Suffix bool
+	// NalTemporalId is nuh_temporal_id_plus1, 0 is marshaled as 1
+	NalTemporalId NalTemporalId
+	Messages      []h264.SEIMessage
+}
+
+// Unmarshal parses the passed SEI NAL unit, its header included, and stores the result in the SEI this method is called upon
+func (s *SEI) Unmarshal(nalu []byte) error {
+	if nalu == nil {
+		return fmt.Errorf("invalid nil nalu")
+	}
+	if len(nalu) < 3 {
+		return fmt.Errorf("buf is not large enough to container SEI")
+	}
+	h := ParseNalHeader(nalu)
+	if h.NalUnitType != NalUnitTypePrefixSeiNut && h.NalUnitType != NalUnitTypeSuffixSeiNut {
+		return fmt.Errorf("NAL unit type %s is not SEI", h.NalUnitType)
+	}
+	messages, err := h264.UnmarshalSEIMessages(bitstream.RemoveEmulationPrevention(nalu[2:]))
+	if err != nil {
+		return err
+	}
+	s.Suffix = h.NalUnitType == NalUnitTypeSuffixSeiNut
+	s.NalTemporalId = h.NalTemporalId
+	s.Messages = messages
+	return nil
+}
+
+// Marshal serializes the SEI into a NAL unit, its header included
+func (s SEI) Marshal() ([]byte, error) {
+	if len(s.Messages) == 0 {
+		return nil, fmt.Errorf("SEI requires a message")
+	}
+	h := NalHeader{NalUnitType: NalUnitTypePrefixSeiNut, NalTemporalId: s.NalTemporalId}
+	if s.Suffix {
+		h.NalUnitType = NalUnitTypeSuffixSeiNut
+	}
+	if h.NalTemporalId == 0 {
+		h.NalTemporalId = 1
+	}
+	return append(h.Bytes(), bitstream.AddEmulationPrevention(h264.MarshalSEIMessages(s.Messages))...), nil
+}
+
+// String helps with debugging by printing SEI information in a readable way
+func (s SEI) String() string {
+	out := "HEVC SEI:\n"
+
+	out += fmt.Sprintf("\tSuffix: %v\n", s.Suffix)
+	for _, m := range s.Messages {
+		out += fmt.Sprintf("\tPayloadType: %v, PayloadSize: %v\n", m.PayloadType, len(m.Payload))
+	}
+
+	return out
+}
+
+func ParseSEI(nalu []byte) (SEI, error) {
+	var s SEI
+	err := (&s).Unmarshal(nalu)
+	return s, err
+}
+
+// InsertSEI inserts a SEI NAL unit into an access unit in the format of Annex B, 7.4.2.4.4
+// A prefix SEI is inserted before the first slice segment, a suffix SEI after the last one
+func InsertSEI(accessUnit []byte, sei []byte) []byte {
+	nalus := h264.SplitAnnexB(accessUnit)
+	suffix := len(sei) > 0 && ParseNalHeader(sei).NalUnitType == NalUnitTypeSuffixSeiNut
+	i := len(nalus)
+	for j, nalu := range nalus {
+		if !ParseNalHeader(nalu).NalUnitType.Vcl() {
+			continue
+		}
+		if !suffix {
+			i = j
+			break
+		}
+		i = j + 1
+	}
+	nalus = append(nalus[:i], append([][]byte{sei}, nalus[i:]...)...)
+	return h264.JoinAnnexB(nalus)
+}
+
+// RecoveryPoint represents a recovery_point() SEI payload, D.2.8 of T-REC-H.265
+type RecoveryPoint struct {
+	RecoveryPocCnt int32
+	ExactMatch     bool
+	BrokenLink     bool
+}
+
+// Unmarshal parses the passed SEI payload
+func (p *RecoveryPoint) Unmarshal(payload []byte) error {
+	r := bitstream.NewReader(payload)
+	p.RecoveryPocCnt = r.ReadSE()
+	p.ExactMatch = r.ReadFlag()
+	p.BrokenLink = r.ReadFlag()
+	return r.Err()
+}
+
+// Marshal serializes the SEI payload
+func (p RecoveryPoint) Marshal() ([]byte, error) {
+	w := bitstream.NewWriter()
+	w.WriteSE(p.RecoveryPocCnt)
+	w.WriteFlag(p.ExactMatch)
+	w.WriteFlag(p.BrokenLink)
+	if !w.ByteAligned() {
+		w.WriteTrailingBits()
+	}
+	return w.Bytes(), nil
+}
+
+// ClockTimestamp represents a clock timestamp of a time code SEI payload
+// The seconds, minutes and hours are coded when FullTimestamp or their flag is set
+type ClockTimestamp struct {
+	UnitsFieldBased  bool
+	CountingType     uint8
+	FullTimestamp    bool
+	Discontinuity    bool
+	CntDropped       bool
+	NFrames          uint16
+	SecondsFlag      bool
+	Seconds          uint8
+	MinutesFlag      bool
+	Minutes          uint8
+	HoursFlag        bool
+	Hours            uint8
+	TimeOffsetLength uint8
+	TimeOffset       int32
+}
+
+// String returns the timecode as hh:mm:ss:ff, or hh:mm:ss;ff when frames are dropped
+func (c ClockTimestamp) String() string {
+	sep := ":"
+	if c.CntDropped {
+		sep = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", c.Hours, c.Minutes, c.Seconds, sep, c.NFrames)
+}
+
+// TimeCode represents a time_code() SEI payload, D.2.27 of T-REC-H.265, a nil timestamp being absent
+type TimeCode struct {
+	ClockTimestamps []*ClockTimestamp
+}
+
+// Unmarshal parses the passed SEI payload
+func (t *TimeCode) Unmarshal(payload []byte) error {
+	r := bitstream.NewReader(payload)
+	t.ClockTimestamps = make([]*ClockTimestamp, r.ReadUint8(2))
+	for i := range t.ClockTimestamps {
+		if !r.ReadFlag() {
+			continue
+		}
+		c := &ClockTimestamp{}
+		c.UnitsFieldBased = r.ReadFlag()
+		c.CountingType = r.ReadUint8(5)
+		c.FullTimestamp = r.ReadFlag()
+		c.Discontinuity = r.ReadFlag()
+		c.CntDropped = r.ReadFlag()
+		c.NFrames = r.ReadUint16(9)
+		if c.FullTimestamp {
+			c.Seconds = r.ReadUint8(6)
+			c.Minutes = r.ReadUint8(6)
+			c.Hours = r.ReadUint8(5)
+		} else if c.SecondsFlag = r.ReadFlag(); c.SecondsFlag {
+			c.Seconds = r.ReadUint8(6)
+			if c.MinutesFlag = r.ReadFlag(); c.MinutesFlag {
+				c.Minutes = r.ReadUint8(6)
+				if c.HoursFlag = r.ReadFlag(); c.HoursFlag {
+					c.Hours = r.ReadUint8(5)
+				}
+			}
+		}
+		if c.TimeOffsetLength = r.ReadUint8(5); c.TimeOffsetLength > 0 {
+			n := uint(c.TimeOffsetLength)
+			c.TimeOffset = int32(r.ReadUint32(int(n))<<(32-n)) >> (32 - n)
+		}
+		t.ClockTimestamps[i] = c
+	}
+	return r.Err()
+}
+
+// Marshal serializes the SEI payload
+func (t TimeCode) Marshal() ([]byte, error) {
+	if len(t.ClockTimestamps) > 3 {
+		return nil, fmt.Errorf("%d clock timestamps exceed 3", len(t.ClockTimestamps))
+	}
+	w := bitstream.NewWriter()
+	w.WriteBits(uint64(len(t.ClockTimestamps)), 2)
+	for _, c := range t.ClockTimestamps {
+		if w.WriteFlag(c != nil); c == nil {
+			continue
+		}
+		w.WriteFlag(c.UnitsFieldBased)
+		w.WriteBits(uint64(c.CountingType), 5)
+		w.WriteFlag(c.FullTimestamp)
+		w.WriteFlag(c.Discontinuity)
+		w.WriteFlag(c.CntDropped)
+		w.WriteBits(uint64(c.NFrames), 9)
+		if c.FullTimestamp {
+			w.WriteBits(uint64(c.Seconds), 6)
+			w.WriteBits(uint64(c.Minutes), 6)
+			w.WriteBits(uint64(c.Hours), 5)
+		} else if w.WriteFlag(c.SecondsFlag); c.SecondsFlag {
+			w.WriteBits(uint64(c.Seconds), 6)
+			if w.WriteFlag(c.MinutesFlag); c.MinutesFlag {
+				w.WriteBits(uint64(c.Minutes), 6)
+				if w.WriteFlag(c.HoursFlag); c.HoursFlag {
+					w.WriteBits(uint64(c.Hours), 5)
+				}
+			}
+		}
+		w.WriteBits(uint64(c.TimeOffsetLength), 5)
+		if c.TimeOffsetLength > 0 {
+			w.WriteBits(uint64(uint32(c.TimeOffset)), int(c.TimeOffsetLength))
+		}
+	}
+	if !w.ByteAligned() {
+		w.WriteTrailingBits()
+	}
+	return w.Bytes(), nil
+}
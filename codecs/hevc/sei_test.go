@@ -0,0 +1,58 @@
+package hevc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/searKing/rtp/codecs/h264"
+)
+
+func TestSEI(t *testing.T) {
+	timeCode := TimeCode{ClockTimestamps: []*ClockTimestamp{
+		{CountingType: 1, NFrames: 24, SecondsFlag: true, Seconds: 5, MinutesFlag: true, Minutes: 1, TimeOffsetLength: 4, TimeOffset: -3},
+		nil,
+	}}
+	p0, err := timeCode.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _ := RecoveryPoint{RecoveryPocCnt: -2, BrokenLink: true}.Marshal()
+	nalu, err := SEI{Messages: []h264.SEIMessage{
+		{PayloadType: SEIPayloadTypeTimeCode, Payload: p0},
+		{PayloadType: h264.SEIPayloadTypeRecoveryPoint, Payload: p1},
+	}}.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(nalu[:2], []byte{0x4e, 0x01}) {
+		t.Errorf("NAL header % x, want 4e 01", nalu[:2])
+	}
+
+	sei, err := ParseSEI(nalu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sei.Suffix || len(sei.Messages) != 2 {
+		t.Fatalf("unexpected %s", sei)
+	}
+	var tc TimeCode
+	if err := tc.Unmarshal(sei.Messages[0].Payload); err != nil || !reflect.DeepEqual(tc, timeCode) {
+		t.Errorf("time code %v, want %v", tc, timeCode)
+	}
+	var rp RecoveryPoint
+	if err := rp.Unmarshal(sei.Messages[1].Payload); err != nil || rp.RecoveryPocCnt != -2 || !rp.BrokenLink {
+		t.Errorf("unexpected recovery point %v", rp)
+	}
+
+	// a suffix SEI follows the last slice segment
+	suffix, _ := SEI{Suffix: true, Messages: sei.Messages}.Marshal()
+	idr0, idr1 := testSliceSegment(NalUnitTypeIdrWRadl, 0, SliceTypeI), testSliceSegment(NalUnitTypeIdrWRadl, 255, SliceTypeI)
+	au := h264.JoinAnnexB([][]byte{testVPS, idr0, idr1})
+	if got, want := InsertSEI(au, nalu), h264.JoinAnnexB([][]byte{testVPS, nalu, idr0, idr1}); !bytes.Equal(got, want) {
+		t.Errorf("prefix SEI inserted at the wrong place")
+	}
+	if got, want := InsertSEI(au, suffix), h264.JoinAnnexB([][]byte{testVPS, idr0, idr1, suffix}); !bytes.Equal(got, want) {
+		t.Errorf("suffix SEI inserted at the wrong place")
+	}
+}
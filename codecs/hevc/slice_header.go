@@ -0,0 +1,129 @@
+package hevc
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// SliceType is slice_type, Table 7-7 in T-REC-H.265
+type SliceType uint32
+
+const (
+	SliceTypeB SliceType = iota
+	SliceTypeP
+	SliceTypeI
+)
+
+func (t SliceType) String() string {
+	switch t {
+	case SliceTypeB:
+		return "B"
+	case SliceTypeP:
+		return "P"
+	case SliceTypeI:
+		return "I"
+	}
+	return fmt.Sprintf("%d", uint32(t))
+}
+
+// Irap reports whether the NAL unit is a slice segment of an intra random access point picture, BLA, IDR or CRA
+func (naluType NalUnitType) Irap() bool {
+	return naluType >= NalUnitTypeBlaWLp && naluType <= NalUnitTypeRsvIrapVcl23
+}
+
+// SliceSegmentHeader represents the start of a slice_segment_header(), 7.3.6.1 of T-REC-H.265, up to slice_type
+type SliceSegmentHeader struct {
+	NalUnitType NalUnitType
+
+	FirstSliceSegmentInPic bool
+	NoOutputOfPriorPics    bool
+	PPSID                  uint32
+	DependentSliceSegment  bool
+	SliceSegmentAddress    uint32
+	// SliceType is not coded in dependent slice segments, which share the one of their slice
+	SliceType SliceType
+}
+
+// Decode reads the slice segment header of a slice segment of the NAL unit described by nalHeader
+// The fields up to slice_pic_parameter_set_id are read even if the PPS or its SPS is missing from ps
+func (h *SliceSegmentHeader) Decode(r *bitstream.Reader, nalHeader NalHeader, ps *ParameterSets) error {
+	*h = SliceSegmentHeader{NalUnitType: nalHeader.NalUnitType}
+	h.FirstSliceSegmentInPic = r.ReadFlag()
+	if h.NalUnitType.Irap() {
+		h.NoOutputOfPriorPics = r.ReadFlag()
+	}
+	h.PPSID = r.ReadUE()
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if h.PPSID >= MaxPpsCount {
+		return fmt.Errorf("slice_pic_parameter_set_id %d exceeds %d", h.PPSID, MaxPpsCount-1)
+	}
+	pps := ps.PPS[h.PPSID]
+	if pps == nil {
+		return fmt.Errorf("missing PPS %d", h.PPSID)
+	}
+	sps := ps.SPS[pps.SPSID]
+	if sps == nil {
+		return fmt.Errorf("missing SPS %d", pps.SPSID)
+	}
+
+	if !h.FirstSliceSegmentInPic {
+		if pps.DependentSliceSegmentsEnabled {
+			h.DependentSliceSegment = r.ReadFlag()
+		}
+		h.SliceSegmentAddress = r.ReadUint32(bits.Len32(sps.PicSizeInCtbsY() - 1))
+	}
+	if !h.DependentSliceSegment {
+		// slice_reserved_flag
+		r.SkipBits(int(pps.NumExtraSliceHeaderBits))
+		h.SliceType = SliceType(r.ReadUE())
+		if h.SliceType > SliceTypeI {
+			return fmt.Errorf("invalid slice_type %d", h.SliceType)
+		}
+	}
+	return r.Err()
+}
+
+// Unmarshal parses the slice segment header of the passed slice segment NAL unit, its header included, and stores the
+// result in the SliceSegmentHeader this method is called upon; ps holds the parameter sets received
+func (h *SliceSegmentHeader) Unmarshal(nalu []byte, ps *ParameterSets) error {
+	if nalu == nil {
+		return fmt.Errorf("invalid nil nalu")
+	}
+	if len(nalu) < 3 {
+		return fmt.Errorf("buf is not large enough to container slice segment header")
+	}
+	nalHeader := ParseNalHeader(nalu)
+	if !nalHeader.NalUnitType.Vcl() {
+		return fmt.Errorf("NAL unit type %s has no slice segment header", nalHeader.NalUnitType)
+	}
+	// the slice segment header is short, only its first bytes are unescaped
+	ebsp := nalu[2:]
+	if len(ebsp) > 32 {
+		ebsp = ebsp[:32]
+	}
+	return h.Decode(bitstream.NewReader(bitstream.RemoveEmulationPrevention(ebsp)), nalHeader, ps)
+}
+
+// String helps with debugging by printing SliceSegmentHeader information in a readable way
+func (h SliceSegmentHeader) String() string {
+	out := "HEVC SliceSegmentHeader:\n"
+
+	out += fmt.Sprintf("\tNalUnitType: %s\n", h.NalUnitType)
+	out += fmt.Sprintf("\tFirstSliceSegmentInPic: %v\n", h.FirstSliceSegmentInPic)
+	out += fmt.Sprintf("\tPPSID: %v\n", h.PPSID)
+	out += fmt.Sprintf("\tDependentSliceSegment: %v\n", h.DependentSliceSegment)
+	out += fmt.Sprintf("\tSliceSegmentAddress: %v\n", h.SliceSegmentAddress)
+	out += fmt.Sprintf("\tSliceType: %s\n", h.SliceType)
+
+	return out
+}
+
+func ParseSliceSegmentHeader(nalu []byte, ps *ParameterSets) (SliceSegmentHeader, error) {
+	var h SliceSegmentHeader
+	err := (&h).Unmarshal(nalu, ps)
+	return h, err
+}
@@ -0,0 +1,313 @@
+package hevc
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// SubLayerOrderingInfo represents the DPB sizing of a sub-layer
+type SubLayerOrderingInfo struct {
+	MaxDecPicBufferingMinus1 uint32
+	MaxNumReorderPics        uint32
+	MaxLatencyIncreasePlus1  uint32
+}
+
+// SPS represents a sequence parameter set, seq_parameter_set_rbsp() in 7.3.2.2 of T-REC-H.265
+// The fields are decoded up to the VUI, the extensions are skipped
+type SPS struct {
+	VPSID              uint8
+	MaxSubLayersMinus1 uint8
+	TemporalIDNesting  bool
+	ProfileTierLevel   ProfileTierLevel
+	ID                 uint32
+
+	// ChromaFormatIdc is 0 for monochrome, 1 for 4:2:0, 2 for 4:2:2 and 3 for 4:4:4
+	ChromaFormatIdc        uint32
+	SeparateColourPlane    bool
+	PicWidthInLumaSamples  uint32
+	PicHeightInLumaSamples uint32
+	ConformanceWindow      bool
+	ConfWinLeftOffset      uint32
+	ConfWinRightOffset     uint32
+	ConfWinTopOffset       uint32
+	ConfWinBottomOffset    uint32
+	BitDepthLuma           uint32
+	BitDepthChroma         uint32
+	Log2MaxPicOrderCntLsb  uint32
+	SubLayerOrderingInfo   []SubLayerOrderingInfo
+
+	Log2MinLumaCodingBlockSize           uint32
+	Log2DiffMaxMinLumaCodingBlockSize    uint32
+	Log2MinLumaTransformBlockSize        uint32
+	Log2DiffMaxMinLumaTransformBlockSize uint32
+	MaxTransformHierarchyDepthInter      uint32
+	MaxTransformHierarchyDepthIntra      uint32
+	ScalingListEnabled                   bool
+	ScalingListDataPresent               bool
+	AmpEnabled                           bool
+	SampleAdaptiveOffsetEnabled          bool
+
+	PcmEnabled                           bool
+	PcmSampleBitDepthLuma                uint8
+	PcmSampleBitDepthChroma              uint8
+	Log2MinPcmLumaCodingBlockSize        uint32
+	Log2DiffMaxMinPcmLumaCodingBlockSize uint32
+	PcmLoopFilterDisabled                bool
+
+	ShortTermRefPicSets         []ShortTermRefPicSet
+	LongTermRefPicsPresent      bool
+	LtRefPicPocLsbSps           []uint32
+	UsedByCurrPicLtSps          []bool
+	TemporalMvpEnabled          bool
+	StrongIntraSmoothingEnabled bool
+
+	// VUI is set when the VUI parameters are present
+	VUI *VUI
+}
+
+// ChromaArrayType returns ChromaArrayType, 0 when the colour planes are coded separately
+func (s SPS) ChromaArrayType() uint32 {
+	if s.SeparateColourPlane {
+		return 0
+	}
+	return s.ChromaFormatIdc
+}
+
+// subSampling returns SubWidthC and SubHeightC, Table 6-1
+func (s SPS) subSampling() (x, y uint32) {
+	switch s.ChromaArrayType() {
+	case 1:
+		return 2, 2
+	case 2:
+		return 2, 1
+	}
+	return 1, 1
+}
+
+// Width returns the width in pixels of the pictures, within the conformance window
+func (s SPS) Width() int {
+	x, _ := s.subSampling()
+	return int(s.PicWidthInLumaSamples) - int(x*(s.ConfWinLeftOffset+s.ConfWinRightOffset))
+}
+
+// Height returns the height in pixels of the pictures, within the conformance window
+func (s SPS) Height() int {
+	_, y := s.subSampling()
+	return int(s.PicHeightInLumaSamples) - int(y*(s.ConfWinTopOffset+s.ConfWinBottomOffset))
+}
+
+// CtbLog2SizeY returns the log2 of the size of the coding tree blocks
+func (s SPS) CtbLog2SizeY() uint32 {
+	return s.Log2MinLumaCodingBlockSize + s.Log2DiffMaxMinLumaCodingBlockSize
+}
+
+// PicSizeInCtbsY returns the number of coding tree blocks of a picture
+func (s SPS) PicSizeInCtbsY() uint32 {
+	size := uint32(1) << s.CtbLog2SizeY()
+	return ((s.PicWidthInLumaSamples + size - 1) / size) * ((s.PicHeightInLumaSamples + size - 1) / size)
+}
+
+// FrameRate returns the picture rate of the timing information, 0 when absent
+func (s SPS) FrameRate() float64 {
+	if s.VUI == nil || !s.VUI.TimingInfoPresent || s.VUI.NumUnitsInTick == 0 {
+		return 0
+	}
+	return float64(s.VUI.TimeScale) / float64(s.VUI.NumUnitsInTick)
+}
+
+// CodecString returns the codecs parameter of the sequence, see ProfileTierLevel.CodecString
+func (s SPS) CodecString() string {
+	return s.ProfileTierLevel.CodecString()
+}
+
+// Decode reads the SPS RBSP, without its NAL header
+func (s *SPS) Decode(r *bitstream.Reader) error {
+	*s = SPS{}
+	s.VPSID = r.ReadUint8(4)
+	s.MaxSubLayersMinus1 = r.ReadUint8(3)
+	if s.MaxSubLayersMinus1 >= MaxSubLayers {
+		return fmt.Errorf("invalid sps_max_sub_layers_minus1 %d", s.MaxSubLayersMinus1)
+	}
+	s.TemporalIDNesting = r.ReadFlag()
+	if err := s.ProfileTierLevel.Decode(r, true, int(s.MaxSubLayersMinus1)); err != nil {
+		return err
+	}
+	s.ID = r.ReadUE()
+	if s.ID >= MaxSpsCount {
+		return fmt.Errorf("sps_seq_parameter_set_id %d exceeds %d", s.ID, MaxSpsCount-1)
+	}
+	s.ChromaFormatIdc = r.ReadUE()
+	if s.ChromaFormatIdc > 3 {
+		return fmt.Errorf("invalid chroma_format_idc %d", s.ChromaFormatIdc)
+	}
+	if s.ChromaFormatIdc == 3 {
+		s.SeparateColourPlane = r.ReadFlag()
+	}
+	s.PicWidthInLumaSamples = r.ReadUE()
+	s.PicHeightInLumaSamples = r.ReadUE()
+	if s.PicWidthInLumaSamples == 0 || s.PicWidthInLumaSamples > MaxWidth ||
+		s.PicHeightInLumaSamples == 0 || s.PicHeightInLumaSamples > MaxHeight {
+		return fmt.Errorf("invalid dimensions %dx%d", s.PicWidthInLumaSamples, s.PicHeightInLumaSamples)
+	}
+	if s.ConformanceWindow = r.ReadFlag(); s.ConformanceWindow {
+		s.ConfWinLeftOffset = r.ReadUE()
+		s.ConfWinRightOffset = r.ReadUE()
+		s.ConfWinTopOffset = r.ReadUE()
+		s.ConfWinBottomOffset = r.ReadUE()
+		if s.Width() <= 0 || s.Height() <= 0 {
+			return fmt.Errorf("conformance window exceeds the picture")
+		}
+	}
+	s.BitDepthLuma = r.ReadUE() + 8
+	s.BitDepthChroma = r.ReadUE() + 8
+	if s.BitDepthLuma > 16 || s.BitDepthChroma > 16 {
+		return fmt.Errorf("invalid bit depth %d/%d", s.BitDepthLuma, s.BitDepthChroma)
+	}
+	s.Log2MaxPicOrderCntLsb = r.ReadUE() + 4
+	if s.Log2MaxPicOrderCntLsb > 16 {
+		return fmt.Errorf("invalid log2_max_pic_order_cnt_lsb_minus4 %d", s.Log2MaxPicOrderCntLsb-4)
+	}
+	subLayers := 1
+	if orderingInfoPresent := r.ReadFlag(); orderingInfoPresent {
+		subLayers = int(s.MaxSubLayersMinus1) + 1
+	}
+	s.SubLayerOrderingInfo = make([]SubLayerOrderingInfo, subLayers)
+	for i := range s.SubLayerOrderingInfo {
+		s.SubLayerOrderingInfo[i].MaxDecPicBufferingMinus1 = r.ReadUE()
+		s.SubLayerOrderingInfo[i].MaxNumReorderPics = r.ReadUE()
+		s.SubLayerOrderingInfo[i].MaxLatencyIncreasePlus1 = r.ReadUE()
+	}
+
+	s.Log2MinLumaCodingBlockSize = r.ReadUE() + 3
+	s.Log2DiffMaxMinLumaCodingBlockSize = r.ReadUE()
+	if ctb := s.CtbLog2SizeY(); ctb < MinLog2CtbSize || ctb > MaxLog2CtbSize {
+		return fmt.Errorf("invalid CtbLog2SizeY %d", ctb)
+	}
+	s.Log2MinLumaTransformBlockSize = r.ReadUE() + 2
+	s.Log2DiffMaxMinLumaTransformBlockSize = r.ReadUE()
+	s.MaxTransformHierarchyDepthInter = r.ReadUE()
+	s.MaxTransformHierarchyDepthIntra = r.ReadUE()
+	if s.ScalingListEnabled = r.ReadFlag(); s.ScalingListEnabled {
+		if s.ScalingListDataPresent = r.ReadFlag(); s.ScalingListDataPresent {
+			if err := skipScalingListData(r); err != nil {
+				return err
+			}
+		}
+	}
+	s.AmpEnabled = r.ReadFlag()
+	s.SampleAdaptiveOffsetEnabled = r.ReadFlag()
+	if s.PcmEnabled = r.ReadFlag(); s.PcmEnabled {
+		s.PcmSampleBitDepthLuma = r.ReadUint8(4) + 1
+		s.PcmSampleBitDepthChroma = r.ReadUint8(4) + 1
+		s.Log2MinPcmLumaCodingBlockSize = r.ReadUE() + 3
+		s.Log2DiffMaxMinPcmLumaCodingBlockSize = r.ReadUE()
+		s.PcmLoopFilterDisabled = r.ReadFlag()
+	}
+
+	numShortTermRefPicSets := r.ReadUE()
+	if numShortTermRefPicSets > MaxShortTermRefPicSets {
+		return fmt.Errorf("num_short_term_ref_pic_sets %d exceeds %d", numShortTermRefPicSets, MaxShortTermRefPicSets)
+	}
+	s.ShortTermRefPicSets = make([]ShortTermRefPicSet, numShortTermRefPicSets)
+	for i := range s.ShortTermRefPicSets {
+		if err := s.ShortTermRefPicSets[i].Decode(r, i, s.ShortTermRefPicSets); err != nil {
+			return err
+		}
+	}
+	if s.LongTermRefPicsPresent = r.ReadFlag(); s.LongTermRefPicsPresent {
+		n := r.ReadUE()
+		if n > MaxLongTermRefPics {
+			return fmt.Errorf("num_long_term_ref_pics_sps %d exceeds %d", n, MaxLongTermRefPics)
+		}
+		s.LtRefPicPocLsbSps = make([]uint32, n)
+		s.UsedByCurrPicLtSps = make([]bool, n)
+		for i := range s.LtRefPicPocLsbSps {
+			s.LtRefPicPocLsbSps[i] = r.ReadUint32(int(s.Log2MaxPicOrderCntLsb))
+			s.UsedByCurrPicLtSps[i] = r.ReadFlag()
+		}
+	}
+	s.TemporalMvpEnabled = r.ReadFlag()
+	s.StrongIntraSmoothingEnabled = r.ReadFlag()
+	if r.ReadFlag() {
+		s.VUI = &VUI{}
+		if err := s.VUI.Decode(r, int(s.MaxSubLayersMinus1)); err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// Unmarshal parses the passed SPS NAL unit, its header included, and stores the result in the SPS this method is called upon
+func (s *SPS) Unmarshal(nalu []byte) error {
+	rbsp, err := parameterSetRBSP(nalu, NalUnitTypeSpsNut)
+	if err != nil {
+		return err
+	}
+	return s.Decode(bitstream.NewReader(rbsp))
+}
+
+// String helps with debugging by printing SPS information in a readable way
+func (s SPS) String() string {
+	out := "HEVC SPS:\n"
+
+	out += fmt.Sprintf("\tID: %v\n", s.ID)
+	out += fmt.Sprintf("\tVPSID: %v\n", s.VPSID)
+	out += fmt.Sprintf("\tCodec: %v\n", s.CodecString())
+	out += fmt.Sprintf("\tChromaFormatIdc: %v\n", s.ChromaFormatIdc)
+	out += fmt.Sprintf("\tBitDepth: %v/%v\n", s.BitDepthLuma, s.BitDepthChroma)
+	out += fmt.Sprintf("\tResolution: %vx%v\n", s.Width(), s.Height())
+	out += fmt.Sprintf("\tFrameRate: %v\n", s.FrameRate())
+
+	return out
+}
+
+func ParseSPS(nalu []byte) (SPS, error) {
+	var s SPS
+	err := (&s).Unmarshal(nalu)
+	return s, err
+}
+
+// parameterSetRBSP checks the type of a parameter set NAL unit, and returns its RBSP
+func parameterSetRBSP(nalu []byte, typ NalUnitType) ([]byte, error) {
+	if nalu == nil {
+		return nil, fmt.Errorf("invalid nil nalu")
+	}
+	if len(nalu) < 3 {
+		return nil, fmt.Errorf("buf is not large enough to container %s", typ)
+	}
+	if h := ParseNalHeader(nalu); h.NalUnitType != typ {
+		return nil, fmt.Errorf("NAL unit type %s is not %s", h.NalUnitType, typ)
+	}
+	return bitstream.RemoveEmulationPrevention(nalu[2:]), nil
+}
+
+// skipScalingListData reads and drops a scaling_list_data(), 7.3.4
+func skipScalingListData(r *bitstream.Reader) error {
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		step := 1
+		if sizeID == 3 {
+			step = 3
+		}
+		for matrixID := 0; matrixID < 6; matrixID += step {
+			if predMode := r.ReadFlag(); !predMode {
+				// scaling_list_pred_matrix_id_delta
+				r.ReadUE()
+				continue
+			}
+			coefNum := 64
+			if sizeID == 0 {
+				coefNum = 16
+			}
+			if sizeID > 1 {
+				// scaling_list_dc_coef_minus8
+				r.ReadSE()
+			}
+			for i := 0; i < coefNum && r.Err() == nil; i++ {
+				// scaling_list_delta_coef
+				r.ReadSE()
+			}
+		}
+	}
+	return r.Err()
+}
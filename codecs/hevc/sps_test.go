@@ -0,0 +1,90 @@
+package hevc
+
+import (
+	"testing"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+var (
+	testVPS = []byte{0x40, 0x01, 0x0c, 0x01, 0xff, 0xff, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00, 0x90, 0x00, 0x00, 0x03,
+		0x00, 0x00, 0x03, 0x00, 0x5d, 0x95, 0x98, 0x09}
+	testSPS = []byte{0x42, 0x01, 0x01, 0x01, 0x60, 0x00, 0x00, 0x03, 0x00, 0x90, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03,
+		0x00, 0x5d, 0xa0, 0x03, 0xc0, 0x80, 0x10, 0xe5, 0x96, 0x56, 0x69, 0x24, 0xca, 0xf0, 0x10, 0x10, 0x00, 0x00,
+		0x03, 0x00, 0x10, 0x00, 0x00, 0x03, 0x01, 0xe0, 0x80}
+	testPPS = []byte{0x44, 0x01, 0xc1, 0x72, 0xb4, 0x62, 0x40}
+)
+
+func TestParameterSets(t *testing.T) {
+	v, err := ParseVPS(testVPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ID != 0 || v.MaxSubLayersMinus1 != 0 || v.ProfileTierLevel.LevelIdc != 93 {
+		t.Errorf("unexpected %s", v)
+	}
+
+	s, err := ParseSPS(testSPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Width() != 1920 || s.Height() != 1080 {
+		t.Errorf("resolution %dx%d, want 1920x1080", s.Width(), s.Height())
+	}
+	if s.ChromaFormatIdc != 1 || s.BitDepthLuma != 8 || s.BitDepthChroma != 8 {
+		t.Errorf("unexpected format %s", s)
+	}
+	if s.FrameRate() != 30 {
+		t.Errorf("frame rate %v, want 30", s.FrameRate())
+	}
+	if w, h := s.VUI.SampleAspectRatio(); w != 1 || h != 1 {
+		t.Errorf("sample aspect ratio %d:%d, want 1:1", w, h)
+	}
+	if s.PicSizeInCtbsY() != 30*17 {
+		t.Errorf("PicSizeInCtbsY %d, want %d", s.PicSizeInCtbsY(), 30*17)
+	}
+	if codec := s.CodecString(); codec != "hvc1.1.6.L93.90" {
+		t.Errorf("codec %s, want hvc1.1.6.L93.90", codec)
+	}
+	if fmtp := s.ProfileTierLevel.Fmtp(); fmtp != "profile-id=1;tier-flag=0;level-id=93" {
+		t.Errorf("fmtp %s", fmtp)
+	}
+
+	p, err := ParsePPS(testPPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 0 || p.SPSID != 0 || !p.EntropyCodingSyncEnabled || p.TilesEnabled {
+		t.Errorf("unexpected %s", p)
+	}
+
+	if _, err := ParseSPS(testPPS); err == nil {
+		t.Error("PPS parsed as SPS")
+	}
+	if _, err := ParseSPS(testSPS[:12]); err == nil {
+		t.Error("truncated SPS parsed")
+	}
+}
+
+func TestShortTermRefPicSet(t *testing.T) {
+	// the second set is predicted from the first one, {-1, -3} shifted by -1 and extended with -1
+	sets := []ShortTermRefPicSet{{DeltaPocS0: []int32{-1, -3}, UsedByCurrPicS0: []bool{true, true}}, {}}
+	s := &sets[1]
+	w := bitstream.NewWriter()
+	w.WriteFlag(true) // inter_ref_pic_set_prediction_flag
+	w.WriteFlag(true) // delta_rps_sign
+	w.WriteUE(0)      // abs_delta_rps_minus1
+	w.WriteBits(7, 3) // used_by_curr_pic_flag
+	if err := s.Decode(bitstream.NewReader(w.Bytes()), 1, sets); err != nil {
+		t.Fatal(err)
+	}
+	want := []int32{-1, -2, -4}
+	if len(s.DeltaPocS0) != len(want) || len(s.DeltaPocS1) != 0 {
+		t.Fatalf("DeltaPocS0 %v, want %v", s.DeltaPocS0, want)
+	}
+	for i := range want {
+		if s.DeltaPocS0[i] != want[i] {
+			t.Fatalf("DeltaPocS0 %v, want %v", s.DeltaPocS0, want)
+		}
+	}
+}
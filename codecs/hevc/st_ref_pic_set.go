@@ -0,0 +1,107 @@
+package hevc
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// ShortTermRefPicSet represents a short-term reference picture set, st_ref_pic_set() in 7.3.7 of T-REC-H.265
+// The sets predicted from another one are stored once derived, 7.4.8
+type ShortTermRefPicSet struct {
+	// DeltaPocS0 and DeltaPocS1 are the POC differences of the pictures before and after the current one
+	DeltaPocS0      []int32
+	UsedByCurrPicS0 []bool
+	DeltaPocS1      []int32
+	UsedByCurrPicS1 []bool
+}
+
+// NumDeltaPocs returns the number of pictures of the set
+func (s ShortTermRefPicSet) NumDeltaPocs() int {
+	return len(s.DeltaPocS0) + len(s.DeltaPocS1)
+}
+
+// Decode reads the set stRpsIdx, sets holds the num_short_term_ref_pic_sets sets of the SPS, those before stRpsIdx read
+// In a slice header, stRpsIdx is num_short_term_ref_pic_sets
+func (s *ShortTermRefPicSet) Decode(r *bitstream.Reader, stRpsIdx int, sets []ShortTermRefPicSet) error {
+	*s = ShortTermRefPicSet{}
+	if stRpsIdx != 0 && r.ReadFlag() {
+		// inter_ref_pic_set_prediction_flag
+		deltaIdx := 1
+		if stRpsIdx == len(sets) {
+			deltaIdx = int(r.ReadUE()) + 1
+		}
+		if deltaIdx > stRpsIdx || stRpsIdx-deltaIdx >= len(sets) {
+			return fmt.Errorf("invalid delta_idx_minus1 %d", deltaIdx-1)
+		}
+		ref := sets[stRpsIdx-deltaIdx]
+		sign := r.ReadFlag()
+		deltaRps := int32(r.ReadUE()) + 1
+		if sign {
+			deltaRps = -deltaRps
+		}
+		used := make([]bool, ref.NumDeltaPocs()+1)
+		useDelta := make([]bool, ref.NumDeltaPocs()+1)
+		for j := range used {
+			used[j] = r.ReadFlag()
+			useDelta[j] = used[j] || r.ReadFlag()
+		}
+		if err := r.Err(); err != nil {
+			return err
+		}
+
+		// 7-61 and 7-62
+		neg, pos, all := len(ref.DeltaPocS0), len(ref.DeltaPocS1), ref.NumDeltaPocs()
+		for j := pos - 1; j >= 0; j-- {
+			if dPoc := ref.DeltaPocS1[j] + deltaRps; dPoc < 0 && useDelta[neg+j] {
+				s.DeltaPocS0 = append(s.DeltaPocS0, dPoc)
+				s.UsedByCurrPicS0 = append(s.UsedByCurrPicS0, used[neg+j])
+			}
+		}
+		if deltaRps < 0 && useDelta[all] {
+			s.DeltaPocS0 = append(s.DeltaPocS0, deltaRps)
+			s.UsedByCurrPicS0 = append(s.UsedByCurrPicS0, used[all])
+		}
+		for j := 0; j < neg; j++ {
+			if dPoc := ref.DeltaPocS0[j] + deltaRps; dPoc < 0 && useDelta[j] {
+				s.DeltaPocS0 = append(s.DeltaPocS0, dPoc)
+				s.UsedByCurrPicS0 = append(s.UsedByCurrPicS0, used[j])
+			}
+		}
+		for j := neg - 1; j >= 0; j-- {
+			if dPoc := ref.DeltaPocS0[j] + deltaRps; dPoc > 0 && useDelta[j] {
+				s.DeltaPocS1 = append(s.DeltaPocS1, dPoc)
+				s.UsedByCurrPicS1 = append(s.UsedByCurrPicS1, used[j])
+			}
+		}
+		if deltaRps > 0 && useDelta[all] {
+			s.DeltaPocS1 = append(s.DeltaPocS1, deltaRps)
+			s.UsedByCurrPicS1 = append(s.UsedByCurrPicS1, used[all])
+		}
+		for j := 0; j < pos; j++ {
+			if dPoc := ref.DeltaPocS1[j] + deltaRps; dPoc > 0 && useDelta[neg+j] {
+				s.DeltaPocS1 = append(s.DeltaPocS1, dPoc)
+				s.UsedByCurrPicS1 = append(s.UsedByCurrPicS1, used[neg+j])
+			}
+		}
+		return nil
+	}
+
+	numNegative, numPositive := r.ReadUE(), r.ReadUE()
+	if numNegative > MaxDpbSize || numPositive > MaxDpbSize-numNegative {
+		return fmt.Errorf("invalid num_negative_pics %d and num_positive_pics %d", numNegative, numPositive)
+	}
+	var poc int32
+	for i := uint32(0); i < numNegative; i++ {
+		poc -= int32(r.ReadUE()) + 1
+		s.DeltaPocS0 = append(s.DeltaPocS0, poc)
+		s.UsedByCurrPicS0 = append(s.UsedByCurrPicS0, r.ReadFlag())
+	}
+	poc = 0
+	for i := uint32(0); i < numPositive; i++ {
+		poc += int32(r.ReadUE()) + 1
+		s.DeltaPocS1 = append(s.DeltaPocS1, poc)
+		s.UsedByCurrPicS1 = append(s.UsedByCurrPicS1, r.ReadFlag())
+	}
+	return r.Err()
+}
@@ -0,0 +1,118 @@
+package hevc
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// VPS represents a video parameter set, video_parameter_set_rbsp() in 7.3.2.1 of T-REC-H.265
+// The fields are decoded up to the timing information, the HRD parameters and the extensions are skipped
+type VPS struct {
+	ID                   uint8
+	BaseLayerInternal    bool
+	BaseLayerAvailable   bool
+	MaxLayersMinus1      uint8
+	MaxSubLayersMinus1   uint8
+	TemporalIDNesting    bool
+	ProfileTierLevel     ProfileTierLevel
+	SubLayerOrderingInfo []SubLayerOrderingInfo
+	MaxLayerID           uint8
+	// LayerIDIncluded holds, for every layer set but the first, whether each layer ID up to MaxLayerID is included
+	LayerIDIncluded [][]bool
+
+	TimingInfoPresent        bool
+	NumUnitsInTick           uint32
+	TimeScale                uint32
+	PocProportionalToTiming  bool
+	NumTicksPocDiffOneMinus1 uint32
+	NumHrdParameters         uint32
+}
+
+// Decode reads the VPS RBSP, without its NAL header
+func (v *VPS) Decode(r *bitstream.Reader) error {
+	*v = VPS{}
+	v.ID = r.ReadUint8(4)
+	v.BaseLayerInternal = r.ReadFlag()
+	v.BaseLayerAvailable = r.ReadFlag()
+	v.MaxLayersMinus1 = r.ReadUint8(6)
+	v.MaxSubLayersMinus1 = r.ReadUint8(3)
+	if v.MaxSubLayersMinus1 >= MaxSubLayers {
+		return fmt.Errorf("invalid vps_max_sub_layers_minus1 %d", v.MaxSubLayersMinus1)
+	}
+	v.TemporalIDNesting = r.ReadFlag()
+	// vps_reserved_0xffff_16bits
+	r.SkipBits(16)
+	if err := v.ProfileTierLevel.Decode(r, true, int(v.MaxSubLayersMinus1)); err != nil {
+		return err
+	}
+	subLayers := 1
+	if orderingInfoPresent := r.ReadFlag(); orderingInfoPresent {
+		subLayers = int(v.MaxSubLayersMinus1) + 1
+	}
+	v.SubLayerOrderingInfo = make([]SubLayerOrderingInfo, subLayers)
+	for i := range v.SubLayerOrderingInfo {
+		v.SubLayerOrderingInfo[i].MaxDecPicBufferingMinus1 = r.ReadUE()
+		v.SubLayerOrderingInfo[i].MaxNumReorderPics = r.ReadUE()
+		v.SubLayerOrderingInfo[i].MaxLatencyIncreasePlus1 = r.ReadUE()
+	}
+	v.MaxLayerID = r.ReadUint8(6)
+	numLayerSets := r.ReadUE() + 1
+	if numLayerSets > MaxLayerSets {
+		return fmt.Errorf("vps_num_layer_sets_minus1 %d exceeds %d", numLayerSets-1, MaxLayerSets-1)
+	}
+	v.LayerIDIncluded = make([][]bool, numLayerSets-1)
+	for i := range v.LayerIDIncluded {
+		v.LayerIDIncluded[i] = make([]bool, int(v.MaxLayerID)+1)
+		for j := range v.LayerIDIncluded[i] {
+			v.LayerIDIncluded[i][j] = r.ReadFlag()
+		}
+	}
+	if v.TimingInfoPresent = r.ReadFlag(); v.TimingInfoPresent {
+		v.NumUnitsInTick = r.ReadUint32(32)
+		v.TimeScale = r.ReadUint32(32)
+		if v.PocProportionalToTiming = r.ReadFlag(); v.PocProportionalToTiming {
+			v.NumTicksPocDiffOneMinus1 = r.ReadUE()
+		}
+		v.NumHrdParameters = r.ReadUE()
+		if v.NumHrdParameters > numLayerSets {
+			return fmt.Errorf("vps_num_hrd_parameters %d exceeds %d", v.NumHrdParameters, numLayerSets)
+		}
+		for i := uint32(0); i < v.NumHrdParameters; i++ {
+			// hrd_layer_set_idx
+			r.ReadUE()
+			commonInfPresent := i == 0 || r.ReadFlag()
+			if err := skipHrdParameters(r, commonInfPresent, int(v.MaxSubLayersMinus1)); err != nil {
+				return err
+			}
+		}
+	}
+	return r.Err()
+}
+
+// Unmarshal parses the passed VPS NAL unit, its header included, and stores the result in the VPS this method is called upon
+func (v *VPS) Unmarshal(nalu []byte) error {
+	rbsp, err := parameterSetRBSP(nalu, NalUnitTypeVpsNut)
+	if err != nil {
+		return err
+	}
+	return v.Decode(bitstream.NewReader(rbsp))
+}
+
+// String helps with debugging by printing VPS information in a readable way
+func (v VPS) String() string {
+	out := "HEVC VPS:\n"
+
+	out += fmt.Sprintf("\tID: %v\n", v.ID)
+	out += fmt.Sprintf("\tMaxLayersMinus1: %v\n", v.MaxLayersMinus1)
+	out += fmt.Sprintf("\tMaxSubLayersMinus1: %v\n", v.MaxSubLayersMinus1)
+	out += fmt.Sprintf("\tCodec: %v\n", v.ProfileTierLevel.CodecString())
+
+	return out
+}
+
+func ParseVPS(nalu []byte) (VPS, error) {
+	var v VPS
+	err := (&v).Unmarshal(nalu)
+	return v, err
+}
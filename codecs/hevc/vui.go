@@ -0,0 +1,191 @@
+package hevc
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+	"github.com/searKing/rtp/codecs/h264"
+)
+
+// VUI represents the video usability information, vui_parameters() in E.2.1 of T-REC-H.265
+// The HRD parameters are skipped
+type VUI struct {
+	AspectRatioInfoPresent bool
+	AspectRatioIdc         uint8
+	// SarWidth and SarHeight are set with h264.AspectRatioIdcExtendedSAR
+	SarWidth  uint16
+	SarHeight uint16
+
+	OverscanInfoPresent bool
+	OverscanAppropriate bool
+
+	VideoSignalTypePresent   bool
+	VideoFormat              uint8
+	VideoFullRange           bool
+	ColourDescriptionPresent bool
+	ColourPrimaries          uint8
+	TransferCharacteristics  uint8
+	MatrixCoefficients       uint8
+
+	ChromaLocInfoPresent           bool
+	ChromaSampleLocTypeTopField    uint32
+	ChromaSampleLocTypeBottomField uint32
+
+	NeutralChromaIndication bool
+	FieldSeq                bool
+	FrameFieldInfoPresent   bool
+
+	DefaultDisplayWindow   bool
+	DefDispWinLeftOffset   uint32
+	DefDispWinRightOffset  uint32
+	DefDispWinTopOffset    uint32
+	DefDispWinBottomOffset uint32
+
+	TimingInfoPresent        bool
+	NumUnitsInTick           uint32
+	TimeScale                uint32
+	PocProportionalToTiming  bool
+	NumTicksPocDiffOneMinus1 uint32
+	HrdParametersPresent     bool
+
+	BitstreamRestriction           bool
+	TilesFixedStructure            bool
+	MotionVectorsOverPicBoundaries bool
+	RestrictedRefPicLists          bool
+	MinSpatialSegmentationIdc      uint32
+	MaxBytesPerPicDenom            uint32
+	MaxBitsPerMinCuDenom           uint32
+	Log2MaxMvLengthHorizontal      uint32
+	Log2MaxMvLengthVertical        uint32
+}
+
+// SampleAspectRatio returns the sample aspect ratio, 0:0 when unspecified
+// The aspect_ratio_idc values are those of T-REC-H.264
+func (v VUI) SampleAspectRatio() (width, height uint16) {
+	return h264.VUI{
+		AspectRatioInfoPresent: v.AspectRatioInfoPresent,
+		AspectRatioIdc:         v.AspectRatioIdc,
+		SarWidth:               v.SarWidth,
+		SarHeight:              v.SarHeight,
+	}.SampleAspectRatio()
+}
+
+// Decode reads the VUI parameters of an SPS of maxSubLayersMinus1 sub-layers
+func (v *VUI) Decode(r *bitstream.Reader, maxSubLayersMinus1 int) error {
+	*v = VUI{}
+	if v.AspectRatioInfoPresent = r.ReadFlag(); v.AspectRatioInfoPresent {
+		v.AspectRatioIdc = r.ReadUint8(8)
+		if v.AspectRatioIdc == h264.AspectRatioIdcExtendedSAR {
+			v.SarWidth = r.ReadUint16(16)
+			v.SarHeight = r.ReadUint16(16)
+		}
+	}
+	if v.OverscanInfoPresent = r.ReadFlag(); v.OverscanInfoPresent {
+		v.OverscanAppropriate = r.ReadFlag()
+	}
+	if v.VideoSignalTypePresent = r.ReadFlag(); v.VideoSignalTypePresent {
+		v.VideoFormat = r.ReadUint8(3)
+		v.VideoFullRange = r.ReadFlag()
+		if v.ColourDescriptionPresent = r.ReadFlag(); v.ColourDescriptionPresent {
+			v.ColourPrimaries = r.ReadUint8(8)
+			v.TransferCharacteristics = r.ReadUint8(8)
+			v.MatrixCoefficients = r.ReadUint8(8)
+		}
+	}
+	if v.ChromaLocInfoPresent = r.ReadFlag(); v.ChromaLocInfoPresent {
+		v.ChromaSampleLocTypeTopField = r.ReadUE()
+		v.ChromaSampleLocTypeBottomField = r.ReadUE()
+	}
+	v.NeutralChromaIndication = r.ReadFlag()
+	v.FieldSeq = r.ReadFlag()
+	v.FrameFieldInfoPresent = r.ReadFlag()
+	if v.DefaultDisplayWindow = r.ReadFlag(); v.DefaultDisplayWindow {
+		v.DefDispWinLeftOffset = r.ReadUE()
+		v.DefDispWinRightOffset = r.ReadUE()
+		v.DefDispWinTopOffset = r.ReadUE()
+		v.DefDispWinBottomOffset = r.ReadUE()
+	}
+	if v.TimingInfoPresent = r.ReadFlag(); v.TimingInfoPresent {
+		v.NumUnitsInTick = r.ReadUint32(32)
+		v.TimeScale = r.ReadUint32(32)
+		if v.PocProportionalToTiming = r.ReadFlag(); v.PocProportionalToTiming {
+			v.NumTicksPocDiffOneMinus1 = r.ReadUE()
+		}
+		if v.HrdParametersPresent = r.ReadFlag(); v.HrdParametersPresent {
+			if err := skipHrdParameters(r, true, maxSubLayersMinus1); err != nil {
+				return err
+			}
+		}
+	}
+	if v.BitstreamRestriction = r.ReadFlag(); v.BitstreamRestriction {
+		v.TilesFixedStructure = r.ReadFlag()
+		v.MotionVectorsOverPicBoundaries = r.ReadFlag()
+		v.RestrictedRefPicLists = r.ReadFlag()
+		v.MinSpatialSegmentationIdc = r.ReadUE()
+		v.MaxBytesPerPicDenom = r.ReadUE()
+		v.MaxBitsPerMinCuDenom = r.ReadUE()
+		v.Log2MaxMvLengthHorizontal = r.ReadUE()
+		v.Log2MaxMvLengthVertical = r.ReadUE()
+	}
+	return r.Err()
+}
+
+// skipHrdParameters reads and drops a hrd_parameters(commonInfPresent, maxSubLayersMinus1), E.2.2
+func skipHrdParameters(r *bitstream.Reader, commonInfPresent bool, maxSubLayersMinus1 int) error {
+	var nalHrd, vclHrd, subPicHrd bool
+	if commonInfPresent {
+		nalHrd = r.ReadFlag()
+		vclHrd = r.ReadFlag()
+		if nalHrd || vclHrd {
+			if subPicHrd = r.ReadFlag(); subPicHrd {
+				// tick_divisor_minus2, du_cpb_removal_delay_increment_length_minus1,
+				// sub_pic_cpb_params_in_pic_timing_sei_flag, dpb_output_delay_du_length_minus1
+				r.SkipBits(8 + 5 + 1 + 5)
+			}
+			// bit_rate_scale, cpb_size_scale
+			r.SkipBits(4 + 4)
+			if subPicHrd {
+				// cpb_size_du_scale
+				r.SkipBits(4)
+			}
+			// initial_cpb_removal_delay_length_minus1, au_cpb_removal_delay_length_minus1, dpb_output_delay_length_minus1
+			r.SkipBits(5 + 5 + 5)
+		}
+	}
+	for i := 0; i <= maxSubLayersMinus1; i++ {
+		fixedPicRateWithinCvs := true
+		if fixedPicRateGeneral := r.ReadFlag(); !fixedPicRateGeneral {
+			fixedPicRateWithinCvs = r.ReadFlag()
+		}
+		lowDelayHrd := false
+		if fixedPicRateWithinCvs {
+			// elemental_duration_in_tc_minus1
+			r.ReadUE()
+		} else {
+			lowDelayHrd = r.ReadFlag()
+		}
+		var cpbCnt uint32 = 1
+		if !lowDelayHrd {
+			cpbCnt = r.ReadUE() + 1
+		}
+		if cpbCnt > MaxCpbCnt {
+			return fmt.Errorf("cpb_cnt_minus1 %d exceeds %d", cpbCnt-1, MaxCpbCnt-1)
+		}
+		for _, present := range []bool{nalHrd, vclHrd} {
+			if !present {
+				continue
+			}
+			// sub_layer_hrd_parameters(i)
+			for j := uint32(0); j < cpbCnt && r.Err() == nil; j++ {
+				r.ReadUE()
+				r.ReadUE()
+				if subPicHrd {
+					r.ReadUE()
+					r.ReadUE()
+				}
+				r.SkipBits(1)
+			}
+		}
+	}
+	return r.Err()
+}
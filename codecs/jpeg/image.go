@@ -0,0 +1,219 @@
+package jpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// QuantizationTable represents a quantization table, B.2.4.1 in T-REC-T.81
+type QuantizationTable struct {
+	// Precision is 0 for 8-bit values, 1 for 16-bit values
+	Precision uint8
+	// Values holds the 64 values in zigzag order, on 1 or 2 bytes each, big-endian
+	Values []byte
+}
+
+// Size returns the size of the values of a table of the precision
+func (t QuantizationTable) Size() int {
+	return QuantizationTableSize * int(t.Precision+1)
+}
+
+// Component represents a component of the frame header, B.2.2 in T-REC-T.81
+type Component struct {
+	ID uint8
+	// H and V are the horizontal and vertical sampling factors
+	H  uint8
+	V  uint8
+	Tq uint8
+}
+
+// Image represents a baseline, sequential, JPEG image, split into the parts rfc2435 carries:
+// the quantization tables, the frame header, the restart interval and the entropy-coded scan data.
+// The Huffman tables are not kept, the standard tables of section K.3 are assumed
+type Image struct {
+	QuantizationTables [MaxQuantizationTables]*QuantizationTable
+	Width              int
+	Height             int
+	Components         []Component
+	RestartInterval    uint16
+	// ScanData holds the entropy-coded segments of the single scan, EOI excluded
+	ScanData []byte
+}
+
+// Unmarshal parses a JPEG interchange format image and stores the result in the Image this method is called upon
+func (img *Image) Unmarshal(buf []byte) error {
+	*img = Image{}
+	if len(buf) < 2 || buf[0] != MarkerPrefix || buf[1] != MarkerSOI {
+		return fmt.Errorf("missing SOI marker")
+	}
+	index := 2
+	for {
+		// markers may be preceded by fill bytes
+		for index < len(buf) && buf[index] == MarkerPrefix && index+1 < len(buf) && buf[index+1] == MarkerPrefix {
+			index++
+		}
+		if index+4 > len(buf) || buf[index] != MarkerPrefix {
+			return fmt.Errorf("invalid marker segment at %d", index)
+		}
+		marker := buf[index+1]
+		length := int(binary.BigEndian.Uint16(buf[index+2:]))
+		if length < 2 || index+2+length > len(buf) {
+			return fmt.Errorf("marker 0x%02x length %d exceeds remaining %d bytes", marker, length, len(buf)-index-2)
+		}
+		segment := buf[index+4 : index+2+length]
+		index += 2 + length
+
+		var err error
+		switch marker {
+		case MarkerDQT:
+			err = img.unmarshalDQT(segment)
+		case MarkerSOF0, MarkerSOF1:
+			err = img.unmarshalSOF(segment)
+		case MarkerDRI:
+			if len(segment) != 2 {
+				err = fmt.Errorf("invalid DRI length %d", length)
+			} else {
+				img.RestartInterval = binary.BigEndian.Uint16(segment)
+			}
+		case MarkerSOS:
+			if img.Components == nil {
+				return fmt.Errorf("SOS before SOF")
+			}
+			if len(segment) < 1 || int(segment[0]) != len(img.Components) {
+				return fmt.Errorf("scan is not interleaved over the %d components", len(img.Components))
+			}
+			data := buf[index:]
+			if end := bytes.LastIndex(data, []byte{MarkerPrefix, MarkerEOI}); end >= 0 {
+				data = data[:end]
+			}
+			img.ScanData = data
+			return nil
+		case MarkerSOI, MarkerEOI:
+			return fmt.Errorf("unexpected marker 0x%02x", marker)
+		default:
+			if marker >= MarkerSOF0 && marker <= 0xcf && marker != MarkerDHT && marker != 0xc8 && marker != 0xcc {
+				return fmt.Errorf("unsupported frame type 0x%02x, only baseline is", marker)
+			}
+			// APPn, COM, DHT and the others are skipped
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (img *Image) unmarshalDQT(segment []byte) error {
+	for len(segment) > 0 {
+		t := &QuantizationTable{Precision: segment[0] >> 4}
+		id := segment[0] & 0x0f
+		if t.Precision > 1 || id >= MaxQuantizationTables {
+			return fmt.Errorf("invalid DQT Pq %d Tq %d", t.Precision, id)
+		}
+		if len(segment) < 1+t.Size() {
+			return fmt.Errorf("DQT is not large enough to container table %d", id)
+		}
+		t.Values = append([]byte(nil), segment[1:1+t.Size()]...)
+		img.QuantizationTables[id] = t
+		segment = segment[1+t.Size():]
+	}
+	return nil
+}
+
+func (img *Image) unmarshalSOF(segment []byte) error {
+	if len(segment) < 6 {
+		return fmt.Errorf("SOF is not large enough")
+	}
+	if segment[0] != 8 {
+		return fmt.Errorf("unsupported sample precision %d", segment[0])
+	}
+	img.Height = int(binary.BigEndian.Uint16(segment[1:]))
+	img.Width = int(binary.BigEndian.Uint16(segment[3:]))
+	count := int(segment[5])
+	if len(segment) != 6+3*count {
+		return fmt.Errorf("SOF length does not match %d components", count)
+	}
+	img.Components = nil
+	for i := 0; i < count; i++ {
+		c := segment[6+3*i:]
+		img.Components = append(img.Components, Component{ID: c[0], H: c[1] >> 4, V: c[1] & 0x0f, Tq: c[2]})
+	}
+	return nil
+}
+
+// Marshal serializes the image into a JFIF file, with the standard Huffman tables
+func (img Image) Marshal() ([]byte, error) {
+	if len(img.Components) == 0 {
+		return nil, fmt.Errorf("image without components")
+	}
+	if img.Width <= 0 || img.Width > MaxDimension || img.Height <= 0 || img.Height > MaxDimension {
+		return nil, fmt.Errorf("invalid dimensions %dx%d", img.Width, img.Height)
+	}
+	w := bytes.NewBuffer(nil)
+	segment := func(marker byte, data []byte) {
+		w.Write([]byte{MarkerPrefix, marker, byte((len(data) + 2) >> 8), byte(len(data) + 2)})
+		w.Write(data)
+	}
+
+	w.Write([]byte{MarkerPrefix, MarkerSOI})
+	// JFIF APP0: version 1.01, no units, 1:1 pixel aspect ratio, no thumbnail
+	segment(MarkerAPP0, []byte{'J', 'F', 'I', 'F', 0, 1, 1, 0, 0, 1, 0, 1, 0, 0})
+
+	var dqt []byte
+	for id, t := range img.QuantizationTables {
+		if t == nil {
+			continue
+		}
+		if len(t.Values) != t.Size() {
+			return nil, fmt.Errorf("quantization table %d has %d bytes, want %d", id, len(t.Values), t.Size())
+		}
+		dqt = append(dqt, t.Precision<<4|byte(id))
+		dqt = append(dqt, t.Values...)
+	}
+	for _, c := range img.Components {
+		if int(c.Tq) >= MaxQuantizationTables || img.QuantizationTables[c.Tq] == nil {
+			return nil, fmt.Errorf("component %d references missing quantization table %d", c.ID, c.Tq)
+		}
+	}
+	segment(MarkerDQT, dqt)
+
+	sof := []byte{8, byte(img.Height >> 8), byte(img.Height), byte(img.Width >> 8), byte(img.Width), byte(len(img.Components))}
+	for _, c := range img.Components {
+		sof = append(sof, c.ID, c.H<<4|c.V&0x0f, c.Tq)
+	}
+	segment(MarkerSOF0, sof)
+
+	if img.RestartInterval != 0 {
+		segment(MarkerDRI, []byte{byte(img.RestartInterval >> 8), byte(img.RestartInterval)})
+	}
+
+	var dht []byte
+	for _, t := range StandardHuffmanTables {
+		dht = append(dht, t.Class<<4|t.ID)
+		dht = append(dht, t.Counts[:]...)
+		dht = append(dht, t.Symbols...)
+	}
+	segment(MarkerDHT, dht)
+
+	// the first component uses the luminance tables, the others the chrominance ones
+	sos := []byte{byte(len(img.Components))}
+	for i, c := range img.Components {
+		var tables byte
+		if i > 0 {
+			tables = 0x11
+		}
+		sos = append(sos, c.ID, tables)
+	}
+	sos = append(sos, 0, 63, 0)
+	segment(MarkerSOS, sos)
+
+	w.Write(img.ScanData)
+	w.Write([]byte{MarkerPrefix, MarkerEOI})
+	return w.Bytes(), nil
+}
+
+func ParseImage(buf []byte) (Image, error) {
+	var img Image
+	err := (&img).Unmarshal(buf)
+	return img, err
+}
@@ -0,0 +1,53 @@
+package jpeg
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestImage_Unmarshal(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	if err := jpeg.Encode(w, image.NewYCbCr(image.Rect(0, 0, 40, 24), image.YCbCrSubsampleRatio420), &jpeg.Options{Quality: 30}); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := ParseImage(w.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 40 || img.Height != 24 || len(img.Components) != 3 || img.RestartInterval != 0 {
+		t.Fatalf("Unexpected image %dx%d, %d components", img.Width, img.Height, len(img.Components))
+	}
+	if c := img.Components[0]; c.ID != 1 || c.H != 2 || c.V != 2 || c.Tq != 0 {
+		t.Fatalf("Unexpected luminance component %+v", c)
+	}
+	if c := img.Components[2]; c.ID != 3 || c.H != 1 || c.V != 1 || c.Tq != 1 {
+		t.Fatalf("Unexpected chrominance component %+v", c)
+	}
+
+	// the encoder scales the tables of Annex K as rfc2435 does
+	luma, chroma := QuantizationTables(30)
+	if !bytes.Equal(img.QuantizationTables[0].Values, luma.Values) || !bytes.Equal(img.QuantizationTables[1].Values, chroma.Values) {
+		t.Fatal("QuantizationTables should match the tables of the encoder")
+	}
+
+	raw, err := img.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = jpeg.Decode(bytes.NewReader(raw)); err != nil {
+		t.Fatal("Marshalled image should decode:", err)
+	}
+	if again, err := ParseImage(raw); err != nil || !bytes.Equal(again.ScanData, img.ScanData) {
+		t.Fatal("Marshalled image should keep the scan data")
+	}
+
+	if _, err = ParseImage([]byte{0xff, 0xd8, 0xff, 0xc2, 0x00, 0x02}); err == nil {
+		t.Fatal("Progressive images should be rejected")
+	}
+	if _, err = ParseImage([]byte{0x00}); err == nil {
+		t.Fatal("Missing SOI should be rejected")
+	}
+}
@@ -0,0 +1,30 @@
+package jpeg
+
+// Table B.1 – Marker code assignments in T-REC-T.81
+const (
+	MarkerPrefix = 0xff
+
+	MarkerSOF0 = 0xc0 // baseline DCT
+	MarkerSOF1 = 0xc1 // extended sequential DCT
+	MarkerSOF2 = 0xc2 // progressive DCT
+	MarkerDHT  = 0xc4
+	MarkerRST0 = 0xd0 // RST0 to RST7: 0xd0 to 0xd7
+	MarkerRST7 = 0xd7
+	MarkerSOI  = 0xd8
+	MarkerEOI  = 0xd9
+	MarkerSOS  = 0xda
+	MarkerDQT  = 0xdb
+	MarkerDRI  = 0xdd
+	MarkerAPP0 = 0xe0
+	MarkerCOM  = 0xfe
+)
+
+const (
+	// B.2.4.1: a quantization table holds 64 values
+	QuantizationTableSize = 64
+	// B.2.4.1: Tq is in [0, 3]
+	MaxQuantizationTables = 4
+
+	// B.2.2: the height and width are 16 bits
+	MaxDimension = 0xffff
+)
@@ -0,0 +1,154 @@
+package mpa
+
+import "fmt"
+
+// An ADU, Application Data Unit, of Layer III is a frame whose main data is not spread over the bit reservoir:
+// the header, the CRC and the side information of the frame, followed by the whole main data of the frame, see rfc5219#section-3
+
+// aduHeadSize returns the size of the header, CRC and side information of a Layer III frame or ADU
+func aduHeadSize(h FrameHeader) int {
+	size := FrameHeaderSize + h.SideInfoSize()
+	if h.Protected {
+		size += CRCSize
+	}
+	return size
+}
+
+// parseADUHead parses the header and the side information of a Layer III frame or ADU
+func parseADUHead(buf []byte) (FrameHeader, SideInfo, int, error) {
+	var info SideInfo
+	h, err := ParseFrameHeader(buf)
+	if err != nil {
+		return h, info, 0, err
+	}
+	if h.Layer != Layer3 {
+		return h, info, 0, fmt.Errorf("%s frames have no ADU", h.Layer)
+	}
+	size := aduHeadSize(h)
+	if len(buf) < size {
+		return h, info, 0, fmt.Errorf("buf is not large enough to container side information")
+	}
+	err = (&info).Unmarshal(h, buf[size-h.SideInfoSize():])
+	return h, info, size, err
+}
+
+// ADUEncoder converts Layer III frames to ADUs, keeping the bit reservoir of the previous frames
+type ADUEncoder struct {
+	reservoir []byte
+}
+
+// Encode returns the ADU of a frame, nil when its main data starts in frames not given to the encoder
+func (e *ADUEncoder) Encode(frame []byte) ([]byte, error) {
+	h, info, headSize, err := parseADUHead(frame)
+	if err != nil {
+		return nil, err
+	}
+	if len(frame) < h.FrameSize() {
+		return nil, fmt.Errorf("frame is not large enough to container %d bytes", h.FrameSize())
+	}
+	start := len(e.reservoir) - info.MainDataBegin
+	e.reservoir = append(e.reservoir, frame[headSize:h.FrameSize()]...)
+	defer func() {
+		// only the bytes the next backpointer can reach are kept
+		if len(e.reservoir) > MaxMainDataBegin {
+			e.reservoir = append(e.reservoir[:0], e.reservoir[len(e.reservoir)-MaxMainDataBegin:]...)
+		}
+	}()
+	if start < 0 {
+		return nil, nil
+	}
+	end := start + info.MainDataSize()
+	if end > len(e.reservoir) {
+		return nil, fmt.Errorf("main data of %d bytes exceeds the frame", info.MainDataSize())
+	}
+	adu := make([]byte, 0, headSize+info.MainDataSize())
+	adu = append(adu, frame[:headSize]...)
+	return append(adu, e.reservoir[start:end]...), nil
+}
+
+// Reset clears the bit reservoir, after a discontinuity
+func (e *ADUEncoder) Reset() {
+	e.reservoir = nil
+}
+
+// ADUDecoder converts ADUs back to Layer III frames, spreading their main data over the bit reservoir
+// as early as the backpointers allow; a frame is returned once no later ADU can put data in it
+type ADUDecoder struct {
+	// main data areas of the frames, from the offset base
+	stream []byte
+	base   int
+	// end of the main data of the last ADU
+	dataEnd int
+	pending []aduFrame
+}
+
+type aduFrame struct {
+	head       []byte
+	start, end int
+}
+
+// Decode adds an ADU, and returns the frames completed
+func (d *ADUDecoder) Decode(adu []byte) ([][]byte, error) {
+	h, _, headSize, err := parseADUHead(adu)
+	if err != nil {
+		return nil, err
+	}
+	data := adu[headSize:]
+	areaStart := d.base + len(d.stream)
+	areaEnd := areaStart + h.FrameSize() - headSize
+	if areaEnd < areaStart {
+		return nil, fmt.Errorf("frame of %d bytes is smaller than its side information", h.FrameSize())
+	}
+	pos := d.dataEnd
+	if pos < areaStart-h.MaxMainDataBegin() {
+		pos = areaStart - h.MaxMainDataBegin()
+	}
+	if pos+len(data) > areaEnd {
+		return nil, fmt.Errorf("main data of %d bytes does not fit the bit reservoir", len(data))
+	}
+
+	d.stream = append(d.stream, make([]byte, areaEnd-areaStart)...)
+	copy(d.stream[pos-d.base:], data)
+	d.dataEnd = pos + len(data)
+	head := append([]byte(nil), adu[:headSize]...)
+	if err := SetMainDataBegin(head, areaStart-pos); err != nil {
+		return nil, err
+	}
+	d.pending = append(d.pending, aduFrame{head: head, start: areaStart, end: areaEnd})
+
+	var frames [][]byte
+	for len(d.pending) > 0 && d.pending[0].end <= d.dataEnd {
+		frames = append(frames, d.frame(d.pending[0]))
+		d.pending = d.pending[1:]
+	}
+	d.trim()
+	return frames, nil
+}
+
+// Flush returns the frames not completed yet, the data of later ADUs will not be put in them
+func (d *ADUDecoder) Flush() [][]byte {
+	var frames [][]byte
+	for _, f := range d.pending {
+		frames = append(frames, d.frame(f))
+	}
+	d.pending = nil
+	d.dataEnd = d.base + len(d.stream)
+	d.trim()
+	return frames
+}
+
+func (d *ADUDecoder) frame(f aduFrame) []byte {
+	frame := make([]byte, 0, len(f.head)+f.end-f.start)
+	frame = append(frame, f.head...)
+	return append(frame, d.stream[f.start-d.base:f.end-d.base]...)
+}
+
+// trim drops the bytes of the stream no frame nor ADU needs anymore
+func (d *ADUDecoder) trim() {
+	keep := d.dataEnd
+	if len(d.pending) > 0 && d.pending[0].start < keep {
+		keep = d.pending[0].start
+	}
+	d.stream = append(d.stream[:0], d.stream[keep-d.base:]...)
+	d.base = keep
+}
@@ -0,0 +1,69 @@
+package mpa
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// testADU builds the ADU of a MPEG-1 Layer III mono frame, 128 kbit/s at 44.1 kHz, with size bytes of main data
+func testADU(size int, fill byte) []byte {
+	w := bitstream.NewWriter()
+	w.WriteBytes([]byte{0xff, 0xfb, 0x90, 0xc0})
+	w.WriteBits(0, 9)   // main_data_begin
+	w.WriteBits(0, 5+4) // private bits, scfsi
+	for gr := 0; gr < 2; gr++ {
+		w.WriteBits(uint64(size*8/2), 12) // part2_3_length
+		w.WriteBits(0, 47)
+	}
+	w.ByteAlign()
+	return append(w.Bytes(), bytes.Repeat([]byte{fill}, size)...)
+}
+
+func TestADU(t *testing.T) {
+	// frames of 417 bytes, 396 bytes of main data area
+	sizes := []int{100, 600, 400, 50, 396, 0, 300}
+	var adus [][]byte
+	for i, size := range sizes {
+		adus = append(adus, testADU(size, byte(i+1)))
+	}
+
+	d := ADUDecoder{}
+	var frames [][]byte
+	for _, adu := range adus {
+		f, err := d.Decode(adu)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, f...)
+	}
+	frames = append(frames, d.Flush()...)
+	if len(frames) != len(adus) {
+		t.Fatalf("Decoder should return a frame per ADU, got %d", len(frames))
+	}
+
+	e := ADUEncoder{}
+	for i, frame := range frames {
+		if len(frame) != 417 {
+			t.Fatalf("Frame %d should be 417 bytes, got %d", i, len(frame))
+		}
+		adu, err := e.Encode(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(adu[21:], adus[i][21:]) {
+			t.Fatalf("ADU %d should be rebuilt from the frames", i)
+		}
+	}
+
+	// an ADU too large for the reservoir
+	if _, err := (&ADUDecoder{}).Decode(testADU(400, 1)); err == nil {
+		t.Fatal("Decoder should reject main data exceeding the bit reservoir")
+	}
+	// backpointer before the first frame
+	frame := append([]byte(nil), frames[2]...)
+	if adu, err := (&ADUEncoder{}).Encode(frame); adu != nil || err != nil {
+		t.Fatal("Encoder should skip frames whose main data was not received")
+	}
+}
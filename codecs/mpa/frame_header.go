@@ -0,0 +1,226 @@
+package mpa
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The frame header, 2.4.1.3 in ISO/IEC 11172-3
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|      syncword       |ID |Lay|P|Bitrate|SR |p|p|Mod|Ext|C|O|Emp|
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	FrameHeaderSyncMask = 0xffe00000
+)
+
+// FrameHeader represents the header of an MPEG audio frame
+type FrameHeader struct {
+	Version Version
+	Layer   Layer
+	// Protected is set when a CRC follows the header, the protection bit being unset
+	Protected bool
+	// Bitrate is in bit/s
+	Bitrate int
+	// SampleRate is in Hz
+	SampleRate      int
+	Padding         bool
+	Private         bool
+	ChannelMode     uint8
+	ModeExtension   uint8
+	Copyright       bool
+	Original        bool
+	Emphasis        uint8
+	bitrateIndex    uint8
+	sampleRateIndex uint8
+}
+
+// Channels returns the number of channels
+func (h FrameHeader) Channels() int {
+	if h.ChannelMode == ChannelModeMono {
+		return 1
+	}
+	return 2
+}
+
+// Samples returns the number of samples per channel of a frame
+func (h FrameHeader) Samples() int {
+	switch {
+	case h.Layer == Layer1:
+		return 384
+	case h.Layer == Layer3 && h.Version != Version1:
+		return 576
+	}
+	return 1152
+}
+
+// FrameSize returns the size of the frame, header included
+func (h FrameHeader) FrameSize() int {
+	var padding int
+	if h.Padding {
+		padding = 1
+	}
+	switch {
+	case h.Layer == Layer1:
+		return (12*h.Bitrate/h.SampleRate + padding) * 4
+	case h.Layer == Layer3 && h.Version != Version1:
+		return 72*h.Bitrate/h.SampleRate + padding
+	}
+	return 144*h.Bitrate/h.SampleRate + padding
+}
+
+// SideInfoSize returns the size of the side information of Layer III, 0 for the other layers
+func (h FrameHeader) SideInfoSize() int {
+	if h.Layer != Layer3 {
+		return 0
+	}
+	switch {
+	case h.Version == Version1 && h.ChannelMode == ChannelModeMono:
+		return 17
+	case h.Version == Version1:
+		return 32
+	case h.ChannelMode == ChannelModeMono:
+		return 9
+	}
+	return 17
+}
+
+// MaxMainDataBegin returns the largest backpointer of Layer III
+func (h FrameHeader) MaxMainDataBegin() int {
+	if h.Version == Version1 {
+		return MaxMainDataBegin
+	}
+	return MaxMainDataBegin2
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the FrameHeader this method is called upon
+func (h *FrameHeader) Unmarshal(buf []byte) error {
+	if len(buf) < FrameHeaderSize {
+		return fmt.Errorf("buf is not large enough to container frame header")
+	}
+	v := binary.BigEndian.Uint32(buf)
+	if v&FrameHeaderSyncMask != FrameHeaderSyncMask {
+		return fmt.Errorf("missing frame sync")
+	}
+	h.Version = Version(v >> 19 & 0x03)
+	h.Layer = Layer(v >> 17 & 0x03)
+	h.Protected = v>>16&0x01 == 0
+	h.bitrateIndex = uint8(v >> 12 & 0x0f)
+	h.sampleRateIndex = uint8(v >> 10 & 0x03)
+	h.Padding = v>>9&0x01 != 0
+	h.Private = v>>8&0x01 != 0
+	h.ChannelMode = uint8(v >> 6 & 0x03)
+	h.ModeExtension = uint8(v >> 4 & 0x03)
+	h.Copyright = v>>3&0x01 != 0
+	h.Original = v>>2&0x01 != 0
+	h.Emphasis = uint8(v & 0x03)
+
+	if h.Version == VersionReserved || h.Layer == LayerReserved {
+		return fmt.Errorf("reserved %s %s", h.Version, h.Layer)
+	}
+	if h.bitrateIndex == 0 || h.bitrateIndex == 0x0f {
+		return fmt.Errorf("unsupported bitrate index %d", h.bitrateIndex)
+	}
+	if h.sampleRateIndex == 0x03 {
+		return fmt.Errorf("reserved sample rate index")
+	}
+	mpeg1 := 0
+	if h.Version == Version1 {
+		mpeg1 = 1
+	}
+	h.Bitrate = bitrates[mpeg1][h.Layer][h.bitrateIndex] * 1000
+	h.SampleRate = sampleRates[h.Version][h.sampleRateIndex]
+	return nil
+}
+
+// Marshal serializes the header into bytes.
+func (h FrameHeader) Marshal() ([]byte, error) {
+	mpeg1 := 0
+	if h.Version == Version1 {
+		mpeg1 = 1
+	}
+	if h.Version == VersionReserved || h.Version > Version1 || h.Layer == LayerReserved || h.Layer > Layer1 {
+		return nil, fmt.Errorf("reserved %s %s", h.Version, h.Layer)
+	}
+	var bitrateIndex, sampleRateIndex = -1, -1
+	for i, b := range bitrates[mpeg1][h.Layer] {
+		if i > 0 && b*1000 == h.Bitrate {
+			bitrateIndex = i
+		}
+	}
+	for i, s := range sampleRates[h.Version] {
+		if s == h.SampleRate {
+			sampleRateIndex = i
+		}
+	}
+	if bitrateIndex < 0 || sampleRateIndex < 0 {
+		return nil, fmt.Errorf("unsupported bitrate %d or sample rate %d", h.Bitrate, h.SampleRate)
+	}
+	v := uint32(FrameHeaderSyncMask) | uint32(h.Version)<<19 | uint32(h.Layer)<<17 |
+		uint32(bitrateIndex)<<12 | uint32(sampleRateIndex)<<10 |
+		uint32(h.ChannelMode&0x03)<<6 | uint32(h.ModeExtension&0x03)<<4 | uint32(h.Emphasis&0x03)
+	if !h.Protected {
+		v |= 1 << 16
+	}
+	if h.Padding {
+		v |= 1 << 9
+	}
+	if h.Private {
+		v |= 1 << 8
+	}
+	if h.Copyright {
+		v |= 1 << 3
+	}
+	if h.Original {
+		v |= 1 << 2
+	}
+	buf := make([]byte, FrameHeaderSize)
+	binary.BigEndian.PutUint32(buf, v)
+	return buf, nil
+}
+
+// String helps with debugging by printing FrameHeader information in a readable way
+func (h FrameHeader) String() string {
+	out := "MPEG audio FrameHeader:\n"
+
+	out += fmt.Sprintf("\tVersion: %s\n", h.Version)
+	out += fmt.Sprintf("\tLayer: %s\n", h.Layer)
+	out += fmt.Sprintf("\tProtected: %v\n", h.Protected)
+	out += fmt.Sprintf("\tBitrate: %d\n", h.Bitrate)
+	out += fmt.Sprintf("\tSampleRate: %d\n", h.SampleRate)
+	out += fmt.Sprintf("\tPadding: %v\n", h.Padding)
+	out += fmt.Sprintf("\tChannelMode: %d\n", h.ChannelMode)
+
+	return out
+}
+
+func ParseFrameHeader(buf []byte) (FrameHeader, error) {
+	var h FrameHeader
+	err := (&h).Unmarshal(buf)
+	return h, err
+}
+
+// SplitFrames splits a stream of MPEG audio frames at the frame boundaries found from the frame headers,
+// bytes before the first frame sync, such as an ID3v2 tag, are skipped
+func SplitFrames(buf []byte) ([][]byte, error) {
+	var frames [][]byte
+	for len(buf) > 0 {
+		h, err := ParseFrameHeader(buf)
+		if err != nil {
+			if frames == nil && len(buf) > 1 {
+				buf = buf[1:]
+				continue
+			}
+			return frames, err
+		}
+		size := h.FrameSize()
+		if size > len(buf) || size < FrameHeaderSize {
+			return frames, fmt.Errorf("frame of %d bytes exceeds remaining %d bytes", size, len(buf))
+		}
+		frames = append(frames, buf[:size])
+		buf = buf[size:]
+	}
+	return frames, nil
+}
@@ -0,0 +1,62 @@
+package mpa
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFrameHeader_Unmarshal(t *testing.T) {
+	// MPEG-1 Layer III, 128 kbit/s, 44.1 kHz, padded, joint stereo
+	h, err := ParseFrameHeader([]byte{0xff, 0xfb, 0x92, 0x40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != Version1 || h.Layer != Layer3 || h.Protected || h.Bitrate != 128000 || h.SampleRate != 44100 ||
+		!h.Padding || h.ChannelMode != ChannelModeJointStereo || h.Channels() != 2 {
+		t.Fatalf("Unexpected frame header %s", h)
+	}
+	if h.FrameSize() != 418 || h.Samples() != 1152 || h.SideInfoSize() != 32 {
+		t.Fatalf("Unexpected frame size %d, samples %d, side information %d", h.FrameSize(), h.Samples(), h.SideInfoSize())
+	}
+	raw, err := h.Marshal()
+	if err != nil || !bytes.Equal(raw, []byte{0xff, 0xfb, 0x92, 0x40}) {
+		t.Fatalf("Marshal should rebuild the header, got %x %v", raw, err)
+	}
+
+	// MPEG-2 Layer III, 64 kbit/s, 22.05 kHz, mono, protected
+	if h, err = ParseFrameHeader([]byte{0xff, 0xf2, 0x80, 0xc0}); err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != Version2 || !h.Protected || h.Bitrate != 64000 || h.SampleRate != 22050 || h.Samples() != 576 ||
+		h.FrameSize() != 208 || h.SideInfoSize() != 9 {
+		t.Fatalf("Unexpected frame header %s", h)
+	}
+
+	// MPEG-1 Layer II, 192 kbit/s, 48 kHz
+	if h, err = ParseFrameHeader([]byte{0xff, 0xfd, 0xa4, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if h.Layer != Layer2 || h.FrameSize() != 576 || h.SideInfoSize() != 0 {
+		t.Fatalf("Unexpected frame header %s", h)
+	}
+
+	if _, err = ParseFrameHeader([]byte{0xff, 0xfb, 0x02, 0x40}); err == nil {
+		t.Fatal("Free format should be rejected")
+	}
+	if _, err = ParseFrameHeader([]byte{0xff, 0x0b, 0x92, 0x40}); err == nil {
+		t.Fatal("Missing sync should be rejected")
+	}
+}
+
+func TestSplitFrames(t *testing.T) {
+	frame := make([]byte, 576)
+	copy(frame, []byte{0xff, 0xfd, 0xa4, 0x00})
+	stream := append(append([]byte("ID3"), frame...), frame...)
+	frames, err := SplitFrames(stream)
+	if err != nil || len(frames) != 2 || len(frames[1]) != 576 {
+		t.Fatalf("SplitFrames should find 2 frames, got %d %v", len(frames), err)
+	}
+	if _, err = SplitFrames(stream[:500]); err == nil {
+		t.Fatal("SplitFrames should reject a truncated frame")
+	}
+}
@@ -0,0 +1,90 @@
+package mpa
+
+// Versions of MPEG audio, the ID bits of the frame header, ISO/IEC 11172-3 and ISO/IEC 13818-3,
+// MPEG 2.5 being the extension to the lower sample rates
+type Version uint8
+
+const (
+	Version25       Version = 0
+	VersionReserved Version = 1
+	Version2        Version = 2
+	Version1        Version = 3
+)
+
+func (v Version) String() string {
+	switch v {
+	case Version25:
+		return "MPEG-2.5"
+	case Version2:
+		return "MPEG-2"
+	case Version1:
+		return "MPEG-1"
+	}
+	return "reserved"
+}
+
+// Layer of MPEG audio, the layer bits of the frame header
+type Layer uint8
+
+const (
+	LayerReserved Layer = 0
+	Layer3        Layer = 1
+	Layer2        Layer = 2
+	Layer1        Layer = 3
+)
+
+func (l Layer) String() string {
+	switch l {
+	case Layer1:
+		return "Layer I"
+	case Layer2:
+		return "Layer II"
+	case Layer3:
+		return "Layer III"
+	}
+	return "reserved"
+}
+
+// Channel modes of the frame header
+const (
+	ChannelModeStereo      = 0
+	ChannelModeJointStereo = 1
+	ChannelModeDualChannel = 2
+	ChannelModeMono        = 3
+)
+
+const (
+	// FrameHeaderSize is the size of the frame header, which starts with 11 bits set
+	FrameHeaderSize = 4
+	// CRCSize is the size of the CRC which follows the frame header when the protection bit is unset
+	CRCSize = 2
+
+	// MaxMainDataBegin is the largest backpointer of Layer III: 9 bits for MPEG-1, 8 bits for the others
+	MaxMainDataBegin  = 511
+	MaxMainDataBegin2 = 255
+)
+
+// bitrates in kbit/s, indexed by [MPEG-1][layer][bitrate index], 0 being the free format
+var bitrates = [2][4][15]int{
+	// MPEG-2 and MPEG-2.5
+	{
+		{},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	},
+	// MPEG-1
+	{
+		{},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	},
+}
+
+// sampleRates in Hz, indexed by [version][sample rate index]
+var sampleRates = [4][3]int{
+	Version25: {11025, 12000, 8000},
+	Version2:  {22050, 24000, 16000},
+	Version1:  {44100, 48000, 32000},
+}
@@ -0,0 +1,114 @@
+package mpa
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// SideInfo represents the fields of the Layer III side information, 2.4.1.7 in ISO/IEC 11172-3 and 2.4.1.7 in ISO/IEC 13818-3,
+// needed to locate the main data of a frame in the bit reservoir
+type SideInfo struct {
+	// MainDataBegin is the backpointer: the main data of the frame starts MainDataBegin bytes
+	// before the end of the side information, or of the CRC, in the main data of the previous frames
+	MainDataBegin int
+	// Part23Lengths holds the part2_3_length of each granule and channel, in bits
+	Part23Lengths []int
+}
+
+// MainDataSize returns the size of the main data of the frame
+func (s SideInfo) MainDataSize() int {
+	var bits int
+	for _, l := range s.Part23Lengths {
+		bits += l
+	}
+	return (bits + 7) / 8
+}
+
+// Unmarshal parses the side information of a frame, which starts after the header and the CRC
+func (s *SideInfo) Unmarshal(h FrameHeader, buf []byte) error {
+	if h.Layer != Layer3 {
+		return fmt.Errorf("%s frames have no side information", h.Layer)
+	}
+	if len(buf) < h.SideInfoSize() {
+		return fmt.Errorf("buf is not large enough to container side information")
+	}
+	r := bitstream.NewReader(buf[:h.SideInfoSize()])
+	granules, channels := 1, h.Channels()
+	if h.Version == Version1 {
+		granules = 2
+		s.MainDataBegin = int(r.ReadBits(9))
+		// private bits, then scfsi
+		if channels == 1 {
+			r.SkipBits(5 + 4)
+		} else {
+			r.SkipBits(3 + 4*2)
+		}
+	} else {
+		s.MainDataBegin = int(r.ReadBits(8))
+		r.SkipBits(channels)
+	}
+	s.Part23Lengths = nil
+	for gr := 0; gr < granules; gr++ {
+		for ch := 0; ch < channels; ch++ {
+			s.Part23Lengths = append(s.Part23Lengths, int(r.ReadBits(12)))
+			// big_values to count1table_select: 47 bits, scalefac_compress being 9 bits instead of 4, and no preflag in MPEG-2
+			if h.Version == Version1 {
+				r.SkipBits(47)
+			} else {
+				r.SkipBits(51)
+			}
+		}
+	}
+	return r.Err()
+}
+
+// SetMainDataBegin rewrites the backpointer of the side information of a Layer III frame or ADU, and its CRC if any
+func SetMainDataBegin(frame []byte, mainDataBegin int) error {
+	h, err := ParseFrameHeader(frame)
+	if err != nil {
+		return err
+	}
+	if h.Layer != Layer3 {
+		return fmt.Errorf("%s frames have no side information", h.Layer)
+	}
+	if mainDataBegin < 0 || mainDataBegin > h.MaxMainDataBegin() {
+		return fmt.Errorf("main_data_begin %d exceeds %d", mainDataBegin, h.MaxMainDataBegin())
+	}
+	offset := FrameHeaderSize
+	if h.Protected {
+		offset += CRCSize
+	}
+	if len(frame) < offset+h.SideInfoSize() {
+		return fmt.Errorf("frame is not large enough to container side information")
+	}
+	if h.Version == Version1 {
+		frame[offset] = byte(mainDataBegin >> 1)
+		frame[offset+1] = frame[offset+1]&0x7f | byte(mainDataBegin&0x01)<<7
+	} else {
+		frame[offset] = byte(mainDataBegin)
+	}
+	if h.Protected {
+		crc := CRC(frame[2:FrameHeaderSize], frame[offset:offset+h.SideInfoSize()])
+		frame[FrameHeaderSize] = byte(crc >> 8)
+		frame[FrameHeaderSize+1] = byte(crc)
+	}
+	return nil
+}
+
+// CRC returns the CRC-16 of MPEG audio, of polynomial 0x8005, over the last two bytes of the header and the side information
+func CRC(data ...[]byte) uint16 {
+	crc := uint16(0xffff)
+	for _, d := range data {
+		for _, b := range d {
+			for i := 7; i >= 0; i-- {
+				bit := (crc>>15)&0x01 != uint16(b>>uint(i))&0x01
+				crc <<= 1
+				if bit {
+					crc ^= 0x8005
+				}
+			}
+		}
+	}
+	return crc
+}
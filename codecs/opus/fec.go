@@ -0,0 +1,63 @@
+package opus
+
+import "time"
+
+// SILKFrameCount returns the number of 20 ms SILK frames coded in each Opus frame,
+// 10 ms frames counting as one, see rfc6716#section-4.2.2
+func (c Configuration) SILKFrameCount() int {
+	if c.Mode() == ModeCELT {
+		return 0
+	}
+	d := c.FrameDuration()
+	if d <= 20*time.Millisecond {
+		return 1
+	}
+	return int(d / (20 * time.Millisecond))
+}
+
+// LBRR describes the Low Bit-Rate Redundancy (LBRR) frames of a packet, the in-band FEC
+// of the Opus frame preceding the packet, see rfc6716#section-4.2.4
+type LBRR struct {
+	// Mid and Side report whether the mid and the side channel carry LBRR frames
+	Mid  bool
+	Side bool
+	// Frame is the first Opus frame of the packet, whose LP layer codes the LBRR frames after
+	// the VAD and LBRR flags: decoding it with FEC enabled recovers the lost Opus frame
+	Frame []byte
+	// Duration is the duration of the audio recovered, ending at the start of the packet
+	Duration time.Duration
+}
+
+// LBRR returns the LBRR frames of the packet, if it carries any.
+//
+// A SILK or Hybrid frame starts with the LP layer, whose first symbols are one VAD
+// flag per SILK frame followed by the LBRR flag, for the mid and then the side channel,
+// see rfc6716#section-4.2.3.  They are coded with uniform probability, so they can be
+// read directly from the most significant bits of the first byte of the frame.
+func (p Packet) LBRR() (LBRR, bool) {
+	silkFrames := p.TOC.Configuration.SILKFrameCount()
+	if silkFrames == 0 || len(p.Frames) == 0 || len(p.Frames[0]) <= 1 {
+		return LBRR{}, false
+	}
+	var flags [2]bool
+	for n := 0; n < p.TOC.Channels(); n++ {
+		bit := uint((n+1)*(silkFrames+1) - 1)
+		flags[n] = p.Frames[0][0]&(0x80>>bit) != 0
+	}
+	if !flags[0] && !flags[1] {
+		return LBRR{}, false
+	}
+	return LBRR{
+		Mid:      flags[0],
+		Side:     flags[1],
+		Frame:    p.Frames[0],
+		Duration: p.TOC.Configuration.FrameDuration(),
+	}, true
+}
+
+// HasLBRR reports whether the packet carries LBRR frames,
+// i.e. the in-band FEC a decoder can use to recover the packet preceding this one
+func (p Packet) HasLBRR() bool {
+	_, ok := p.LBRR()
+	return ok
+}
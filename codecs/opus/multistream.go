@@ -0,0 +1,286 @@
+package opus
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ChannelMappingFamily identifies how the decoded channels of a multistream are laid out,
+// see rfc7845#section-5.1.1
+type ChannelMappingFamily uint8
+
+const (
+	// RTP mapping: mono or stereo, a single stream
+	ChannelMappingFamilyRTP ChannelMappingFamily = 0
+	// Vorbis channel order, 1 to 8 channels
+	ChannelMappingFamilyVorbis ChannelMappingFamily = 1
+	// No defined channel meaning
+	ChannelMappingFamilyUndefined ChannelMappingFamily = 255
+)
+
+// MaxChannels is the maximum number of output channels of a multistream, see rfc7845#section-5.1
+const MaxChannels = 255
+
+// ChannelMapping describes how the streams of an Opus multistream packet
+// are decoded into output channels, see rfc7845#section-5.1.1
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|  Stream Count | Coupled Count |              Channel          :
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+                               :
+//	:               Mapping... (8*C bits)                           :
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+type ChannelMapping struct {
+	Family ChannelMappingFamily
+	// Number of Opus streams in each packet
+	StreamCount int
+	// Number of streams whose decoders are configured for two channels,
+	// always the first streams of the packet
+	CoupledCount int
+	// Decoded channel index for each output channel, 255 for silence
+	Mapping []uint8
+}
+
+// vorbisMappings holds the default streams layout of family 1, from 1 to 8 channels
+var vorbisMappings = [...]ChannelMapping{
+	{ChannelMappingFamilyVorbis, 1, 0, []uint8{0}},
+	{ChannelMappingFamilyVorbis, 1, 1, []uint8{0, 1}},
+	{ChannelMappingFamilyVorbis, 2, 1, []uint8{0, 2, 1}},
+	{ChannelMappingFamilyVorbis, 2, 2, []uint8{0, 1, 2, 3}},
+	{ChannelMappingFamilyVorbis, 3, 2, []uint8{0, 4, 1, 2, 3}},
+	{ChannelMappingFamilyVorbis, 4, 2, []uint8{0, 4, 1, 2, 3, 5}},
+	{ChannelMappingFamilyVorbis, 4, 3, []uint8{0, 4, 1, 2, 3, 5, 6}},
+	{ChannelMappingFamilyVorbis, 5, 3, []uint8{0, 6, 1, 2, 3, 4, 5, 7}},
+}
+
+// NewChannelMapping returns the default channel mapping of a family for a number of channels
+func NewChannelMapping(family ChannelMappingFamily, channels int) (*ChannelMapping, error) {
+	switch family {
+	case ChannelMappingFamilyRTP:
+		if channels < 1 || channels > 2 {
+			return nil, fmt.Errorf("channel mapping family 0 supports 1 or 2 channels, got %d", channels)
+		}
+		m := vorbisMappings[channels-1]
+		m.Family = family
+		m.Mapping = append([]uint8(nil), m.Mapping...)
+		return &m, nil
+	case ChannelMappingFamilyVorbis:
+		if channels < 1 || channels > len(vorbisMappings) {
+			return nil, fmt.Errorf("channel mapping family 1 supports 1 to %d channels, got %d", len(vorbisMappings), channels)
+		}
+		m := vorbisMappings[channels-1]
+		m.Mapping = append([]uint8(nil), m.Mapping...)
+		return &m, nil
+	case ChannelMappingFamilyUndefined:
+		if channels < 1 || channels > MaxChannels {
+			return nil, fmt.Errorf("channel mapping family 255 supports 1 to %d channels, got %d", MaxChannels, channels)
+		}
+		// one uncoupled stream per channel
+		m := &ChannelMapping{Family: family, StreamCount: channels, Mapping: make([]uint8, channels)}
+		for i := range m.Mapping {
+			m.Mapping[i] = uint8(i)
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown channel mapping family %d", family)
+	}
+}
+
+// Channels returns the number of output channels
+func (m ChannelMapping) Channels() int {
+	return len(m.Mapping)
+}
+
+// Validate checks the constraints of rfc7845#section-5.1.1
+func (m ChannelMapping) Validate() error {
+	if m.StreamCount < 1 {
+		return fmt.Errorf("stream count must be at least 1, got %d", m.StreamCount)
+	}
+	if m.CoupledCount > m.StreamCount {
+		return fmt.Errorf("coupled count %d exceeds stream count %d", m.CoupledCount, m.StreamCount)
+	}
+	if m.StreamCount+m.CoupledCount > MaxChannels {
+		return fmt.Errorf("stream count %d plus coupled count %d exceeds %d", m.StreamCount, m.CoupledCount, MaxChannels)
+	}
+	if len(m.Mapping) < 1 || len(m.Mapping) > MaxChannels {
+		return fmt.Errorf("channel count must be in [1, %d], got %d", MaxChannels, len(m.Mapping))
+	}
+	for i, index := range m.Mapping {
+		if index != 255 && int(index) >= m.StreamCount+m.CoupledCount {
+			return fmt.Errorf("channel %d maps to invalid decoded channel %d", i, index)
+		}
+	}
+	if m.Family == ChannelMappingFamilyRTP && (m.StreamCount != 1 || len(m.Mapping) > 2) {
+		return fmt.Errorf("channel mapping family 0 allows a single mono or stereo stream")
+	}
+	if m.Family == ChannelMappingFamilyVorbis && len(m.Mapping) > len(vorbisMappings) {
+		return fmt.Errorf("channel mapping family 1 allows at most %d channels", len(vorbisMappings))
+	}
+	return nil
+}
+
+// Marshal serializes the channel mapping table into bytes.
+func (m ChannelMapping) Marshal() ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	w := bytes.NewBuffer(make([]byte, 0, m.MarshalSize()))
+	w.WriteByte(byte(m.StreamCount))
+	w.WriteByte(byte(m.CoupledCount))
+	w.Write(m.Mapping)
+	return w.Bytes(), nil
+}
+
+// MarshalSize returns the size of the channel mapping table once marshaled.
+func (m ChannelMapping) MarshalSize() int {
+	// NOTE: Be careful to match the Marshal() method.
+	return 2 + len(m.Mapping)
+}
+
+// Unmarshal parses the passed channel mapping table, as found in the OpusHead header,
+// for the given number of output channels of the family already set in m
+func (m *ChannelMapping) Unmarshal(buf []byte, channels int) error {
+	if len(buf) < 2+channels {
+		return fmt.Errorf("buf is not large enough to container channel mapping table")
+	}
+	m.StreamCount = int(buf[0])
+	m.CoupledCount = int(buf[1])
+	m.Mapping = append([]uint8(nil), buf[2:2+channels]...)
+	return m.Validate()
+}
+
+// UnmarshalFmtp parses the num_streams, coupled_streams and channel_mapping
+// parameters of a multiopus SDP fmtp line, such as
+// "minptime=10;num_streams=4;coupled_streams=2;channel_mapping=0,4,1,2,3,5"
+func (m *ChannelMapping) UnmarshalFmtp(fmtp string) error {
+	var hasStreams, hasCoupled, hasMapping bool
+	for _, param := range strings.Split(fmtp, ";") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		var err error
+		switch strings.ToLower(kv[0]) {
+		case "num_streams":
+			m.StreamCount, err = strconv.Atoi(kv[1])
+			hasStreams = true
+		case "coupled_streams":
+			m.CoupledCount, err = strconv.Atoi(kv[1])
+			hasCoupled = true
+		case "channel_mapping":
+			m.Mapping = nil
+			for _, index := range strings.Split(kv[1], ",") {
+				var v int
+				if v, err = strconv.Atoi(strings.TrimSpace(index)); err != nil {
+					break
+				}
+				if v < 0 || v > 255 {
+					return fmt.Errorf("invalid channel_mapping index %d", v)
+				}
+				m.Mapping = append(m.Mapping, uint8(v))
+			}
+			hasMapping = true
+		}
+		if err != nil {
+			return fmt.Errorf("invalid fmtp parameter %q: %v", param, err)
+		}
+	}
+	if !hasStreams || !hasCoupled || !hasMapping {
+		return fmt.Errorf("fmtp requires num_streams, coupled_streams and channel_mapping")
+	}
+	switch {
+	case m.StreamCount == 1 && len(m.Mapping) <= 2:
+		m.Family = ChannelMappingFamilyRTP
+	case len(m.Mapping) <= len(vorbisMappings):
+		m.Family = ChannelMappingFamilyVorbis
+	default:
+		m.Family = ChannelMappingFamilyUndefined
+	}
+	return m.Validate()
+}
+
+// String helps with debugging by printing channel mapping information in a readable way
+func (m ChannelMapping) String() string {
+	out := "Opus ChannelMapping:\n"
+
+	out += fmt.Sprintf("\tFamily: %d\n", m.Family)
+	out += fmt.Sprintf("\tStreamCount: %d\n", m.StreamCount)
+	out += fmt.Sprintf("\tCoupledCount: %d\n", m.CoupledCount)
+	out += fmt.Sprintf("\tMapping: %v\n", m.Mapping)
+
+	return out
+}
+
+// MultistreamPacket represents an Opus multistream packet: every stream but the last one
+// uses the self-delimiting framing of rfc6716#appendix-B, the last one the regular framing
+type MultistreamPacket struct {
+	Streams []Packet
+}
+
+// Unmarshal parses the passed byte slice, which must hold exactly streamCount streams
+func (p *MultistreamPacket) Unmarshal(buf []byte, streamCount int) error {
+	if buf == nil {
+		return fmt.Errorf("invalid nil packet")
+	}
+	if streamCount < 1 {
+		return fmt.Errorf("stream count must be at least 1, got %d", streamCount)
+	}
+	p.Streams = make([]Packet, streamCount)
+	for i := 0; i < streamCount-1; i++ {
+		n, err := (&p.Streams[i]).UnmarshalSelfDelimited(buf)
+		if err != nil {
+			return fmt.Errorf("stream %d: %v", i, err)
+		}
+		buf = buf[n:]
+	}
+	if err := (&p.Streams[streamCount-1]).Unmarshal(buf); err != nil {
+		return fmt.Errorf("stream %d: %v", streamCount-1, err)
+	}
+	for i, stream := range p.Streams[1:] {
+		if stream.Duration() != p.Streams[0].Duration() {
+			return fmt.Errorf("stream %d lasts %v, stream 0 lasts %v", i+1, stream.Duration(), p.Streams[0].Duration())
+		}
+	}
+	return nil
+}
+
+// Marshal serializes the multistream packet into bytes.
+func (p MultistreamPacket) Marshal() ([]byte, error) {
+	if len(p.Streams) == 0 {
+		return nil, fmt.Errorf("multistream packet contains no streams")
+	}
+	w := bytes.NewBuffer(nil)
+	for i, stream := range p.Streams {
+		var b []byte
+		var err error
+		if i < len(p.Streams)-1 {
+			b, err = stream.MarshalSelfDelimited()
+		} else {
+			b, err = stream.Marshal()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stream %d: %v", i, err)
+		}
+		w.Write(b)
+	}
+	return w.Bytes(), nil
+}
+
+// HasLBRR reports whether any stream of the packet carries LBRR frames
+func (p MultistreamPacket) HasLBRR() bool {
+	for _, stream := range p.Streams {
+		if stream.HasLBRR() {
+			return true
+		}
+	}
+	return false
+}
+
+func ParseMultistreamPacket(buf []byte, streamCount int) (MultistreamPacket, error) {
+	var p MultistreamPacket
+	err := (&p).Unmarshal(buf, streamCount)
+	return p, err
+}
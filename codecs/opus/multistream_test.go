@@ -0,0 +1,124 @@
+package opus
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMultistreamPacket(t *testing.T) {
+	frame := bytes.Repeat([]byte{0x42}, 260)
+	want := MultistreamPacket{Streams: []Packet{
+		{TOC: TOC{Configuration: 1, Stereo: true, FrameCountCode: FrameCountCodeOneFrame}, Frames: [][]byte{frame}},
+		{TOC: TOC{Configuration: 0, FrameCountCode: FrameCountCodeTwoEqualFrames}, Frames: [][]byte{frame[:10], frame[:10]}},
+		{TOC: TOC{Configuration: 0, FrameCountCode: FrameCountCodeTwoDifferentFrames}, Frames: [][]byte{frame[:3], frame[:5]}},
+		{TOC: TOC{Configuration: 0, FrameCountCode: FrameCountCodeArbitraryFrames}, VBR: true, Padding: 2, Frames: [][]byte{frame[:1], frame[:2]}},
+		{TOC: TOC{Configuration: 1, FrameCountCode: FrameCountCodeOneFrame}, Frames: [][]byte{frame[:7]}},
+	}}
+
+	raw, err := want.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseMultistreamPacket(raw, len(want.Streams))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want.Streams {
+		if got.Streams[i].TOC != want.Streams[i].TOC {
+			t.Fatalf("stream %d: got %s, want %s", i, got.Streams[i].TOC, want.Streams[i].TOC)
+		}
+		if !bytes.Equal(bytes.Join(got.Streams[i].Frames, []byte{0}), bytes.Join(want.Streams[i].Frames, []byte{0})) {
+			t.Fatalf("stream %d: frames mismatch", i)
+		}
+	}
+
+	if _, err = ParseMultistreamPacket(raw, len(want.Streams)+1); err == nil {
+		t.Fatal("Parsing with a wrong stream count should fail")
+	}
+}
+
+func TestPacket_HasLBRR(t *testing.T) {
+	tests := []struct {
+		packet []byte
+		lbrr   bool
+	}{
+		// SILK NB 20ms mono: VAD, LBRR
+		{[]byte{0x08, 0x40, 0x00}, true},
+		{[]byte{0x08, 0x80, 0x00}, false},
+		// SILK NB 60ms mono: VAD, VAD, VAD, LBRR
+		{[]byte{0x18, 0x10, 0x00}, true},
+		// SILK NB 20ms stereo: VAD, LBRR (mid), VAD, LBRR (side)
+		{[]byte{0x0c, 0x10, 0x00}, true},
+		// Hybrid SWB 20ms mono
+		{[]byte{0x68, 0x40, 0x00}, true},
+		// CELT-only never carries LBRR
+		{[]byte{0xf8, 0xff, 0xff}, false},
+		// a 1 byte frame is DTX
+		{[]byte{0x08, 0x40}, false},
+	}
+	for i, test := range tests {
+		p, err := ParsePacket(test.packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.HasLBRR() != test.lbrr {
+			t.Fatalf("#%d: HasLBRR should be %v", i, test.lbrr)
+		}
+	}
+}
+
+func TestPacket_LBRR(t *testing.T) {
+	// SILK NB 60ms mono: VAD, VAD, VAD, LBRR
+	p, err := ParsePacket([]byte{0x18, 0x10, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lbrr, ok := p.LBRR()
+	if !ok || !lbrr.Mid || lbrr.Side || lbrr.Duration != 60*time.Millisecond || !bytes.Equal(lbrr.Frame, []byte{0x10, 0x00}) {
+		t.Fatalf("LBRR should cover the 60 ms of the mid channel, got %+v", lbrr)
+	}
+	// SILK NB 20ms stereo: LBRR of the side channel only
+	if p, err = ParsePacket([]byte{0x0c, 0x10, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if lbrr, ok = p.LBRR(); !ok || lbrr.Mid || !lbrr.Side || lbrr.Duration != 20*time.Millisecond {
+		t.Fatalf("LBRR should cover the 20 ms of the side channel, got %+v", lbrr)
+	}
+	if p, err = ParsePacket([]byte{0x08, 0x80, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = p.LBRR(); ok {
+		t.Fatal("LBRR should not be found without the LBRR flag")
+	}
+}
+
+func TestChannelMapping(t *testing.T) {
+	var m ChannelMapping
+	if err := m.UnmarshalFmtp("minptime=10;useinbandfec=1;num_streams=4;coupled_streams=2;channel_mapping=0,4,1,2,3,5"); err != nil {
+		t.Fatal(err)
+	}
+	want, err := NewChannelMapping(ChannelMappingFamilyVorbis, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.String() != want.String() {
+		t.Fatalf("got %s, want %s", m, want)
+	}
+
+	raw, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ChannelMapping{Family: ChannelMappingFamilyVorbis}
+	if err = got.Unmarshal(raw, m.Channels()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Mapping, m.Mapping) || got.StreamCount != 4 || got.CoupledCount != 2 {
+		t.Fatalf("got %s, want %s", got, m)
+	}
+
+	if err = m.UnmarshalFmtp("num_streams=1;coupled_streams=1;channel_mapping=0,2"); err == nil {
+		t.Fatal("Mapping to a non existing channel should fail")
+	}
+}
@@ -0,0 +1,16 @@
+package opus
+
+import "time"
+
+const (
+	// RFC 7587 4.1: the RTP clock rate for Opus is always 48000 Hz,
+	// whatever the internal sampling rate of the encoder.
+	ClockRate = 48000
+
+	// RFC 6716 3.2.1: a frame may not be larger than 1275 bytes.
+	MaxFrameSize = 1275
+	// RFC 6716 3.2.5: a code 3 packet holds at most 48 frames.
+	MaxFrameCount = 48
+	// RFC 6716 3.2.5: the total duration of a packet is at most 120 ms.
+	MaxPacketDuration = 120 * time.Millisecond
+)
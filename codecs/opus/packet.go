@@ -0,0 +1,419 @@
+package opus
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+const (
+	// RFC 6716 3.2.5: the frame count byte of a code 3 packet
+	//	 0 1 2 3 4 5 6 7
+	//	+-+-+-+-+-+-+-+-+
+	//	|v|p|     M     |
+	//	+-+-+-+-+-+-+-+-+
+	FrameCountVBRMask   = 1 << FrameCountVBROffset
+	FrameCountVBROffset = 7
+
+	FrameCountPaddingMask   = 1 << FrameCountPaddingOffset
+	FrameCountPaddingOffset = 6
+
+	FrameCountMask   = 0x3f
+	FrameCountOffset = 0
+)
+
+// Packet represents an Opus packet, as defined in rfc6716#section-3
+type Packet struct {
+	TOC TOC
+
+	// VBR and Padding are only meaningful for code 3 packets
+	VBR     bool
+	Padding int
+
+	Frames [][]byte
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the Packet this method is called upon.
+// The framing is validated against the requirements [R1] to [R7] of rfc6716#section-3.4
+func (p *Packet) Unmarshal(buf []byte) error {
+	_, err := p.unmarshal(buf, false)
+	return err
+}
+
+// UnmarshalSelfDelimited parses a packet with the self-delimiting framing of rfc6716#appendix-B
+// from the beginning of buf, and returns the number of bytes consumed
+func (p *Packet) UnmarshalSelfDelimited(buf []byte) (int, error) {
+	return p.unmarshal(buf, true)
+}
+
+func (p *Packet) unmarshal(buf []byte, selfDelimited bool) (int, error) {
+	if buf == nil {
+		return 0, fmt.Errorf("invalid nil packet")
+	}
+	if len(buf) < 1 {
+		// [R1] Packets are at least one byte.
+		return 0, fmt.Errorf("packet is not large enough to container TOC")
+	}
+	_ = (&p.TOC).Unmarshal(buf[TOCByteIndex:])
+	p.VBR = false
+	p.Padding = 0
+	p.Frames = nil
+
+	data := buf[1:]
+	// In the self-delimiting framing, the size of the last frame is coded
+	// explicitly, right after the other frame lengths
+	lastFrameLength := func() (int, error) {
+		size, n, err := ParseFrameLength(data)
+		if err != nil {
+			return 0, err
+		}
+		data = data[n:]
+		if size > len(data) {
+			return 0, fmt.Errorf("frame size %d exceeds remaining %d bytes", size, len(data))
+		}
+		return size, nil
+	}
+
+	switch p.TOC.FrameCountCode {
+	case FrameCountCodeOneFrame:
+		size := len(data)
+		if selfDelimited {
+			var err error
+			if size, err = lastFrameLength(); err != nil {
+				return 0, err
+			}
+		}
+		// [R2] No implicit frame length is larger than 1275 bytes.
+		if size > MaxFrameSize {
+			return 0, fmt.Errorf("frame size %d exceeds %d", size, MaxFrameSize)
+		}
+		p.Frames = [][]byte{data[:size]}
+		data = data[size:]
+	case FrameCountCodeTwoEqualFrames:
+		var size int
+		if selfDelimited {
+			var err error
+			if size, err = lastFrameLength(); err != nil {
+				return 0, err
+			}
+			if 2*size > len(data) {
+				return 0, fmt.Errorf("frame sizes %d exceed remaining %d bytes", 2*size, len(data))
+			}
+		} else {
+			// [R3] Code 1 packets have an odd total length, N, so that (N-1)/2 is an integer.
+			if len(data)%2 != 0 {
+				return 0, fmt.Errorf("code 1 packet has an even total length %d", len(buf))
+			}
+			size = len(data) / 2
+		}
+		if size > MaxFrameSize {
+			return 0, fmt.Errorf("frame size %d exceeds %d", size, MaxFrameSize)
+		}
+		p.Frames = [][]byte{data[:size], data[size : 2*size]}
+		data = data[2*size:]
+	case FrameCountCodeTwoDifferentFrames:
+		// [R4] Code 2 packets have enough bytes after the TOC for a valid
+		// frame length, and that length is no larger than the number of
+		// bytes remaining in the packet.
+		size, n, err := ParseFrameLength(data)
+		if err != nil {
+			return 0, err
+		}
+		data = data[n:]
+		last := len(data) - size
+		if selfDelimited {
+			if last, err = lastFrameLength(); err != nil {
+				return 0, err
+			}
+		}
+		if size+last > len(data) || last < 0 {
+			return 0, fmt.Errorf("frame size %d exceeds remaining %d bytes", size, len(data))
+		}
+		if last > MaxFrameSize {
+			return 0, fmt.Errorf("frame size %d exceeds %d", last, MaxFrameSize)
+		}
+		p.Frames = [][]byte{data[:size], data[size : size+last]}
+		data = data[size+last:]
+	case FrameCountCodeArbitraryFrames:
+		frames, remain, err := p.unmarshalArbitraryFrames(data, selfDelimited)
+		if err != nil {
+			return 0, err
+		}
+		p.Frames = frames
+		data = remain
+	}
+	return len(buf) - len(data), nil
+}
+
+func (p *Packet) unmarshalArbitraryFrames(data []byte, selfDelimited bool) (frames [][]byte, rest []byte, err error) {
+	// [R6] Code 3 packets contain at least one frame, but no more than 120 ms of audio total.
+	if len(data) < 1 {
+		return nil, nil, fmt.Errorf("code 3 packet is not large enough to container frame count byte")
+	}
+	p.VBR = data[0]&FrameCountVBRMask != 0
+	hasPadding := data[0]&FrameCountPaddingMask != 0
+	count := int((data[0] & FrameCountMask) >> FrameCountOffset)
+	data = data[1:]
+	if count == 0 {
+		return nil, nil, fmt.Errorf("code 3 packet contains no frames")
+	}
+	if time.Duration(count)*p.TOC.Configuration.FrameDuration() > MaxPacketDuration {
+		return nil, nil, fmt.Errorf("code 3 packet contains %d frames, more than %v", count, MaxPacketDuration)
+	}
+
+	if hasPadding {
+		// Values from 0...254 indicate that 0...254 bytes of padding are
+		// included, in addition to the byte(s) used to indicate the size
+		// of the padding.  If the value is 255, then the size of the
+		// additional padding is 254 bytes, plus the padding value encoded
+		// in the next byte.
+		for {
+			if len(data) < 1 {
+				return nil, nil, fmt.Errorf("code 3 packet is not large enough to container padding length")
+			}
+			v := int(data[0])
+			data = data[1:]
+			if v < 255 {
+				p.Padding += v
+				break
+			}
+			p.Padding += 254
+		}
+		if p.Padding > len(data) {
+			return nil, nil, fmt.Errorf("padding %d exceeds remaining %d bytes", p.Padding, len(data))
+		}
+	}
+
+	sizes := make([]int, count)
+	explicit := 0
+	if p.VBR {
+		explicit = count - 1
+	}
+	if selfDelimited {
+		explicit++
+	}
+	total := 0
+	for i := 0; i < explicit; i++ {
+		size, n, err := ParseFrameLength(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = data[n:]
+		sizes[i] = size
+		total += size
+	}
+	remain := len(data) - p.Padding
+	switch {
+	case p.VBR && selfDelimited:
+	case p.VBR:
+		// [R7] VBR code 3 packets contain enough bytes for M-1 frame lengths,
+		// and the sum of the frame lengths is no larger than the number of
+		// bytes remaining in the packet, less the padding.
+		if total > remain {
+			return nil, nil, fmt.Errorf("frame sizes %d exceed remaining %d bytes", total, remain)
+		}
+		sizes[count-1] = remain - total
+	case selfDelimited:
+		for i := range sizes {
+			sizes[i] = sizes[0]
+		}
+	default:
+		// [R5] CBR code 3 packets contain an integral number of bytes per frame.
+		if remain%count != 0 {
+			return nil, nil, fmt.Errorf("CBR code 3 packet has %d bytes for %d frames", remain, count)
+		}
+		for i := range sizes {
+			sizes[i] = remain / count
+		}
+	}
+
+	frames = make([][]byte, count)
+	for i, size := range sizes {
+		if size > MaxFrameSize {
+			return nil, nil, fmt.Errorf("frame size %d exceeds %d", size, MaxFrameSize)
+		}
+		if size > len(data) {
+			return nil, nil, fmt.Errorf("frame size %d exceeds remaining %d bytes", size, len(data))
+		}
+		frames[i] = data[:size]
+		data = data[size:]
+	}
+	if p.Padding > len(data) {
+		return nil, nil, fmt.Errorf("padding %d exceeds remaining %d bytes", p.Padding, len(data))
+	}
+	return frames, data[p.Padding:], nil
+}
+
+// Marshal serializes the packet into bytes, using the framing indicated by the TOC
+func (p Packet) Marshal() ([]byte, error) {
+	return p.marshal(false)
+}
+
+// MarshalSelfDelimited serializes the packet into bytes, using the self-delimiting framing of rfc6716#appendix-B
+func (p Packet) MarshalSelfDelimited() ([]byte, error) {
+	return p.marshal(true)
+}
+
+func (p Packet) marshal(selfDelimited bool) ([]byte, error) {
+	w := bytes.NewBuffer(make([]byte, 0, p.marshalSize(selfDelimited)))
+	w.WriteByte(p.TOC.Byte())
+
+	if len(p.Frames) == 0 {
+		return nil, fmt.Errorf("packet contains no frames")
+	}
+	last := p.Frames[len(p.Frames)-1]
+	switch p.TOC.FrameCountCode {
+	case FrameCountCodeOneFrame:
+		if len(p.Frames) != 1 {
+			return nil, fmt.Errorf("code 0 packet requires 1 frame, got %d", len(p.Frames))
+		}
+	case FrameCountCodeTwoEqualFrames:
+		if len(p.Frames) != 2 || len(p.Frames[0]) != len(p.Frames[1]) {
+			return nil, fmt.Errorf("code 1 packet requires 2 frames of equal size")
+		}
+	case FrameCountCodeTwoDifferentFrames:
+		if len(p.Frames) != 2 {
+			return nil, fmt.Errorf("code 2 packet requires 2 frames, got %d", len(p.Frames))
+		}
+		w.Write(FrameLength(len(p.Frames[0])))
+	case FrameCountCodeArbitraryFrames:
+		count := len(p.Frames)
+		if count > MaxFrameCount {
+			return nil, fmt.Errorf("code 3 packet requires 1 to %d frames, got %d", MaxFrameCount, count)
+		}
+		b := byte(count<<FrameCountOffset) & FrameCountMask
+		if p.VBR {
+			b |= FrameCountVBRMask
+		}
+		if p.Padding > 0 {
+			b |= FrameCountPaddingMask
+		}
+		w.WriteByte(b)
+		if p.Padding > 0 {
+			w.Write(paddingLength(p.Padding))
+		}
+		if p.VBR {
+			for _, frame := range p.Frames[:count-1] {
+				w.Write(FrameLength(len(frame)))
+			}
+		} else {
+			for _, frame := range p.Frames {
+				if len(frame) != len(p.Frames[0]) {
+					return nil, fmt.Errorf("CBR code 3 packet requires frames of equal size")
+				}
+			}
+		}
+	}
+	if selfDelimited {
+		w.Write(FrameLength(len(last)))
+	}
+	for _, frame := range p.Frames {
+		if len(frame) > MaxFrameSize {
+			return nil, fmt.Errorf("frame size %d exceeds %d", len(frame), MaxFrameSize)
+		}
+		w.Write(frame)
+	}
+	if p.TOC.FrameCountCode == FrameCountCodeArbitraryFrames {
+		w.Write(make([]byte, p.Padding))
+	}
+	return w.Bytes(), nil
+}
+
+// MarshalSize returns the size of the packet once marshaled.
+func (p Packet) MarshalSize() int {
+	return p.marshalSize(false)
+}
+
+func (p Packet) marshalSize(selfDelimited bool) int {
+	// NOTE: Be careful to match the marshal() method.
+	size := 1
+	for _, frame := range p.Frames {
+		size += len(frame)
+	}
+	if selfDelimited && len(p.Frames) > 0 {
+		size += len(FrameLength(len(p.Frames[len(p.Frames)-1])))
+	}
+	switch p.TOC.FrameCountCode {
+	case FrameCountCodeTwoDifferentFrames:
+		if len(p.Frames) > 0 {
+			size += len(FrameLength(len(p.Frames[0])))
+		}
+	case FrameCountCodeArbitraryFrames:
+		size++
+		if p.Padding > 0 {
+			size += len(paddingLength(p.Padding)) + p.Padding
+		}
+		if p.VBR {
+			for i := 0; i < len(p.Frames)-1; i++ {
+				size += len(FrameLength(len(p.Frames[i])))
+			}
+		}
+	}
+	return size
+}
+
+// FrameCount returns the number of frames in the packet
+func (p Packet) FrameCount() int {
+	return len(p.Frames)
+}
+
+// Duration returns the total duration of audio in the packet
+func (p Packet) Duration() time.Duration {
+	return time.Duration(p.FrameCount()) * p.TOC.Configuration.FrameDuration()
+}
+
+// Samples returns the number of samples in the packet, at the given sample rate
+func (p Packet) Samples(sampleRate int) int {
+	return p.FrameCount() * p.TOC.Configuration.FrameSamples(sampleRate)
+}
+
+// String helps with debugging by printing packet information in a readable way
+func (p Packet) String() string {
+	out := "Opus Packet:\n"
+
+	out += fmt.Sprintf("\t%s\n", p.TOC)
+	out += fmt.Sprintf("\tVBR: %v\n", p.VBR)
+	out += fmt.Sprintf("\tPadding: %d\n", p.Padding)
+	out += fmt.Sprintf("\tFrame Count: %d\n", p.FrameCount())
+	out += fmt.Sprintf("\tDuration: %v\n", p.Duration())
+
+	return out
+}
+
+// ParseFrameLength decodes a frame length, as defined in rfc6716#section-3.2.1,
+// returning the length and the number of bytes consumed
+func ParseFrameLength(buf []byte) (size int, n int, err error) {
+	if len(buf) < 1 {
+		return 0, 0, fmt.Errorf("buf is not large enough to container frame length")
+	}
+	if buf[0] < 252 {
+		return int(buf[0]), 1, nil
+	}
+	if len(buf) < 2 {
+		return 0, 0, fmt.Errorf("buf is not large enough to container frame length")
+	}
+	return int(buf[1])*4 + int(buf[0]), 2, nil
+}
+
+// FrameLength encodes a frame length, as defined in rfc6716#section-3.2.1
+func FrameLength(size int) []byte {
+	if size < 252 {
+		return []byte{byte(size)}
+	}
+	first := 252 + (size & 0x3)
+	return []byte{byte(first), byte((size - first) >> 2)}
+}
+
+func paddingLength(padding int) []byte {
+	var out []byte
+	for ; padding > 254; padding -= 254 {
+		out = append(out, 255)
+	}
+	return append(out, byte(padding))
+}
+
+func ParsePacket(buf []byte) (Packet, error) {
+	var p Packet
+	err := (&p).Unmarshal(buf)
+	return p, err
+}
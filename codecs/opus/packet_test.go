@@ -0,0 +1,50 @@
+package opus
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPacket_Marshal(t *testing.T) {
+	frame := bytes.Repeat([]byte{0x42}, 300)
+
+	tests := []Packet{
+		{TOC: TOC{Configuration: 1, FrameCountCode: FrameCountCodeOneFrame}, Frames: [][]byte{frame}},
+		{TOC: TOC{Configuration: 1, FrameCountCode: FrameCountCodeTwoEqualFrames}, Frames: [][]byte{frame, frame}},
+		{TOC: TOC{Configuration: 1, Stereo: true, FrameCountCode: FrameCountCodeTwoDifferentFrames}, Frames: [][]byte{frame, frame[:3]}},
+		{TOC: TOC{Configuration: 16, FrameCountCode: FrameCountCodeArbitraryFrames}, Padding: 600, Frames: [][]byte{frame[:2], frame[:2], frame[:2]}},
+		{TOC: TOC{Configuration: 16, FrameCountCode: FrameCountCodeArbitraryFrames}, VBR: true, Frames: [][]byte{frame, frame[:1], frame[:2]}},
+	}
+
+	for i, want := range tests {
+		raw, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("#%d: Marshal failed: %v", i, err)
+		}
+		if len(raw) != want.MarshalSize() {
+			t.Fatalf("#%d: MarshalSize %d, Marshal %d", i, want.MarshalSize(), len(raw))
+		}
+		got, err := ParsePacket(raw)
+		if err != nil {
+			t.Fatalf("#%d: Unmarshal failed: %v", i, err)
+		}
+		if got.TOC != want.TOC || got.VBR != want.VBR || got.Padding != want.Padding {
+			t.Fatalf("#%d: got %s, want %s", i, got, want)
+		}
+		if !bytes.Equal(bytes.Join(got.Frames, []byte{0}), bytes.Join(want.Frames, []byte{0})) {
+			t.Fatalf("#%d: frames mismatch", i)
+		}
+	}
+}
+
+func TestFrameLength(t *testing.T) {
+	for _, size := range []int{0, 1, 251, 252, 253, 255, 256, 1000, MaxFrameSize} {
+		got, n, err := ParseFrameLength(FrameLength(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != size || n != len(FrameLength(size)) {
+			t.Fatalf("frame length %d decoded as %d", size, got)
+		}
+	}
+}
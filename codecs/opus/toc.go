@@ -0,0 +1,250 @@
+package opus
+
+import (
+	"fmt"
+	"time"
+)
+
+// The TOC byte, Figure 1 in rfc6716#section-3.1
+//
+//	 0 1 2 3 4 5 6 7
+//	+-+-+-+-+-+-+-+-+
+//	| config  |s| c |
+//	+-+-+-+-+-+-+-+-+
+const (
+	TOCByteIndex = 0
+
+	ConfigurationMask   = 0xf8
+	ConfigurationOffset = 3
+
+	StereoMask   = 1 << StereoOffset
+	StereoOffset = 2
+
+	FrameCountCodeMask   = 0x03
+	FrameCountCodeOffset = 0
+)
+
+// Mode is the coding mode of an Opus frame
+type Mode uint8
+
+const (
+	ModeSILK Mode = iota
+	ModeHybrid
+	ModeCELT
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeSILK:
+		return "SILK-only"
+	case ModeHybrid:
+		return "Hybrid"
+	case ModeCELT:
+		return "CELT-only"
+	default:
+		return fmt.Sprintf("unknown mode %d", m)
+	}
+}
+
+// Bandwidth is the audio bandwidth coded by an Opus frame
+type Bandwidth uint8
+
+const (
+	BandwidthNarrowband    Bandwidth = iota // NB, 4 kHz
+	BandwidthMediumband                     // MB, 6 kHz
+	BandwidthWideband                       // WB, 8 kHz
+	BandwidthSuperwideband                  // SWB, 12 kHz
+	BandwidthFullband                       // FB, 20 kHz
+)
+
+// SampleRate returns the effective sample rate of the bandwidth
+func (b Bandwidth) SampleRate() int {
+	switch b {
+	case BandwidthNarrowband:
+		return 8000
+	case BandwidthMediumband:
+		return 12000
+	case BandwidthWideband:
+		return 16000
+	case BandwidthSuperwideband:
+		return 24000
+	default:
+		return 48000
+	}
+}
+
+func (b Bandwidth) String() string {
+	switch b {
+	case BandwidthNarrowband:
+		return "NB"
+	case BandwidthMediumband:
+		return "MB"
+	case BandwidthWideband:
+		return "WB"
+	case BandwidthSuperwideband:
+		return "SWB"
+	case BandwidthFullband:
+		return "FB"
+	default:
+		return fmt.Sprintf("unknown bandwidth %d", b)
+	}
+}
+
+// Configuration is the 5-bit config field of the TOC byte
+//
+//	+-----------------------+-----------+-----------+-------------------+
+//	| Configuration         | Mode      | Bandwidth | Frame Sizes       |
+//	| Number(s)             |           |           |                   |
+//	+-----------------------+-----------+-----------+-------------------+
+//	| 0...3                 | SILK-only | NB        | 10, 20, 40, 60 ms |
+//	| 4...7                 | SILK-only | MB        | 10, 20, 40, 60 ms |
+//	| 8...11                | SILK-only | WB        | 10, 20, 40, 60 ms |
+//	| 12...13               | Hybrid    | SWB       | 10, 20 ms         |
+//	| 14...15               | Hybrid    | FB        | 10, 20 ms         |
+//	| 16...19               | CELT-only | NB        | 2.5, 5, 10, 20 ms |
+//	| 20...23               | CELT-only | WB        | 2.5, 5, 10, 20 ms |
+//	| 24...27               | CELT-only | SWB       | 2.5, 5, 10, 20 ms |
+//	| 28...31               | CELT-only | FB        | 2.5, 5, 10, 20 ms |
+//	+-----------------------+-----------+-----------+-------------------+
+//
+//	Table 2: TOC Byte Configuration Parameters in rfc6716#section-3.1
+type Configuration uint8
+
+func (c Configuration) Mode() Mode {
+	switch {
+	case c < 12:
+		return ModeSILK
+	case c < 16:
+		return ModeHybrid
+	default:
+		return ModeCELT
+	}
+}
+
+func (c Configuration) Bandwidth() Bandwidth {
+	switch {
+	case c < 12:
+		return Bandwidth(c / 4)
+	case c < 16:
+		return BandwidthSuperwideband + Bandwidth((c-12)/2)
+	case c < 20:
+		return BandwidthNarrowband
+	default:
+		// MB is not available in CELT-only mode
+		return BandwidthWideband + Bandwidth((c-20)/4)
+	}
+}
+
+// FrameDuration returns the duration of each frame coded with this configuration
+func (c Configuration) FrameDuration() time.Duration {
+	switch {
+	case c < 12:
+		return [...]time.Duration{
+			10 * time.Millisecond,
+			20 * time.Millisecond,
+			40 * time.Millisecond,
+			60 * time.Millisecond}[c%4]
+	case c < 16:
+		return [...]time.Duration{
+			10 * time.Millisecond,
+			20 * time.Millisecond}[c%2]
+	default:
+		return [...]time.Duration{
+			2500 * time.Microsecond,
+			5 * time.Millisecond,
+			10 * time.Millisecond,
+			20 * time.Millisecond}[c%4]
+	}
+}
+
+// FrameSamples returns the number of samples of each frame, at the given sample rate
+func (c Configuration) FrameSamples(sampleRate int) int {
+	return int(c.FrameDuration() * time.Duration(sampleRate) / time.Second)
+}
+
+func (c Configuration) String() string {
+	return fmt.Sprintf("%d (%s %s %v)", uint8(c), c.Mode(), c.Bandwidth(), c.FrameDuration())
+}
+
+// FrameCountCode is the 2-bit c field of the TOC byte
+type FrameCountCode uint8
+
+const (
+	FrameCountCodeOneFrame           FrameCountCode = iota // 0: 1 frame in the packet
+	FrameCountCodeTwoEqualFrames                           // 1: 2 frames in the packet, each with equal compressed size
+	FrameCountCodeTwoDifferentFrames                       // 2: 2 frames in the packet, with different compressed sizes
+	FrameCountCodeArbitraryFrames                          // 3: an arbitrary number of frames in the packet
+)
+
+func (c FrameCountCode) String() string {
+	switch c {
+	case FrameCountCodeOneFrame:
+		return "code 0 (one frame)"
+	case FrameCountCodeTwoEqualFrames:
+		return "code 1 (two equal frames)"
+	case FrameCountCodeTwoDifferentFrames:
+		return "code 2 (two different frames)"
+	case FrameCountCodeArbitraryFrames:
+		return "code 3 (arbitrary frames)"
+	default:
+		return fmt.Sprintf("unknown code %d", c)
+	}
+}
+
+// TOC represents the table-of-contents byte that starts every Opus packet
+type TOC struct {
+	Configuration  Configuration
+	Stereo         bool
+	FrameCountCode FrameCountCode
+}
+
+func (t TOC) Byte() byte {
+	b, _ := t.Marshal()
+	return b[0]
+}
+
+// Marshal serializes the TOC into bytes.
+func (t TOC) Marshal() ([]byte, error) {
+	b := byte(t.Configuration<<ConfigurationOffset) & ConfigurationMask
+	if t.Stereo {
+		b |= StereoMask
+	}
+	b |= byte(t.FrameCountCode<<FrameCountCodeOffset) & FrameCountCodeMask
+	return []byte{b}, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the TOC this method is called upon
+func (t *TOC) Unmarshal(buf []byte) error {
+	if len(buf) < 1 {
+		return fmt.Errorf("buf is not large enough to container TOC")
+	}
+	t.Configuration = Configuration((buf[0] & ConfigurationMask) >> ConfigurationOffset)
+	t.Stereo = (buf[0] & StereoMask) != 0
+	t.FrameCountCode = FrameCountCode((buf[0] & FrameCountCodeMask) >> FrameCountCodeOffset)
+	return nil
+}
+
+// Channels returns the number of channels coded in the packet
+func (t TOC) Channels() int {
+	if t.Stereo {
+		return 2
+	}
+	return 1
+}
+
+// String helps with debugging by printing TOC information in a readable way
+func (t TOC) String() string {
+	out := "Opus TOC:\n"
+
+	out += fmt.Sprintf("\tConfiguration: %s\n", t.Configuration)
+	out += fmt.Sprintf("\tStereo: %v\n", t.Stereo)
+	out += fmt.Sprintf("\tFrameCountCode: %s\n", t.FrameCountCode)
+
+	return out
+}
+
+func ParseTOC(packet []byte) TOC {
+	var t TOC
+	_ = (&t).Unmarshal(packet[TOCByteIndex:])
+	return t
+}
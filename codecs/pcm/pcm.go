@@ -0,0 +1,89 @@
+package pcm
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sample sizes of the linear PCM formats, rfc3551#section-4.5.11 and rfc3190#section-4
+const (
+	L16SampleSize = 2
+	L24SampleSize = 3
+)
+
+// FrameSize returns the number of bytes of a sample for every channel
+func FrameSize(sampleSize, channels int) int {
+	return sampleSize * channels
+}
+
+// Frames returns the number of samples per channel in a packet time at a sample rate
+func Frames(sampleRate int, packetTime time.Duration) int {
+	return int(int64(sampleRate) * int64(packetTime) / int64(time.Second))
+}
+
+// swap reverses the byte order of each sample of buf into a new buffer
+func swap(buf []byte, sampleSize int) ([]byte, error) {
+	if sampleSize <= 0 || len(buf)%sampleSize != 0 {
+		return nil, fmt.Errorf("%d bytes are not a multiple of the sample size %d", len(buf), sampleSize)
+	}
+	out := make([]byte, len(buf))
+	for i := 0; i < len(buf); i += sampleSize {
+		for j := 0; j < sampleSize; j++ {
+			out[i+j] = buf[i+sampleSize-1-j]
+		}
+	}
+	return out, nil
+}
+
+// FromLittleEndian converts little-endian PCM samples, as in WAV files, to network byte order
+func FromLittleEndian(buf []byte, sampleSize int) ([]byte, error) {
+	return swap(buf, sampleSize)
+}
+
+// ToLittleEndian converts PCM samples in network byte order to little-endian
+func ToLittleEndian(buf []byte, sampleSize int) ([]byte, error) {
+	return swap(buf, sampleSize)
+}
+
+// FromInt16 converts 16-bit samples to L16, in network byte order
+func FromInt16(samples []int16) []byte {
+	out := make([]byte, 0, len(samples)*L16SampleSize)
+	for _, s := range samples {
+		out = append(out, byte(uint16(s)>>8), byte(s))
+	}
+	return out
+}
+
+// ToInt16 converts L16 samples, in network byte order, to 16-bit samples
+func ToInt16(buf []byte) ([]int16, error) {
+	if len(buf)%L16SampleSize != 0 {
+		return nil, fmt.Errorf("%d bytes are not a multiple of the sample size %d", len(buf), L16SampleSize)
+	}
+	out := make([]int16, 0, len(buf)/L16SampleSize)
+	for i := 0; i < len(buf); i += L16SampleSize {
+		out = append(out, int16(uint16(buf[i])<<8|uint16(buf[i+1])))
+	}
+	return out, nil
+}
+
+// FromInt32 converts 24-bit samples, held in the low-order bits of int32, to L24, in network byte order
+func FromInt32(samples []int32) []byte {
+	out := make([]byte, 0, len(samples)*L24SampleSize)
+	for _, s := range samples {
+		out = append(out, byte(s>>16), byte(s>>8), byte(s))
+	}
+	return out
+}
+
+// ToInt32 converts L24 samples, in network byte order, to 24-bit samples sign-extended to int32
+func ToInt32(buf []byte) ([]int32, error) {
+	if len(buf)%L24SampleSize != 0 {
+		return nil, fmt.Errorf("%d bytes are not a multiple of the sample size %d", len(buf), L24SampleSize)
+	}
+	out := make([]int32, 0, len(buf)/L24SampleSize)
+	for i := 0; i < len(buf); i += L24SampleSize {
+		v := int32(buf[i])<<24 | int32(buf[i+1])<<16 | int32(buf[i+2])<<8
+		out = append(out, v>>8)
+	}
+	return out, nil
+}
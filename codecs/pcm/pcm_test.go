@@ -0,0 +1,45 @@
+package pcm
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestInt(t *testing.T) {
+	l16 := FromInt16([]int16{0x0102, -2})
+	if !bytes.Equal(l16, []byte{0x01, 0x02, 0xff, 0xfe}) {
+		t.Fatalf("FromInt16 should write network byte order, got %x", l16)
+	}
+	if s, err := ToInt16(l16); err != nil || s[0] != 0x0102 || s[1] != -2 {
+		t.Fatalf("ToInt16 should read network byte order, got %v %v", s, err)
+	}
+	if _, err := ToInt16(l16[:3]); err == nil {
+		t.Fatal("ToInt16 should reject a partial sample")
+	}
+
+	l24 := FromInt32([]int32{0x010203, -2})
+	if !bytes.Equal(l24, []byte{0x01, 0x02, 0x03, 0xff, 0xff, 0xfe}) {
+		t.Fatalf("FromInt32 should write network byte order, got %x", l24)
+	}
+	if s, err := ToInt32(l24); err != nil || s[0] != 0x010203 || s[1] != -2 {
+		t.Fatalf("ToInt32 should sign-extend, got %v %v", s, err)
+	}
+}
+
+func TestLittleEndian(t *testing.T) {
+	be, err := FromLittleEndian([]byte{0x03, 0x02, 0x01, 0x06, 0x05, 0x04}, L24SampleSize)
+	if err != nil || !bytes.Equal(be, []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}) {
+		t.Fatalf("FromLittleEndian should swap each sample, got %x %v", be, err)
+	}
+	le, err := ToLittleEndian(be, L16SampleSize)
+	if err != nil || !bytes.Equal(le, []byte{0x02, 0x01, 0x04, 0x03, 0x06, 0x05}) {
+		t.Fatalf("ToLittleEndian should swap each sample, got %x %v", le, err)
+	}
+	if _, err = ToLittleEndian(be, 4); err == nil {
+		t.Fatal("ToLittleEndian should reject a partial sample")
+	}
+	if Frames(48000, 125*time.Microsecond) != 6 || Frames(44100, 20*time.Millisecond) != 882 {
+		t.Fatal("Frames should count the samples of a packet time")
+	}
+}
@@ -0,0 +1,128 @@
+package vp8
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// The frame tag, 9.1 in rfc6386
+//
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|Size0|H| VER |P|     Size1     |     Size2     |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//
+// The bits are numbered from the least significant bit of each byte:
+// P is the inverse key frame flag, VER the version, H show_frame,
+// and first_part_size = Size0 + 8 * Size1 + 2048 * Size2
+const (
+	InverseKeyFrameMask = 0x01
+	VersionMask         = 0x0e
+	VersionOffset       = 1
+	ShowFrameMask       = 0x10
+	Size0Mask           = 0xe0
+	Size0Offset         = 5
+)
+
+// FrameHeader represents the uncompressed data chunk at the start of every VP8 frame, 9.1 in rfc6386
+// Width, Height and the scales are only present in key frames
+type FrameHeader struct {
+	KeyFrame  bool
+	Version   uint8
+	ShowFrame bool
+	// FirstPartitionSize is the size of the first partition, the frame header excluded
+	FirstPartitionSize uint32
+
+	Width           uint16
+	HorizontalScale uint8
+	Height          uint16
+	VerticalScale   uint8
+}
+
+// Size returns the size of the marshaled header
+func (h FrameHeader) Size() int {
+	if h.KeyFrame {
+		return KeyFrameHeaderSize
+	}
+	return FrameTagSize
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the FrameHeader this method is called upon
+func (h *FrameHeader) Unmarshal(buf []byte) error {
+	if len(buf) < FrameTagSize {
+		return fmt.Errorf("buf is not large enough to container frame tag")
+	}
+	*h = FrameHeader{
+		KeyFrame:           buf[0]&InverseKeyFrameMask == 0,
+		Version:            (buf[0] & VersionMask) >> VersionOffset,
+		ShowFrame:          buf[0]&ShowFrameMask != 0,
+		FirstPartitionSize: uint32(buf[0]&Size0Mask)>>Size0Offset | uint32(buf[1])<<3 | uint32(buf[2])<<11,
+	}
+	if !h.KeyFrame {
+		return nil
+	}
+	if len(buf) < KeyFrameHeaderSize {
+		return fmt.Errorf("buf is not large enough to container key frame header")
+	}
+	if !bytes.Equal(buf[FrameTagSize:FrameTagSize+len(StartCode)], StartCode) {
+		return fmt.Errorf("invalid key frame start code %x", buf[FrameTagSize:FrameTagSize+len(StartCode)])
+	}
+	size := buf[FrameTagSize+len(StartCode):]
+	h.Width = uint16(size[0]) | uint16(size[1]&0x3f)<<8
+	h.HorizontalScale = size[1] >> 6
+	h.Height = uint16(size[2]) | uint16(size[3]&0x3f)<<8
+	h.VerticalScale = size[3] >> 6
+	return nil
+}
+
+// Marshal serializes the header into bytes.
+func (h FrameHeader) Marshal() ([]byte, error) {
+	if h.FirstPartitionSize > MaxFirstPartitionSize {
+		return nil, fmt.Errorf("first partition size %d exceeds %d", h.FirstPartitionSize, MaxFirstPartitionSize)
+	}
+	buf := make([]byte, h.Size())
+	buf[0] = (h.Version<<VersionOffset)&VersionMask | byte(h.FirstPartitionSize<<Size0Offset)&Size0Mask
+	if !h.KeyFrame {
+		buf[0] |= InverseKeyFrameMask
+	}
+	if h.ShowFrame {
+		buf[0] |= ShowFrameMask
+	}
+	buf[1] = byte(h.FirstPartitionSize >> 3)
+	buf[2] = byte(h.FirstPartitionSize >> 11)
+	if !h.KeyFrame {
+		return buf, nil
+	}
+	if h.Width > MaxDimension || h.Height > MaxDimension {
+		return nil, fmt.Errorf("dimensions %dx%d exceed %d", h.Width, h.Height, MaxDimension)
+	}
+	copy(buf[FrameTagSize:], StartCode)
+	size := buf[FrameTagSize+len(StartCode):]
+	size[0] = byte(h.Width)
+	size[1] = byte(h.Width>>8) | h.HorizontalScale<<6
+	size[2] = byte(h.Height)
+	size[3] = byte(h.Height>>8) | h.VerticalScale<<6
+	return buf, nil
+}
+
+// String helps with debugging by printing frame header information in a readable way
+func (h FrameHeader) String() string {
+	out := "VP8 Frame Header:\n"
+
+	out += fmt.Sprintf("\tKeyFrame: %v\n", h.KeyFrame)
+	out += fmt.Sprintf("\tVersion: %d\n", h.Version)
+	out += fmt.Sprintf("\tShowFrame: %v\n", h.ShowFrame)
+	out += fmt.Sprintf("\tFirstPartitionSize: %d\n", h.FirstPartitionSize)
+	if h.KeyFrame {
+		out += fmt.Sprintf("\tWidth: %d (scale %d)\n", h.Width, h.HorizontalScale)
+		out += fmt.Sprintf("\tHeight: %d (scale %d)\n", h.Height, h.VerticalScale)
+	}
+
+	return out
+}
+
+func ParseFrameHeader(buf []byte) (FrameHeader, error) {
+	var h FrameHeader
+	err := (&h).Unmarshal(buf)
+	return h, err
+}
@@ -0,0 +1,46 @@
+package vp8
+
+import (
+	"testing"
+)
+
+func TestFrameHeader_Marshal(t *testing.T) {
+	tests := []FrameHeader{
+		{KeyFrame: true, ShowFrame: true, FirstPartitionSize: 2410, Width: 640, HorizontalScale: 1, Height: 480},
+		{KeyFrame: true, Version: 3, FirstPartitionSize: MaxFirstPartitionSize, Width: MaxDimension, Height: 1, VerticalScale: 3},
+		{Version: 1, ShowFrame: true, FirstPartitionSize: 9},
+	}
+
+	for i, want := range tests {
+		raw, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("#%d: Marshal failed: %v", i, err)
+		}
+		if len(raw) != want.Size() {
+			t.Fatalf("#%d: Size %d, Marshal %d", i, want.Size(), len(raw))
+		}
+		got, err := ParseFrameHeader(raw)
+		if err != nil {
+			t.Fatalf("#%d: Unmarshal failed: %v", i, err)
+		}
+		if got != want {
+			t.Fatalf("#%d: got %s, want %s", i, got, want)
+		}
+	}
+
+	if _, err := (FrameHeader{FirstPartitionSize: MaxFirstPartitionSize + 1}).Marshal(); err == nil {
+		t.Fatal("Marshal accepted an oversized first partition")
+	}
+}
+
+func TestFrameHeader_Unmarshal(t *testing.T) {
+	if _, err := ParseFrameHeader([]byte{0x00, 0x00}); err == nil {
+		t.Fatal("Unmarshal accepted a truncated frame tag")
+	}
+	if _, err := ParseFrameHeader([]byte{0x00, 0x00, 0x00, 0x9d, 0x01}); err == nil {
+		t.Fatal("Unmarshal accepted a truncated key frame header")
+	}
+	if _, err := ParseFrameHeader([]byte{0x00, 0x00, 0x00, 0x9d, 0x01, 0x2b, 0x80, 0x02, 0xe0, 0x01}); err == nil {
+		t.Fatal("Unmarshal accepted an invalid start code")
+	}
+}
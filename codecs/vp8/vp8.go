@@ -0,0 +1,18 @@
+package vp8
+
+var (
+	// 9.1: the start code of key frames, following the frame tag
+	StartCode = []byte{0x9d, 0x01, 0x2a}
+)
+
+const (
+	// 9.1: the frame tag, common to all frames
+	FrameTagSize = 3
+	// 9.1: the start code, width and height of key frames
+	KeyFrameHeaderSize = FrameTagSize + 7
+
+	// 9.2: width and height are 14 bits
+	MaxDimension = 1<<14 - 1
+	// 9.1: first_part_size is 19 bits
+	MaxFirstPartitionSize = 1<<19 - 1
+)
@@ -0,0 +1,130 @@
+package vp9
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// FrameHeader represents the start of the uncompressed header of a VP9 frame, 6.2 in the
+// VP9 Bitstream & Decoding Process Specification, up to the frame size of key frames
+type FrameHeader struct {
+	Profile uint8
+	// ShowExistingFrame is set by a header-only frame that shows the frame FrameToShow
+	ShowExistingFrame bool
+	FrameToShow       uint8
+
+	KeyFrame       bool
+	ShowFrame      bool
+	ErrorResilient bool
+	IntraOnly      bool
+	BitDepth       int
+	ColorSpace     uint8
+	ColorRange     bool
+	SubsamplingX   bool
+	SubsamplingY   bool
+	Width, Height  int
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the FrameHeader this method is called upon
+// The color config and frame size are only decoded for key frames
+func (h *FrameHeader) Unmarshal(buf []byte) error {
+	*h = FrameHeader{}
+	r := bitstream.NewReader(buf)
+	if r.ReadBits(2) != FrameMarker {
+		if r.Err() != nil {
+			return fmt.Errorf("buf is not large enough to container frame header")
+		}
+		return fmt.Errorf("invalid frame_marker")
+	}
+	low := r.ReadUint8(1)
+	high := r.ReadUint8(1)
+	h.Profile = high<<1 | low
+	if h.Profile == 3 && r.ReadBit() != 0 {
+		return fmt.Errorf("invalid reserved_zero bit")
+	}
+	h.ShowExistingFrame = r.ReadFlag()
+	if h.ShowExistingFrame {
+		h.FrameToShow = r.ReadUint8(3)
+		return r.Err()
+	}
+	h.KeyFrame = r.ReadBit() == 0
+	h.ShowFrame = r.ReadFlag()
+	h.ErrorResilient = r.ReadFlag()
+	if !h.KeyFrame {
+		if !h.ShowFrame {
+			h.IntraOnly = r.ReadFlag()
+		}
+		return r.Err()
+	}
+
+	if !bytes.Equal(r.ReadBytes(len(SyncCode)), SyncCode) {
+		if r.Err() != nil {
+			return fmt.Errorf("buf is not large enough to container key frame header")
+		}
+		return fmt.Errorf("invalid frame_sync_code")
+	}
+	h.decodeColorConfig(r)
+	h.Width = int(r.ReadBits(16)) + 1
+	h.Height = int(r.ReadBits(16)) + 1
+	if r.Err() != nil {
+		return fmt.Errorf("buf is not large enough to container key frame header")
+	}
+	return nil
+}
+
+// decodeColorConfig decodes color_config(), 6.2.2
+func (h *FrameHeader) decodeColorConfig(r *bitstream.Reader) {
+	h.BitDepth = 8
+	if h.Profile >= 2 {
+		h.BitDepth = 10
+		if r.ReadFlag() {
+			h.BitDepth = 12
+		}
+	}
+	h.ColorSpace = r.ReadUint8(3)
+	if h.ColorSpace != ColorSpaceRGB {
+		h.ColorRange = r.ReadFlag()
+		if h.Profile == 1 || h.Profile == 3 {
+			h.SubsamplingX = r.ReadFlag()
+			h.SubsamplingY = r.ReadFlag()
+			r.SkipBits(1)
+		} else {
+			h.SubsamplingX, h.SubsamplingY = true, true
+		}
+		return
+	}
+	h.ColorRange = true
+	if h.Profile == 1 || h.Profile == 3 {
+		r.SkipBits(1)
+	}
+}
+
+// String helps with debugging by printing frame header information in a readable way
+func (h FrameHeader) String() string {
+	out := "VP9 Frame Header:\n"
+
+	out += fmt.Sprintf("\tProfile: %d\n", h.Profile)
+	if h.ShowExistingFrame {
+		out += fmt.Sprintf("\tShowExistingFrame: %d\n", h.FrameToShow)
+		return out
+	}
+	out += fmt.Sprintf("\tKeyFrame: %v\n", h.KeyFrame)
+	out += fmt.Sprintf("\tShowFrame: %v\n", h.ShowFrame)
+	out += fmt.Sprintf("\tErrorResilient: %v\n", h.ErrorResilient)
+	out += fmt.Sprintf("\tIntraOnly: %v\n", h.IntraOnly)
+	if h.KeyFrame {
+		out += fmt.Sprintf("\tBitDepth: %d\n", h.BitDepth)
+		out += fmt.Sprintf("\tColorSpace: %d\n", h.ColorSpace)
+		out += fmt.Sprintf("\tSize: %dx%d\n", h.Width, h.Height)
+	}
+
+	return out
+}
+
+func ParseFrameHeader(buf []byte) (FrameHeader, error) {
+	var h FrameHeader
+	err := (&h).Unmarshal(buf)
+	return h, err
+}
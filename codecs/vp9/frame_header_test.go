@@ -0,0 +1,60 @@
+package vp9
+
+import (
+	"testing"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+func TestFrameHeader_Unmarshal(t *testing.T) {
+	// profile 1 key frame, 4:4:4 BT.709 1920x1080
+	w := bitstream.NewWriter()
+	w.WriteBits(FrameMarker, 2)
+	w.WriteBits(1, 1) // profile_low_bit
+	w.WriteBits(0, 1) // profile_high_bit
+	w.WriteBits(0, 1) // show_existing_frame
+	w.WriteBits(0, 1) // frame_type
+	w.WriteBits(1, 1) // show_frame
+	w.WriteBits(1, 1) // error_resilient_mode
+	w.WriteBytes(SyncCode)
+	w.WriteBits(2, 3) // color_space
+	w.WriteBits(1, 1) // color_range
+	w.WriteBits(0, 1) // subsampling_x
+	w.WriteBits(0, 1) // subsampling_y
+	w.WriteBits(0, 1) // reserved_zero
+	w.WriteBits(1920-1, 16)
+	w.WriteBits(1080-1, 16)
+	w.ByteAlign()
+
+	h, err := ParseFrameHeader(w.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Profile != 1 || !h.KeyFrame || !h.ShowFrame || !h.ErrorResilient || h.BitDepth != 8 ||
+		h.ColorSpace != 2 || !h.ColorRange || h.SubsamplingX || h.SubsamplingY || h.Width != 1920 || h.Height != 1080 {
+		t.Fatalf("Unmarshal should decode the key frame header, got %s", h)
+	}
+	if _, err = ParseFrameHeader(w.Bytes()[:6]); err == nil {
+		t.Fatal("Unmarshal accepted a truncated key frame header")
+	}
+
+	// profile 2 intra-only frame
+	if h, err = ParseFrameHeader([]byte{0x94, 0x80}); err != nil {
+		t.Fatal(err)
+	}
+	if h.Profile != 2 || h.KeyFrame || h.ShowFrame || !h.IntraOnly {
+		t.Fatalf("Unmarshal should decode the intra-only frame header, got %s", h)
+	}
+
+	// profile 0 frame showing frame 5
+	if h, err = ParseFrameHeader([]byte{0x8d}); err != nil {
+		t.Fatal(err)
+	}
+	if !h.ShowExistingFrame || h.FrameToShow != 5 {
+		t.Fatalf("Unmarshal should decode show_existing_frame, got %s", h)
+	}
+
+	if _, err = ParseFrameHeader([]byte{0x40}); err == nil {
+		t.Fatal("Unmarshal accepted an invalid frame_marker")
+	}
+}
@@ -0,0 +1,14 @@
+package vp9
+
+var (
+	// 7.2: the sync code of key frames and intra-only frames
+	SyncCode = []byte{0x49, 0x83, 0x42}
+)
+
+const (
+	// 7.2: frame_marker, the first 2 bits of every frame
+	FrameMarker = 2
+
+	// 7.2.2: the color space of RGB streams, which carry no color_range nor subsampling
+	ColorSpaceRGB = 7
+)
@@ -0,0 +1,163 @@
+package amr
+
+import (
+	"fmt"
+
+	amr_codec "github.com/searKing/rtp/codecs/amr"
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// The payload header, 4.3.1 and 4.4.1 in rfc4867
+//
+//	0 1 2 3 4 5 6 7
+//	+-+-+-+-+-+-+-+-+
+//	|  CMR  |R|R|R|R|  octet-aligned mode
+//	+-+-+-+-+-+-+-+-+
+//	|  CMR  |          bandwidth-efficient mode
+//	+-+-+-+-+
+const (
+	CMRBits = 4
+	// CMRNoRequest means no mode request is present
+	CMRNoRequest = 15
+)
+
+// A table of contents entry, 4.3.2 and 4.4.2 in rfc4867
+//
+//	0 1 2 3 4 5 6 7
+//	+-+-+-+-+-+-+-+-+
+//	|F|  FT   |Q|P|P|  octet-aligned mode
+//	+-+-+-+-+-+-+-+-+
+//	|F|  FT   |Q|      bandwidth-efficient mode
+//	+-+-+-+-+-+-+
+const (
+	TOCEntryBits = 6
+)
+
+// TOCEntry represents an entry of the table of contents, one per frame
+type TOCEntry struct {
+	// F is set when another entry follows
+	F bool
+	// FT is the frame type of the frame
+	FT uint8
+	// Q is unset when the frame is damaged
+	Q bool
+}
+
+// Encode writes the entry, without the padding of the octet-aligned mode
+func (e TOCEntry) Encode(w *bitstream.Writer) {
+	w.WriteFlag(e.F)
+	w.WriteBits(uint64(e.FT), 4)
+	w.WriteFlag(e.Q)
+}
+
+// Decode reads the entry, without the padding of the octet-aligned mode
+func (e *TOCEntry) Decode(r *bitstream.Reader) error {
+	e.F = r.ReadFlag()
+	e.FT = r.ReadUint8(4)
+	e.Q = r.ReadFlag()
+	return r.Err()
+}
+
+// Packet represents the payload of an RTP Packet, without interleaving nor CRCs
+type Packet struct {
+	// CMR is the codec mode the receiver of the payload requests, CMRNoRequest for none
+	CMR uint8
+	// TOC holds an entry per frame, the F bit derived from its position when marshalled
+	TOC []TOCEntry
+	// Frames holds the speech bits of each frame, as amr.Frame Data
+	Frames [][]byte
+}
+
+// Marshal serializes the payload in the octet-aligned or bandwidth-efficient mode
+func (p Packet) Marshal(wideband, octetAligned bool) ([]byte, error) {
+	if len(p.TOC) == 0 || len(p.TOC) != len(p.Frames) {
+		return nil, fmt.Errorf("%d TOC entries for %d frames", len(p.TOC), len(p.Frames))
+	}
+	w := bitstream.NewWriter()
+	w.WriteBits(uint64(p.CMR), CMRBits)
+	if octetAligned {
+		w.WriteBits(0, 4)
+	}
+	for i, e := range p.TOC {
+		e.F = i != len(p.TOC)-1
+		e.Encode(w)
+		if octetAligned {
+			w.WriteBits(0, 2)
+		}
+	}
+	for i, e := range p.TOC {
+		bits, err := amr_codec.FrameBits(wideband, e.FT)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.Frames[i]) != (bits+7)/8 {
+			return nil, fmt.Errorf("frame %d of type %d has %d bytes, want %d", i, e.FT, len(p.Frames[i]), (bits+7)/8)
+		}
+		if octetAligned {
+			w.WriteBytes(p.Frames[i])
+			continue
+		}
+		// the speech bits only, without the padding of the frame
+		w.WriteBytes(p.Frames[i][:bits/8])
+		if rem := bits % 8; rem != 0 {
+			w.WriteBits(uint64(p.Frames[i][bits/8]>>uint(8-rem)), rem)
+		}
+	}
+	w.ByteAlign()
+	return w.Bytes(), nil
+}
+
+// Unmarshal parses the passed byte slice, in the octet-aligned or bandwidth-efficient mode,
+// and stores the result in the Packet this method is called upon
+func (p *Packet) Unmarshal(buf []byte, wideband, octetAligned bool) error {
+	r := bitstream.NewReader(buf)
+	p.CMR = r.ReadUint8(CMRBits)
+	if octetAligned {
+		r.SkipBits(4)
+	}
+	p.TOC = nil
+	p.Frames = nil
+	for {
+		var e TOCEntry
+		if err := (&e).Decode(r); err != nil {
+			return fmt.Errorf("buf is not large enough to container table of contents")
+		}
+		if octetAligned {
+			r.SkipBits(2)
+		}
+		p.TOC = append(p.TOC, e)
+		if !e.F {
+			break
+		}
+	}
+	for i, e := range p.TOC {
+		bits, err := amr_codec.FrameBits(wideband, e.FT)
+		if err != nil {
+			return err
+		}
+		if octetAligned {
+			bits = (bits + 7) / 8 * 8
+		}
+		frame := append([]byte(nil), r.ReadBytes(bits/8)...)
+		if rem := bits % 8; rem != 0 {
+			frame = append(frame, r.ReadUint8(rem)<<uint(8-rem))
+		}
+		if r.Err() != nil {
+			return fmt.Errorf("buf is not large enough to container frame %d of type %d", i, e.FT)
+		}
+		p.Frames = append(p.Frames, frame)
+	}
+	return nil
+}
+
+// String helps with debugging by printing Payload information in a readable way
+func (p Packet) String() string {
+	out := "AMR Packet:\n"
+
+	out += fmt.Sprintf("\tCMR: %d\n", p.CMR)
+	for i, e := range p.TOC {
+		out += fmt.Sprintf("\tFrame %d: FT: %d Q: %v, %d bytes\n", i, e.FT, e.Q, len(p.Frames[i]))
+	}
+
+	return out
+}
@@ -0,0 +1,183 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	amr_codec "github.com/searKing/rtp/codecs/amr"
+	"github.com/searKing/rtp/format/amr"
+)
+
+// AMRPayloader payloads AMR and AMR-WB speech frames, see rfc4867#section-4
+// The payload holds frames of the storage format, rfc4867#section-5, optionally after the magic number.
+// Packetize stamps every packet of a payload with the same timestamp, so each call takes the frames
+// of a single packet, at most MaxFrames of them that fit the MTU.
+// Interleaving and frame CRCs are not supported
+type AMRPayloader struct {
+	// WideBand selects AMR-WB
+	WideBand bool
+	// OctetAligned selects the octet-aligned mode instead of the bandwidth-efficient one, as the octet-align fmtp parameter
+	OctetAligned bool
+	// ModeRequest, if set, is sent as CMR to request a codec mode from the receiver
+	ModeRequest *uint8
+	// MaxFrames limits the number of frames per packet, as the maxptime fmtp parameter in 20 ms units; 0 means no limit
+	MaxFrames int
+
+	err error
+}
+
+// Err returns the error of the last call to Payload, such as more frames than MaxFrames or the MTU allows
+func (p *AMRPayloader) Err() error {
+	return p.err
+}
+
+// UnmarshalFmtp configures the payloader from the parameters of an SDP fmtp line, such as
+// "octet-align=1;maxptime=100", see rfc4867#section-8.1
+func (p *AMRPayloader) UnmarshalFmtp(fmtp string) error {
+	octetAligned, maxFrames, err := parseAMRFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.OctetAligned, p.MaxFrames = octetAligned, maxFrames
+	return nil
+}
+
+// parseAMRFmtp returns the octet-align parameter, and the maxptime parameter in frames of 20 ms.
+// Interleaving, frame CRCs and robust sorting are not supported
+func parseAMRFmtp(fmtp string) (octetAligned bool, maxFrames int, err error) {
+	params := parseFmtp(fmtp)
+	for _, name := range []string{"octet-align", "crc", "robust-sorting"} {
+		v, ok := params[name]
+		if !ok {
+			continue
+		}
+		if v != "0" && v != "1" {
+			return false, 0, fmt.Errorf("invalid %s %q", name, v)
+		}
+		if v == "1" && name != "octet-align" {
+			return false, 0, fmt.Errorf("unsupported %s", name)
+		}
+	}
+	if _, ok := params["interleaving"]; ok {
+		return false, 0, fmt.Errorf("unsupported interleaving")
+	}
+	if v, ok := params["maxptime"]; ok {
+		ptime, err := strconv.Atoi(v)
+		maxFrames = int(time.Duration(ptime) * time.Millisecond / amr_codec.FrameDuration)
+		if err != nil || maxFrames <= 0 {
+			return false, 0, fmt.Errorf("invalid maxptime %q", v)
+		}
+	}
+	return params["octet-align"] == "1", maxFrames, nil
+}
+
+// ClockRate returns the RTP clock rate, the sample rate
+func (p *AMRPayloader) ClockRate() uint32 {
+	if p.WideBand {
+		return amr_codec.WBSampleRate
+	}
+	return amr_codec.SampleRate
+}
+
+// Payload packs AMR frames in a single byte array
+// Payloads of invalid frames, more frames than MaxFrames, or frames larger than the MTU result in no packets,
+// Err returns why
+func (p *AMRPayloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	p.err = nil
+	if payload == nil || mtu <= 0 {
+		return out
+	}
+	frames, err := amr_codec.ParseFrames(p.WideBand, payload)
+	if err != nil {
+		p.err = err
+		return out
+	}
+	if len(frames) == 0 {
+		return out
+	}
+	if p.MaxFrames > 0 && len(frames) > p.MaxFrames {
+		p.err = fmt.Errorf("%d frames exceed MaxFrames %d", len(frames), p.MaxFrames)
+		return out
+	}
+
+	pkt := amr.Packet{CMR: amr.CMRNoRequest}
+	if p.ModeRequest != nil {
+		pkt.CMR = *p.ModeRequest
+	}
+	for _, f := range frames {
+		pkt.TOC = append(pkt.TOC, amr.TOCEntry{FT: f.FT, Q: f.Q})
+		pkt.Frames = append(pkt.Frames, f.Data)
+	}
+	o, err := pkt.Marshal(p.WideBand, p.OctetAligned)
+	if err != nil {
+		p.err = err
+		return out
+	}
+	if len(o) > mtu {
+		p.err = fmt.Errorf("packet of %d bytes is larger than the MTU %d", len(o), mtu)
+		return out
+	}
+	return append(out, o)
+}
+
+// Samples returns the number of samples of the frames of the payload, at the given clock rate
+func (p *AMRPayloader) Samples(clockRate uint32, payload []byte) uint32 {
+	frames, err := amr_codec.ParseFrames(p.WideBand, payload)
+	if err != nil {
+		return 0
+	}
+	samples := len(frames) * amr_codec.SamplesPerFrame(p.WideBand)
+	if rate := p.ClockRate(); clockRate > 0 && clockRate != rate {
+		samples = samples * int(clockRate) / int(rate)
+	}
+	return uint32(samples)
+}
+
+// AMRPacket represents the AMR payload that is stored in the payload of an RTP Packet
+type AMRPacket struct {
+	// WideBand selects AMR-WB
+	WideBand bool
+	// OctetAligned selects the octet-aligned mode instead of the bandwidth-efficient one
+	OctetAligned bool
+
+	amr.Packet
+
+	Payload []byte
+}
+
+// UnmarshalFmtp configures the depacketizer from the parameters of an SDP fmtp line, such as "octet-align=1"
+func (p *AMRPacket) UnmarshalFmtp(fmtp string) error {
+	octetAligned, _, err := parseAMRFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.OctetAligned = octetAligned
+	return nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the AMRPacket this method is called upon
+// It returns the frames of the packet in the storage format, without magic number; NO_DATA frames included
+func (p *AMRPacket) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) == 0 {
+		return nil, fmt.Errorf("Payload is not large enough")
+	}
+	if err := (&p.Packet).Unmarshal(packet, p.WideBand, p.OctetAligned); err != nil {
+		return nil, err
+	}
+	out := bytes.NewBuffer(nil)
+	for i, e := range p.TOC {
+		raw, err := amr_codec.Frame{FT: e.FT, Q: e.Q, Data: p.Frames[i]}.Marshal(p.WideBand)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(raw)
+	}
+	p.Payload = out.Bytes()
+	return p.Payload, nil
+}
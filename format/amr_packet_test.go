@@ -0,0 +1,111 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	amr_codec "github.com/searKing/rtp/codecs/amr"
+)
+
+func testAMRFrames(count int) []byte {
+	// AMR 12.2 kbit/s frames: 244 speech bits, all set, padded to 31 bytes
+	frame := append([]byte{0x3c}, bytes.Repeat([]byte{0xff}, 30)...)
+	frame = append(frame, 0xf0)
+	return bytes.Repeat(frame, count)
+}
+
+func TestAMRPayloader_Payload(t *testing.T) {
+	pck := AMRPayloader{}
+	if res := pck.Payload(100, nil); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+	if res := pck.Payload(100, []byte{0x3c, 0x00}); len(res) != 0 {
+		t.Fatal("Generated payload should be empty for a truncated frame")
+	}
+	if res := pck.Payload(31, testAMRFrames(1)); len(res) != 0 {
+		t.Fatal("Generated payload should be empty if a frame exceeds the MTU")
+	}
+
+	// bandwidth-efficient: 4 bits of CMR, 6 bits of TOC, 244 speech bits, 2 bits of padding
+	res := pck.Payload(100, append([]byte(amr_codec.Magic), testAMRFrames(1)...))
+	expected := append([]byte{0xf3}, bytes.Repeat([]byte{0xff}, 30)...)
+	expected = append(expected, 0xfc)
+	if len(res) != 1 || !bytes.Equal(res[0], expected) {
+		t.Fatalf("Generated payload should be bit-packed, got %x", res)
+	}
+
+	// all the frames of a call share a packet, within the MTU and MaxFrames
+	if res = pck.Payload(100, testAMRFrames(3)); len(res) != 1 || len(res[0]) != 95 {
+		t.Fatalf("Generated payload should carry 3 frames, got %d packets", len(res))
+	}
+	if res = pck.Payload(63, testAMRFrames(3)); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty if the frames exceed the MTU")
+	}
+	pck.MaxFrames = 2
+	if res = pck.Payload(100, testAMRFrames(3)); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty for more than MaxFrames")
+	}
+	if res = pck.Payload(100, testAMRFrames(2)); len(res) != 1 || pck.Err() != nil {
+		t.Fatal("Generated payload should carry MaxFrames frames")
+	}
+	if pck.Samples(8000, testAMRFrames(3)) != 480 {
+		t.Fatal("Samples should count 160 samples per frame")
+	}
+
+	// octet-aligned, with a mode request and a NO_DATA frame
+	mode := uint8(5)
+	pck = AMRPayloader{OctetAligned: true, ModeRequest: &mode}
+	res = pck.Payload(100, append(testAMRFrames(1), 0x7c))
+	if len(res) != 1 || len(res[0]) != 1+2+31 || res[0][0] != 0x50 || res[0][1] != 0xbc || res[0][2] != 0x7c {
+		t.Fatalf("Generated payload should be octet-aligned, got %x", res)
+	}
+}
+
+func TestAMRPacket_Unmarshal(t *testing.T) {
+	for _, octetAligned := range []bool{false, true} {
+		payloader := AMRPayloader{OctetAligned: octetAligned}
+		frames := append(testAMRFrames(2), 0x7c)
+		res := payloader.Payload(200, frames)
+		if len(res) != 1 {
+			t.Fatal("Generated payload should be a single packet")
+		}
+
+		pck := AMRPacket{OctetAligned: octetAligned}
+		if _, err := pck.Unmarshal(nil); err == nil {
+			t.Fatal("Unmarshal should reject a nil packet")
+		}
+		raw, err := pck.Unmarshal(res[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(raw, frames) {
+			t.Fatalf("Unmarshal should rebuild the frames, octet-aligned %v, got %x", octetAligned, raw)
+		}
+		if pck.CMR != 15 || len(pck.TOC) != 3 || pck.TOC[2].FT != amr_codec.FrameTypeNoData {
+			t.Fatalf("Unexpected table of contents %s", pck.Packet)
+		}
+		if _, err = pck.Unmarshal(res[0][:20]); err == nil {
+			t.Fatal("Unmarshal should reject a truncated packet")
+		}
+	}
+}
+
+func TestAMRPayloader_UnmarshalFmtp(t *testing.T) {
+	pck := AMRPayloader{}
+	if err := pck.UnmarshalFmtp("octet-align=1; maxptime=100; mode-set=0,2,5,7"); err != nil {
+		t.Fatal(err)
+	}
+	if !pck.OctetAligned || pck.MaxFrames != 5 {
+		t.Fatalf("UnmarshalFmtp should select the octet-aligned mode and 5 frames, got %v and %d", pck.OctetAligned, pck.MaxFrames)
+	}
+	for _, fmtp := range []string{"octet-align=2", "maxptime=10", "crc=1", "robust-sorting=1", "interleaving=10"} {
+		if err := pck.UnmarshalFmtp(fmtp); err == nil {
+			t.Fatalf("UnmarshalFmtp accepted %q", fmtp)
+		}
+	}
+
+	depacketizer := AMRPacket{}
+	if err := depacketizer.UnmarshalFmtp("octet-align=1"); err != nil || !depacketizer.OctetAligned {
+		t.Fatal("UnmarshalFmtp should select the octet-aligned mode")
+	}
+}
@@ -0,0 +1,83 @@
+package av1
+
+import "fmt"
+
+// The aggregation header, 4.4 in the RTP Payload Format For AV1
+//
+//	+-+-+-+-+-+-+-+-+
+//	|Z|Y| W |N|-|-|-|
+//	+-+-+-+-+-+-+-+-+
+const (
+	AggregationHeaderByteIndex = 0
+
+	AggregationHeaderZMask  = 0x80
+	AggregationHeaderYMask  = 0x40
+	AggregationHeaderWMask  = 0x30
+	AggregationHeaderWShift = 4
+	AggregationHeaderNMask  = 0x08
+
+	// W is 2 bits: up to 3 OBU elements can be counted, the last one without length field
+	MaxCountedObuElements = 3
+)
+
+// AggregationHeader represents the aggregation header that starts the payload of an RTP Packet
+type AggregationHeader struct {
+	// Z is set when the first OBU element continues an OBU fragment of the previous packet
+	Z bool
+	// Y is set when the last OBU element is continued by the next packet
+	Y bool
+	// W is the number of OBU elements, the last one without length field; 0 means every element has a length field
+	W uint8
+	// N is set on the first packet of a coded video sequence
+	N bool
+}
+
+func (h AggregationHeader) Byte() byte {
+	b, _ := h.Marshal()
+	return b[0]
+}
+
+// Marshal serializes the header into bytes.
+func (h AggregationHeader) Marshal() ([]byte, error) {
+	b := h.W << AggregationHeaderWShift & AggregationHeaderWMask
+	if h.Z {
+		b |= AggregationHeaderZMask
+	}
+	if h.Y {
+		b |= AggregationHeaderYMask
+	}
+	if h.N {
+		b |= AggregationHeaderNMask
+	}
+	return []byte{b}, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the AggregationHeader this method is called upon
+func (h *AggregationHeader) Unmarshal(buf []byte) error {
+	if len(buf) < 1 {
+		return fmt.Errorf("buf is not large enough to container aggregation header")
+	}
+	h.Z = buf[0]&AggregationHeaderZMask != 0
+	h.Y = buf[0]&AggregationHeaderYMask != 0
+	h.W = (buf[0] & AggregationHeaderWMask) >> AggregationHeaderWShift
+	h.N = buf[0]&AggregationHeaderNMask != 0
+	return nil
+}
+
+// String helps with debugging by printing AggregationHeader information in a readable way
+func (h AggregationHeader) String() string {
+	out := "AV1 AggregationHeader:\n"
+
+	out += fmt.Sprintf("\tZ: %v\n", h.Z)
+	out += fmt.Sprintf("\tY: %v\n", h.Y)
+	out += fmt.Sprintf("\tW: %d\n", h.W)
+	out += fmt.Sprintf("\tN: %v\n", h.N)
+
+	return out
+}
+
+func ParseAggregationHeader(rtpPayload []byte) AggregationHeader {
+	var h AggregationHeader
+	_ = (&h).Unmarshal(rtpPayload[AggregationHeaderByteIndex:])
+	return h
+}
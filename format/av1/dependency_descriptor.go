@@ -0,0 +1,516 @@
+package av1
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+// DependencyDescriptorURI is the URI of the Dependency Descriptor RTP header extension, Appendix A
+const DependencyDescriptorURI = "https://aomediacodec.github.io/av1-rtp-spec/#dependency-descriptor-rtp-header-extension"
+
+const (
+	// A.2: start_of_frame, end_of_frame, frame_dependency_template_id and frame_number
+	dependencyDescriptorMandatorySize = 3
+
+	maxTemplateID         = 63
+	maxDecodeTargets      = 32
+	maxTemplateFdiff      = 16
+	maxFrameFdiff         = 1 << 12
+	maxChainFdiff         = 0xff
+	maxTemplateChainFdiff = 0x0f
+)
+
+// DecodeTargetIndication tells how a frame is used by a decode target, A.8.3
+type DecodeTargetIndication uint8
+
+const (
+	// the frame is not associated with the decode target
+	DecodeTargetNotPresent DecodeTargetIndication = iota
+	// the frame is not used by the following frames of the decode target
+	DecodeTargetDiscardable
+	// the decode target can be switched to at this frame
+	DecodeTargetSwitch
+	// the frame is needed to decode the decode target
+	DecodeTargetRequired
+)
+
+func (dti DecodeTargetIndication) String() string {
+	switch dti {
+	case DecodeTargetNotPresent:
+		return "-"
+	case DecodeTargetDiscardable:
+		return "D"
+	case DecodeTargetSwitch:
+		return "S"
+	default:
+		return "R"
+	}
+}
+
+// Resolution is the maximum render resolution of the frames of a spatial layer
+type Resolution struct {
+	Width  int
+	Height int
+}
+
+// FrameDependencyTemplate describes the layer and the dependencies of a frame
+type FrameDependencyTemplate struct {
+	SpatialID  int
+	TemporalID int
+	// DecodeTargetIndications holds the indication of each decode target
+	DecodeTargetIndications []DecodeTargetIndication
+	// FrameDiffs holds the frame number differences to the frames it references
+	FrameDiffs []int
+	// ChainDiffs holds, for each chain, the frame number difference to the previous frame in the chain
+	ChainDiffs []int
+}
+
+// FrameDependencyStructure represents the template dependency structure, A.2 and A.4
+type FrameDependencyStructure struct {
+	TemplateIDOffset  int
+	DecodeTargetCount int
+	// Templates are ordered by spatial layer, then temporal layer
+	Templates []FrameDependencyTemplate
+	// ChainCount is the number of chains, num_chains; each template has ChainCount ChainDiffs
+	ChainCount int
+	// DecodeTargetProtectedBy holds the chain protecting each decode target, if ChainCount is not 0
+	DecodeTargetProtectedBy []int
+	// Resolutions holds the resolution of each spatial layer, or is empty
+	Resolutions []Resolution
+}
+
+// DecodeTargetLayer is the highest layer a decode target includes
+type DecodeTargetLayer struct {
+	SpatialID  int
+	TemporalID int
+}
+
+// DecodeTargetLayers returns the highest layer of each decode target, decode_target_layers() of A.4
+func (s FrameDependencyStructure) DecodeTargetLayers() []DecodeTargetLayer {
+	layers := make([]DecodeTargetLayer, s.DecodeTargetCount)
+	for dt := range layers {
+		for _, t := range s.Templates {
+			if dt < len(t.DecodeTargetIndications) && t.DecodeTargetIndications[dt] != DecodeTargetNotPresent {
+				if t.SpatialID > layers[dt].SpatialID {
+					layers[dt].SpatialID = t.SpatialID
+				}
+				if t.TemporalID > layers[dt].TemporalID {
+					layers[dt].TemporalID = t.TemporalID
+				}
+			}
+		}
+	}
+	return layers
+}
+
+func (s FrameDependencyStructure) maxSpatialID() int {
+	if len(s.Templates) == 0 {
+		return 0
+	}
+	return s.Templates[len(s.Templates)-1].SpatialID
+}
+
+func (s *FrameDependencyStructure) decode(r *bitstream.Reader) error {
+	*s = FrameDependencyStructure{
+		TemplateIDOffset:  int(r.ReadBits(6)),
+		DecodeTargetCount: int(r.ReadBits(5)) + 1,
+	}
+
+	// template_layers()
+	spatialID, temporalID := 0, 0
+	for {
+		s.Templates = append(s.Templates, FrameDependencyTemplate{SpatialID: spatialID, TemporalID: temporalID})
+		if r.Err() != nil {
+			return r.Err()
+		}
+		if len(s.Templates) > maxTemplateID+1 {
+			return fmt.Errorf("more than %d templates", maxTemplateID+1)
+		}
+		next := r.ReadBits(2)
+		if next == 3 {
+			break
+		}
+		if next == 1 {
+			temporalID++
+		} else if next == 2 {
+			temporalID = 0
+			spatialID++
+		}
+	}
+	// template_dtis()
+	for i := range s.Templates {
+		t := &s.Templates[i]
+		for dt := 0; dt < s.DecodeTargetCount; dt++ {
+			t.DecodeTargetIndications = append(t.DecodeTargetIndications, DecodeTargetIndication(r.ReadBits(2)))
+		}
+	}
+	// template_fdiffs()
+	for i := range s.Templates {
+		t := &s.Templates[i]
+		for r.ReadFlag() {
+			t.FrameDiffs = append(t.FrameDiffs, int(r.ReadBits(4))+1)
+		}
+	}
+	// template_chains()
+	s.ChainCount = readNonSymmetric(r, s.DecodeTargetCount+1)
+	if s.ChainCount > 0 {
+		for dt := 0; dt < s.DecodeTargetCount; dt++ {
+			s.DecodeTargetProtectedBy = append(s.DecodeTargetProtectedBy, readNonSymmetric(r, s.ChainCount))
+		}
+		for i := range s.Templates {
+			t := &s.Templates[i]
+			for c := 0; c < s.ChainCount; c++ {
+				t.ChainDiffs = append(t.ChainDiffs, int(r.ReadBits(4)))
+			}
+		}
+	}
+	// render_resolutions()
+	if r.ReadFlag() {
+		for sid := 0; sid <= s.maxSpatialID(); sid++ {
+			s.Resolutions = append(s.Resolutions, Resolution{
+				Width:  int(r.ReadBits(16)) + 1,
+				Height: int(r.ReadBits(16)) + 1,
+			})
+		}
+	}
+	return r.Err()
+}
+
+func (s FrameDependencyStructure) encode(w *bitstream.Writer) error {
+	if s.TemplateIDOffset < 0 || s.TemplateIDOffset > maxTemplateID {
+		return fmt.Errorf("invalid template_id_offset %d", s.TemplateIDOffset)
+	}
+	if s.DecodeTargetCount < 1 || s.DecodeTargetCount > maxDecodeTargets {
+		return fmt.Errorf("invalid decode target count %d", s.DecodeTargetCount)
+	}
+	if len(s.Templates) == 0 || len(s.Templates) > maxTemplateID+1 {
+		return fmt.Errorf("invalid template count %d", len(s.Templates))
+	}
+	if s.ChainCount < 0 || s.ChainCount > s.DecodeTargetCount ||
+		(s.ChainCount > 0 && len(s.DecodeTargetProtectedBy) != s.DecodeTargetCount) {
+		return fmt.Errorf("invalid chain count %d", s.ChainCount)
+	}
+	if len(s.Resolutions) > 0 && len(s.Resolutions) != s.maxSpatialID()+1 {
+		return fmt.Errorf("%d resolutions for %d spatial layers", len(s.Resolutions), s.maxSpatialID()+1)
+	}
+	w.WriteBits(uint64(s.TemplateIDOffset), 6)
+	w.WriteBits(uint64(s.DecodeTargetCount-1), 5)
+
+	// template_layers()
+	if s.Templates[0].SpatialID != 0 || s.Templates[0].TemporalID != 0 {
+		return fmt.Errorf("the first template shall be of spatial and temporal layer 0")
+	}
+	for i := 1; i <= len(s.Templates); i++ {
+		if i == len(s.Templates) {
+			w.WriteBits(3, 2)
+			break
+		}
+		prev, t := s.Templates[i-1], s.Templates[i]
+		switch {
+		case t.SpatialID == prev.SpatialID && t.TemporalID == prev.TemporalID:
+			w.WriteBits(0, 2)
+		case t.SpatialID == prev.SpatialID && t.TemporalID == prev.TemporalID+1:
+			w.WriteBits(1, 2)
+		case t.SpatialID == prev.SpatialID+1 && t.TemporalID == 0:
+			w.WriteBits(2, 2)
+		default:
+			return fmt.Errorf("template %d is not in layer order", i)
+		}
+	}
+	// template_dtis()
+	for i, t := range s.Templates {
+		if len(t.DecodeTargetIndications) != s.DecodeTargetCount {
+			return fmt.Errorf("template %d has %d decode target indications, want %d", i, len(t.DecodeTargetIndications), s.DecodeTargetCount)
+		}
+		for _, dti := range t.DecodeTargetIndications {
+			w.WriteBits(uint64(dti), 2)
+		}
+	}
+	// template_fdiffs()
+	for i, t := range s.Templates {
+		for _, fdiff := range t.FrameDiffs {
+			if fdiff < 1 || fdiff > maxTemplateFdiff {
+				return fmt.Errorf("template %d frame diff %d out of range", i, fdiff)
+			}
+			w.WriteFlag(true)
+			w.WriteBits(uint64(fdiff-1), 4)
+		}
+		w.WriteFlag(false)
+	}
+	// template_chains()
+	writeNonSymmetric(w, s.ChainCount, s.DecodeTargetCount+1)
+	if s.ChainCount > 0 {
+		for _, c := range s.DecodeTargetProtectedBy {
+			if c < 0 || c >= s.ChainCount {
+				return fmt.Errorf("invalid protecting chain %d", c)
+			}
+			writeNonSymmetric(w, c, s.ChainCount)
+		}
+		for i, t := range s.Templates {
+			if len(t.ChainDiffs) != s.ChainCount {
+				return fmt.Errorf("template %d has %d chain diffs, want %d", i, len(t.ChainDiffs), s.ChainCount)
+			}
+			for _, diff := range t.ChainDiffs {
+				if diff < 0 || diff > maxTemplateChainFdiff {
+					return fmt.Errorf("template %d chain diff %d out of range", i, diff)
+				}
+				w.WriteBits(uint64(diff), 4)
+			}
+		}
+	}
+	// render_resolutions()
+	w.WriteFlag(len(s.Resolutions) > 0)
+	for _, res := range s.Resolutions {
+		w.WriteBits(uint64(res.Width-1), 16)
+		w.WriteBits(uint64(res.Height-1), 16)
+	}
+	return nil
+}
+
+// DependencyDescriptor represents the Dependency Descriptor RTP header extension, Appendix A
+type DependencyDescriptor struct {
+	StartOfFrame bool
+	EndOfFrame   bool
+	TemplateID   int
+	FrameNumber  uint16
+
+	// AttachedStructure is the template dependency structure carried by this descriptor, if any
+	AttachedStructure *FrameDependencyStructure
+	// ActiveDecodeTargetsBitmask has a bit set for each decode target the sender still produces;
+	// nil when absent, in which case it is unchanged, or all the decode targets with an attached structure
+	ActiveDecodeTargetsBitmask *uint32
+
+	// CustomDecodeTargetIndications, CustomFrameDiffs and CustomChains are set
+	// when the FrameDependencies do not come from the template
+	CustomDecodeTargetIndications bool
+	CustomFrameDiffs              bool
+	CustomChains                  bool
+
+	// FrameDependencies are the layer and dependencies of the frame, from its template or custom
+	FrameDependencies FrameDependencyTemplate
+	// Resolution is the resolution of the spatial layer of the frame, if the structure has resolutions
+	Resolution *Resolution
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the DependencyDescriptor this method is called upon
+// The frame dependencies are resolved with the structure attached to the descriptor, if any,
+// or with the structure passed, the last one received
+func (d *DependencyDescriptor) Unmarshal(buf []byte, structure *FrameDependencyStructure) error {
+	if len(buf) < dependencyDescriptorMandatorySize {
+		return fmt.Errorf("buf is not large enough to container dependency descriptor")
+	}
+	*d = DependencyDescriptor{}
+	r := bitstream.NewReader(buf)
+	d.StartOfFrame = r.ReadFlag()
+	d.EndOfFrame = r.ReadFlag()
+	d.TemplateID = int(r.ReadBits(6))
+	d.FrameNumber = r.ReadUint16(16)
+
+	if len(buf) > dependencyDescriptorMandatorySize {
+		// extended_descriptor_fields()
+		structurePresent := r.ReadFlag()
+		activeDecodeTargetsPresent := r.ReadFlag()
+		d.CustomDecodeTargetIndications = r.ReadFlag()
+		d.CustomFrameDiffs = r.ReadFlag()
+		d.CustomChains = r.ReadFlag()
+		if structurePresent {
+			d.AttachedStructure = &FrameDependencyStructure{}
+			if err := d.AttachedStructure.decode(r); err != nil {
+				return fmt.Errorf("invalid template dependency structure: %v", err)
+			}
+			structure = d.AttachedStructure
+		}
+		if activeDecodeTargetsPresent {
+			if structure == nil {
+				return fmt.Errorf("active decode targets without template dependency structure")
+			}
+			mask := r.ReadUint32(structure.DecodeTargetCount)
+			d.ActiveDecodeTargetsBitmask = &mask
+		}
+	}
+	if structure == nil {
+		return fmt.Errorf("dependency descriptor without template dependency structure")
+	}
+
+	// frame_dependency_definition()
+	index := (d.TemplateID + maxTemplateID + 1 - structure.TemplateIDOffset) % (maxTemplateID + 1)
+	if index >= len(structure.Templates) {
+		return fmt.Errorf("unknown frame dependency template %d", d.TemplateID)
+	}
+	t := structure.Templates[index]
+	d.FrameDependencies = FrameDependencyTemplate{
+		SpatialID:               t.SpatialID,
+		TemporalID:              t.TemporalID,
+		DecodeTargetIndications: t.DecodeTargetIndications,
+		FrameDiffs:              t.FrameDiffs,
+		ChainDiffs:              t.ChainDiffs,
+	}
+	if d.CustomDecodeTargetIndications {
+		d.FrameDependencies.DecodeTargetIndications = nil
+		for dt := 0; dt < structure.DecodeTargetCount; dt++ {
+			d.FrameDependencies.DecodeTargetIndications = append(d.FrameDependencies.DecodeTargetIndications, DecodeTargetIndication(r.ReadBits(2)))
+		}
+	}
+	if d.CustomFrameDiffs {
+		d.FrameDependencies.FrameDiffs = nil
+		for {
+			size := int(r.ReadBits(2))
+			if size == 0 || r.Err() != nil {
+				break
+			}
+			d.FrameDependencies.FrameDiffs = append(d.FrameDependencies.FrameDiffs, int(r.ReadBits(4*size))+1)
+		}
+	}
+	if d.CustomChains {
+		d.FrameDependencies.ChainDiffs = nil
+		for c := 0; c < structure.ChainCount; c++ {
+			d.FrameDependencies.ChainDiffs = append(d.FrameDependencies.ChainDiffs, int(r.ReadBits(8)))
+		}
+	}
+	if r.Err() != nil {
+		return fmt.Errorf("buf is not large enough to container dependency descriptor")
+	}
+	if len(structure.Resolutions) > t.SpatialID {
+		res := structure.Resolutions[t.SpatialID]
+		d.Resolution = &res
+	}
+	return nil
+}
+
+// Marshal serializes the descriptor into bytes, with the structure its frame dependencies refer to
+func (d DependencyDescriptor) Marshal(structure *FrameDependencyStructure) ([]byte, error) {
+	if d.AttachedStructure != nil {
+		structure = d.AttachedStructure
+	}
+	if structure == nil {
+		return nil, fmt.Errorf("dependency descriptor without template dependency structure")
+	}
+	if d.TemplateID < 0 || d.TemplateID > maxTemplateID {
+		return nil, fmt.Errorf("invalid frame dependency template %d", d.TemplateID)
+	}
+	index := (d.TemplateID + maxTemplateID + 1 - structure.TemplateIDOffset) % (maxTemplateID + 1)
+	if index >= len(structure.Templates) {
+		return nil, fmt.Errorf("unknown frame dependency template %d", d.TemplateID)
+	}
+
+	w := bitstream.NewWriter()
+	w.WriteFlag(d.StartOfFrame)
+	w.WriteFlag(d.EndOfFrame)
+	w.WriteBits(uint64(d.TemplateID), 6)
+	w.WriteBits(uint64(d.FrameNumber), 16)
+
+	extended := d.AttachedStructure != nil || d.ActiveDecodeTargetsBitmask != nil ||
+		d.CustomDecodeTargetIndications || d.CustomFrameDiffs || d.CustomChains
+	if !extended {
+		return w.Bytes(), nil
+	}
+	w.WriteFlag(d.AttachedStructure != nil)
+	w.WriteFlag(d.ActiveDecodeTargetsBitmask != nil)
+	w.WriteFlag(d.CustomDecodeTargetIndications)
+	w.WriteFlag(d.CustomFrameDiffs)
+	w.WriteFlag(d.CustomChains)
+	if d.AttachedStructure != nil {
+		if err := d.AttachedStructure.encode(w); err != nil {
+			return nil, err
+		}
+	}
+	if d.ActiveDecodeTargetsBitmask != nil {
+		w.WriteBits(uint64(*d.ActiveDecodeTargetsBitmask), structure.DecodeTargetCount)
+	}
+
+	deps := d.FrameDependencies
+	if d.CustomDecodeTargetIndications {
+		if len(deps.DecodeTargetIndications) != structure.DecodeTargetCount {
+			return nil, fmt.Errorf("%d decode target indications, want %d", len(deps.DecodeTargetIndications), structure.DecodeTargetCount)
+		}
+		for _, dti := range deps.DecodeTargetIndications {
+			w.WriteBits(uint64(dti), 2)
+		}
+	}
+	if d.CustomFrameDiffs {
+		for _, fdiff := range deps.FrameDiffs {
+			if fdiff < 1 || fdiff > maxFrameFdiff {
+				return nil, fmt.Errorf("frame diff %d out of range", fdiff)
+			}
+			size := 1
+			for fdiff-1 >= 1<<uint(4*size) {
+				size++
+			}
+			w.WriteBits(uint64(size), 2)
+			w.WriteBits(uint64(fdiff-1), 4*size)
+		}
+		w.WriteBits(0, 2)
+	}
+	if d.CustomChains {
+		if len(deps.ChainDiffs) != structure.ChainCount {
+			return nil, fmt.Errorf("%d chain diffs, want %d", len(deps.ChainDiffs), structure.ChainCount)
+		}
+		for _, diff := range deps.ChainDiffs {
+			if diff < 0 || diff > maxChainFdiff {
+				return nil, fmt.Errorf("chain diff %d out of range", diff)
+			}
+			w.WriteBits(uint64(diff), 8)
+		}
+	}
+	// zero_padding
+	w.ByteAlign()
+	return w.Bytes(), nil
+}
+
+// DecodeTargetIndication returns the indication of the frame for the decode target
+func (d DependencyDescriptor) DecodeTargetIndication(decodeTarget int) DecodeTargetIndication {
+	dtis := d.FrameDependencies.DecodeTargetIndications
+	if decodeTarget < 0 || decodeTarget >= len(dtis) {
+		return DecodeTargetNotPresent
+	}
+	return dtis[decodeTarget]
+}
+
+// String helps with debugging by printing DependencyDescriptor information in a readable way
+func (d DependencyDescriptor) String() string {
+	out := "AV1 DependencyDescriptor:\n"
+
+	out += fmt.Sprintf("\tStartOfFrame: %v EndOfFrame: %v\n", d.StartOfFrame, d.EndOfFrame)
+	out += fmt.Sprintf("\tTemplateID: %d FrameNumber: %d\n", d.TemplateID, d.FrameNumber)
+	out += fmt.Sprintf("\tSpatialID: %d TemporalID: %d\n", d.FrameDependencies.SpatialID, d.FrameDependencies.TemporalID)
+	out += fmt.Sprintf("\tDecodeTargetIndications: %v\n", d.FrameDependencies.DecodeTargetIndications)
+	out += fmt.Sprintf("\tFrameDiffs: %v\n", d.FrameDependencies.FrameDiffs)
+	out += fmt.Sprintf("\tChainDiffs: %v\n", d.FrameDependencies.ChainDiffs)
+	if d.AttachedStructure != nil {
+		out += fmt.Sprintf("\tAttachedStructure: %d templates, %d decode targets\n", len(d.AttachedStructure.Templates), d.AttachedStructure.DecodeTargetCount)
+	}
+	if d.ActiveDecodeTargetsBitmask != nil {
+		out += fmt.Sprintf("\tActiveDecodeTargetsBitmask: %b\n", *d.ActiveDecodeTargetsBitmask)
+	}
+
+	return out
+}
+
+// readNonSymmetric reads ns(n), a value in [0, n) with a non-symmetric unsigned encoding, 4.10.7 in the AV1 specification
+func readNonSymmetric(r *bitstream.Reader, n int) int {
+	w := 0
+	for x := n; x != 0; x >>= 1 {
+		w++
+	}
+	m := 1<<uint(w) - n
+	v := int(r.ReadBits(w - 1))
+	if v < m {
+		return v
+	}
+	return v<<1 - m + int(r.ReadBits(1))
+}
+
+func writeNonSymmetric(w *bitstream.Writer, v, n int) {
+	bits := 0
+	for x := n; x != 0; x >>= 1 {
+		bits++
+	}
+	m := 1<<uint(bits) - n
+	if v < m {
+		w.WriteBits(uint64(v), bits-1)
+		return
+	}
+	w.WriteBits(uint64(v+m)>>1, bits-1)
+	w.WriteBits(uint64(v+m)&1, 1)
+}
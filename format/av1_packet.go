@@ -0,0 +1,185 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+
+	av1_codec "github.com/searKing/rtp/codecs/av1"
+	"github.com/searKing/rtp/format/av1"
+)
+
+// AV1Payloader payloads AV1 temporal units, see the RTP Payload Format For AV1
+type AV1Payloader struct{}
+
+// Payload fragments an AV1 temporal unit across one or more byte arrays
+// The temporal unit is a sequence of OBUs in the low overhead bitstream format, each with obu_size.
+// Temporal delimiter, tile list and padding OBUs are dropped, and obu_size is removed from the others;
+// OBUs that fit are aggregated into one packet, larger ones are fragmented, see section 4.4.
+// N is set on the first packet of a temporal unit holding a sequence header, the start of a coded video sequence
+func (p *AV1Payloader) Payload(mtu int, payload []byte) [][]byte {
+	var payloads [][]byte
+	if payload == nil || mtu <= 0 {
+		return payloads
+	}
+	obus, err := av1_codec.ParseObus(payload)
+	if err != nil {
+		return payloads
+	}
+
+	var elements [][]byte
+	newSequence := false
+	for _, obu := range obus {
+		switch obu.Header.Type {
+		case av1_codec.ObuTypeTemporalDelimiter, av1_codec.ObuTypeTileList, av1_codec.ObuTypePadding:
+			continue
+		case av1_codec.ObuTypeSequenceHeader:
+			newSequence = true
+		}
+		obu.Header.HasSizeField = false
+		element, err := obu.Marshal()
+		if err != nil {
+			return payloads
+		}
+		elements = append(elements, element)
+	}
+
+	var header av1.AggregationHeader
+	var pending [][]byte
+	flush := func() {
+		header.N = newSequence && len(payloads) == 0
+		if len(pending) <= av1.MaxCountedObuElements {
+			header.W = uint8(len(pending))
+		}
+		out := []byte{header.Byte()}
+		for i, element := range pending {
+			if header.W == 0 || i < len(pending)-1 {
+				out = av1_codec.AppendLEB128(out, uint64(len(element)))
+			}
+			out = append(out, element...)
+		}
+		payloads = append(payloads, out)
+		// the next packet continues the last element if it was fragmented
+		header = av1.AggregationHeader{Z: header.Y}
+		pending = nil
+	}
+
+	room := mtu - 1
+	for _, element := range elements {
+		for len(element) > 0 {
+			if size := av1_codec.LEB128Size(uint64(len(element))) + len(element); size <= room {
+				pending = append(pending, element)
+				room -= size
+				break
+			}
+			// fragment: the largest part fitting with its length field
+			n := room - av1_codec.LEB128Size(uint64(room))
+			for n > 0 && av1_codec.LEB128Size(uint64(n))+n > room {
+				n--
+			}
+			if n <= 0 {
+				if len(pending) == 0 {
+					// Make sure the fragment/payload size is correct
+					return [][]byte{}
+				}
+				flush()
+				room = mtu - 1
+				continue
+			}
+			pending = append(pending, element[:n])
+			element = element[n:]
+			header.Y = true
+			flush()
+			room = mtu - 1
+		}
+	}
+	if len(pending) > 0 {
+		flush()
+	}
+	return payloads
+}
+
+// AV1Packet represents the AV1 aggregation header that is stored in the payload of an RTP Packet,
+// and reassembles the OBUs fragmented across packets
+type AV1Packet struct {
+	// the aggregation header of the last packet
+	av1.AggregationHeader
+
+	// OBUs holds the OBUs completed by the last packet, with obu_size
+	OBUs [][]byte
+
+	Payload []byte
+
+	fragment []byte
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the AV1Packet this method is called upon
+// It returns the OBUs completed by this packet, with obu_size, concatenated in the low overhead bitstream format;
+// the fragment of an OBU is buffered until the OBU is complete.  Fragments whose previous or next part
+// was not received are dropped, and so are temporal delimiter OBUs
+func (p *AV1Packet) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) < 2 {
+		return nil, fmt.Errorf("Payload is not large enough to container header")
+	}
+	p.OBUs = nil
+	p.Payload = nil
+	if err := (&p.AggregationHeader).Unmarshal(packet); err != nil {
+		return nil, err
+	}
+
+	var elements [][]byte
+	for data := packet[1:]; len(data) > 0; {
+		if p.W != 0 && len(elements) == int(p.W)-1 {
+			elements = append(elements, data)
+			break
+		}
+		size, n, err := av1_codec.ReadLEB128(data)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(data)-n) < size {
+			return nil, fmt.Errorf("OBU element size %d exceeds remaining %d bytes", size, len(data)-n)
+		}
+		elements = append(elements, data[n:n+int(size)])
+		data = data[n+int(size):]
+	}
+	if p.W != 0 && len(elements) != int(p.W) {
+		return nil, fmt.Errorf("%d OBU elements, W is %d", len(elements), p.W)
+	}
+
+	fragment := p.fragment
+	p.fragment = nil
+	for i, element := range elements {
+		if i == 0 && p.Z {
+			if fragment == nil {
+				// the start of the OBU was lost
+				continue
+			}
+			element = append(fragment, element...)
+		}
+		if i == len(elements)-1 && p.Y {
+			p.fragment = append([]byte(nil), element...)
+			break
+		}
+		var obu av1_codec.Obu
+		if _, err := (&obu).Unmarshal(element); err != nil {
+			return nil, err
+		}
+		if obu.Header.Type == av1_codec.ObuTypeTemporalDelimiter {
+			continue
+		}
+		obu.Header.HasSizeField = true
+		raw, err := obu.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		p.OBUs = append(p.OBUs, raw)
+	}
+	if len(p.OBUs) == 0 {
+		return nil, nil
+	}
+	p.Payload = bytes.Join(p.OBUs, nil)
+	return p.Payload, nil
+}
@@ -0,0 +1,217 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	av1_codec "github.com/searKing/rtp/codecs/av1"
+	"github.com/searKing/rtp/format/av1"
+)
+
+func av1TemporalUnit(t *testing.T, obus ...av1_codec.Obu) []byte {
+	var tu []byte
+	for _, o := range obus {
+		o.Header.HasSizeField = true
+		raw, err := o.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tu = append(tu, raw...)
+	}
+	return tu
+}
+
+func TestAV1Payloader_Payload(t *testing.T) {
+	pck := AV1Payloader{}
+	sequenceHeader := av1_codec.Obu{Header: av1_codec.ObuHeader{Type: av1_codec.ObuTypeSequenceHeader}, Payload: []byte{0x01, 0x02, 0x03}}
+	frame := av1_codec.Obu{Header: av1_codec.ObuHeader{Type: av1_codec.ObuTypeFrame}, Payload: bytes.Repeat([]byte{0x42}, 300)}
+	tu := av1TemporalUnit(t,
+		av1_codec.Obu{Header: av1_codec.ObuHeader{Type: av1_codec.ObuTypeTemporalDelimiter}},
+		sequenceHeader,
+		frame,
+	)
+
+	// Nil payload
+	if res := pck.Payload(100, nil); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+	// Invalid OBUs
+	if res := pck.Payload(100, tu[:10]); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+	// MTU too small
+	if res := pck.Payload(2, tu); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+
+	// Aggregated, the temporal delimiter dropped and obu_size removed
+	res := pck.Payload(1200, tu)
+	expected := []byte{0x28, 0x04, 0x08, 0x01, 0x02, 0x03, 0x30}
+	expected = append(expected, frame.Payload...)
+	if len(res) != 1 || !bytes.Equal(res[0], expected) {
+		t.Fatalf("Generated payload should aggregate the OBUs, got %x", res)
+	}
+
+	// Fragmented
+	res = pck.Payload(100, tu)
+	if len(res) != 4 {
+		t.Fatalf("Generated payload should be 4 packets, got %d", len(res))
+	}
+	for i, packet := range res {
+		if len(packet) > 100 {
+			t.Fatalf("Packet %d exceeds the MTU", i)
+		}
+		h := av1.ParseAggregationHeader(packet)
+		if h.N != (i == 0) || h.Z != (i > 0) || h.Y != (i < len(res)-1) {
+			t.Fatalf("Packet %d has an invalid aggregation header %s", i, h)
+		}
+	}
+
+	// the depacketizer gets the OBUs back, with obu_size
+	depacketizer := AV1Packet{}
+	var out []byte
+	for _, packet := range res {
+		raw, err := depacketizer.Unmarshal(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, raw...)
+	}
+	if !bytes.Equal(out, av1TemporalUnit(t, sequenceHeader, frame)) {
+		t.Fatal("Unmarshal should reassemble the OBUs")
+	}
+}
+
+func TestAV1Packet_Unmarshal(t *testing.T) {
+	pck := AV1Packet{}
+
+	// Nil packet
+	if raw, err := pck.Unmarshal(nil); raw != nil || err == nil {
+		t.Fatal("Unmarshal did not fail on nil payload")
+	}
+	// Aggregation header only
+	if raw, err := pck.Unmarshal([]byte{0x00}); raw != nil || err == nil {
+		t.Fatal("Unmarshal did not fail on empty payload")
+	}
+	// Element size exceeding the packet
+	if raw, err := pck.Unmarshal([]byte{0x00, 0x05, 0x30, 0x01}); raw != nil || err == nil {
+		t.Fatal("Unmarshal accepted a truncated OBU element")
+	}
+	// W not matching the elements
+	if raw, err := pck.Unmarshal([]byte{0x30, 0x02, 0x30, 0x01}); raw != nil || err == nil {
+		t.Fatal("Unmarshal accepted fewer elements than W")
+	}
+
+	// W=2: the last element without length field, the temporal delimiter dropped
+	raw, err := pck.Unmarshal([]byte{0x20, 0x01, 0x10, 0x30, 0x01, 0x02})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, []byte{0x32, 0x02, 0x01, 0x02}) || len(pck.OBUs) != 1 || pck.W != 2 {
+		t.Fatalf("Unmarshal should restore obu_size, got %x", raw)
+	}
+
+	// a fragment whose start was lost is dropped
+	raw, err = pck.Unmarshal([]byte{0xa0, 0x01, 0x02, 0x30, 0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, []byte{0x32, 0x01, 0x01}) {
+		t.Fatalf("Unmarshal should drop the continued fragment, got %x", raw)
+	}
+
+	// a fragment whose end was lost is dropped
+	if raw, err = pck.Unmarshal([]byte{0x50, 0x30, 0x01}); raw != nil || err != nil {
+		t.Fatal("Unmarshal should buffer the fragment")
+	}
+	if raw, err = pck.Unmarshal([]byte{0x10, 0x30, 0x05}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, []byte{0x32, 0x01, 0x05}) {
+		t.Fatalf("Unmarshal should drop the unfinished fragment, got %x", raw)
+	}
+}
+
+func TestAV1DependencyDescriptor(t *testing.T) {
+	// L1T2: one spatial layer, two temporal layers, a decode target for each, one chain
+	structure := &av1.FrameDependencyStructure{
+		TemplateIDOffset:  60,
+		DecodeTargetCount: 2,
+		Templates: []av1.FrameDependencyTemplate{
+			{DecodeTargetIndications: []av1.DecodeTargetIndication{av1.DecodeTargetSwitch, av1.DecodeTargetSwitch}, ChainDiffs: []int{0}},
+			{DecodeTargetIndications: []av1.DecodeTargetIndication{av1.DecodeTargetSwitch, av1.DecodeTargetSwitch}, FrameDiffs: []int{2}, ChainDiffs: []int{2}},
+			{TemporalID: 1, DecodeTargetIndications: []av1.DecodeTargetIndication{av1.DecodeTargetNotPresent, av1.DecodeTargetDiscardable}, FrameDiffs: []int{1}, ChainDiffs: []int{1}},
+		},
+		ChainCount:              1,
+		DecodeTargetProtectedBy: []int{0, 0},
+		Resolutions:             []av1.Resolution{{Width: 1280, Height: 720}},
+	}
+	if layers := structure.DecodeTargetLayers(); len(layers) != 2 || layers[0].TemporalID != 0 || layers[1].TemporalID != 1 {
+		t.Fatalf("DecodeTargetLayers should be T0 and T1, got %v", layers)
+	}
+
+	// key frame with the attached structure
+	mask := uint32(0x3)
+	keyFrame := av1.DependencyDescriptor{StartOfFrame: true, EndOfFrame: true, TemplateID: 60, FrameNumber: 100,
+		AttachedStructure: structure, ActiveDecodeTargetsBitmask: &mask}
+	raw, err := keyFrame.Marshal(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d av1.DependencyDescriptor
+	if err = d.Unmarshal(raw, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !d.StartOfFrame || !d.EndOfFrame || d.TemplateID != 60 || d.FrameNumber != 100 ||
+		d.ActiveDecodeTargetsBitmask == nil || *d.ActiveDecodeTargetsBitmask != 3 ||
+		d.Resolution == nil || d.Resolution.Width != 1280 || d.Resolution.Height != 720 {
+		t.Fatalf("Unmarshal should decode the key frame descriptor, got %s", d)
+	}
+	got := d.AttachedStructure
+	if got == nil || got.TemplateIDOffset != 60 || got.DecodeTargetCount != 2 || len(got.Templates) != 3 ||
+		got.ChainCount != 1 || got.Templates[2].TemporalID != 1 || got.Templates[1].FrameDiffs[0] != 2 ||
+		got.Templates[2].DecodeTargetIndications[1] != av1.DecodeTargetDiscardable || got.Templates[2].ChainDiffs[0] != 1 {
+		t.Fatalf("Unmarshal should decode the structure, got %+v", got)
+	}
+
+	// T1 frame, with the structure received before
+	raw, err = av1.DependencyDescriptor{StartOfFrame: true, TemplateID: 62, FrameNumber: 101}.Marshal(structure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 3 {
+		t.Fatalf("Marshal should only write the mandatory fields, got %x", raw)
+	}
+	if err = d.Unmarshal(raw, nil); err == nil {
+		t.Fatal("Unmarshal accepted a descriptor without structure")
+	}
+	if err = d.Unmarshal(raw, got); err != nil {
+		t.Fatal(err)
+	}
+	if d.FrameDependencies.TemporalID != 1 || d.DecodeTargetIndication(0) != av1.DecodeTargetNotPresent ||
+		d.DecodeTargetIndication(1) != av1.DecodeTargetDiscardable || d.FrameDependencies.FrameDiffs[0] != 1 {
+		t.Fatalf("Unmarshal should resolve the template, got %s", d)
+	}
+
+	// custom frame diffs and chains
+	custom := av1.DependencyDescriptor{TemplateID: 61, FrameNumber: 102, CustomFrameDiffs: true, CustomChains: true,
+		FrameDependencies: av1.FrameDependencyTemplate{FrameDiffs: []int{1, 20, 300}, ChainDiffs: []int{200}}}
+	if raw, err = custom.Marshal(structure); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Unmarshal(raw, structure); err != nil {
+		t.Fatal(err)
+	}
+	if fd := d.FrameDependencies.FrameDiffs; len(fd) != 3 || fd[0] != 1 || fd[1] != 20 || fd[2] != 300 ||
+		d.FrameDependencies.ChainDiffs[0] != 200 || d.DecodeTargetIndication(0) != av1.DecodeTargetSwitch {
+		t.Fatalf("Unmarshal should decode the custom dependencies, got %s", d)
+	}
+
+	// unknown template
+	if _, err = (av1.DependencyDescriptor{TemplateID: 10}).Marshal(structure); err == nil {
+		t.Fatal("Marshal accepted an unknown template")
+	}
+	if err = d.Unmarshal([]byte{0x0a, 0x00, 0x00}, structure); err == nil {
+		t.Fatal("Unmarshal accepted an unknown template")
+	}
+}
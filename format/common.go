@@ -1,8 +1,24 @@
 package format
 
+import "strings"
+
 func min(a, b int) int {
 	if a < b {
 		return a
 	}
 	return b
 }
+
+// parseFmtp splits the parameters of an SDP fmtp line, such as
+// "profile-level-id=1;mode=AAC-hbr;sizelength=13", into a map keyed by lower-case name
+func parseFmtp(fmtp string) map[string]string {
+	params := make(map[string]string)
+	for _, param := range strings.Split(fmtp, ";") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		params[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.TrimSpace(kv[1])
+	}
+	return params
+}
@@ -0,0 +1,38 @@
+package format
+
+import "fmt"
+
+// G711Payloader payloads G.711 µ-law and A-law samples, PCMU and PCMA, see rfc3551#section-4.5.14
+// A sample is a byte, at 8 kHz.  Packetize stamps every packet of a payload with the same timestamp,
+// so each call takes the samples of a single packet
+type G711Payloader struct {
+	err error
+}
+
+// Err returns the error of the last call to Payload, a payload larger than the MTU
+func (p *G711Payloader) Err() error {
+	return p.err
+}
+
+// Payload packs G.711 samples in a single byte array
+// Payloads larger than the MTU result in no packets, Err returns why
+func (p *G711Payloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	p.err = nil
+	if payload == nil || mtu <= 0 {
+		return out
+	}
+	if len(payload) > mtu {
+		p.err = fmt.Errorf("payload of %d bytes is larger than the MTU %d", len(payload), mtu)
+		return out
+	}
+
+	o := make([]byte, len(payload))
+	copy(o, payload)
+	return append(out, o)
+}
+
+// Samples returns the number of samples in the payload, at the given clock rate
+func (p *G711Payloader) Samples(clockRate uint32, payload []byte) uint32 {
+	return uint32(uint64(len(payload)) * uint64(clockRate) / 8000)
+}
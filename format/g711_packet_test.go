@@ -0,0 +1,33 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestG711Payloader_Payload(t *testing.T) {
+	pck := G711Payloader{}
+	payload := make([]byte, 160)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	if res := pck.Payload(100, nil); len(res) != 0 || pck.Err() != nil {
+		t.Fatal("Generated payload should be empty for a nil payload")
+	}
+	res := pck.Payload(200, payload)
+	if len(res) != 1 || !bytes.Equal(res[0], payload) || pck.Err() != nil {
+		t.Fatal("Generated payload should be a single packet of the samples")
+	}
+	if pck.Samples(8000, payload) != 160 {
+		t.Fatal("Samples should count a sample per byte")
+	}
+
+	// a payload larger than the MTU would stamp several packets with one timestamp
+	if res = pck.Payload(100, payload); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty for more than the MTU")
+	}
+	if res = pck.Payload(160, payload); len(res) != 1 || pck.Err() != nil {
+		t.Fatal("Err should be reset by the next call to Payload")
+	}
+}
@@ -20,3 +20,10 @@ func (p *G722Payloader) Payload(mtu int, payload []byte) [][]byte {
 	copy(o, payload)
 	return append(out, o)
 }
+
+// Samples returns the duration of the payload at the given clock rate
+// G.722 samples at 16 kHz, 4 bits per sample, but its RTP clock rate is 8 kHz for historical reasons,
+// see rfc3551#section-4.5.2: each byte lasts one tick of the 8 kHz clock
+func (p *G722Payloader) Samples(clockRate uint32, payload []byte) uint32 {
+	return uint32(uint64(len(payload)) * uint64(clockRate) / 8000)
+}
@@ -0,0 +1,117 @@
+package h263
+
+import "fmt"
+
+// The payload header, 5.1 in rfc4629
+//
+//	0                   1
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|   RR    |P|V|   PLEN    |PEBIT|
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	PayloadHeaderSize         = 2
+	VideoRedundancyCodingSize = 1
+
+	PayloadHeaderRRMask    = 0xf800
+	PayloadHeaderPMask     = 0x0400
+	PayloadHeaderVMask     = 0x0200
+	PayloadHeaderPLENMask  = 0x01f8
+	PayloadHeaderPLENShift = 3
+	PayloadHeaderPEBITMask = 0x0007
+	PayloadHeaderPLENMax   = 0x3f
+)
+
+// PayloadHeader represents the payload header that starts the payload of an RTP Packet
+type PayloadHeader struct {
+	RR uint8
+	// P is set when the packet starts with a picture, GOB, slice or EOS start code, whose two zero bytes are omitted
+	P bool
+	// V is set when the VRC byte follows, for Video Redundancy Coding
+	V bool
+	// VRC is the Video Redundancy Coding byte
+	VRC uint8
+	// ExtraPictureHeader is a copy of the picture header, PLEN bytes, whose PEBIT last bits are to be ignored
+	ExtraPictureHeader []byte
+	PEBIT              uint8
+}
+
+// MarshalSize returns the size of the header once serialized
+func (h PayloadHeader) MarshalSize() int {
+	size := PayloadHeaderSize + len(h.ExtraPictureHeader)
+	if h.V {
+		size += VideoRedundancyCodingSize
+	}
+	return size
+}
+
+// Marshal serializes the header into bytes.
+func (h PayloadHeader) Marshal() ([]byte, error) {
+	if len(h.ExtraPictureHeader) > PayloadHeaderPLENMax {
+		return nil, fmt.Errorf("extra picture header of %d bytes exceeds %d", len(h.ExtraPictureHeader), PayloadHeaderPLENMax)
+	}
+	v := uint16(h.RR)<<11&PayloadHeaderRRMask | uint16(len(h.ExtraPictureHeader))<<PayloadHeaderPLENShift | uint16(h.PEBIT)&PayloadHeaderPEBITMask
+	if h.P {
+		v |= PayloadHeaderPMask
+	}
+	if h.V {
+		v |= PayloadHeaderVMask
+	}
+	buf := make([]byte, 0, h.MarshalSize())
+	buf = append(buf, byte(v>>8), byte(v))
+	if h.V {
+		buf = append(buf, h.VRC)
+	}
+	return append(buf, h.ExtraPictureHeader...), nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the PayloadHeader this method is called upon,
+// it returns the number of bytes read
+func (h *PayloadHeader) Unmarshal(buf []byte) (int, error) {
+	if len(buf) < PayloadHeaderSize {
+		return 0, fmt.Errorf("buf is not large enough to container payload header")
+	}
+	v := uint16(buf[0])<<8 | uint16(buf[1])
+	h.RR = uint8((v & PayloadHeaderRRMask) >> 11)
+	h.P = v&PayloadHeaderPMask != 0
+	h.V = v&PayloadHeaderVMask != 0
+	plen := int((v & PayloadHeaderPLENMask) >> PayloadHeaderPLENShift)
+	h.PEBIT = uint8(v & PayloadHeaderPEBITMask)
+	n := PayloadHeaderSize
+	h.VRC = 0
+	if h.V {
+		if len(buf) < n+VideoRedundancyCodingSize {
+			return 0, fmt.Errorf("buf is not large enough to container VRC")
+		}
+		h.VRC = buf[n]
+		n += VideoRedundancyCodingSize
+	}
+	if len(buf) < n+plen {
+		return 0, fmt.Errorf("buf is not large enough to container extra picture header of %d bytes", plen)
+	}
+	h.ExtraPictureHeader = nil
+	if plen > 0 {
+		h.ExtraPictureHeader = buf[n : n+plen]
+	}
+	return n + plen, nil
+}
+
+// String helps with debugging by printing PayloadHeader information in a readable way
+func (h PayloadHeader) String() string {
+	out := "H263 PayloadHeader:\n"
+
+	out += fmt.Sprintf("\tRR: %d\n", h.RR)
+	out += fmt.Sprintf("\tP: %v\n", h.P)
+	out += fmt.Sprintf("\tV: %v\n", h.V)
+	out += fmt.Sprintf("\tVRC: %d\n", h.VRC)
+	out += fmt.Sprintf("\tPLEN: %d\n", len(h.ExtraPictureHeader))
+	out += fmt.Sprintf("\tPEBIT: %d\n", h.PEBIT)
+
+	return out
+}
+
+func ParsePayloadHeader(rtpPayload []byte) (PayloadHeader, int, error) {
+	var h PayloadHeader
+	n, err := (&h).Unmarshal(rtpPayload)
+	return h, n, err
+}
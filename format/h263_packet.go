@@ -0,0 +1,105 @@
+package format
+
+import (
+	"fmt"
+
+	h263_codec "github.com/searKing/rtp/codecs/h263"
+	"github.com/searKing/rtp/format/h263"
+)
+
+// H263Payloader payloads H.263 pictures, of H.263-1998 and H.263-2000, see rfc4629#section-6
+// Packets start at picture, GOB or slice start codes when possible: whole GOBs are aggregated
+// as long as they fit, larger ones are split into follow-on packets
+type H263Payloader struct{}
+
+// Payload fragments an H.263 picture across one or more byte arrays
+func (p *H263Payloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	if payload == nil || mtu <= h263.PayloadHeaderSize {
+		return out
+	}
+
+	// split at the byte-aligned start codes
+	var segments [][]byte
+	start := 0
+	for i := 1; i+2 < len(payload); i++ {
+		if h263_codec.IsStartCode(payload[i:]) {
+			segments = append(segments, payload[start:i])
+			start = i
+		}
+	}
+	segments = append(segments, payload[start:])
+
+	var pending []byte
+	flush := func() {
+		if pending != nil {
+			out = append(out, pending)
+		}
+		pending = nil
+	}
+	for _, segment := range segments {
+		if pending != nil && len(pending)+len(segment) <= mtu {
+			pending = append(pending, segment...)
+			continue
+		}
+		flush()
+		// the two zero bytes of the start code are omitted, and signalled by P
+		h := h263.PayloadHeader{P: h263_codec.IsStartCode(segment)}
+		if h.P {
+			segment = segment[h263_codec.StartCodePrefixSize:]
+		}
+		for {
+			o, _ := h.Marshal()
+			n := min(mtu-len(o), len(segment))
+			pending = append(o, segment[:n]...)
+			segment = segment[n:]
+			if len(segment) == 0 {
+				break
+			}
+			flush()
+			h.P = false
+		}
+	}
+	flush()
+	return out
+}
+
+// H263Packet represents the H263 header that is stored in the payload of an RTP Packet
+type H263Packet struct {
+	// the payload header of the last packet
+	h263.PayloadHeader
+
+	// PictureHeader is the picture header of the last packet starting a picture
+	PictureHeader *h263_codec.PictureHeader
+
+	Payload []byte
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the H263Packet this method is called upon
+// It returns the H.263 bitstream of the packet, with the zero bytes of the start code restored
+func (p *H263Packet) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	n, err := (&p.PayloadHeader).Unmarshal(packet)
+	if err != nil {
+		return nil, err
+	}
+	data := packet[n:]
+	p.PictureHeader = nil
+	if p.P {
+		data = append([]byte{0x00, 0x00}, data...)
+		if h263_codec.IsPictureStartCode(data) {
+			if h, err := h263_codec.ParsePictureHeader(data); err == nil {
+				p.PictureHeader = &h
+			}
+		}
+	}
+	p.Payload = data
+	return data, nil
+}
+
+// IsKeyFrame reports whether the last packet starts an intra picture
+func (p *H263Packet) IsKeyFrame() bool {
+	return p.PictureHeader != nil && p.PictureHeader.IsIntra()
+}
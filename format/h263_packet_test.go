@@ -0,0 +1,91 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/searKing/rtp/format/h263"
+)
+
+func TestH263Payloader_Payload(t *testing.T) {
+	// QCIF I picture header, then two GOBs
+	picture := []byte{0x00, 0x00, 0x80, 0x02, 0x08, 0x20, 0x01, 0x02, 0x03}
+	gob1 := []byte{0x00, 0x00, 0x88, 0x11, 0x12, 0x13, 0x14}
+	gob2 := []byte{0x00, 0x00, 0x90, 0x21, 0x22}
+	payload := append(append(append([]byte(nil), picture...), gob1...), gob2...)
+
+	pck := H263Payloader{}
+	if res := pck.Payload(2, payload); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+
+	// everything in a packet, the first start code elided
+	res := pck.Payload(100, payload)
+	if len(res) != 1 || !bytes.Equal(res[0], append([]byte{0x04, 0x00}, payload[2:]...)) {
+		t.Fatalf("Generated payload should be a single packet, got %x", res)
+	}
+
+	// a packet per GOB, the picture split in follow-on packets
+	res = pck.Payload(9, payload)
+	expected := [][]byte{
+		{0x04, 0x00, 0x80, 0x02, 0x08, 0x20, 0x01, 0x02, 0x03},
+		{0x04, 0x00, 0x88, 0x11, 0x12, 0x13, 0x14},
+		{0x04, 0x00, 0x90, 0x21, 0x22},
+	}
+	if len(res) != len(expected) {
+		t.Fatalf("Generated payload should be %d packets, got %x", len(expected), res)
+	}
+	for i := range expected {
+		if !bytes.Equal(res[i], expected[i]) {
+			t.Fatalf("Packet %d should be %x, got %x", i, expected[i], res[i])
+		}
+	}
+	res = pck.Payload(6, payload)
+	if len(res) < 2 || !bytes.Equal(res[1], []byte{0x00, 0x00, 0x01, 0x02, 0x03}) {
+		t.Fatalf("Follow-on packets should be sent without P, got %x", res)
+	}
+
+	// depacketize back to the bitstream
+	depacketizer := H263Packet{}
+	var out []byte
+	for i, r := range res {
+		raw, err := depacketizer.Unmarshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && !depacketizer.IsKeyFrame() {
+			t.Fatal("First packet should start an I picture")
+		}
+		out = append(out, raw...)
+	}
+	if !bytes.Equal(out, payload) {
+		t.Fatalf("Depacketized bitstream should restore the start codes, got %x", out)
+	}
+	if depacketizer.IsKeyFrame() {
+		t.Fatal("Only packets starting a picture carry its header")
+	}
+}
+
+func TestH263Packet_Unmarshal(t *testing.T) {
+	pck := H263Packet{}
+	if _, err := pck.Unmarshal(nil); err == nil {
+		t.Fatal("Unmarshal should reject a nil packet")
+	}
+	if _, err := pck.Unmarshal([]byte{0x04}); err == nil {
+		t.Fatal("Unmarshal should reject a packet smaller than the header")
+	}
+	// VRC and an extra picture header of 2 bytes, PEBIT 3
+	raw, err := pck.Unmarshal([]byte{0x02, 0x13, 0x55, 0xaa, 0xbb, 0x01, 0x02})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pck.V || pck.P || pck.VRC != 0x55 || !bytes.Equal(pck.ExtraPictureHeader, []byte{0xaa, 0xbb}) || pck.PEBIT != 3 || !bytes.Equal(raw, []byte{0x01, 0x02}) {
+		t.Fatalf("Unexpected payload header %s", pck.PayloadHeader)
+	}
+	if _, err = pck.Unmarshal([]byte{0x00, 0x18, 0x01}); err == nil {
+		t.Fatal("Unmarshal should reject a truncated extra picture header")
+	}
+	if b, _ := (h263.PayloadHeader{P: true, V: true, VRC: 1}).Marshal(); !bytes.Equal(b, []byte{0x06, 0x00, 0x01}) {
+		t.Fatalf("Marshal should write P, V and VRC, got %x", b)
+	}
+}
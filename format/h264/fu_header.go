@@ -43,7 +43,7 @@ func (h FuHeader) Marshal() ([]byte, error) {
 		fuHeader |= FuHeaderStartBitMask
 	}
 
-	if h.StartBit {
+	if h.EndBit {
 		fuHeader |= FuHeaderEndBitMask
 	}
 
@@ -1,8 +1,66 @@
 package format
 
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+
+	h264_codec "github.com/searKing/rtp/codecs/h264"
+	"github.com/searKing/rtp/format/h264"
+)
+
 // H264Payloader payloads H264 packets
 type H264Payloader struct {
 	SkipAggregate bool
+	// MaxAggregatedNALUs caps the number of NAL units of a STAP-A; 0 means no limit
+	MaxAggregatedNALUs int
+	// SeparateParameterSets keeps the SPS and PPS out of the STAP-A aggregating the other NAL units
+	SeparateParameterSets bool
+	// SkipAUD, SkipFillerData and SkipSEI drop the access unit delimiters, filler data and SEI NAL units
+	SkipAUD        bool
+	SkipFillerData bool
+	SkipSEI        bool
+	// NALULengthSize is the size of the length prefixing the NAL units when the access units are in the AVCC format
+	// of MP4 and Matroska, LengthSize of their h264.AVCDecoderConfigurationRecord; 0 for the format of Annex B
+	NALULengthSize int
+	// SkipParameterSets disables the repetition of the most recent SPS and PPS before the IDR access units lacking them
+	SkipParameterSets bool
+	// SPSRewriter rewrites the SPS NAL units sent, to patch their VUI or level; nil leaves them as is
+	SPSRewriter *h264_codec.SPSRewriter
+	// SingleNALUnit selects the single NAL unit mode, packetization-mode=0: a packet per NAL unit,
+	// NAL units larger than the MTU are dropped, see rfc6184#section-6.2
+	SingleNALUnit bool
+	// Interleaved selects the interleaved mode, packetization-mode=2: NAL units are sent in STAP-B,
+	// or fragmented in FU-B and FU-A, with their decoding order number, see rfc6184#section-6.4
+	Interleaved bool
+
+	parameterSets parameterSets
+	// the decoding order number of the next NAL unit in the interleaved mode
+	don uint16
+	err error
+}
+
+// UnmarshalFmtp configures the payloader from the parameters of an SDP fmtp line,
+// the out-of-band SPS and PPS of sprop-parameter-sets, such as
+// "packetization-mode=1;profile-level-id=42e01f;sprop-parameter-sets=Z0LgH9oBQBbpUgAAAwACAAADAGQeMGVA,aM4yyA=="
+func (p *H264Payloader) UnmarshalFmtp(fmtp string) error {
+	params := parseFmtp(fmtp)
+	if v, ok := params["packetization-mode"]; ok {
+		mode, err := strconv.Atoi(v)
+		if err != nil || mode < 0 || mode > 2 {
+			return fmt.Errorf("invalid packetization-mode %q", v)
+		}
+		p.SingleNALUnit = mode == 0
+		p.Interleaved = mode == 2
+	}
+	return p.parameterSets.unmarshalSprop(params["sprop-parameter-sets"], true)
+}
+
+// Err returns the error of the last call to Payload, a NAL unit larger than the MTU in the single NAL unit mode
+func (p *H264Payloader) Err() error {
+	return p.err
 }
 
 const (
@@ -18,17 +76,178 @@ const (
 )
 
 // Payload fragments a H264 packet across one or more byte arrays
+// payload is an access unit in the format of Annex B, h264.AccessUnitSplitter splits a byte stream into them,
+// or in the AVCC format with NALULengthSize set
+// The cached SPS and PPS are inserted before the IDR slices of an access unit lacking them, then the SPS are
+// rewritten by SPSRewriter if set
 // ffmpeg/libavformat/rtpenc_h264_hevc.c nal_send
 func (p *H264Payloader) Payload(maxPayloadSize int, payload []byte) [][]byte {
-
+	p.err = nil
 	if payload == nil {
 		return nil
 	}
-	var nalubuffer [][]byte
+	nalus := p.filter(splitNalus(payload, p.NALULengthSize))
+	if !p.SkipParameterSets {
+		nalus = p.parameterSets.inject(nalus, true)
+	}
+	if p.SPSRewriter != nil {
+		nalus = p.rewriteSPS(nalus)
+	}
+	if p.SingleNALUnit {
+		return p.singleNalUnitPacket(maxPayloadSize, nalus)
+	}
+	if p.Interleaved {
+		return p.interleavedPacket(maxPayloadSize, nalus)
+	}
+	return naluPacket(maxPayloadSize, nalus, p.SkipAggregate, p.MaxAggregatedNALUs, p.SeparateParameterSets, true)
+}
+
+// filter drops the NAL units of the types skipped
+func (p *H264Payloader) filter(nalus [][]byte) [][]byte {
+	if !p.SkipAUD && !p.SkipFillerData && !p.SkipSEI {
+		return nalus
+	}
+	filtered := nalus[:0]
+	for _, nalu := range nalus {
+		if len(nalu) == 0 {
+			continue
+		}
+		switch h264_codec.ParseNalUnitType(nalu) {
+		case h264_codec.NalUnitTypeAud:
+			if p.SkipAUD {
+				continue
+			}
+		case h264_codec.NalUnitTypeFillerData:
+			if p.SkipFillerData {
+				continue
+			}
+		case h264_codec.NalUnitTypeSei:
+			if p.SkipSEI {
+				continue
+			}
+		}
+		filtered = append(filtered, nalu)
+	}
+	return filtered
+}
+
+// rewriteSPS rewrites the SPS NAL units with SPSRewriter, leaving the ones which can't be parsed as is
+func (p *H264Payloader) rewriteSPS(nalus [][]byte) [][]byte {
+	rewritten := make([][]byte, 0, len(nalus))
+	for _, nalu := range nalus {
+		if len(nalu) > 0 && h264_codec.ParseNalUnitType(nalu) == h264_codec.NalUnitTypeSps {
+			if raw, err := p.SPSRewriter.Rewrite(nalu); err == nil {
+				nalu = raw
+			}
+		}
+		rewritten = append(rewritten, nalu)
+	}
+	return rewritten
+}
+
+// singleNalUnitPacket sends each NAL unit as is, dropping the ones larger than maxPayloadSize
+func (p *H264Payloader) singleNalUnitPacket(maxPayloadSize int, nalus [][]byte) [][]byte {
+	var packets [][]byte
+	for _, nalu := range nalus {
+		if len(nalu) == 0 {
+			continue
+		}
+		if len(nalu) > maxPayloadSize {
+			p.err = fmt.Errorf("NAL unit of %d bytes exceeds the MTU of %d bytes in the single NAL unit mode", len(nalu), maxPayloadSize)
+			continue
+		}
+		packets = append(packets, append([]byte(nil), nalu...))
+	}
+	return packets
+}
+
+// interleavedPacket aggregates the NAL units in STAP-B, and fragments the ones too large in a FU-B followed by FU-A,
+// numbering them in decoding order
+func (p *H264Payloader) interleavedPacket(maxPayloadSize int, nalus [][]byte) [][]byte {
+	var packets [][]byte
+	var stapB *bytes.Buffer
+	flush := func() {
+		if stapB != nil {
+			packets = append(packets, stapB.Bytes())
+			stapB = nil
+		}
+	}
+	var word = make([]byte, 2)
+	for _, nalu := range nalus {
+		if len(nalu) == 0 || len(nalu) > math.MaxUint16 {
+			continue
+		}
+		don := p.don
+		p.don++
+
+		//	 0                   1                   2                   3
+		//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+		//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		//	|F|NRI|  Type   |  decoding order number (DON)  |               |
+		//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+               |
+		// the NAL units of a STAP-B follow each other in decoding order, only the DON of the first one is sent
+		if stapB != nil && stapB.Len()+2+len(nalu) > maxPayloadSize {
+			flush()
+		}
+		// a NAL unit of 2 bytes or less can't be split in two FUs, it goes in a STAP-B larger than the MTU
+		if stapB == nil && (h264.RTPPacketTypeStapB.HeaderSize()+2+len(nalu) <= maxPayloadSize || len(nalu) <= 2) {
+			stapB = bytes.NewBuffer(make([]byte, 0, maxPayloadSize))
+			fuIndicator := h264.FuIndicator{}
+			fuIndicator.NalUnitType = h264.RTPPacketTypeStapB.NalUnitType()
+			stapB.WriteByte(fuIndicator.Byte())
+			binary.BigEndian.PutUint16(word, don)
+			stapB.Write(word)
+		}
+		if stapB != nil {
+			stapB.Bytes()[0] = h264AggregationHeader(stapB.Bytes()[0], nalu)
+			binary.BigEndian.PutUint16(word, uint16(len(nalu)))
+			stapB.Write(word)
+			stapB.Write(nalu)
+			continue
+		}
+
+		// FU-B starts the fragmented NAL unit with its DON, FU-A carries the rest;
+		// a FU can't both start and end a NAL unit, so a NAL unit is split in two at least
+		fuSize := maxPayloadSize - 2
+		if len(nalu)-1 <= fuSize-h264.RTPPacketTypeFuA.HeaderSize() {
+			fuSize = h264.RTPPacketTypeFuA.HeaderSize() + len(nalu)/2
+		}
+		fragments := fragmentNalu(fuSize, nalu, true)
+		if len(fragments) == 0 {
+			continue
+		}
+		fuB := make([]byte, 0, len(fragments[0])+2)
+		fuB = append(fuB, fragments[0][0]&^h264.RTPPacketTypeMask|h264.RTPPacketTypeFuB.Byte(), fragments[0][1])
+		binary.BigEndian.PutUint16(word, don)
+		fuB = append(fuB, word...)
+		fuB = append(fuB, fragments[0][2:]...)
+		packets = append(packets, fuB)
+		packets = append(packets, fragments[1:]...)
+	}
+	flush()
+	return packets
+}
+
+// splitNalus splits an access unit in the format of Annex B, or in the AVCC format with a lengthSize not 0,
+// dropping the empty NAL units, of trailing or back-to-back start codes
+// An access unit in the AVCC format which cannot be parsed results in no NAL units
+func splitNalus(payload []byte, lengthSize int) [][]byte {
+	var nalus [][]byte
+	if lengthSize != 0 {
+		avcc, _ := h264_codec.SplitAVCC(payload, lengthSize)
+		for _, nalu := range avcc {
+			if len(nalu) > 0 {
+				nalus = append(nalus, nalu)
+			}
+		}
+		return nalus
+	}
 	emitNalus(payload, func(nalu []byte) {
-		nalubuffer = append(nalubuffer, nalu)
+		if len(nalu) > 0 {
+			nalus = append(nalus, nalu)
+		}
 	})
-	return naluPacket(maxPayloadSize, nalubuffer, p.SkipAggregate, false)
+	return nalus
 }
 
 // traversal nals and emit when a nalu is meet
@@ -67,3 +286,172 @@ func emitNalus(nals []byte, emit func([]byte)) {
 		emit(nals[prevStart:nextIndStart])
 	}
 }
+
+// H264Packet depacketizes H264 RTP payloads of any packetization mode, see rfc6184#section-5.2,
+// reassembling the NAL units fragmented across packets.
+// The NAL units carrying a decoding order number, of STAP-B, MTAP and FU-B, go through a de-interleaving buffer
+// of InterleavingDepth NAL units, released in decoding order, see rfc6184#section-7.2
+type H264Packet struct {
+	// InterleavingDepth is sprop-interleaving-depth, the maximum number of NAL units preceding any NAL unit
+	// in transmission order and following it in decoding order
+	InterleavingDepth int
+
+	// NALUs holds the NAL units released by the last packet, in decoding order
+	NALUs [][]byte
+
+	Payload []byte
+
+	fragment    []byte
+	fragmentDON int // the DON of the NAL unit fragmented, -1 if none
+
+	buffer []donNalu
+}
+
+// donNalu is a NAL unit with its decoding order number
+type donNalu struct {
+	don  uint16
+	nalu []byte
+}
+
+// UnmarshalFmtp configures the depacketizer from the parameters of an SDP fmtp line, such as
+// "packetization-mode=2;sprop-interleaving-depth=4"
+func (p *H264Packet) UnmarshalFmtp(fmtp string) error {
+	if v, ok := parseFmtp(fmtp)["sprop-interleaving-depth"]; ok {
+		depth, err := strconv.Atoi(v)
+		if err != nil || depth < 0 || depth > math.MaxUint16 {
+			return fmt.Errorf("invalid sprop-interleaving-depth %q", v)
+		}
+		p.InterleavingDepth = depth
+	}
+	return nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the H264Packet this method is called upon
+// It returns the NAL units released by this packet in the format of Annex B; the fragment of a NAL unit
+// is buffered until the NAL unit is complete. Fragments whose start was not received are dropped.
+// The TS offsets of MTAPs, relative to the RTP timestamp, are ignored
+func (p *H264Packet) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) < 1 {
+		return nil, fmt.Errorf("Payload is not large enough to container header")
+	}
+	p.NALUs = nil
+	p.Payload = nil
+
+	typ := h264.ParseRTPPacketType(packet)
+	if len(packet) < typ.HeaderSize() {
+		return nil, fmt.Errorf("Payload is not large enough to container %d header", typ)
+	}
+	switch {
+	case typ.SingleNALUnitPacket():
+		p.NALUs = append(p.NALUs, append([]byte(nil), packet...))
+	case typ == h264.RTPPacketTypeStapA, typ == h264.RTPPacketTypeStapB:
+		data := packet[typ.HeaderSize():]
+		var don uint16
+		if typ == h264.RTPPacketTypeStapB {
+			don = binary.BigEndian.Uint16(packet[1:])
+		}
+		for len(data) > 0 {
+			if len(data) < 2 {
+				return nil, fmt.Errorf("Payload is not large enough to container NAL unit size")
+			}
+			size := int(binary.BigEndian.Uint16(data))
+			if len(data)-2 < size {
+				return nil, fmt.Errorf("NAL unit size %d exceeds remaining %d bytes", size, len(data)-2)
+			}
+			if typ == h264.RTPPacketTypeStapA {
+				p.NALUs = append(p.NALUs, append([]byte(nil), data[2:2+size]...))
+			} else {
+				p.deinterleave(don, data[2:2+size])
+				don++
+			}
+			data = data[2+size:]
+		}
+	case typ.MultiTimeAggregationPacket():
+		tsOffsetSize := 2
+		if typ == h264.RTPPacketTypeMtap24 {
+			tsOffsetSize = 3
+		}
+		donb := binary.BigEndian.Uint16(packet[1:])
+		for data := packet[typ.HeaderSize():]; len(data) > 0; {
+			if len(data) < 2+1+tsOffsetSize {
+				return nil, fmt.Errorf("Payload is not large enough to container multi-time aggregation unit")
+			}
+			size := int(binary.BigEndian.Uint16(data))
+			dond := data[2]
+			data = data[2+1+tsOffsetSize:]
+			// the NAL unit size covers the NAL unit only, see rfc6184#section-5.7.2
+			if len(data) < size {
+				return nil, fmt.Errorf("NAL unit size %d exceeds remaining %d bytes", size, len(data))
+			}
+			p.deinterleave(donb+uint16(dond), data[:size])
+			data = data[size:]
+		}
+	case typ.FragmentationUnit():
+		fuIndicator := h264.ParseFuIndicator(packet)
+		fuHeader := h264.ParseFuHeader(packet)
+		data := packet[typ.HeaderSize():]
+		if fuHeader.StartBit {
+			fuIndicator.NalUnitType = fuHeader.Type
+			p.fragment = append([]byte{fuIndicator.Byte()}, data...)
+			p.fragmentDON = -1
+			if typ == h264.RTPPacketTypeFuB {
+				p.fragmentDON = int(binary.BigEndian.Uint16(packet[2:]))
+			}
+		} else if p.fragment != nil {
+			p.fragment = append(p.fragment, data...)
+		}
+		if fuHeader.EndBit && p.fragment != nil {
+			if p.fragmentDON < 0 {
+				p.NALUs = append(p.NALUs, p.fragment)
+			} else {
+				p.deinterleave(uint16(p.fragmentDON), p.fragment)
+			}
+			p.fragment = nil
+		}
+	default:
+		return nil, fmt.Errorf("reserved packet type %d", typ)
+	}
+	if len(p.NALUs) == 0 {
+		return nil, nil
+	}
+	p.Payload = h264_codec.JoinAnnexB(p.NALUs)
+	return p.Payload, nil
+}
+
+// Flush releases the NAL units left in the de-interleaving buffer, in decoding order, in the format of Annex B
+func (p *H264Packet) Flush() []byte {
+	p.NALUs = nil
+	p.Payload = nil
+	for len(p.buffer) > 0 {
+		p.release()
+	}
+	if len(p.NALUs) == 0 {
+		return nil
+	}
+	p.Payload = h264_codec.JoinAnnexB(p.NALUs)
+	return p.Payload
+}
+
+// deinterleave buffers the NAL unit, releasing the first one in decoding order once the buffer exceeds InterleavingDepth
+func (p *H264Packet) deinterleave(don uint16, nalu []byte) {
+	p.buffer = append(p.buffer, donNalu{don: don, nalu: append([]byte(nil), nalu...)})
+	for len(p.buffer) > p.InterleavingDepth {
+		p.release()
+	}
+}
+
+// release moves the first NAL unit of the buffer in decoding order to NALUs
+func (p *H264Packet) release() {
+	first := 0
+	for i := range p.buffer {
+		// don_diff of rfc6184#section-5.5, DON wraps around
+		if int16(p.buffer[i].don-p.buffer[first].don) < 0 {
+			first = i
+		}
+	}
+	p.NALUs = append(p.NALUs, p.buffer[first].nalu)
+	p.buffer = append(p.buffer[:first], p.buffer[first+1:]...)
+}
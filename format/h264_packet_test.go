@@ -1,7 +1,13 @@
 package format
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"reflect"
 	"testing"
+
+	h264_codec "github.com/searKing/rtp/codecs/h264"
 )
 
 func TestH264Payloader_Payload(t *testing.T) {
@@ -61,3 +67,306 @@ func TestH264Payloader_Payload(t *testing.T) {
 	//	t.Fatal("Generated payload should be empty")
 	//}
 }
+
+func TestH264Payloader_PayloadAVCC(t *testing.T) {
+	annexB := []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0, 0x00, 0x00, 0x01, 0x65, 0x88, 0x84, 0x21}
+	avcc := []byte{0x00, 0x02, 0x09, 0xf0, 0x00, 0x04, 0x65, 0x88, 0x84, 0x21}
+
+	want := (&H264Payloader{}).Payload(1200, annexB)
+	res := (&H264Payloader{NALULengthSize: 2}).Payload(1200, avcc)
+	if !reflect.DeepEqual(res, want) {
+		t.Fatalf("AVCC packets %v, want %v", res, want)
+	}
+	if res := (&H264Payloader{NALULengthSize: 4}).Payload(1200, avcc); len(res) != 0 {
+		t.Fatal("Generated payload of an invalid AVCC access unit should be empty")
+	}
+	if res := (&H265Payloader{NALULengthSize: 2}).Payload(1200, avcc); len(res) == 0 {
+		t.Fatal("Generated payload shouldn't be empty")
+	}
+}
+
+func TestH264Payloader_EmptyNalus(t *testing.T) {
+	idr := []byte{0x65, 0x88}
+	tests := [][]byte{
+		{},
+		// a start code alone
+		{0x00, 0x00, 0x00, 0x01},
+		// a trailing start code
+		{0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x00, 0x00, 0x00, 0x01},
+		// back-to-back start codes
+		{0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x65, 0x88},
+	}
+	for _, pck := range []H264Payloader{{}, {SingleNALUnit: true}, {Interleaved: true}} {
+		for i, payload := range tests {
+			res := pck.Payload(1200, payload)
+			if i == 0 || i == 1 {
+				if len(res) != 0 {
+					t.Fatalf("#%d: Generated payload should be empty, got %v", i, res)
+				}
+				continue
+			}
+			if len(res) != 1 || !bytes.Contains(res[0], idr) {
+				t.Fatalf("#%d: Generated payload should only carry the IDR slice, got %v", i, res)
+			}
+		}
+	}
+}
+
+func TestH264Payloader_ParameterSets(t *testing.T) {
+	sps := []byte{0x67, 0x42, 0x00, 0x1f}
+	pps := []byte{0x68, 0xce, 0x3c, 0x80}
+	idr := []byte{0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x84, 0x21}
+	nonIdr := []byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x9a, 0x02}
+	stapA := []byte{0x78, 0x00, 0x04, 0x67, 0x42, 0x00, 0x1f, 0x00, 0x04, 0x68, 0xce, 0x3c, 0x80}
+
+	// IDR without parameter sets, nothing to inject yet
+	pck := H264Payloader{}
+	if res := pck.Payload(1200, idr); len(res) != 1 || !bytes.Equal(res[0], idr[4:]) {
+		t.Fatal("Payload shouldn't inject parameter sets before any is known")
+	}
+
+	// parameter sets are aggregated in a STAP-A and cached
+	var au []byte
+	au = append(au, 0x00, 0x00, 0x00, 0x01)
+	au = append(au, sps...)
+	au = append(au, 0x00, 0x00, 0x00, 0x01)
+	au = append(au, pps...)
+	au = append(au, idr...)
+	if res := pck.Payload(16, au); len(res) != 2 || !bytes.Equal(res[0], stapA) || !bytes.Equal(res[1], idr[4:]) {
+		t.Fatalf("Payload should aggregate the parameter sets, got %v", res)
+	}
+	if res := pck.Payload(1200, nonIdr); len(res) != 1 || !bytes.Equal(res[0], nonIdr[4:]) {
+		t.Fatal("Payload shouldn't inject parameter sets before a non IDR slice")
+	}
+	if res := pck.Payload(16, idr); len(res) != 2 || !bytes.Equal(res[0], stapA) || !bytes.Equal(res[1], idr[4:]) {
+		t.Fatalf("Payload should inject the cached parameter sets before the IDR slice, got %v", res)
+	}
+	pck.SkipParameterSets = true
+	if res := pck.Payload(16, idr); len(res) != 1 {
+		t.Fatal("Payload shouldn't inject parameter sets when skipped")
+	}
+
+	// out of band parameter sets
+	pck = H264Payloader{}
+	if err := pck.UnmarshalFmtp("packetization-mode=1;sprop-parameter-sets=Z0IAHw==,aM48gA=="); err != nil {
+		t.Fatal(err)
+	}
+	if res := pck.Payload(16, idr); len(res) != 2 || !bytes.Equal(res[0], stapA) {
+		t.Fatalf("Payload should inject the sprop-parameter-sets before the IDR slice, got %v", res)
+	}
+	if err := pck.UnmarshalFmtp("sprop-parameter-sets=Z0IAHw==,!"); err == nil {
+		t.Fatal("UnmarshalFmtp should fail on malformed sprop-parameter-sets")
+	}
+
+}
+
+func TestH265Payloader_ParameterSets(t *testing.T) {
+	vps := []byte{0x40, 0x01, 0x0c}
+	sps := []byte{0x42, 0x01, 0x01}
+	pps := []byte{0x44, 0x01, 0xc1}
+	idr := []byte{0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xaf}
+	ap := []byte{0x60, 0x01, 0x00, 0x03, 0x40, 0x01, 0x0c, 0x00, 0x03, 0x42, 0x01, 0x01, 0x00, 0x03, 0x44, 0x01, 0xc1}
+
+	pck := H265Payloader{}
+	if err := pck.UnmarshalFmtp("sprop-vps=" + base64.StdEncoding.EncodeToString(vps) +
+		";sprop-sps=" + base64.StdEncoding.EncodeToString(sps) +
+		";sprop-pps=" + base64.StdEncoding.EncodeToString(pps)); err != nil {
+		t.Fatal(err)
+	}
+	if res := pck.Payload(20, idr); len(res) != 2 || !bytes.Equal(res[0], ap) || !bytes.Equal(res[1], idr[4:]) {
+		t.Fatalf("Payload should inject the parameter sets in an AP before the IRAP slice, got %v", res)
+	}
+	if err := pck.UnmarshalFmtp("sprop-vps=" + base64.StdEncoding.EncodeToString(idr[4:])); err == nil {
+		t.Fatal("UnmarshalFmtp should fail on a slice in sprop-vps")
+	}
+}
+
+func TestH264Payloader_PacketizationMode(t *testing.T) {
+	small := []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0}
+	large := append([]byte{0x00, 0x00, 0x00, 0x01, 0x65}, bytes.Repeat([]byte{0xaa}, 50)...)
+	au := append(append([]byte(nil), small...), large...)
+
+	// single NAL unit mode
+	pck := H264Payloader{}
+	if err := pck.UnmarshalFmtp("packetization-mode=0"); err != nil || !pck.SingleNALUnit || pck.Interleaved {
+		t.Fatal("UnmarshalFmtp should select the single NAL unit mode")
+	}
+	res := pck.Payload(100, au)
+	if len(res) != 2 || !bytes.Equal(res[0], small[4:]) || !bytes.Equal(res[1], large[4:]) || pck.Err() != nil {
+		t.Fatal("Payload should send each NAL unit in a single NAL unit packet")
+	}
+	if res = pck.Payload(20, au); len(res) != 1 || !bytes.Equal(res[0], small[4:]) || pck.Err() == nil {
+		t.Fatal("Payload should drop the NAL unit larger than the MTU, and report it")
+	}
+	if err := pck.UnmarshalFmtp("packetization-mode=3"); err == nil {
+		t.Fatal("UnmarshalFmtp should fail on an unknown packetization-mode")
+	}
+
+	// interleaved mode
+	pck = H264Payloader{Interleaved: true}
+	res = pck.Payload(20, au)
+	if len(res) != 5 {
+		t.Fatalf("Payload should make a STAP-B, a FU-B and 3 FU-A, got %d", len(res))
+	}
+	if !bytes.Equal(res[0], []byte{0x19, 0x00, 0x00, 0x00, 0x02, 0x09, 0xf0}) {
+		t.Fatalf("STAP-B is packed incorrectly: %v", res[0])
+	}
+	if res[1][0]&0x1f != 29 || res[1][1] != 0x85 || res[1][2] != 0x00 || res[1][3] != 0x01 {
+		t.Fatalf("FU-B is packed incorrectly: %v", res[1][:4])
+	}
+	for i, fu := range res[2:] {
+		if fu[0]&0x1f != 28 || len(fu) > 20 {
+			t.Fatalf("FU-A %d is packed incorrectly", i)
+		}
+	}
+	if res[len(res)-1][1]&0x40 == 0 {
+		t.Fatal("The last FU-A should end the NAL unit")
+	}
+
+	// the interleaved packets are depacketized back into the access unit
+	var depck H264Packet
+	var out []byte
+	for _, packet := range res {
+		raw, err := depck.Unmarshal(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, raw...)
+	}
+	if !bytes.Equal(out, au) {
+		t.Fatalf("Unmarshal should reassemble the access unit, got %v", out)
+	}
+	// DON carries on across access units
+	if res = pck.Payload(20, small); len(res) != 1 || binary.BigEndian.Uint16(res[0][1:]) != 2 {
+		t.Fatal("DON should carry on across access units")
+	}
+	// a NAL unit too small to be fragmented goes in a STAP-B, whatever the MTU
+	if res = pck.Payload(5, small); len(res) != 1 || !bytes.Equal(res[0], []byte{0x19, 0x00, 0x03, 0x00, 0x02, 0x09, 0xf0}) {
+		t.Fatalf("Payload should make a STAP-B out of a NAL unit of 2 bytes, got %v", res)
+	}
+}
+
+func TestH264Packet_Unmarshal(t *testing.T) {
+	var pck H264Packet
+	if _, err := pck.Unmarshal(nil); err == nil {
+		t.Fatal("Unmarshal did not fail on nil payload")
+	}
+	if _, err := pck.Unmarshal([]byte{0x1e}); err == nil {
+		t.Fatal("Unmarshal did not fail on a reserved packet type")
+	}
+
+	// single NAL unit
+	if raw, err := pck.Unmarshal([]byte{0x09, 0xf0}); err != nil || !bytes.Equal(raw, []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0}) {
+		t.Fatal("Unmarshal should return the single NAL unit")
+	}
+
+	// STAP-A
+	raw, err := pck.Unmarshal([]byte{0x18, 0x00, 0x02, 0x09, 0xf0, 0x00, 0x01, 0x68})
+	if err != nil || len(pck.NALUs) != 2 || !bytes.Equal(raw, []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xf0, 0x00, 0x00, 0x00, 0x01, 0x68}) {
+		t.Fatal("Unmarshal should return the NAL units of the STAP-A")
+	}
+	if _, err := pck.Unmarshal([]byte{0x18, 0x00, 0x03, 0x09, 0xf0}); err == nil {
+		t.Fatal("Unmarshal did not fail on a truncated STAP-A")
+	}
+
+	// FU-A whose start was lost
+	if raw, err := pck.Unmarshal([]byte{0x7c, 0x45, 0xbb}); err != nil || raw != nil {
+		t.Fatal("Unmarshal should drop the FU-A without its start")
+	}
+	if raw, err := pck.Unmarshal([]byte{0x7c, 0x85, 0xaa}); err != nil || raw != nil {
+		t.Fatal("Unmarshal should buffer the FU-A fragment")
+	}
+	if raw, err := pck.Unmarshal([]byte{0x7c, 0x45, 0xbb}); err != nil || !bytes.Equal(raw, []byte{0x00, 0x00, 0x00, 0x01, 0x65, 0xaa, 0xbb}) {
+		t.Fatal("Unmarshal should reassemble the FU-A fragments")
+	}
+
+	// MTAP16 and MTAP24 released in decoding order through the de-interleaving buffer
+	pck = H264Packet{}
+	if err := pck.UnmarshalFmtp("packetization-mode=2;sprop-interleaving-depth=2"); err != nil || pck.InterleavingDepth != 2 {
+		t.Fatal("UnmarshalFmtp should set the interleaving depth")
+	}
+	// DON 0xfffe+2 = 0 and 0xfffe+0
+	mtap16 := []byte{0x1a, 0xff, 0xfe,
+		0x00, 0x01, 0x02, 0x00, 0x10, 0x41,
+		0x00, 0x01, 0x00, 0x00, 0x00, 0x65}
+	if raw, err := pck.Unmarshal(mtap16); err != nil || raw != nil {
+		t.Fatal("Unmarshal should buffer the NAL units up to the interleaving depth")
+	}
+	// DON 0xffff
+	mtap24 := []byte{0x1b, 0xff, 0xff, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x21}
+	if raw, err := pck.Unmarshal(mtap24); err != nil || !bytes.Equal(raw, []byte{0x00, 0x00, 0x00, 0x01, 0x65}) {
+		t.Fatalf("Unmarshal should release the first NAL unit in decoding order, got %v", raw)
+	}
+	if raw := pck.Flush(); !bytes.Equal(raw, []byte{0x00, 0x00, 0x00, 0x01, 0x21, 0x00, 0x00, 0x00, 0x01, 0x41}) {
+		t.Fatalf("Flush should release the NAL units left in decoding order, got %v", raw)
+	}
+}
+
+func TestH264Payloader_Options(t *testing.T) {
+	aud := []byte{0x09, 0xf0}
+	sei := []byte{0x06, 0x05, 0x01, 0xaa, 0x80}
+	sps := []byte{0x67, 0x42, 0x00, 0x1f}
+	pps := []byte{0x68, 0xce, 0x3c, 0x80}
+	filler := []byte{0x0c, 0xff, 0xff, 0x80}
+	slice := []byte{0x41, 0x9a, 0x02}
+	au := h264_codec.JoinAnnexB([][]byte{aud, sei, sps, pps, filler, slice})
+
+	// STAP-A F and NRI are the maximum of the aggregated NAL units
+	pck := H264Payloader{SkipParameterSets: true}
+	res := pck.Payload(1200, au)
+	if len(res) != 1 || res[0][0] != 0x78 {
+		t.Fatalf("Payload should aggregate all NAL units in a STAP-A with NRI 3, got %v", res)
+	}
+	res = pck.Payload(1200, h264_codec.JoinAnnexB([][]byte{aud, slice}))
+	if len(res) != 1 || res[0][0] != 0x58 {
+		t.Fatalf("Payload should aggregate in a STAP-A with NRI 2, got %v", res)
+	}
+
+	// NAL units filtering
+	pck = H264Payloader{SkipParameterSets: true, SkipAUD: true, SkipFillerData: true, SkipSEI: true}
+	res = pck.Payload(1200, au)
+	want := []byte{0x78, 0x00, 0x04, 0x67, 0x42, 0x00, 0x1f, 0x00, 0x04, 0x68, 0xce, 0x3c, 0x80, 0x00, 0x03, 0x41, 0x9a, 0x02}
+	if len(res) != 1 || !bytes.Equal(res[0], want) {
+		t.Fatalf("Payload should drop the AUD, SEI and filler data, got %v", res)
+	}
+
+	// STAP-A limits
+	pck = H264Payloader{SkipParameterSets: true, MaxAggregatedNALUs: 2}
+	if res = pck.Payload(1200, au); len(res) != 3 {
+		t.Fatalf("Payload should aggregate 2 NAL units per STAP-A, got %d packets", len(res))
+	}
+	pck = H264Payloader{SkipParameterSets: true, MaxAggregatedNALUs: 1}
+	if res = pck.Payload(1200, au); len(res) != 6 {
+		t.Fatalf("Payload should send single NAL unit packets, got %d packets", len(res))
+	}
+
+	// parameter sets in their own packets
+	pck = H264Payloader{SkipParameterSets: true, SeparateParameterSets: true}
+	res = pck.Payload(1200, au)
+	if len(res) != 3 || !bytes.Equal(res[1], want[:13]) {
+		t.Fatalf("Payload should aggregate the parameter sets apart, got %v", res)
+	}
+}
+
+func TestH264Payloader_SPSRewriter(t *testing.T) {
+	sps := []byte{0x67, 0x42, 0xc0, 0x28, 0xd9, 0x00, 0x78, 0x02, 0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+		0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc9, 0x20}
+	pps := []byte{0x68, 0xce, 0x3c, 0x80}
+	idr := []byte{0x65, 0x88, 0x84, 0x21}
+	au := h264_codec.JoinAnnexB([][]byte{sps, pps, idr})
+
+	pck := H264Payloader{SkipAggregate: true, SPSRewriter: &h264_codec.SPSRewriter{LevelIdc: 31}}
+	res := pck.Payload(1200, au)
+	if len(res) != 3 || !bytes.Equal(res[1], pps) || !bytes.Equal(res[2], idr) {
+		t.Fatalf("Payload should send the NAL units apart, got %d packets", len(res))
+	}
+	s, err := h264_codec.ParseSPS(res[0])
+	if err != nil || s.LevelIdc != 31 {
+		t.Fatal("Payload should rewrite the SPS")
+	}
+	// the cached SPS is rewritten as well
+	res = pck.Payload(1200, h264_codec.JoinAnnexB([][]byte{idr}))
+	if len(res) != 3 || res[0][3] != 31 {
+		t.Fatal("Payload should rewrite the SPS injected")
+	}
+}
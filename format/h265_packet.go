@@ -1,4 +1,39 @@
 package format
 
-// H265Payloader payloads H265 packets
-type H265Payloader struct{}
+// H265Payloader payloads H265 packets, see rfc7798
+type H265Payloader struct {
+	SkipAggregate bool
+	// NALULengthSize is the size of the length prefixing the NAL units when the access units are in the AVCC format
+	// of MP4 and Matroska, LengthSize of their hevc.HEVCDecoderConfigurationRecord; 0 for the format of Annex B
+	NALULengthSize int
+	// SkipParameterSets disables the repetition of the most recent VPS, SPS and PPS before the IRAP access units lacking them
+	SkipParameterSets bool
+
+	parameterSets parameterSets
+}
+
+// UnmarshalFmtp configures the payloader from the parameters of an SDP fmtp line,
+// the out-of-band VPS, SPS and PPS of sprop-vps, sprop-sps and sprop-pps
+func (p *H265Payloader) UnmarshalFmtp(fmtp string) error {
+	params := parseFmtp(fmtp)
+	for _, key := range []string{"sprop-vps", "sprop-sps", "sprop-pps"} {
+		if err := p.parameterSets.unmarshalSprop(params[key], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Payload fragments a H265 access unit across one or more byte arrays, in the format of Annex B,
+// or in the AVCC format with NALULengthSize set
+// The cached VPS, SPS and PPS are inserted before the IRAP slices of an access unit lacking them
+func (p *H265Payloader) Payload(maxPayloadSize int, payload []byte) [][]byte {
+	if payload == nil {
+		return nil
+	}
+	nalus := splitNalus(payload, p.NALULengthSize)
+	if !p.SkipParameterSets {
+		nalus = p.parameterSets.inject(nalus, false)
+	}
+	return naluPacket(maxPayloadSize, nalus, p.SkipAggregate, 0, false, false)
+}
@@ -0,0 +1,25 @@
+package jpeg
+
+// ClockRate is the RTP clock rate of JPEG, 90 kHz, see rfc2435#section-3
+const ClockRate = 90000
+
+// 3.1.3 and 3.1.7 in rfc2435: types 0 and 1 are defined, 64 and 65 are the same with restart markers
+const (
+	Type422 = 0 // 4:2:2, 16x8 MCUs
+	Type420 = 1 // 4:2:0, 16x16 MCUs
+
+	// types 64 to 127 carry a restart marker header
+	TypeRestartMarkerMin = 64
+	TypeRestartMarkerMax = 127
+	TypeRestartMarkerBit = 0x40
+)
+
+// 3.1.4 and 3.1.8 in rfc2435: Q values 1 to 99 derive the quantization tables from the tables of Annex K,
+// Q values 128 to 255 carry them in-band; the tables of Q 255 change from frame to frame
+const (
+	QMin = 1
+	QMax = 99
+
+	QInBandMin = 128
+	QInBand    = 255
+)
@@ -0,0 +1,112 @@
+package jpeg
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The main JPEG header, 3.1 in rfc2435
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	| Type-specific |              Fragment Offset                  |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|      Type     |       Q       |     Width     |     Height    |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	MainHeaderSize = 8
+
+	MainHeaderTypeSpecificByteIndex   = 0
+	MainHeaderFragmentOffsetByteIndex = 1
+	MainHeaderTypeByteIndex           = 4
+	MainHeaderQByteIndex              = 5
+	MainHeaderWidthByteIndex          = 6
+	MainHeaderHeightByteIndex         = 7
+
+	// the fragment offset is 24 bits
+	MaxFragmentOffset = 0xffffff
+	// the width and height are in 8-pixel multiples, on 8 bits
+	MaxDimension = 2040
+)
+
+// MainHeader represents the main JPEG header that starts the payload of an RTP Packet
+type MainHeader struct {
+	TypeSpecific uint8
+	// FragmentOffset is the offset in bytes of the packet data in the scan data of the frame
+	FragmentOffset uint32
+	Type           uint8
+	Q              uint8
+	// Width and Height are the dimensions of the image in 8-pixel multiples
+	Width  uint8
+	Height uint8
+}
+
+// HasRestartMarkerHeader reports whether the restart marker header follows the main header
+func (h MainHeader) HasRestartMarkerHeader() bool {
+	return h.Type >= TypeRestartMarkerMin && h.Type <= TypeRestartMarkerMax
+}
+
+// HasQuantizationTableHeader reports whether the quantization table header follows the main and restart marker headers
+func (h MainHeader) HasQuantizationTableHeader() bool {
+	return h.Q >= QInBandMin && h.FragmentOffset == 0
+}
+
+// PixelWidth returns the width of the image in pixels
+func (h MainHeader) PixelWidth() int {
+	return int(h.Width) * 8
+}
+
+// PixelHeight returns the height of the image in pixels
+func (h MainHeader) PixelHeight() int {
+	return int(h.Height) * 8
+}
+
+// Marshal serializes the header into bytes.
+func (h MainHeader) Marshal() ([]byte, error) {
+	if h.FragmentOffset > MaxFragmentOffset {
+		return nil, fmt.Errorf("fragment offset %d exceeds %d", h.FragmentOffset, MaxFragmentOffset)
+	}
+	buf := make([]byte, MainHeaderSize)
+	binary.BigEndian.PutUint32(buf, h.FragmentOffset)
+	buf[MainHeaderTypeSpecificByteIndex] = h.TypeSpecific
+	buf[MainHeaderTypeByteIndex] = h.Type
+	buf[MainHeaderQByteIndex] = h.Q
+	buf[MainHeaderWidthByteIndex] = h.Width
+	buf[MainHeaderHeightByteIndex] = h.Height
+	return buf, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the MainHeader this method is called upon
+func (h *MainHeader) Unmarshal(buf []byte) error {
+	if len(buf) < MainHeaderSize {
+		return fmt.Errorf("buf is not large enough to container main header")
+	}
+	h.TypeSpecific = buf[MainHeaderTypeSpecificByteIndex]
+	h.FragmentOffset = binary.BigEndian.Uint32(buf) & MaxFragmentOffset
+	h.Type = buf[MainHeaderTypeByteIndex]
+	h.Q = buf[MainHeaderQByteIndex]
+	h.Width = buf[MainHeaderWidthByteIndex]
+	h.Height = buf[MainHeaderHeightByteIndex]
+	return nil
+}
+
+// String helps with debugging by printing MainHeader information in a readable way
+func (h MainHeader) String() string {
+	out := "JPEG MainHeader:\n"
+
+	out += fmt.Sprintf("\tTypeSpecific: %d\n", h.TypeSpecific)
+	out += fmt.Sprintf("\tFragmentOffset: %d\n", h.FragmentOffset)
+	out += fmt.Sprintf("\tType: %d\n", h.Type)
+	out += fmt.Sprintf("\tQ: %d\n", h.Q)
+	out += fmt.Sprintf("\tWidth: %d\n", h.Width)
+	out += fmt.Sprintf("\tHeight: %d\n", h.Height)
+
+	return out
+}
+
+func ParseMainHeader(rtpPayload []byte) (MainHeader, error) {
+	var h MainHeader
+	err := (&h).Unmarshal(rtpPayload)
+	return h, err
+}
@@ -0,0 +1,123 @@
+package jpeg
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/jpeg"
+)
+
+// The quantization table header, 3.1.8 in rfc2435
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|      MBZ      |   Precision   |             Length            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                    Quantization Table Data                    |
+//	|                              ...                              |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	QuantizationTableHeaderMinSize = 4
+
+	QuantizationTableHeaderMBZByteIndex       = 0
+	QuantizationTableHeaderPrecisionByteIndex = 1
+	QuantizationTableHeaderLengthByteIndex    = 2
+)
+
+// QuantizationTableHeader represents the quantization table header,
+// which follows the main and restart marker headers in the first packet of a frame with Q in [128, 255]
+type QuantizationTableHeader struct {
+	MBZ uint8
+	// Tables holds the tables in order, bit i of the precision is set when table i has 16-bit values
+	Tables []jpeg.QuantizationTable
+}
+
+// Precision returns the precision field of the header
+func (h QuantizationTableHeader) Precision() uint8 {
+	var precision uint8
+	for i, t := range h.Tables {
+		if t.Precision != 0 && i < 8 {
+			precision |= 1 << uint(i)
+		}
+	}
+	return precision
+}
+
+// Length returns the length of the table data
+func (h QuantizationTableHeader) Length() int {
+	var length int
+	for _, t := range h.Tables {
+		length += t.Size()
+	}
+	return length
+}
+
+// MarshalSize returns the size of the header once serialized
+func (h QuantizationTableHeader) MarshalSize() int {
+	return QuantizationTableHeaderMinSize + h.Length()
+}
+
+// Marshal serializes the header into bytes.
+func (h QuantizationTableHeader) Marshal() ([]byte, error) {
+	if len(h.Tables) > 8 {
+		return nil, fmt.Errorf("%d quantization tables exceed the 8 bits of precision", len(h.Tables))
+	}
+	if h.Length() > 0xffff {
+		return nil, fmt.Errorf("quantization table length %d exceeds 16 bits", h.Length())
+	}
+	buf := make([]byte, QuantizationTableHeaderMinSize, h.MarshalSize())
+	buf[QuantizationTableHeaderMBZByteIndex] = h.MBZ
+	buf[QuantizationTableHeaderPrecisionByteIndex] = h.Precision()
+	binary.BigEndian.PutUint16(buf[QuantizationTableHeaderLengthByteIndex:], uint16(h.Length()))
+	for i, t := range h.Tables {
+		if len(t.Values) != t.Size() {
+			return nil, fmt.Errorf("quantization table %d has %d bytes, want %d", i, len(t.Values), t.Size())
+		}
+		buf = append(buf, t.Values...)
+	}
+	return buf, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the QuantizationTableHeader this method is called upon,
+// it returns the number of bytes read
+func (h *QuantizationTableHeader) Unmarshal(buf []byte) (int, error) {
+	if len(buf) < QuantizationTableHeaderMinSize {
+		return 0, fmt.Errorf("buf is not large enough to container quantization table header")
+	}
+	h.MBZ = buf[QuantizationTableHeaderMBZByteIndex]
+	precision := buf[QuantizationTableHeaderPrecisionByteIndex]
+	length := int(binary.BigEndian.Uint16(buf[QuantizationTableHeaderLengthByteIndex:]))
+	if len(buf) < QuantizationTableHeaderMinSize+length {
+		return 0, fmt.Errorf("buf is not large enough to container quantization tables of %d bytes", length)
+	}
+	data := buf[QuantizationTableHeaderMinSize : QuantizationTableHeaderMinSize+length]
+	h.Tables = nil
+	for i := 0; len(data) > 0; i++ {
+		t := jpeg.QuantizationTable{}
+		if i < 8 {
+			t.Precision = precision >> uint(i) & 0x01
+		}
+		if len(data) < t.Size() {
+			return 0, fmt.Errorf("quantization table %d is truncated", i)
+		}
+		t.Values = append([]byte(nil), data[:t.Size()]...)
+		h.Tables = append(h.Tables, t)
+		data = data[t.Size():]
+	}
+	return QuantizationTableHeaderMinSize + length, nil
+}
+
+// String helps with debugging by printing QuantizationTableHeader information in a readable way
+func (h QuantizationTableHeader) String() string {
+	out := "JPEG QuantizationTableHeader:\n"
+
+	out += fmt.Sprintf("\tMBZ: %d\n", h.MBZ)
+	out += fmt.Sprintf("\tPrecision: %#02x\n", h.Precision())
+	out += fmt.Sprintf("\tLength: %d\n", h.Length())
+	for i, t := range h.Tables {
+		out += fmt.Sprintf("\tTable %d: %x\n", i, t.Values)
+	}
+
+	return out
+}
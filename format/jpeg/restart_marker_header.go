@@ -0,0 +1,84 @@
+package jpeg
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The restart marker header, 3.1.7 in rfc2435
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|       Restart Interval        |F|L|       Restart Count       |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	RestartMarkerHeaderSize = 4
+
+	RestartMarkerHeaderIntervalByteIndex = 0
+	RestartMarkerHeaderCountByteIndex    = 2
+
+	RestartMarkerHeaderFMask     = 0x8000
+	RestartMarkerHeaderLMask     = 0x4000
+	RestartMarkerHeaderCountMask = 0x3fff
+)
+
+// RestartMarkerHeader represents the restart marker header that follows the main header of types 64 to 127
+type RestartMarkerHeader struct {
+	// Interval is the number of MCUs between restart markers, as in the DRI marker
+	Interval uint16
+	// F and L are set when the packet starts and ends a chunk of restart intervals
+	F bool
+	L bool
+	// Count is the index of the first restart interval of the chunk, 0x3fff when F and L are always set
+	Count uint16
+}
+
+// Marshal serializes the header into bytes.
+func (h RestartMarkerHeader) Marshal() ([]byte, error) {
+	if h.Count > RestartMarkerHeaderCountMask {
+		return nil, fmt.Errorf("restart count %d exceeds %d", h.Count, RestartMarkerHeaderCountMask)
+	}
+	buf := make([]byte, RestartMarkerHeaderSize)
+	binary.BigEndian.PutUint16(buf[RestartMarkerHeaderIntervalByteIndex:], h.Interval)
+	v := h.Count
+	if h.F {
+		v |= RestartMarkerHeaderFMask
+	}
+	if h.L {
+		v |= RestartMarkerHeaderLMask
+	}
+	binary.BigEndian.PutUint16(buf[RestartMarkerHeaderCountByteIndex:], v)
+	return buf, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the RestartMarkerHeader this method is called upon
+func (h *RestartMarkerHeader) Unmarshal(buf []byte) error {
+	if len(buf) < RestartMarkerHeaderSize {
+		return fmt.Errorf("buf is not large enough to container restart marker header")
+	}
+	h.Interval = binary.BigEndian.Uint16(buf[RestartMarkerHeaderIntervalByteIndex:])
+	v := binary.BigEndian.Uint16(buf[RestartMarkerHeaderCountByteIndex:])
+	h.F = v&RestartMarkerHeaderFMask != 0
+	h.L = v&RestartMarkerHeaderLMask != 0
+	h.Count = v & RestartMarkerHeaderCountMask
+	return nil
+}
+
+// String helps with debugging by printing RestartMarkerHeader information in a readable way
+func (h RestartMarkerHeader) String() string {
+	out := "JPEG RestartMarkerHeader:\n"
+
+	out += fmt.Sprintf("\tInterval: %d\n", h.Interval)
+	out += fmt.Sprintf("\tF: %v\n", h.F)
+	out += fmt.Sprintf("\tL: %v\n", h.L)
+	out += fmt.Sprintf("\tCount: %d\n", h.Count)
+
+	return out
+}
+
+func ParseRestartMarkerHeader(buf []byte) (RestartMarkerHeader, error) {
+	var h RestartMarkerHeader
+	err := (&h).Unmarshal(buf)
+	return h, err
+}
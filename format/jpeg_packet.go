@@ -0,0 +1,267 @@
+package format
+
+import (
+	"fmt"
+
+	jpeg_codec "github.com/searKing/rtp/codecs/jpeg"
+	"github.com/searKing/rtp/format/jpeg"
+)
+
+// JPEGPayloader payloads baseline JPEG images, see rfc2435
+// Only the images rfc2435 can describe are payloaded: three components, sampled 4:2:2 or 4:2:0,
+// the luminance quantization table for the first one, the chrominance one for the others,
+// and the standard Huffman tables.  The Huffman tables are not sent: a receiver rebuilds them
+type JPEGPayloader struct {
+	// Q is the quality factor in [1, 99] the images were encoded with, their quantization tables
+	// are then derived from it by the receiver and not sent; 0 sends the tables in-band, with Q 255
+	Q uint8
+}
+
+// ClockRate returns the RTP clock rate of JPEG, 90 kHz
+func (p *JPEGPayloader) ClockRate() uint32 {
+	return jpeg.ClockRate
+}
+
+// headers returns the main header and the restart marker and quantization table headers of an image, to be completed per packet
+func (p *JPEGPayloader) headers(img jpeg_codec.Image) (jpeg.MainHeader, *jpeg.RestartMarkerHeader, *jpeg.QuantizationTableHeader, error) {
+	var h jpeg.MainHeader
+	if len(img.Components) != 3 {
+		return h, nil, nil, fmt.Errorf("%d components, rfc2435 requires 3", len(img.Components))
+	}
+	luma, cb, cr := img.Components[0], img.Components[1], img.Components[2]
+	switch {
+	case cb.H != 1 || cb.V != 1 || cr.H != 1 || cr.V != 1 || luma.H != 2:
+		return h, nil, nil, fmt.Errorf("unsupported sampling factors")
+	case luma.V == 1:
+		h.Type = jpeg.Type422
+	case luma.V == 2:
+		h.Type = jpeg.Type420
+	default:
+		return h, nil, nil, fmt.Errorf("unsupported sampling factors")
+	}
+	if cb.Tq != cr.Tq {
+		return h, nil, nil, fmt.Errorf("chrominance components use different quantization tables")
+	}
+	if img.Width > jpeg.MaxDimension || img.Height > jpeg.MaxDimension {
+		return h, nil, nil, fmt.Errorf("dimensions %dx%d exceed %d", img.Width, img.Height, jpeg.MaxDimension)
+	}
+	h.Width = uint8((img.Width + 7) / 8)
+	h.Height = uint8((img.Height + 7) / 8)
+
+	var restart *jpeg.RestartMarkerHeader
+	if img.RestartInterval != 0 {
+		h.Type |= jpeg.TypeRestartMarkerBit
+		// the scan data is not split on restart markers, every packet is its own chunk
+		restart = &jpeg.RestartMarkerHeader{Interval: img.RestartInterval, F: true, L: true, Count: jpeg.RestartMarkerHeaderCountMask}
+	}
+
+	h.Q = p.Q
+	if h.Q >= jpeg.QMin && h.Q <= jpeg.QMax {
+		return h, restart, nil, nil
+	}
+	h.Q = jpeg.QInBand
+	tables := &jpeg.QuantizationTableHeader{}
+	for _, tq := range []uint8{luma.Tq, cb.Tq} {
+		t := img.QuantizationTables[tq]
+		if t == nil {
+			return h, nil, nil, fmt.Errorf("missing quantization table %d", tq)
+		}
+		tables.Tables = append(tables.Tables, *t)
+	}
+	return h, restart, tables, nil
+}
+
+// Payload fragments a JPEG image across one or more byte arrays
+// Images which cannot be parsed, or not described by rfc2435, result in no packets
+func (p *JPEGPayloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	if payload == nil || mtu <= 0 {
+		return out
+	}
+	img, err := jpeg_codec.ParseImage(payload)
+	if err != nil || len(img.ScanData) == 0 || len(img.ScanData) > jpeg.MaxFragmentOffset {
+		return out
+	}
+	h, restart, tables, err := p.headers(img)
+	if err != nil {
+		return out
+	}
+
+	for offset := 0; offset < len(img.ScanData); {
+		h.FragmentOffset = uint32(offset)
+		o, _ := h.Marshal()
+		if restart != nil {
+			b, _ := restart.Marshal()
+			o = append(o, b...)
+		}
+		if tables != nil && offset == 0 {
+			b, err := tables.Marshal()
+			if err != nil {
+				return nil
+			}
+			o = append(o, b...)
+		}
+		size := min(mtu-len(o), len(img.ScanData)-offset)
+		if size <= 0 {
+			return nil
+		}
+		o = append(o, img.ScanData[offset:offset+size]...)
+		out = append(out, o)
+		offset += size
+	}
+	return out
+}
+
+// JPEGPacket represents the JPEG header that is stored in the payload of an RTP Packet
+type JPEGPacket struct {
+	// the headers of the last packet
+	jpeg.MainHeader
+	RestartMarkerHeader     *jpeg.RestartMarkerHeader
+	QuantizationTableHeader *jpeg.QuantizationTableHeader
+
+	// Marker is the RTP marker bit of the packet of the next call to Unmarshal, set by the caller:
+	// it ends a frame, see rfc2435#section-3.1
+	Marker bool
+
+	// Payload is the last image rebuilt
+	Payload []byte
+
+	// the frame being reassembled
+	frame      *jpeg.MainHeader
+	restart    *jpeg.RestartMarkerHeader
+	tables     []jpeg_codec.QuantizationTable
+	scanData   []byte
+	incomplete bool
+
+	// the tables of the Q values in [128, 254], which may be sent only once
+	cachedTables map[uint8][]jpeg_codec.QuantizationTable
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the JPEGPacket this method is called upon
+// The scan data is buffered until the packet with the RTP marker bit set, which returns the frame rebuilt as a JFIF image;
+// nothing is returned until then.  Frames with a missing fragment are dropped, the end of a frame included:
+// a packet starting a new frame, with a fragment offset of 0, drops the frame still buffered
+func (p *JPEGPacket) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) < jpeg.MainHeaderSize {
+		return nil, fmt.Errorf("Payload is not large enough to container header")
+	}
+	p.RestartMarkerHeader = nil
+	p.QuantizationTableHeader = nil
+	p.Payload = nil
+	if err := (&p.MainHeader).Unmarshal(packet); err != nil {
+		return nil, err
+	}
+	data := packet[jpeg.MainHeaderSize:]
+	if p.HasRestartMarkerHeader() {
+		restart, err := jpeg.ParseRestartMarkerHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		p.RestartMarkerHeader = &restart
+		data = data[jpeg.RestartMarkerHeaderSize:]
+	}
+	if p.HasQuantizationTableHeader() {
+		tables := &jpeg.QuantizationTableHeader{}
+		n, err := tables.Unmarshal(data)
+		if err != nil {
+			return nil, err
+		}
+		p.QuantizationTableHeader = tables
+		data = data[n:]
+	}
+
+	if p.FragmentOffset == 0 {
+		if err := p.start(); err != nil {
+			return nil, err
+		}
+	} else if p.frame == nil || int(p.FragmentOffset) != len(p.scanData) || p.MainHeader.Type != p.frame.Type {
+		// a fragment was lost
+		p.incomplete = true
+	}
+	if !p.incomplete {
+		p.scanData = append(p.scanData, data...)
+	}
+	if !p.Marker {
+		return nil, nil
+	}
+	return p.Flush()
+}
+
+// start starts a new frame from the headers of the last packet, and resolves its quantization tables
+func (p *JPEGPacket) start() error {
+	header := p.MainHeader
+	p.frame = &header
+	p.restart = p.RestartMarkerHeader
+	p.tables = nil
+	p.scanData = nil
+	p.incomplete = false
+
+	switch {
+	case p.Q >= jpeg.QMin && p.Q <= jpeg.QMax:
+		luma, chroma := jpeg_codec.QuantizationTables(int(p.Q))
+		p.tables = []jpeg_codec.QuantizationTable{luma, chroma}
+	case p.Q >= jpeg.QInBandMin:
+		if p.QuantizationTableHeader.Length() > 0 {
+			p.tables = p.QuantizationTableHeader.Tables
+			if p.Q != jpeg.QInBand {
+				if p.cachedTables == nil {
+					p.cachedTables = make(map[uint8][]jpeg_codec.QuantizationTable)
+				}
+				p.cachedTables[p.Q] = p.tables
+			}
+		} else if p.Q != jpeg.QInBand {
+			p.tables = p.cachedTables[p.Q]
+		}
+		if len(p.tables) == 0 {
+			p.incomplete = true
+			return fmt.Errorf("no quantization tables received for Q %d", p.Q)
+		}
+	default:
+		p.incomplete = true
+		return fmt.Errorf("reserved Q %d", p.Q)
+	}
+	return nil
+}
+
+// Flush rebuilds the buffered frame as a JFIF image, and returns it; nil if no complete frame is buffered.
+// It returns a frame whose marker packet is not received, such as at the end of a stream
+func (p *JPEGPacket) Flush() ([]byte, error) {
+	frame, incomplete := p.frame, p.incomplete
+	p.frame = nil
+	if frame == nil || incomplete || len(p.scanData) == 0 {
+		return nil, nil
+	}
+
+	img := jpeg_codec.Image{
+		Width:    frame.PixelWidth(),
+		Height:   frame.PixelHeight(),
+		ScanData: p.scanData,
+	}
+	luma := jpeg_codec.Component{ID: 1, H: 2, V: 1, Tq: 0}
+	switch frame.Type &^ jpeg.TypeRestartMarkerBit {
+	case jpeg.Type422:
+	case jpeg.Type420:
+		luma.V = 2
+	default:
+		return nil, fmt.Errorf("unsupported type %d", frame.Type)
+	}
+	img.Components = []jpeg_codec.Component{luma, {ID: 2, H: 1, V: 1, Tq: 1}, {ID: 3, H: 1, V: 1, Tq: 1}}
+	if p.restart != nil {
+		img.RestartInterval = p.restart.Interval
+	}
+	for i := range img.QuantizationTables[:2] {
+		// a single table applies to every component
+		t := p.tables[min(i, len(p.tables)-1)]
+		img.QuantizationTables[i] = &t
+	}
+
+	raw, err := img.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	p.Payload = raw
+	return raw, nil
+}
@@ -0,0 +1,207 @@
+package format
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	format_jpeg "github.com/searKing/rtp/format/jpeg"
+)
+
+func testJPEGImage(t *testing.T, quality int) ([]byte, image.Image) {
+	src := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			src.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 5), B: uint8(x * y), A: 0xff})
+		}
+	}
+	w := bytes.NewBuffer(nil)
+	if err := jpeg.Encode(w, src, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(w.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w.Bytes(), decoded
+}
+
+func testJPEGSameImage(t *testing.T, raw []byte, expected image.Image) {
+	decoded, err := jpeg.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal("Rebuilt image should decode:", err)
+	}
+	if decoded.Bounds() != expected.Bounds() {
+		t.Fatalf("Rebuilt image should be %v, got %v", expected.Bounds(), decoded.Bounds())
+	}
+	b := expected.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if decoded.At(x, y) != expected.At(x, y) {
+				t.Fatalf("Rebuilt image differs at %d,%d", x, y)
+			}
+		}
+	}
+}
+
+func TestJPEGPayloader_Payload(t *testing.T) {
+	raw, expected := testJPEGImage(t, 75)
+
+	pck := JPEGPayloader{}
+	if res := pck.Payload(100, nil); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+	if res := pck.Payload(100, []byte{0xff, 0xd8, 0xff, 0xd9}); len(res) != 0 {
+		t.Fatal("Generated payload should be empty for an invalid image")
+	}
+	// MTU too small for the headers
+	if res := pck.Payload(8+4+128, raw); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+
+	// in-band tables
+	res := pck.Payload(200, raw)
+	if len(res) < 2 {
+		t.Fatal("Generated payload should be fragmented")
+	}
+	for i, r := range res {
+		if len(r) > 200 {
+			t.Fatalf("Packet %d exceeds the MTU", i)
+		}
+		h, err := format_jpeg.ParseMainHeader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Type != format_jpeg.Type420 || h.Q != format_jpeg.QInBand || h.Width != 8 || h.Height != 6 {
+			t.Fatalf("Unexpected main header %s", h)
+		}
+		if h.HasQuantizationTableHeader() != (i == 0) {
+			t.Fatal("Only the first packet should carry the quantization tables")
+		}
+	}
+
+	// the frame is returned by its marker packet
+	depacketizer := JPEGPacket{}
+	var out []byte
+	for i, r := range res {
+		depacketizer.Marker = i == len(res)-1
+		o, err := depacketizer.Unmarshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (o != nil) != depacketizer.Marker {
+			t.Fatalf("Unmarshal should buffer the frame until the marker packet, packet %d", i)
+		}
+		out = o
+	}
+	if depacketizer.QuantizationTableHeader != nil || depacketizer.FragmentOffset == 0 {
+		t.Fatal("Headers should be those of the last packet")
+	}
+	testJPEGSameImage(t, out, expected)
+	if out, _ = depacketizer.Flush(); out != nil {
+		t.Fatal("Flush should not return a frame already returned")
+	}
+
+	// Flush returns a frame whose marker packet is not received
+	depacketizer.Marker = false
+	for _, r := range res {
+		if out, err := depacketizer.Unmarshal(r); out != nil || err != nil {
+			t.Fatal("Unmarshal should buffer the frame without a marker packet")
+		}
+	}
+	out, err := depacketizer.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testJPEGSameImage(t, out, expected)
+	if out, _ = depacketizer.Flush(); out != nil {
+		t.Fatal("Flush should return the frame once")
+	}
+}
+
+func TestJPEGPacket_Q(t *testing.T) {
+	raw, expected := testJPEGImage(t, 50)
+	pck := JPEGPayloader{Q: 50}
+	res := pck.Payload(150, raw)
+	first := pck.Payload(150, raw)
+	if len(res) < 3 {
+		t.Fatal("Generated payload should be fragmented")
+	}
+
+	// the tables are derived from Q
+	depacketizer := JPEGPacket{}
+	var out []byte
+	var err error
+	for i, r := range res {
+		depacketizer.Marker = i == len(res)-1
+		if out, err = depacketizer.Unmarshal(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testJPEGSameImage(t, out, expected)
+	if !bytes.Equal(depacketizer.Payload, out) {
+		t.Fatal("Payload should be the rebuilt image")
+	}
+
+	// a lost fragment drops the frame
+	for i, r := range first {
+		if i == 1 {
+			continue
+		}
+		depacketizer.Marker = i == len(first)-1
+		if out, err = depacketizer.Unmarshal(r); out != nil || err != nil {
+			t.Fatal("Frame with a lost fragment should be dropped")
+		}
+	}
+	// a lost marker packet drops the frame at the start of the next one
+	depacketizer.Marker = false
+	for _, r := range res[:len(res)-1] {
+		if _, err = depacketizer.Unmarshal(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err = depacketizer.Unmarshal(first[0]); out != nil || err != nil {
+		t.Fatal("Frame with a lost marker packet should be dropped")
+	}
+
+	// reserved Q
+	bad := append([]byte(nil), res[0]...)
+	bad[format_jpeg.MainHeaderQByteIndex] = 100
+	if _, err = depacketizer.Unmarshal(bad); err == nil {
+		t.Fatal("Reserved Q should be rejected")
+	}
+	if out, _ = depacketizer.Flush(); out != nil {
+		t.Fatal("Frame with a reserved Q should be dropped")
+	}
+}
+
+func TestJPEGPacket_RestartMarkers(t *testing.T) {
+	raw, expected := testJPEGImage(t, 90)
+	// add a DRI segment, with an interval longer than the 12 MCUs of the image so that the scan data stays valid
+	sos := bytes.Index(raw, []byte{0xff, 0xda})
+	raw = append(append(append([]byte(nil), raw[:sos]...), 0xff, 0xdd, 0x00, 0x04, 0x00, 0x40), raw[sos:]...)
+
+	pck := JPEGPayloader{}
+	res := pck.Payload(200, raw)
+	depacketizer := JPEGPacket{}
+	var out []byte
+	var err error
+	for i, r := range res {
+		depacketizer.Marker = i == len(res)-1
+		if out, err = depacketizer.Unmarshal(r); err != nil {
+			t.Fatal(err)
+		}
+		if depacketizer.Type != format_jpeg.Type420|format_jpeg.TypeRestartMarkerBit {
+			t.Fatalf("Type should signal the restart markers, got %d", depacketizer.Type)
+		}
+		if h := depacketizer.RestartMarkerHeader; h == nil || h.Interval != 0x40 || !h.F || !h.L || h.Count != 0x3fff {
+			t.Fatalf("Unexpected restart marker header %v", h)
+		}
+	}
+	if !bytes.Contains(out, []byte{0xff, 0xdd, 0x00, 0x04, 0x00, 0x40}) {
+		t.Fatal("Rebuilt image should carry the restart interval")
+	}
+	testJPEGSameImage(t, out, expected)
+}
@@ -0,0 +1,131 @@
+package format
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/searKing/rtp/codecs/pcm"
+)
+
+// Packet times of linear PCM: AES67 and SMPTE ST 2110-30 use packet times from 125 µs to 20 ms,
+// and default to the 1 ms of AES67, while rfc3551#section-4.5 suggests 20 ms
+const (
+	LPCMMinPacketTime     = 125 * time.Microsecond
+	LPCMMaxPacketTime     = 20 * time.Millisecond
+	LPCMDefaultPacketTime = 1 * time.Millisecond
+)
+
+// LPCMPayloader payloads L16 and L24 linear PCM, see rfc3551#section-4.5.11 and rfc3190#section-4
+// The payload holds interleaved samples in network byte order, converted with pcm.FromLittleEndian if need be.
+// Packetize stamps every packet of a payload with the same timestamp, so each call takes at most
+// a packet time of samples and makes a single packet: PacketSize returns its size, and Samples its duration
+type LPCMPayloader struct {
+	// SampleSize is the number of bytes of a sample: pcm.L16SampleSize or pcm.L24SampleSize
+	SampleSize int
+	SampleRate int
+	Channels   int
+	// PacketTime is the duration of the packets, in [LPCMMinPacketTime, LPCMMaxPacketTime];
+	// 0 means LPCMDefaultPacketTime
+	PacketTime time.Duration
+
+	err error
+}
+
+// Err returns the error of the last call to Payload, a payload longer than a packet time or the MTU
+func (p *LPCMPayloader) Err() error {
+	return p.err
+}
+
+// ClockRate returns the RTP clock rate, the sample rate
+func (p *LPCMPayloader) ClockRate() uint32 {
+	return uint32(p.SampleRate)
+}
+
+func (p *LPCMPayloader) packetTime() time.Duration {
+	if p.PacketTime == 0 {
+		return LPCMDefaultPacketTime
+	}
+	return p.PacketTime
+}
+
+// PacketSize returns the number of bytes of a packet time of samples, 0 if the parameters are invalid
+func (p *LPCMPayloader) PacketSize() int {
+	if p.SampleSize != pcm.L16SampleSize && p.SampleSize != pcm.L24SampleSize || p.Channels <= 0 || p.SampleRate <= 0 {
+		return 0
+	}
+	if d := p.packetTime(); d < LPCMMinPacketTime || d > LPCMMaxPacketTime {
+		return 0
+	}
+	return pcm.Frames(p.SampleRate, p.packetTime()) * pcm.FrameSize(p.SampleSize, p.Channels)
+}
+
+// Payload packs interleaved PCM samples, of at most a packet time, in a single byte array
+// Payloads of partial samples, longer than a packet time or larger than the MTU result in no packets,
+// Err returns why
+func (p *LPCMPayloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	p.err = nil
+	size := p.PacketSize()
+	if payload == nil || mtu <= 0 || size == 0 {
+		return out
+	}
+	if len(payload)%pcm.FrameSize(p.SampleSize, p.Channels) != 0 {
+		p.err = fmt.Errorf("payload of %d bytes holds a partial sample", len(payload))
+		return out
+	}
+	if len(payload) > size {
+		p.err = fmt.Errorf("payload of %d bytes is longer than a packet time of %d bytes", len(payload), size)
+		return out
+	}
+	if len(payload) > mtu {
+		p.err = fmt.Errorf("payload of %d bytes is larger than the MTU %d", len(payload), mtu)
+		return out
+	}
+
+	o := make([]byte, len(payload))
+	copy(o, payload)
+	return append(out, o)
+}
+
+// Samples returns the number of samples per channel in the payload, at the given clock rate
+func (p *LPCMPayloader) Samples(clockRate uint32, payload []byte) uint32 {
+	frameSize := pcm.FrameSize(p.SampleSize, p.Channels)
+	if frameSize <= 0 {
+		return 0
+	}
+	samples := len(payload) / frameSize
+	if p.SampleRate > 0 && clockRate > 0 && uint32(p.SampleRate) != clockRate {
+		samples = samples * int(clockRate) / p.SampleRate
+	}
+	return uint32(samples)
+}
+
+// LPCMPacket represents the L16 or L24 samples that are stored in the payload of an RTP Packet
+type LPCMPacket struct {
+	// SampleSize is the number of bytes of a sample: pcm.L16SampleSize or pcm.L24SampleSize
+	SampleSize int
+	Channels   int
+
+	// Frames is the number of samples per channel of the last packet
+	Frames int
+
+	Payload []byte
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the LPCMPacket this method is called upon
+// It returns the interleaved samples in network byte order, to be converted with pcm.ToLittleEndian if need be
+func (p *LPCMPacket) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if p.SampleSize != pcm.L16SampleSize && p.SampleSize != pcm.L24SampleSize || p.Channels <= 0 {
+		return nil, fmt.Errorf("invalid sample size %d or channels %d", p.SampleSize, p.Channels)
+	}
+	frameSize := pcm.FrameSize(p.SampleSize, p.Channels)
+	if len(packet)%frameSize != 0 {
+		return nil, fmt.Errorf("Payload of %d bytes is not a multiple of %d channels of %d bytes", len(packet), p.Channels, p.SampleSize)
+	}
+	p.Frames = len(packet) / frameSize
+	p.Payload = packet
+	return packet, nil
+}
@@ -0,0 +1,70 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/searKing/rtp/codecs/pcm"
+)
+
+func TestLPCMPayloader_Payload(t *testing.T) {
+	// L24 stereo at 48 kHz, 125 µs: 6 samples per channel, 36 bytes
+	pck := LPCMPayloader{SampleSize: pcm.L24SampleSize, SampleRate: 48000, Channels: 2, PacketTime: 125 * time.Microsecond}
+	if pck.PacketSize() != 36 || pck.ClockRate() != 48000 {
+		t.Fatalf("PacketSize should be 36 bytes, got %d", pck.PacketSize())
+	}
+	payload := make([]byte, 36*2+6)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	if res := pck.Payload(100, payload[:5]); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty for a partial sample")
+	}
+	res := pck.Payload(100, payload[:36])
+	if len(res) != 1 || !bytes.Equal(res[0], payload[:36]) || pck.Err() != nil {
+		t.Fatal("Generated payload should be a packet time of samples")
+	}
+	res = pck.Payload(100, payload[36:42])
+	if len(res) != 1 || !bytes.Equal(res[0], payload[36:42]) {
+		t.Fatal("Generated payload should keep a shorter payload")
+	}
+	if pck.Samples(48000, payload[:36]) != 6 || pck.Samples(96000, payload[:36]) != 12 {
+		t.Fatal("Samples should count the samples per channel")
+	}
+
+	// a payload longer than a packet time would stamp several packets with one timestamp
+	if res = pck.Payload(100, payload); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty for more than a packet time")
+	}
+	// a payload larger than the MTU
+	if res = pck.Payload(20, payload[:36]); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty for more than the MTU")
+	}
+
+	// packet time out of range
+	pck.PacketTime = 40 * time.Millisecond
+	if res = pck.Payload(100, payload); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+	// default packet time
+	pck = LPCMPayloader{SampleSize: pcm.L16SampleSize, SampleRate: 44100, Channels: 1}
+	if pck.PacketSize() != 88 {
+		t.Fatalf("PacketSize should default to 1 ms, got %d", pck.PacketSize())
+	}
+}
+
+func TestLPCMPacket_Unmarshal(t *testing.T) {
+	pck := LPCMPacket{SampleSize: pcm.L16SampleSize, Channels: 2}
+	if _, err := pck.Unmarshal(nil); err == nil {
+		t.Fatal("Unmarshal should reject a nil packet")
+	}
+	if _, err := pck.Unmarshal([]byte{0x01, 0x02}); err == nil {
+		t.Fatal("Unmarshal should reject a partial frame")
+	}
+	raw, err := pck.Unmarshal([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08})
+	if err != nil || len(raw) != 8 || pck.Frames != 2 {
+		t.Fatalf("Unmarshal should count 2 frames, got %d %v", pck.Frames, err)
+	}
+}
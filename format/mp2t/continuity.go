@@ -0,0 +1,49 @@
+package mp2t
+
+import "fmt"
+
+// ContinuityChecker tracks the continuity_counter of each PID, 2.4.3.3 in ISO/IEC 13818-1
+// The zero value is ready to use
+type ContinuityChecker struct {
+	counters map[uint16]continuity
+}
+
+type continuity struct {
+	counter    uint8
+	duplicated bool
+}
+
+// Check updates the counter of the packet PID with the packet.
+// It reports whether the packet duplicates the previous one of its PID, which is allowed once,
+// and returns an error if packets of the PID were lost
+func (c *ContinuityChecker) Check(p Packet) (duplicate bool, err error) {
+	h := p.Header
+	if h.PID == PIDNull || !h.HasPayload() {
+		// the counter does not increment for packets without payload
+		return false, nil
+	}
+	if c.counters == nil {
+		c.counters = make(map[uint16]continuity)
+	}
+	last, ok := c.counters[h.PID]
+	c.counters[h.PID] = continuity{counter: h.ContinuityCounter}
+	if !ok || (p.AdaptationField != nil && p.AdaptationField.DiscontinuityIndicator) {
+		return false, nil
+	}
+	switch h.ContinuityCounter {
+	case (last.counter + 1) & continuityCounterMask:
+		return false, nil
+	case last.counter:
+		if !last.duplicated {
+			c.counters[h.PID] = continuity{counter: h.ContinuityCounter, duplicated: true}
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("PID 0x%04x continuity counter %d, expected %d",
+		h.PID, h.ContinuityCounter, (last.counter+1)&continuityCounterMask)
+}
+
+// Reset forgets the counters of all PIDs
+func (c *ContinuityChecker) Reset() {
+	c.counters = nil
+}
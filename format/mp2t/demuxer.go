@@ -0,0 +1,294 @@
+package mp2t
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PES is an elementary stream packet reassembled by the Demuxer
+type PES struct {
+	PID        uint16
+	StreamType StreamType
+	Header     PESHeader
+	// RandomAccess is set when the first transport packet of the PES packet had the random_access_indicator set
+	RandomAccess bool
+	// Data holds the PES packet data: an Annex B byte stream for H.264, ADTS frames for AAC
+	Data []byte
+}
+
+// Demuxer extracts the PES packets of the elementary streams of a transport stream,
+// following the PAT and the PMTs of its programs.
+// The zero value is ready to use
+type Demuxer struct {
+	continuity ContinuityChecker
+
+	// PSI sections being reassembled, keyed by PID
+	sections map[uint16][]byte
+	// program_number of each PMT, keyed by PID
+	pmts    map[uint16]uint16
+	streams map[uint16]*demuxerStream
+}
+
+type demuxerStream struct {
+	PMTStream
+	program uint16
+
+	data         []byte
+	started      bool
+	randomAccess bool
+}
+
+// Streams returns the elementary streams announced by the PMTs read so far, ordered by PID
+func (d *Demuxer) Streams() []PMTStream {
+	var streams []PMTStream
+	for _, s := range d.streams {
+		streams = append(streams, s.PMTStream)
+	}
+	sort.Slice(streams, func(i, j int) bool { return streams[i].PID < streams[j].PID })
+	return streams
+}
+
+// Write demuxes transport packets, whose size shall be a multiple of 188 bytes,
+// and returns the PES packets they complete.
+// A PES packet without PES_packet_length is completed by the start of the next one of its PID.
+// Errors do not stop demuxing: the PES packets completed by the other transport packets are returned
+// alongside the first error, and a PES packet missing transport packets is dropped
+func (d *Demuxer) Write(buf []byte) ([]*PES, error) {
+	if len(buf)%PacketSize != 0 {
+		return nil, fmt.Errorf("TS size %d is not a multiple of %d", len(buf), PacketSize)
+	}
+	var out []*PES
+	var firstErr error
+	for ; len(buf) > 0; buf = buf[PacketSize:] {
+		pes, err := d.writePacket(buf[:PacketSize])
+		out = append(out, pes...)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return out, firstErr
+}
+
+// Flush returns the PES packets still buffered, at the end of the stream
+func (d *Demuxer) Flush() []*PES {
+	var pids []int
+	for pid := range d.streams {
+		pids = append(pids, int(pid))
+	}
+	sort.Ints(pids)
+	var out []*PES
+	for _, pid := range pids {
+		if pes, err := d.streams[uint16(pid)].flush(); err == nil && pes != nil {
+			out = append(out, pes)
+		}
+	}
+	return out
+}
+
+func (d *Demuxer) writePacket(buf []byte) ([]*PES, error) {
+	p, err := ParsePacket(buf)
+	if err != nil {
+		return nil, err
+	}
+	h := p.Header
+	if h.TransportErrorIndicator || h.PID == PIDNull {
+		return nil, nil
+	}
+	duplicate, err := d.continuity.Check(p)
+	if duplicate || !h.HasPayload() {
+		return nil, nil
+	}
+	if h.PID == PIDPAT || d.pmts[h.PID] != 0 {
+		if err != nil {
+			delete(d.sections, h.PID)
+		}
+		if perr := d.writeSection(h.PID, h.PayloadUnitStartIndicator, p.Payload); err == nil {
+			err = perr
+		}
+		return nil, err
+	}
+
+	s, ok := d.streams[h.PID]
+	if !ok {
+		return nil, nil
+	}
+	var out []*PES
+	if err != nil {
+		// lost transport packets, drop the PES packet in progress
+		s.data, s.started = nil, false
+	}
+	if h.PayloadUnitStartIndicator {
+		pes, perr := s.flush()
+		if pes != nil {
+			out = append(out, pes)
+		}
+		if err == nil {
+			err = perr
+		}
+		s.started = true
+		s.randomAccess = p.AdaptationField != nil && p.AdaptationField.RandomAccessIndicator
+	}
+	if !s.started {
+		return out, err
+	}
+	s.data = append(s.data, p.Payload...)
+	if pes, perr := s.complete(); pes != nil || perr != nil {
+		if pes != nil {
+			out = append(out, pes)
+		}
+		if err == nil {
+			err = perr
+		}
+	}
+	return out, err
+}
+
+// writeSection reassembles the PSI sections of a PID, see 2.4.4.1 and 2.4.4.2 in ISO/IEC 13818-1
+func (d *Demuxer) writeSection(pid uint16, start bool, payload []byte) error {
+	if d.sections == nil {
+		d.sections = make(map[uint16][]byte)
+	}
+	data, ok := d.sections[pid]
+	if start {
+		if len(payload) < 1 || int(payload[0]) >= len(payload) {
+			delete(d.sections, pid)
+			return fmt.Errorf("PID 0x%04x invalid pointer_field", pid)
+		}
+		pointer := int(payload[0])
+		// the bytes before the pointer end the previous section
+		if ok {
+			data = append(data, payload[1:1+pointer]...)
+			if err := d.readSections(pid, data); err != nil {
+				return err
+			}
+		}
+		data, ok = payload[1+pointer:], true
+	} else if ok {
+		data = append(data, payload...)
+	}
+	if !ok {
+		return nil
+	}
+	d.sections[pid] = data
+	return d.readSections(pid, data)
+}
+
+// readSections reads the complete sections buffered for the PID, keeping the incomplete one
+func (d *Demuxer) readSections(pid uint16, data []byte) error {
+	for len(data) > 0 && data[0] != 0xff {
+		size, err := sectionSize(data)
+		if err != nil {
+			if len(data) < sectionHeaderSize {
+				break
+			}
+			delete(d.sections, pid)
+			return err
+		}
+		if len(data) < size {
+			break
+		}
+		if err := d.readSection(pid, data[:size]); err != nil {
+			delete(d.sections, pid)
+			return err
+		}
+		data = data[size:]
+	}
+	if len(data) == 0 || data[0] == 0xff {
+		// stuffing up to the end of the packet
+		delete(d.sections, pid)
+		return nil
+	}
+	d.sections[pid] = append([]byte(nil), data...)
+	return nil
+}
+
+func (d *Demuxer) readSection(pid uint16, section []byte) error {
+	if pid == PIDPAT {
+		var pat PAT
+		if err := (&pat).Unmarshal(section); err != nil {
+			return err
+		}
+		d.pmts = make(map[uint16]uint16)
+		for _, program := range pat.Programs {
+			if program.ProgramNumber != 0 {
+				d.pmts[program.PID] = program.ProgramNumber
+			}
+		}
+		for pid, s := range d.streams {
+			if _, ok := d.pmtProgram(s.program); !ok {
+				delete(d.streams, pid)
+			}
+		}
+		return nil
+	}
+	if section[0] != TableIDPMT {
+		// other tables may share the PID
+		return nil
+	}
+	var pmt PMT
+	if err := (&pmt).Unmarshal(section); err != nil {
+		return err
+	}
+	if d.streams == nil {
+		d.streams = make(map[uint16]*demuxerStream)
+	}
+	announced := make(map[uint16]bool)
+	for _, stream := range pmt.Streams {
+		announced[stream.PID] = true
+		if s, ok := d.streams[stream.PID]; ok && s.StreamType == stream.StreamType {
+			s.PMTStream = stream
+			continue
+		}
+		d.streams[stream.PID] = &demuxerStream{PMTStream: stream, program: pmt.ProgramNumber}
+	}
+	for pid, s := range d.streams {
+		if s.program == pmt.ProgramNumber && !announced[pid] {
+			delete(d.streams, pid)
+		}
+	}
+	return nil
+}
+
+func (d *Demuxer) pmtProgram(program uint16) (uint16, bool) {
+	for pid, p := range d.pmts {
+		if p == program {
+			return pid, true
+		}
+	}
+	return 0, false
+}
+
+// complete returns the PES packet buffered if its PES_packet_length is reached
+func (s *demuxerStream) complete() (*PES, error) {
+	if len(s.data) < pesStartCodeSize {
+		return nil, nil
+	}
+	length := int(s.data[4])<<8 | int(s.data[5])
+	if length == 0 || len(s.data) < pesStartCodeSize+length {
+		return nil, nil
+	}
+	return s.flush()
+}
+
+// flush returns the PES packet buffered, if any
+func (s *demuxerStream) flush() (*PES, error) {
+	data := s.data
+	started := s.started
+	s.data, s.started = nil, false
+	if !started || len(data) == 0 {
+		return nil, nil
+	}
+	pes := &PES{PID: s.PID, StreamType: s.StreamType, RandomAccess: s.randomAccess}
+	n, err := (&pes.Header).Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("PID 0x%04x: %v", s.PID, err)
+	}
+	if length := pes.Header.PacketLength; length != 0 {
+		if len(data) < pesStartCodeSize+length {
+			return nil, fmt.Errorf("PID 0x%04x PES packet truncated to %d of %d bytes", s.PID, len(data)-pesStartCodeSize, length)
+		}
+		data = data[:pesStartCodeSize+length]
+	}
+	pes.Data = data[n:]
+	return pes, nil
+}
@@ -0,0 +1,75 @@
+package mp2t
+
+import "fmt"
+
+const (
+	// ISO/IEC 13818-1 2.4.3.2: transport packets are 188 bytes long, starting with the sync byte
+	PacketSize = 188
+	SyncByte   = 0x47
+
+	// RFC 2250 2: the RTP clock rate of MP2T is 90 kHz
+	ClockRate = 90000
+)
+
+// Table 2-3 – PID table in ISO/IEC 13818-1
+const (
+	PIDPAT  uint16 = 0x0000
+	PIDCAT  uint16 = 0x0001
+	PIDTSDT uint16 = 0x0002
+	PIDNull uint16 = 0x1fff
+)
+
+// StreamType is the stream_type of a program map table entry, Table 2-34 in ISO/IEC 13818-1
+type StreamType uint8
+
+const (
+	StreamTypeMPEG1Video StreamType = 0x01
+	StreamTypeMPEG2Video StreamType = 0x02
+	StreamTypeMPEG1Audio StreamType = 0x03
+	StreamTypeMPEG2Audio StreamType = 0x04
+	StreamTypePrivate    StreamType = 0x06
+	StreamTypeAACADTS    StreamType = 0x0f
+	StreamTypeMPEG4Video StreamType = 0x10
+	StreamTypeAACLATM    StreamType = 0x11
+	StreamTypeH264       StreamType = 0x1b
+	StreamTypeHEVC       StreamType = 0x24
+	StreamTypeAC3        StreamType = 0x81
+)
+
+func (t StreamType) String() string {
+	switch t {
+	case StreamTypeMPEG1Video:
+		return "MPEG-1 video"
+	case StreamTypeMPEG2Video:
+		return "MPEG-2 video"
+	case StreamTypeMPEG1Audio:
+		return "MPEG-1 audio"
+	case StreamTypeMPEG2Audio:
+		return "MPEG-2 audio"
+	case StreamTypePrivate:
+		return "private PES"
+	case StreamTypeAACADTS:
+		return "AAC ADTS"
+	case StreamTypeMPEG4Video:
+		return "MPEG-4 video"
+	case StreamTypeAACLATM:
+		return "AAC LATM"
+	case StreamTypeH264:
+		return "H.264"
+	case StreamTypeHEVC:
+		return "HEVC"
+	case StreamTypeAC3:
+		return "AC-3"
+	default:
+		return fmt.Sprintf("stream type 0x%02x", uint8(t))
+	}
+}
+
+// Video reports whether the stream type carries video
+func (t StreamType) Video() bool {
+	switch t {
+	case StreamTypeMPEG1Video, StreamTypeMPEG2Video, StreamTypeMPEG4Video, StreamTypeH264, StreamTypeHEVC:
+		return true
+	}
+	return false
+}
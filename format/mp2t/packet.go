@@ -0,0 +1,254 @@
+package mp2t
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// The transport packet header, 2.4.3.2 in ISO/IEC 13818-1
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|   sync byte   |E|S|T|            PID          |SC |AFC|  CC   |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	headerSize = 4
+
+	transportErrorIndicatorMask   = 0x80
+	payloadUnitStartIndicatorMask = 0x40
+	transportPriorityMask         = 0x20
+	pidMask                       = 0x1fff
+	scramblingControlMask         = 0xc0
+	scramblingControlOffset       = 6
+	adaptationFieldControlMask    = 0x30
+	adaptationFieldControlOffset  = 4
+	continuityCounterMask         = 0x0f
+
+	adaptationFieldPresent = 0x2
+	payloadPresent         = 0x1
+)
+
+// Header represents the 4-byte header of a transport packet, 2.4.3.2 in ISO/IEC 13818-1
+type Header struct {
+	TransportErrorIndicator   bool
+	PayloadUnitStartIndicator bool
+	TransportPriority         bool
+	PID                       uint16
+	ScramblingControl         uint8
+	AdaptationFieldControl    uint8
+	ContinuityCounter         uint8
+}
+
+// HasAdaptationField reports whether the packet carries an adaptation field
+func (h Header) HasAdaptationField() bool {
+	return h.AdaptationFieldControl&adaptationFieldPresent != 0
+}
+
+// HasPayload reports whether the packet carries a payload
+func (h Header) HasPayload() bool {
+	return h.AdaptationFieldControl&payloadPresent != 0
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the Header this method is called upon
+func (h *Header) Unmarshal(buf []byte) error {
+	if len(buf) < headerSize {
+		return fmt.Errorf("buf is not large enough to container TS header")
+	}
+	if buf[0] != SyncByte {
+		return fmt.Errorf("invalid TS sync byte 0x%02x", buf[0])
+	}
+	h.TransportErrorIndicator = buf[1]&transportErrorIndicatorMask != 0
+	h.PayloadUnitStartIndicator = buf[1]&payloadUnitStartIndicatorMask != 0
+	h.TransportPriority = buf[1]&transportPriorityMask != 0
+	h.PID = binary.BigEndian.Uint16(buf[1:]) & pidMask
+	h.ScramblingControl = (buf[3] & scramblingControlMask) >> scramblingControlOffset
+	h.AdaptationFieldControl = (buf[3] & adaptationFieldControlMask) >> adaptationFieldControlOffset
+	h.ContinuityCounter = buf[3] & continuityCounterMask
+	return nil
+}
+
+// Marshal serializes the header into bytes.
+func (h Header) Marshal() ([]byte, error) {
+	buf := make([]byte, headerSize)
+	buf[0] = SyncByte
+	binary.BigEndian.PutUint16(buf[1:], h.PID&pidMask)
+	if h.TransportErrorIndicator {
+		buf[1] |= transportErrorIndicatorMask
+	}
+	if h.PayloadUnitStartIndicator {
+		buf[1] |= payloadUnitStartIndicatorMask
+	}
+	if h.TransportPriority {
+		buf[1] |= transportPriorityMask
+	}
+	buf[3] = h.ScramblingControl<<scramblingControlOffset&scramblingControlMask |
+		h.AdaptationFieldControl<<adaptationFieldControlOffset&adaptationFieldControlMask |
+		h.ContinuityCounter&continuityCounterMask
+	return buf, nil
+}
+
+// AdaptationField represents the adaptation field of a transport packet, 2.4.3.4 in ISO/IEC 13818-1
+type AdaptationField struct {
+	DiscontinuityIndicator bool
+	RandomAccessIndicator  bool
+	ESPriorityIndicator    bool
+	// PCR is the program clock reference, in 27 MHz units
+	HasPCR bool
+	PCR    uint64
+	// Length is the adaptation_field_length, stuffing included
+	Length int
+}
+
+// Unmarshal parses the passed byte slice, starting at adaptation_field_length,
+// and stores the result in the AdaptationField this method is called upon
+func (a *AdaptationField) Unmarshal(buf []byte) error {
+	if len(buf) < 1 {
+		return fmt.Errorf("buf is not large enough to container adaptation field")
+	}
+	*a = AdaptationField{Length: int(buf[0])}
+	if len(buf) < 1+a.Length {
+		return fmt.Errorf("adaptation field length %d exceeds remaining %d bytes", a.Length, len(buf)-1)
+	}
+	if a.Length == 0 {
+		return nil
+	}
+	flags := buf[1]
+	a.DiscontinuityIndicator = flags&0x80 != 0
+	a.RandomAccessIndicator = flags&0x40 != 0
+	a.ESPriorityIndicator = flags&0x20 != 0
+	a.HasPCR = flags&0x10 != 0
+	if a.HasPCR {
+		if a.Length < 7 {
+			return fmt.Errorf("adaptation field length %d is not large enough to container PCR", a.Length)
+		}
+		b := buf[2:8]
+		base := uint64(b[0])<<25 | uint64(b[1])<<17 | uint64(b[2])<<9 | uint64(b[3])<<1 | uint64(b[4])>>7
+		ext := uint64(b[4]&0x01)<<8 | uint64(b[5])
+		a.PCR = base*300 + ext
+	}
+	return nil
+}
+
+// Marshal serializes the adaptation field into bytes, stuffed up to Length
+func (a AdaptationField) Marshal() ([]byte, error) {
+	minLength := 1
+	if a.HasPCR {
+		minLength += 6
+	}
+	length := a.Length
+	if length < minLength {
+		length = minLength
+	}
+	if length > PacketSize-headerSize-1 {
+		return nil, fmt.Errorf("adaptation field length %d exceeds %d", length, PacketSize-headerSize-1)
+	}
+	w := bytes.NewBuffer(make([]byte, 0, 1+length))
+	w.WriteByte(byte(length))
+	var flags byte
+	if a.DiscontinuityIndicator {
+		flags |= 0x80
+	}
+	if a.RandomAccessIndicator {
+		flags |= 0x40
+	}
+	if a.ESPriorityIndicator {
+		flags |= 0x20
+	}
+	if a.HasPCR {
+		flags |= 0x10
+	}
+	w.WriteByte(flags)
+	if a.HasPCR {
+		base, ext := a.PCR/300, a.PCR%300
+		w.Write([]byte{
+			byte(base >> 25), byte(base >> 17), byte(base >> 9), byte(base >> 1),
+			byte(base<<7) | 0x7e | byte(ext>>8), byte(ext),
+		})
+	}
+	for w.Len() < 1+length {
+		w.WriteByte(0xff)
+	}
+	return w.Bytes(), nil
+}
+
+// Packet represents a 188-byte transport packet
+type Packet struct {
+	Header          Header
+	AdaptationField *AdaptationField
+	Payload         []byte
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the Packet this method is called upon
+func (p *Packet) Unmarshal(buf []byte) error {
+	if len(buf) < PacketSize {
+		return fmt.Errorf("buf is not large enough to container TS packet")
+	}
+	buf = buf[:PacketSize]
+	if err := (&p.Header).Unmarshal(buf); err != nil {
+		return err
+	}
+	p.AdaptationField = nil
+	p.Payload = nil
+	index := headerSize
+	if p.Header.HasAdaptationField() {
+		p.AdaptationField = &AdaptationField{}
+		if err := p.AdaptationField.Unmarshal(buf[index:]); err != nil {
+			return err
+		}
+		index += 1 + p.AdaptationField.Length
+	}
+	if p.Header.HasPayload() {
+		p.Payload = buf[index:]
+	}
+	return nil
+}
+
+// Marshal serializes the packet into bytes, stuffing the adaptation field so that it is 188 bytes long
+func (p Packet) Marshal() ([]byte, error) {
+	h := p.Header
+	room := PacketSize - headerSize - len(p.Payload)
+	if room < 0 {
+		return nil, fmt.Errorf("payload size %d exceeds %d", len(p.Payload), PacketSize-headerSize)
+	}
+	var af []byte
+	if p.AdaptationField != nil || room > 0 {
+		a := AdaptationField{}
+		if p.AdaptationField != nil {
+			a = *p.AdaptationField
+		}
+		a.Length = room - 1
+		if room == 1 {
+			// a single byte of stuffing: adaptation_field_length 0
+			af = []byte{0}
+		} else {
+			var err error
+			if af, err = a.Marshal(); err != nil {
+				return nil, err
+			}
+		}
+		if len(af) != room {
+			return nil, fmt.Errorf("payload size %d leaves no room for the adaptation field", len(p.Payload))
+		}
+	}
+	h.AdaptationFieldControl = 0
+	if af != nil {
+		h.AdaptationFieldControl |= adaptationFieldPresent
+	}
+	if len(p.Payload) > 0 {
+		h.AdaptationFieldControl |= payloadPresent
+	}
+	hdr, _ := h.Marshal()
+	w := bytes.NewBuffer(make([]byte, 0, PacketSize))
+	w.Write(hdr)
+	w.Write(af)
+	w.Write(p.Payload)
+	return w.Bytes(), nil
+}
+
+func ParsePacket(buf []byte) (Packet, error) {
+	var p Packet
+	err := (&p).Unmarshal(buf)
+	return p, err
+}
@@ -0,0 +1,162 @@
+package mp2t
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Table 2-22 – Stream_id assignments in ISO/IEC 13818-1
+const (
+	StreamIDProgramStreamMap uint8 = 0xbc
+	StreamIDPrivateStream1   uint8 = 0xbd
+	StreamIDPaddingStream    uint8 = 0xbe
+	StreamIDPrivateStream2   uint8 = 0xbf
+	StreamIDAudio            uint8 = 0xc0 // audio streams are 0xc0 to 0xdf
+	StreamIDVideo            uint8 = 0xe0 // video streams are 0xe0 to 0xef
+)
+
+const (
+	// packet_start_code_prefix, stream_id and PES_packet_length
+	pesStartCodeSize = 6
+	// the flags and PES_header_data_length of the optional header
+	pesOptionalHeaderSize = 3
+	pesTimestampSize      = 5
+
+	ptsFlag = 0x80
+	dtsFlag = 0x40
+)
+
+// PESHeader represents the header of a PES packet, 2.4.3.6 in ISO/IEC 13818-1
+type PESHeader struct {
+	StreamID uint8
+	// PacketLength is the PES_packet_length; 0 means unbounded, which is only allowed for video
+	PacketLength int
+	// PTS and DTS are in 90 kHz units
+	HasPTS bool
+	PTS    uint64
+	HasDTS bool
+	DTS    uint64
+	// DataAlignmentIndicator is set when the payload starts with an access unit, or a video start code
+	DataAlignmentIndicator bool
+}
+
+// hasOptionalHeader reports whether the stream_id is followed by the optional PES header
+func hasOptionalHeader(streamID uint8) bool {
+	switch streamID {
+	case StreamIDProgramStreamMap, StreamIDPaddingStream, StreamIDPrivateStream2,
+		0xf0, 0xf1, 0xf2, 0xf8, 0xff:
+		return false
+	}
+	return true
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the PESHeader this method is called upon
+// It returns the size of the header, the offset of the PES packet data
+func (h *PESHeader) Unmarshal(buf []byte) (int, error) {
+	if len(buf) < pesStartCodeSize {
+		return 0, fmt.Errorf("buf is not large enough to container PES header")
+	}
+	if buf[0] != 0 || buf[1] != 0 || buf[2] != 1 {
+		return 0, fmt.Errorf("invalid PES packet_start_code_prefix %x", buf[:3])
+	}
+	*h = PESHeader{
+		StreamID:     buf[3],
+		PacketLength: int(binary.BigEndian.Uint16(buf[4:])),
+	}
+	if !hasOptionalHeader(h.StreamID) {
+		return pesStartCodeSize, nil
+	}
+	if len(buf) < pesStartCodeSize+pesOptionalHeaderSize {
+		return 0, fmt.Errorf("buf is not large enough to container PES optional header")
+	}
+	flags := buf[pesStartCodeSize : pesStartCodeSize+pesOptionalHeaderSize]
+	if flags[0]&0xc0 != 0x80 {
+		return 0, fmt.Errorf("invalid PES optional header marker 0x%02x", flags[0])
+	}
+	h.DataAlignmentIndicator = flags[0]&0x04 != 0
+	size := pesStartCodeSize + pesOptionalHeaderSize + int(flags[2])
+	if len(buf) < size {
+		return 0, fmt.Errorf("PES_header_data_length %d exceeds remaining %d bytes", flags[2], len(buf)-pesStartCodeSize-pesOptionalHeaderSize)
+	}
+	data := buf[pesStartCodeSize+pesOptionalHeaderSize : size]
+	if flags[1]&ptsFlag != 0 {
+		if len(data) < pesTimestampSize {
+			return 0, fmt.Errorf("PES header is not large enough to container PTS")
+		}
+		h.HasPTS = true
+		h.PTS = parseTimestamp(data)
+		data = data[pesTimestampSize:]
+		if flags[1]&dtsFlag != 0 {
+			if len(data) < pesTimestampSize {
+				return 0, fmt.Errorf("PES header is not large enough to container DTS")
+			}
+			h.HasDTS = true
+			h.DTS = parseTimestamp(data)
+		}
+	}
+	return size, nil
+}
+
+// Marshal serializes the PES header into bytes.
+// PacketLength is written as is, the caller sets it to the size of the following bytes, or 0
+func (h PESHeader) Marshal() ([]byte, error) {
+	buf := []byte{0, 0, 1, h.StreamID, byte(h.PacketLength >> 8), byte(h.PacketLength)}
+	if !hasOptionalHeader(h.StreamID) {
+		return buf, nil
+	}
+	var flags [pesOptionalHeaderSize]byte
+	flags[0] = 0x80
+	if h.DataAlignmentIndicator {
+		flags[0] |= 0x04
+	}
+	var data []byte
+	if h.HasPTS {
+		prefix := byte(0x20)
+		if h.HasDTS {
+			prefix = 0x30
+			flags[1] |= dtsFlag
+		}
+		flags[1] |= ptsFlag
+		data = append(data, marshalTimestamp(prefix, h.PTS)...)
+		if h.HasDTS {
+			data = append(data, marshalTimestamp(0x10, h.DTS)...)
+		}
+	} else if h.HasDTS {
+		return nil, fmt.Errorf("PES header with DTS requires a PTS")
+	}
+	flags[2] = byte(len(data))
+	buf = append(buf, flags[:]...)
+	return append(buf, data...), nil
+}
+
+// HeaderSize returns the size of the marshaled header
+func (h PESHeader) HeaderSize() int {
+	if !hasOptionalHeader(h.StreamID) {
+		return pesStartCodeSize
+	}
+	size := pesStartCodeSize + pesOptionalHeaderSize
+	if h.HasPTS {
+		size += pesTimestampSize
+	}
+	if h.HasDTS {
+		size += pesTimestampSize
+	}
+	return size
+}
+
+// parseTimestamp parses a 33-bit PTS or DTS, split by marker bits across 5 bytes
+func parseTimestamp(b []byte) uint64 {
+	return uint64(b[0]>>1&0x07)<<30 |
+		uint64(b[1])<<22 | uint64(b[2]>>1)<<15 |
+		uint64(b[3])<<7 | uint64(b[4]>>1)
+}
+
+func marshalTimestamp(prefix byte, ts uint64) []byte {
+	return []byte{
+		prefix | byte(ts>>29)&0x0e | 0x01,
+		byte(ts >> 22),
+		byte(ts>>14) | 0x01,
+		byte(ts >> 7),
+		byte(ts<<1) | 0x01,
+	}
+}
@@ -0,0 +1,261 @@
+package mp2t
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Table 2-31 – table_id assignment values in ISO/IEC 13818-1
+const (
+	TableIDPAT uint8 = 0x00
+	TableIDPMT uint8 = 0x02
+)
+
+const (
+	// table_id, section_syntax_indicator and section_length
+	sectionHeaderSize = 3
+	// transport_stream_id or program_number, version_number, section_number and last_section_number
+	sectionSyntaxSize = 5
+	crcSize           = 4
+
+	sectionLengthMask = 0x0fff
+	maxSectionLength  = 1021
+)
+
+// Section represents a long form PSI section, 2.4.4 in ISO/IEC 13818-1
+type Section struct {
+	TableID uint8
+	// TableIDExtension is the transport_stream_id of a PAT, the program_number of a PMT
+	TableIDExtension  uint16
+	Version           uint8
+	CurrentNext       bool
+	SectionNumber     uint8
+	LastSectionNumber uint8
+	// Data holds the table data, between the section header and the CRC_32
+	Data []byte
+}
+
+// Unmarshal parses the passed byte slice, starting at table_id, validates the CRC_32
+// and stores the result in the Section this method is called upon
+func (s *Section) Unmarshal(buf []byte) error {
+	size, err := sectionSize(buf)
+	if err != nil {
+		return err
+	}
+	if len(buf) < size {
+		return fmt.Errorf("buf is not large enough to container section of %d bytes", size)
+	}
+	buf = buf[:size]
+	if buf[1]&0x80 == 0 {
+		return fmt.Errorf("section with table_id 0x%02x is not a long form section", buf[0])
+	}
+	if size < sectionHeaderSize+sectionSyntaxSize+crcSize {
+		return fmt.Errorf("section length %d is not large enough", size-sectionHeaderSize)
+	}
+	if crc := binary.BigEndian.Uint32(buf[size-crcSize:]); crc != crc32(buf[:size-crcSize]) {
+		return fmt.Errorf("section CRC_32 0x%08x mismatch", crc)
+	}
+	s.TableID = buf[0]
+	s.TableIDExtension = binary.BigEndian.Uint16(buf[3:])
+	s.Version = (buf[5] >> 1) & 0x1f
+	s.CurrentNext = buf[5]&0x01 != 0
+	s.SectionNumber = buf[6]
+	s.LastSectionNumber = buf[7]
+	s.Data = buf[sectionHeaderSize+sectionSyntaxSize : size-crcSize]
+	return nil
+}
+
+// Marshal serializes the section into bytes, CRC_32 included.
+func (s Section) Marshal() ([]byte, error) {
+	length := sectionSyntaxSize + len(s.Data) + crcSize
+	if length > maxSectionLength {
+		return nil, fmt.Errorf("section length %d exceeds %d", length, maxSectionLength)
+	}
+	buf := make([]byte, sectionHeaderSize+length)
+	buf[0] = s.TableID
+	// section_syntax_indicator, '0' and reserved bits
+	binary.BigEndian.PutUint16(buf[1:], 0xb000|uint16(length))
+	binary.BigEndian.PutUint16(buf[3:], s.TableIDExtension)
+	buf[5] = 0xc0 | (s.Version&0x1f)<<1
+	if s.CurrentNext {
+		buf[5] |= 0x01
+	}
+	buf[6] = s.SectionNumber
+	buf[7] = s.LastSectionNumber
+	copy(buf[sectionHeaderSize+sectionSyntaxSize:], s.Data)
+	binary.BigEndian.PutUint32(buf[len(buf)-crcSize:], crc32(buf[:len(buf)-crcSize]))
+	return buf, nil
+}
+
+// sectionSize returns the size of the section starting at buf, header included
+func sectionSize(buf []byte) (int, error) {
+	if len(buf) < sectionHeaderSize {
+		return 0, fmt.Errorf("buf is not large enough to container section header")
+	}
+	length := int(binary.BigEndian.Uint16(buf[1:]) & sectionLengthMask)
+	if length > maxSectionLength {
+		return 0, fmt.Errorf("section length %d exceeds %d", length, maxSectionLength)
+	}
+	return sectionHeaderSize + length, nil
+}
+
+// PATEntry maps a program to the PID of its program map table
+type PATEntry struct {
+	ProgramNumber uint16
+	// PID is the network_PID for program 0, the program_map_PID otherwise
+	PID uint16
+}
+
+// PAT represents the program association table, 2.4.4.3 in ISO/IEC 13818-1
+type PAT struct {
+	TransportStreamID uint16
+	Version           uint8
+	Programs          []PATEntry
+}
+
+// Unmarshal parses the passed byte slice, starting at table_id, and stores the result in the PAT this method is called upon
+func (t *PAT) Unmarshal(buf []byte) error {
+	var s Section
+	if err := (&s).Unmarshal(buf); err != nil {
+		return err
+	}
+	if s.TableID != TableIDPAT {
+		return fmt.Errorf("invalid PAT table_id 0x%02x", s.TableID)
+	}
+	if len(s.Data)%4 != 0 {
+		return fmt.Errorf("PAT data size %d is not a whole number of programs", len(s.Data))
+	}
+	t.TransportStreamID = s.TableIDExtension
+	t.Version = s.Version
+	t.Programs = nil
+	for data := s.Data; len(data) > 0; data = data[4:] {
+		t.Programs = append(t.Programs, PATEntry{
+			ProgramNumber: binary.BigEndian.Uint16(data),
+			PID:           binary.BigEndian.Uint16(data[2:]) & pidMask,
+		})
+	}
+	return nil
+}
+
+// Marshal serializes the PAT into a section.
+func (t PAT) Marshal() ([]byte, error) {
+	data := make([]byte, 4*len(t.Programs))
+	for i, p := range t.Programs {
+		binary.BigEndian.PutUint16(data[4*i:], p.ProgramNumber)
+		binary.BigEndian.PutUint16(data[4*i+2:], 0xe000|p.PID&pidMask)
+	}
+	return Section{
+		TableID:          TableIDPAT,
+		TableIDExtension: t.TransportStreamID,
+		Version:          t.Version,
+		CurrentNext:      true,
+		Data:             data,
+	}.Marshal()
+}
+
+// PMTStream describes an elementary stream of a program
+type PMTStream struct {
+	StreamType StreamType
+	PID        uint16
+	// Descriptors holds the raw ES_info descriptors
+	Descriptors []byte
+}
+
+// PMT represents the program map table, 2.4.4.8 in ISO/IEC 13818-1
+type PMT struct {
+	ProgramNumber uint16
+	Version       uint8
+	PCRPID        uint16
+	// Descriptors holds the raw program_info descriptors
+	Descriptors []byte
+	Streams     []PMTStream
+}
+
+// Unmarshal parses the passed byte slice, starting at table_id, and stores the result in the PMT this method is called upon
+func (t *PMT) Unmarshal(buf []byte) error {
+	var s Section
+	if err := (&s).Unmarshal(buf); err != nil {
+		return err
+	}
+	if s.TableID != TableIDPMT {
+		return fmt.Errorf("invalid PMT table_id 0x%02x", s.TableID)
+	}
+	data := s.Data
+	if len(data) < 4 {
+		return fmt.Errorf("PMT data is not large enough")
+	}
+	t.ProgramNumber = s.TableIDExtension
+	t.Version = s.Version
+	t.PCRPID = binary.BigEndian.Uint16(data) & pidMask
+	infoLength := int(binary.BigEndian.Uint16(data[2:]) & sectionLengthMask)
+	data = data[4:]
+	if infoLength > len(data) {
+		return fmt.Errorf("PMT program_info_length %d exceeds remaining %d bytes", infoLength, len(data))
+	}
+	t.Descriptors = data[:infoLength]
+	data = data[infoLength:]
+	t.Streams = nil
+	for len(data) > 0 {
+		if len(data) < 5 {
+			return fmt.Errorf("PMT stream entry is not large enough")
+		}
+		stream := PMTStream{
+			StreamType: StreamType(data[0]),
+			PID:        binary.BigEndian.Uint16(data[1:]) & pidMask,
+		}
+		infoLength := int(binary.BigEndian.Uint16(data[3:]) & sectionLengthMask)
+		data = data[5:]
+		if infoLength > len(data) {
+			return fmt.Errorf("PMT ES_info_length %d exceeds remaining %d bytes", infoLength, len(data))
+		}
+		stream.Descriptors = data[:infoLength]
+		data = data[infoLength:]
+		t.Streams = append(t.Streams, stream)
+	}
+	return nil
+}
+
+// Marshal serializes the PMT into a section.
+func (t PMT) Marshal() ([]byte, error) {
+	data := make([]byte, 4, 4+len(t.Descriptors)+5*len(t.Streams))
+	binary.BigEndian.PutUint16(data, 0xe000|t.PCRPID&pidMask)
+	binary.BigEndian.PutUint16(data[2:], 0xf000|uint16(len(t.Descriptors)))
+	data = append(data, t.Descriptors...)
+	for _, s := range t.Streams {
+		data = append(data, byte(s.StreamType),
+			byte(0xe0|s.PID>>8&0x1f), byte(s.PID),
+			byte(0xf0|len(s.Descriptors)>>8&0x0f), byte(len(s.Descriptors)))
+		data = append(data, s.Descriptors...)
+	}
+	return Section{
+		TableID:          TableIDPMT,
+		TableIDExtension: t.ProgramNumber,
+		Version:          t.Version,
+		CurrentNext:      true,
+		Data:             data,
+	}.Marshal()
+}
+
+var crcTable = func() (table [256]uint32) {
+	for i := range table {
+		c := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ 0x04c11db7
+			} else {
+				c <<= 1
+			}
+		}
+		table[i] = c
+	}
+	return table
+}()
+
+// crc32 computes the CRC_32 of PSI sections, Annex A in ISO/IEC 13818-1
+func crc32(buf []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range buf {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^b]
+	}
+	return crc
+}
@@ -0,0 +1,115 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/format/mp2t"
+)
+
+// MP2TPayloader payloads an MPEG-2 transport stream, see rfc2250#section-2
+// Each packet carries as many whole 188-byte transport packets as the MTU allows,
+// seven with an Ethernet MTU.  A transport packet split across calls to Payload
+// is buffered until its end is received
+type MP2TPayloader struct {
+	buffer []byte
+}
+
+// ClockRate returns the RTP clock rate of MP2T, 90 kHz
+func (p *MP2TPayloader) ClockRate() uint32 {
+	return mp2t.ClockRate
+}
+
+// Payload fragments a transport stream across one or more byte arrays, on transport packet boundaries
+// Bytes before a sync byte are skipped, to resynchronize on the transport packets
+func (p *MP2TPayloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	if payload == nil || mtu <= 0 {
+		return out
+	}
+	count := mtu / mp2t.PacketSize
+	if count == 0 {
+		return out
+	}
+
+	data := append(p.buffer, payload...)
+	p.buffer = nil
+	var pending []byte
+	flush := func() {
+		if len(pending) > 0 {
+			out = append(out, pending)
+		}
+		pending = nil
+	}
+	for len(data) >= mp2t.PacketSize {
+		if data[0] != mp2t.SyncByte {
+			data = data[1:]
+			continue
+		}
+		if pending == nil {
+			pending = make([]byte, 0, count*mp2t.PacketSize)
+		}
+		pending = append(pending, data[:mp2t.PacketSize]...)
+		data = data[mp2t.PacketSize:]
+		if len(pending) == count*mp2t.PacketSize {
+			flush()
+		}
+	}
+	flush()
+	if len(data) > 0 {
+		p.buffer = append([]byte(nil), data...)
+	}
+	return out
+}
+
+// MP2TPacket represents the transport packets that are stored in the payload of an RTP Packet
+type MP2TPacket struct {
+	// Packets holds the transport packets of the last packet, duplicates excluded
+	Packets []mp2t.Packet
+
+	Payload []byte
+
+	continuity mp2t.ContinuityChecker
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the MP2TPacket this method is called upon
+// Every transport packet shall start with the 0x47 sync byte.  Duplicate transport packets are removed;
+// if a continuity counter reveals lost transport packets, an error is returned, but Packets and Payload
+// still hold the transport packets received
+func (p *MP2TPacket) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) == 0 || len(packet)%mp2t.PacketSize != 0 {
+		return nil, fmt.Errorf("Payload size %d is not a multiple of %d", len(packet), mp2t.PacketSize)
+	}
+	p.Packets = nil
+	p.Payload = nil
+
+	var packets []mp2t.Packet
+	var payload []byte
+	for data := packet; len(data) > 0; data = data[mp2t.PacketSize:] {
+		pkt, err := mp2t.ParsePacket(data)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, pkt)
+	}
+
+	var lost error
+	for i, pkt := range packets {
+		duplicate, err := p.continuity.Check(pkt)
+		if err != nil && lost == nil {
+			lost = err
+		}
+		if duplicate {
+			continue
+		}
+		p.Packets = append(p.Packets, pkt)
+		payload = append(payload, packet[i*mp2t.PacketSize:(i+1)*mp2t.PacketSize]...)
+	}
+	p.Payload = payload
+	if lost != nil {
+		return nil, lost
+	}
+	return p.Payload, nil
+}
@@ -0,0 +1,256 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/searKing/rtp/format/mp2t"
+)
+
+// tsMuxer builds transport packets for tests
+type tsMuxer struct {
+	counters map[uint16]uint8
+}
+
+func (m *tsMuxer) packets(t *testing.T, pid uint16, randomAccess bool, data []byte) []byte {
+	if m.counters == nil {
+		m.counters = make(map[uint16]uint8)
+	}
+	var out []byte
+	start := true
+	for len(data) > 0 {
+		pkt := mp2t.Packet{Header: mp2t.Header{PID: pid, PayloadUnitStartIndicator: start, ContinuityCounter: m.counters[pid]}}
+		room := mp2t.PacketSize - 4
+		if start && randomAccess {
+			pkt.AdaptationField = &mp2t.AdaptationField{RandomAccessIndicator: true}
+			room -= 2
+		}
+		if len(data) < room && len(data) > room-2 {
+			// leave room for an adaptation field with stuffing
+			room -= 2
+		}
+		n := len(data)
+		if n > room {
+			n = room
+		}
+		pkt.Payload = data[:n]
+		data = data[n:]
+		raw, err := pkt.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, raw...)
+		m.counters[pid] = (m.counters[pid] + 1) & 0x0f
+		start = false
+	}
+	return out
+}
+
+func (m *tsMuxer) section(t *testing.T, pid uint16, section []byte, err error) []byte {
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.packets(t, pid, false, append([]byte{0}, section...))
+}
+
+func (m *tsMuxer) pes(t *testing.T, pid uint16, h mp2t.PESHeader, randomAccess bool, data []byte) []byte {
+	if h.StreamID != mp2t.StreamIDVideo {
+		h.PacketLength = h.HeaderSize() - 6 + len(data)
+	}
+	raw, err := h.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.packets(t, pid, randomAccess, append(raw, data...))
+}
+
+func TestMP2TPayloader_Payload(t *testing.T) {
+	pck := MP2TPayloader{}
+	if pck.ClockRate() != 90000 {
+		t.Fatalf("Clock rate should be 90000, got %d", pck.ClockRate())
+	}
+	var m tsMuxer
+	stream := m.packets(t, 0x100, false, bytes.Repeat([]byte{0x01}, 184*10))
+
+	// Nil payload
+	if res := pck.Payload(1400, nil); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+	// MTU smaller than a transport packet
+	if res := pck.Payload(187, stream); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+
+	// Seven transport packets per packet, then the rest
+	res := pck.Payload(1400, stream)
+	if len(res) != 2 || len(res[0]) != 7*188 || len(res[1]) != 3*188 {
+		t.Fatalf("Generated payload should be 7 and 3 transport packets, got %d packets", len(res))
+	}
+	if !bytes.Equal(append(res[0], res[1]...), stream) {
+		t.Fatal("Generated payload should be the transport stream")
+	}
+
+	// Transport packet split across calls, after garbage
+	res = pck.Payload(1400, append([]byte{0x00, 0x00}, stream[:300]...))
+	if len(res) != 1 || !bytes.Equal(res[0], stream[:188]) {
+		t.Fatal("Generated payload should be the first transport packet")
+	}
+	res = pck.Payload(1400, stream[300:376])
+	if len(res) != 1 || !bytes.Equal(res[0], stream[188:376]) {
+		t.Fatal("Generated payload should be the buffered transport packet")
+	}
+}
+
+func TestMP2TPacket_Unmarshal(t *testing.T) {
+	pck := MP2TPacket{}
+
+	// Nil packet
+	if raw, err := pck.Unmarshal(nil); raw != nil || err == nil {
+		t.Fatal("Unmarshal did not fail on nil payload")
+	}
+	// Not a whole number of transport packets
+	if raw, err := pck.Unmarshal(make([]byte, 100)); raw != nil || err == nil {
+		t.Fatal("Unmarshal accepted a partial transport packet")
+	}
+
+	var m tsMuxer
+	stream := m.packets(t, 0x100, false, bytes.Repeat([]byte{0x01}, 184*6))
+
+	// Invalid sync byte
+	bad := append([]byte(nil), stream[:2*188]...)
+	bad[188] = 0x48
+	if raw, err := pck.Unmarshal(bad); raw != nil || err == nil {
+		t.Fatal("Unmarshal accepted an invalid sync byte")
+	}
+
+	raw, err := pck.Unmarshal(stream[:2*188])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, stream[:2*188]) || len(pck.Packets) != 2 || pck.Packets[1].Header.ContinuityCounter != 1 {
+		t.Fatal("Unmarshal should return the transport packets")
+	}
+
+	// Duplicate transport packet
+	raw, err = pck.Unmarshal(append(append([]byte(nil), stream[188:2*188]...), stream[2*188:3*188]...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, stream[2*188:3*188]) {
+		t.Fatal("Unmarshal should drop the duplicate transport packet")
+	}
+
+	// Lost transport packet
+	if raw, err = pck.Unmarshal(stream[4*188 : 5*188]); raw != nil || err == nil {
+		t.Fatal("Unmarshal did not report the lost transport packet")
+	}
+	if !bytes.Equal(pck.Payload, stream[4*188:5*188]) {
+		t.Fatal("Payload should hold the transport packets received")
+	}
+	if raw, err = pck.Unmarshal(stream[5*188:]); err != nil || !bytes.Equal(raw, stream[5*188:]) {
+		t.Fatal("Unmarshal should resume after the lost transport packet")
+	}
+}
+
+func TestMP2TDemuxer(t *testing.T) {
+	var m tsMuxer
+	pat := mp2t.PAT{TransportStreamID: 1, Programs: []mp2t.PATEntry{{ProgramNumber: 1, PID: 0x1000}}}
+	pmt := mp2t.PMT{ProgramNumber: 1, PCRPID: 0x100, Streams: []mp2t.PMTStream{
+		{StreamType: mp2t.StreamTypeH264, PID: 0x100},
+		{StreamType: mp2t.StreamTypeAACADTS, PID: 0x101},
+	}}
+	au := append([]byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x1f, 0x00, 0x00, 0x00, 0x01, 0x68, 0xce, 0x3c, 0x80, 0x00, 0x00, 0x00, 0x01, 0x65},
+		bytes.Repeat([]byte{0xaa}, 3000)...)
+	adts, _ := adtsStream(t, 200, 100)
+
+	var stream []byte
+	patRaw, err := pat.Marshal()
+	stream = append(stream, m.section(t, mp2t.PIDPAT, patRaw, err)...)
+	pmtRaw, err := pmt.Marshal()
+	stream = append(stream, m.section(t, 0x1000, pmtRaw, err)...)
+	stream = append(stream, m.pes(t, 0x100, mp2t.PESHeader{StreamID: mp2t.StreamIDVideo, HasPTS: true, PTS: 1 << 32, HasDTS: true, DTS: 90000}, true, au)...)
+	stream = append(stream, m.pes(t, 0x101, mp2t.PESHeader{StreamID: mp2t.StreamIDAudio, HasPTS: true, PTS: 90000}, false, adts)...)
+	stream = append(stream, m.pes(t, 0x100, mp2t.PESHeader{StreamID: mp2t.StreamIDVideo, HasPTS: true, PTS: 93000}, false, au[:30])...)
+
+	var d mp2t.Demuxer
+	if _, err := d.Write(stream[:100]); err == nil {
+		t.Fatal("Write accepted a partial transport packet")
+	}
+	var pes []*mp2t.PES
+	for data := stream; len(data) > 0; data = data[7*188:] {
+		n := min(7*188, len(data))
+		out, err := d.Write(data[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		pes = append(pes, out...)
+		if n < 7*188 {
+			break
+		}
+	}
+	if streams := d.Streams(); len(streams) != 2 || streams[0].StreamType != mp2t.StreamTypeH264 || streams[1].PID != 0x101 {
+		t.Fatalf("Streams should be announced by the PMT, got %v", streams)
+	}
+	// the unbounded video PES packet is completed by the next one, the audio one by its length
+	if len(pes) != 2 {
+		t.Fatalf("Write should return 2 PES packets, got %d", len(pes))
+	}
+	audio, video := pes[0], pes[1]
+	if audio.PID != 0x101 || !bytes.Equal(audio.Data, adts) || audio.Header.PTS != 90000 || audio.Header.HasDTS {
+		t.Fatal("Write should return the AAC PES packet")
+	}
+	if video.StreamType != mp2t.StreamTypeH264 || !video.RandomAccess || !bytes.Equal(video.Data, au) ||
+		video.Header.PTS != 1<<32 || video.Header.DTS != 90000 {
+		t.Fatal("Write should return the H.264 PES packet")
+	}
+	pes = d.Flush()
+	if len(pes) != 1 || !bytes.Equal(pes[0].Data, au[:30]) || pes[0].Header.PTS != 93000 {
+		t.Fatal("Flush should return the last H.264 PES packet")
+	}
+
+	// re-packetize the elementary streams
+	// the SPS and PPS share a STAP-A, the IDR slice is fragmented in 3 FU-A
+	h264 := H264Payloader{}
+	res := h264.Payload(1200, video.Data)
+	if len(res) != 4 {
+		t.Fatalf("H264Payloader should make 4 packets out of the access unit, got %d", len(res))
+	}
+	stapA := []byte{0x78, 0x00, 0x04, 0x67, 0x42, 0x00, 0x1f, 0x00, 0x04, 0x68, 0xce, 0x3c, 0x80}
+	if !bytes.Equal(res[0], stapA) {
+		t.Fatalf("H264Payloader should aggregate the SPS and PPS in a STAP-A, got % x", res[0])
+	}
+	var slice []byte
+	for i, fuA := range res[1:] {
+		fuHeader := byte(0x05)
+		switch i {
+		case 0:
+			fuHeader |= 0x80
+		case 2:
+			fuHeader |= 0x40
+		}
+		if fuA[0] != 0x7c || fuA[1] != fuHeader {
+			t.Fatalf("H264Payloader should fragment the IDR slice in FU-A, got % x", fuA[:2])
+		}
+		slice = append(slice, fuA[2:]...)
+	}
+	if !bytes.Equal(slice, video.Data[21:]) {
+		t.Fatal("FU-A fragments should reassemble into the IDR slice")
+	}
+	aac := MPEG4GenericPayloader{}
+	if res := aac.Payload(1200, audio.Data); len(res) != 1 || len(res[0]) != 6+300 {
+		t.Fatal("MPEG4GenericPayloader should aggregate the ADTS frames")
+	}
+
+	// A lost transport packet drops the PES packet in progress
+	var lossy []byte
+	lossy = append(lossy, stream[:3*188]...)
+	lossy = append(lossy, stream[4*188:]...)
+	d = mp2t.Demuxer{}
+	pes, err = d.Write(lossy)
+	if err == nil {
+		t.Fatal("Write did not report the lost transport packet")
+	}
+	if len(pes) != 1 || pes[0].PID != 0x101 {
+		t.Fatal("Write should only return the AAC PES packet")
+	}
+}
@@ -0,0 +1,211 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/aac"
+)
+
+// LATMPayloader payloads AAC access units as MP4A-LATM, see rfc6416#section-6.
+// The StreamMuxConfig is conveyed out of band, in the SDP (cpresent=0),
+// so each packet carries AudioMuxElements made of PayloadLengthInfo and PayloadMux only
+type LATMPayloader struct {
+	// Config is the stream configuration; nil means one access unit per AudioMuxElement
+	Config *aac.StreamMuxConfig
+
+	err error
+}
+
+// Err returns the error of the last call to Payload, such as access units that do not make an AudioMuxElement
+func (p *LATMPayloader) Err() error {
+	return p.err
+}
+
+// UnmarshalFmtp configures the payloader from the parameters of an SDP fmtp line, such as
+// "profile-level-id=30;object=2;cpresent=0;config=400023203fc0"
+func (p *LATMPayloader) UnmarshalFmtp(fmtp string) error {
+	config, err := parseLATMFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.Config = config
+	return nil
+}
+
+// ClockRate returns the RTP clock rate, the sample rate of the configuration
+func (p *LATMPayloader) ClockRate() uint32 {
+	if p.Config == nil {
+		return 0
+	}
+	return uint32(p.Config.Config.SampleRate)
+}
+
+// Channels returns the number of channels of the configuration
+func (p *LATMPayloader) Channels() int {
+	if p.Config == nil {
+		return 0
+	}
+	return p.Config.Config.Channels()
+}
+
+// latmSubFrames returns the number of access units in each AudioMuxElement
+func latmSubFrames(config *aac.StreamMuxConfig) int {
+	if config == nil {
+		return 1
+	}
+	return int(config.NumSubFrames) + 1
+}
+
+// Payload fragments access units across one or more byte arrays
+// The payload is either a stream of ADTS frames, whose headers are stripped, or a single raw access unit.
+// Packetize stamps every packet of a payload with the same timestamp, so each call takes the numSubFrames+1
+// access units of a single AudioMuxElement, which is fragmented if larger than the MTU;
+// the RTP marker bit is set on the packet ending it, see rfc6416#section-6.1.
+// Payloads of any other number of access units result in no packets, Err returns why
+func (p *LATMPayloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	p.err = nil
+	if payload == nil || mtu <= 0 {
+		return out
+	}
+	aus := [][]byte{payload}
+	if aac.IsADTS(payload) {
+		var err error
+		if aus, _, err = aac.SplitADTS(payload); err != nil {
+			p.err = err
+			return out
+		}
+	}
+	if subFrames := latmSubFrames(p.Config); len(aus) != subFrames {
+		p.err = fmt.Errorf("%d access units do not make an AudioMuxElement of %d", len(aus), subFrames)
+		return out
+	}
+
+	w := bytes.NewBuffer(nil)
+	for _, au := range aus {
+		w.Write(aac.PayloadLengthInfo(len(au)))
+		w.Write(au)
+	}
+	element := w.Bytes()
+	for len(element) > 0 {
+		n := min(mtu, len(element))
+		o := make([]byte, n)
+		copy(o, element[:n])
+		out = append(out, o)
+		element = element[n:]
+	}
+	return out
+}
+
+// Samples returns the number of samples in the access units of the payload, at the given clock rate
+func (p *LATMPayloader) Samples(clockRate uint32, payload []byte) uint32 {
+	count := 1
+	if aac.IsADTS(payload) {
+		aus, _, err := aac.SplitADTS(payload)
+		if err != nil {
+			return 0
+		}
+		count = len(aus)
+	}
+	samples := aac.SamplesPerFrame
+	if p.Config != nil {
+		config := p.Config.Config
+		samples = config.SamplesPerFrame()
+		if config.SampleRate > 0 && clockRate > 0 && uint32(config.SampleRate) != clockRate {
+			samples = samples * int(clockRate) / config.SampleRate
+		}
+	}
+	if count != latmSubFrames(p.Config) {
+		return 0
+	}
+	return uint32(count * samples)
+}
+
+// LATMPacket represents the MP4A-LATM payload that is stored in the payload of an RTP Packet,
+// with an out-of-band StreamMuxConfig (cpresent=0)
+type LATMPacket struct {
+	// Config is the stream configuration; nil means one access unit per AudioMuxElement
+	Config *aac.StreamMuxConfig
+
+	// Marker is the RTP marker bit of the packet of the next call to Unmarshal, set by the caller:
+	// it ends an AudioMuxElement, while the packets without it carry its fragments
+	Marker bool
+
+	// AUs holds the access units completed by the last packet
+	AUs [][]byte
+
+	Payload []byte
+
+	fragment []byte
+}
+
+// UnmarshalFmtp configures the depacketizer from the parameters of an SDP fmtp line
+func (p *LATMPacket) UnmarshalFmtp(fmtp string) error {
+	config, err := parseLATMFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.Config = config
+	return nil
+}
+
+// latmMaxFragmentSize limits the fragments of an AudioMuxElement buffered until the RTP marker bit
+const latmMaxFragmentSize = 1 << 16
+
+// Unmarshal parses the passed byte slice and stores the result in the LATMPacket this method is called upon
+// It returns the access units completed by this packet, concatenated.  The fragments of an AudioMuxElement
+// are buffered until the packet with the RTP marker bit set, and nothing is returned until then;
+// an AudioMuxElement larger than 64 KiB, or whose access units do not add up, is dropped
+func (p *LATMPacket) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) == 0 {
+		return nil, fmt.Errorf("Payload is not large enough")
+	}
+	p.AUs = nil
+	p.Payload = nil
+
+	data := append(p.fragment, packet...)
+	p.fragment = nil
+	if !p.Marker {
+		if len(data) > latmMaxFragmentSize {
+			return nil, fmt.Errorf("AudioMuxElement fragments of %d bytes exceed %d bytes", len(data), latmMaxFragmentSize)
+		}
+		// a fragment, wait for the rest of the AudioMuxElement
+		p.fragment = data
+		return nil, nil
+	}
+
+	subFrames := latmSubFrames(p.Config)
+	for len(data) > 0 {
+		for i := 0; i < subFrames; i++ {
+			size, n, err := aac.ParsePayloadLengthInfo(data)
+			if err != nil || n+size > len(data) {
+				p.AUs = nil
+				return nil, fmt.Errorf("truncated AudioMuxElement of %d bytes", len(data))
+			}
+			p.AUs = append(p.AUs, data[n:n+size])
+			data = data[n+size:]
+		}
+	}
+	p.Payload = bytes.Join(p.AUs, nil)
+	return p.Payload, nil
+}
+
+func parseLATMFmtp(fmtp string) (*aac.StreamMuxConfig, error) {
+	params := parseFmtp(fmtp)
+	if cpresent, ok := params["cpresent"]; ok && cpresent != "0" {
+		return nil, fmt.Errorf("in-band StreamMuxConfig (cpresent=%s) is not supported", cpresent)
+	}
+	s, ok := params["config"]
+	if !ok {
+		return nil, fmt.Errorf("fmtp requires the config parameter")
+	}
+	config, err := aac.ParseLATMConfig(s)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
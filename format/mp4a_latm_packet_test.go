@@ -0,0 +1,98 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLATMPayloader_Payload(t *testing.T) {
+	var pck LATMPayloader
+	if err := pck.UnmarshalFmtp("profile-level-id=30;object=2;cpresent=0;config=400023203fc0"); err != nil {
+		t.Fatal(err)
+	}
+	if pck.ClockRate() != 48000 || pck.Channels() != 2 {
+		t.Fatalf("Clock rate and channels should be derived from config, got %d %d", pck.ClockRate(), pck.Channels())
+	}
+	if err := (&LATMPayloader{}).UnmarshalFmtp("cpresent=1"); err == nil {
+		t.Fatal("In-band config should fail")
+	}
+
+	// Nil payload
+	if res := pck.Payload(100, nil); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+
+	// a small access unit
+	au := bytes.Repeat([]byte{0x42}, 300)
+	res := pck.Payload(1500, au)
+	if len(res) != 1 || !bytes.Equal(res[0][:2], []byte{0xff, 0x2d}) || !bytes.Equal(res[0][2:], au) {
+		t.Fatal("AudioMuxElement is packed incorrectly")
+	}
+
+	var depack LATMPacket
+	depack.Marker = true
+	raw, err := depack.Unmarshal(res[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, au) {
+		t.Fatal("Access unit is depacketized incorrectly")
+	}
+
+	// an AudioMuxElement per call, from an ADTS stream
+	stream, aus := adtsStream(t, 300, 10)
+	if res = pck.Payload(100, stream); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty for more access units than an AudioMuxElement")
+	}
+	if samples := pck.Samples(48000, stream); samples != 0 {
+		t.Fatalf("Samples should be 0 for more access units than an AudioMuxElement, got %d", samples)
+	}
+	first, _ := adtsStream(t, 300)
+	if samples := pck.Samples(48000, first); samples != 1024 {
+		t.Fatalf("Samples should be 1024, got %d", samples)
+	}
+
+	// fragmented access units, reassembled on the marker bit
+	var got [][]byte
+	for _, au := range aus {
+		res = pck.Payload(100, au)
+		if len(res) != (len(au)+2+99)/100 {
+			t.Fatalf("Generated %d payloads for an access unit of %d bytes", len(res), len(au))
+		}
+		for i, payload := range res {
+			depack.Marker = i == len(res)-1
+			raw, err = depack.Unmarshal(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (raw != nil) != depack.Marker {
+				t.Fatal("Unmarshal should return the access unit on the marker bit only")
+			}
+			if raw != nil {
+				got = append(got, raw)
+			}
+		}
+	}
+	if len(got) != 2 || !bytes.Equal(got[0], aus[0]) || !bytes.Equal(got[1], aus[1]) {
+		t.Fatal("Fragmented access units are reassembled incorrectly")
+	}
+
+	// a truncated AudioMuxElement, its last fragment lost
+	res = pck.Payload(100, aus[0])
+	depack.Marker = false
+	if _, err = depack.Unmarshal(res[0]); err != nil {
+		t.Fatal(err)
+	}
+	depack.Marker = true
+	if raw, err = depack.Unmarshal(res[1]); raw != nil || err == nil {
+		t.Fatal("Unmarshal should drop a truncated AudioMuxElement")
+	}
+	// fragments without end
+	depack.Marker = false
+	for i := 0; i <= latmMaxFragmentSize/len(res[1]); i++ {
+		_, err = depack.Unmarshal(res[1])
+	}
+	if err == nil {
+		t.Fatal("Unmarshal should limit the buffered fragments")
+	}
+}
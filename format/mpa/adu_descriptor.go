@@ -0,0 +1,117 @@
+package mpa
+
+import (
+	"fmt"
+
+	"github.com/searKing/rtp/codecs/mpa"
+)
+
+// The ADU descriptor, 4.3 in rfc5219, which precedes each ADU, or fragment of ADU, of a packet
+//
+//	0 1 2 3 4 5 6 7
+//	+-+-+-+-+-+-+-+-+
+//	|C|0|ADU size   |  6-bit size
+//	+-+-+-+-+-+-+-+-+
+//
+//	0                   1
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|C|1|     ADU size (14 bits)    |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	ADUDescriptorCMask     = 0x80
+	ADUDescriptorTMask     = 0x40
+	ADUDescriptorSizeMask  = 0x3f
+	ADUDescriptorMaxSize6  = 0x3f
+	ADUDescriptorMaxSize14 = 0x3fff
+)
+
+// ADUDescriptor represents an ADU descriptor
+type ADUDescriptor struct {
+	// C is set on the packets continuing a fragmented ADU
+	C bool
+	// T is set for the 2-byte form, written when Size exceeds 6 bits
+	T bool
+	// Size is the size of the whole ADU, not of the fragment
+	Size int
+}
+
+// MarshalSize returns the size of the descriptor once serialized
+func (d ADUDescriptor) MarshalSize() int {
+	if d.T || d.Size > ADUDescriptorMaxSize6 {
+		return 2
+	}
+	return 1
+}
+
+// Marshal serializes the descriptor into bytes, in the 2-byte form if T is set or if the size needs it
+func (d ADUDescriptor) Marshal() ([]byte, error) {
+	if d.Size < 0 || d.Size > ADUDescriptorMaxSize14 {
+		return nil, fmt.Errorf("ADU size %d exceeds %d", d.Size, ADUDescriptorMaxSize14)
+	}
+	var b byte
+	if d.C {
+		b |= ADUDescriptorCMask
+	}
+	if d.MarshalSize() == 1 {
+		return []byte{b | byte(d.Size)}, nil
+	}
+	return []byte{b | ADUDescriptorTMask | byte(d.Size>>8), byte(d.Size)}, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the ADUDescriptor this method is called upon,
+// it returns the number of bytes read
+func (d *ADUDescriptor) Unmarshal(buf []byte) (int, error) {
+	if len(buf) < 1 {
+		return 0, fmt.Errorf("buf is not large enough to container ADU descriptor")
+	}
+	d.C = buf[0]&ADUDescriptorCMask != 0
+	d.T = buf[0]&ADUDescriptorTMask != 0
+	if !d.T {
+		d.Size = int(buf[0] & ADUDescriptorSizeMask)
+		return 1, nil
+	}
+	if len(buf) < 2 {
+		return 0, fmt.Errorf("buf is not large enough to container ADU descriptor")
+	}
+	d.Size = int(buf[0]&ADUDescriptorSizeMask)<<8 | int(buf[1])
+	return 2, nil
+}
+
+// String helps with debugging by printing ADUDescriptor information in a readable way
+func (d ADUDescriptor) String() string {
+	out := "MPA ADUDescriptor:\n"
+
+	out += fmt.Sprintf("\tC: %v\n", d.C)
+	out += fmt.Sprintf("\tT: %v\n", d.T)
+	out += fmt.Sprintf("\tSize: %d\n", d.Size)
+
+	return out
+}
+
+// Interleaving of ADUs, 7 in rfc5219: the first 11 bits of the header of each ADU, the frame sync,
+// are replaced by an 8-bit interleave index and a 3-bit interleave cycle count
+const (
+	MaxInterleaveCycleCount = 7
+)
+
+// SetInterleaving writes the interleave index and cycle count over the frame sync of an ADU
+func SetInterleaving(adu []byte, index, cycle uint8) error {
+	if len(adu) < mpa.FrameHeaderSize {
+		return fmt.Errorf("ADU is not large enough to container header")
+	}
+	adu[0] = index
+	adu[1] = cycle<<5 | adu[1]&0x1f
+	return nil
+}
+
+// Interleaving reads the interleave index and cycle count of an ADU, and restores its frame sync
+func Interleaving(adu []byte) (index, cycle uint8, err error) {
+	if len(adu) < mpa.FrameHeaderSize {
+		return 0, 0, fmt.Errorf("ADU is not large enough to container header")
+	}
+	index, cycle = adu[0], adu[1]>>5
+	adu[0] = 0xff
+	adu[1] |= 0xe0
+	return index, cycle, nil
+}
@@ -0,0 +1,64 @@
+package mpa
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ClockRate is the RTP clock rate of MPEG audio, 90 kHz, see rfc3551#section-4.5.13
+const ClockRate = 90000
+
+// The MPEG audio specific header, 3.5 in rfc2250
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|             MBZ               |          Frag_offset          |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+const (
+	HeaderSize = 4
+
+	HeaderMBZByteIndex            = 0
+	HeaderFragmentOffsetByteIndex = 2
+)
+
+// Header represents the MPEG audio specific header that starts the payload of an RTP Packet
+type Header struct {
+	MBZ uint16
+	// FragmentOffset is the offset of the packet data in the frame, for frames split across packets
+	FragmentOffset uint16
+}
+
+// Marshal serializes the header into bytes.
+func (h Header) Marshal() ([]byte, error) {
+	buf := make([]byte, HeaderSize)
+	binary.BigEndian.PutUint16(buf[HeaderMBZByteIndex:], h.MBZ)
+	binary.BigEndian.PutUint16(buf[HeaderFragmentOffsetByteIndex:], h.FragmentOffset)
+	return buf, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the Header this method is called upon
+func (h *Header) Unmarshal(buf []byte) error {
+	if len(buf) < HeaderSize {
+		return fmt.Errorf("buf is not large enough to container header")
+	}
+	h.MBZ = binary.BigEndian.Uint16(buf[HeaderMBZByteIndex:])
+	h.FragmentOffset = binary.BigEndian.Uint16(buf[HeaderFragmentOffsetByteIndex:])
+	return nil
+}
+
+// String helps with debugging by printing Header information in a readable way
+func (h Header) String() string {
+	out := "MPA Header:\n"
+
+	out += fmt.Sprintf("\tMBZ: %d\n", h.MBZ)
+	out += fmt.Sprintf("\tFragmentOffset: %d\n", h.FragmentOffset)
+
+	return out
+}
+
+func ParseHeader(rtpPayload []byte) (Header, error) {
+	var h Header
+	err := (&h).Unmarshal(rtpPayload)
+	return h, err
+}
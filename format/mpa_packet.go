@@ -0,0 +1,372 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	mpa_codec "github.com/searKing/rtp/codecs/mpa"
+	"github.com/searKing/rtp/format/mpa"
+)
+
+// MPAPayloader payloads MPEG audio frames, of any layer, see rfc2250#section-3.5
+// The frame boundaries are found from the frame headers.  Packetize stamps every packet of a payload with
+// the same timestamp, so each call takes either whole frames that fit a packet, or a single frame,
+// split across packets with fragment offsets if larger than the MTU
+type MPAPayloader struct {
+	err error
+}
+
+// Err returns the error of the last call to Payload, such as frames that do not fit a packet
+func (p *MPAPayloader) Err() error {
+	return p.err
+}
+
+// ClockRate returns the RTP clock rate of MPEG audio, 90 kHz
+func (p *MPAPayloader) ClockRate() uint32 {
+	return mpa.ClockRate
+}
+
+// Payload fragments MPEG audio frames across one or more byte arrays
+// Several frames larger than the MTU, or a frame larger than a fragment offset allows, result in no packets,
+// Err returns why
+func (p *MPAPayloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	p.err = nil
+	if payload == nil || mtu <= mpa.HeaderSize {
+		return out
+	}
+	frames, _ := mpa_codec.SplitFrames(payload)
+	if len(frames) == 0 {
+		return out
+	}
+
+	h, _ := mpa.Header{}.Marshal()
+	size := len(h)
+	for _, frame := range frames {
+		size += len(frame)
+	}
+	if size <= mtu {
+		for _, frame := range frames {
+			h = append(h, frame...)
+		}
+		return append(out, h)
+	}
+	if len(frames) > 1 {
+		p.err = fmt.Errorf("%d frames of %d bytes do not fit the MTU %d", len(frames), size, mtu)
+		return out
+	}
+
+	// fragments of a single frame
+	frame := frames[0]
+	if len(frame) > 0xffff {
+		p.err = fmt.Errorf("frame of %d bytes is too large to be fragmented", len(frame))
+		return out
+	}
+	for offset := 0; offset < len(frame); {
+		h, _ := mpa.Header{FragmentOffset: uint16(offset)}.Marshal()
+		n := min(mtu-len(h), len(frame)-offset)
+		out = append(out, append(h, frame[offset:offset+n]...))
+		offset += n
+	}
+	return out
+}
+
+// Samples returns the duration of the frames of the payload, at the given clock rate
+func (p *MPAPayloader) Samples(clockRate uint32, payload []byte) uint32 {
+	return mpaSamples(clockRate, payload)
+}
+
+func mpaSamples(clockRate uint32, payload []byte) uint32 {
+	frames, _ := mpa_codec.SplitFrames(payload)
+	var samples uint64
+	for _, frame := range frames {
+		h, _ := mpa_codec.ParseFrameHeader(frame)
+		samples += uint64(h.Samples()) * uint64(clockRate) / uint64(h.SampleRate)
+	}
+	return uint32(samples)
+}
+
+// MPAPacket represents the MPEG audio header that is stored in the payload of an RTP Packet
+type MPAPacket struct {
+	// the header of the last packet
+	mpa.Header
+
+	// FrameHeader is the header of the first frame of the last packet starting a frame
+	FrameHeader *mpa_codec.FrameHeader
+
+	Payload []byte
+
+	fragment []byte
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the MPAPacket this method is called upon
+// It returns the whole frames of the packet; the fragments of a frame are buffered until the frame is complete,
+// and dropped if a fragment is lost
+func (p *MPAPacket) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) <= mpa.HeaderSize {
+		return nil, fmt.Errorf("Payload is not large enough")
+	}
+	p.Payload = nil
+	if err := (&p.Header).Unmarshal(packet); err != nil {
+		return nil, err
+	}
+	data := packet[mpa.HeaderSize:]
+
+	if p.FragmentOffset != 0 {
+		fragment := p.fragment
+		p.fragment = nil
+		if fragment == nil || int(p.FragmentOffset) != len(fragment) {
+			return nil, nil
+		}
+		fragment = append(fragment, data...)
+		size := p.FrameHeader.FrameSize()
+		if len(fragment) < size {
+			p.fragment = fragment
+			return nil, nil
+		}
+		p.Payload = fragment[:size]
+		return p.Payload, nil
+	}
+
+	p.fragment = nil
+	h, err := mpa_codec.ParseFrameHeader(data)
+	if err != nil {
+		p.FrameHeader = nil
+		return nil, err
+	}
+	p.FrameHeader = &h
+	if h.FrameSize() > len(data) {
+		p.fragment = append([]byte(nil), data...)
+		return nil, nil
+	}
+	p.Payload = data
+	return data, nil
+}
+
+// MP3ADUPayloader payloads MPEG audio Layer III frames as ADUs, in the loss-tolerant format of rfc5219
+// Each frame is converted to an ADU, whose main data no longer depends on the previous frames.
+// Packetize stamps every packet of a payload with the same timestamp, so each call takes a single frame,
+// whose ADU is sent in one packet, or fragmented across packets if larger than the MTU
+type MP3ADUPayloader struct {
+	// Interleaving, if set, is the order in which the ADUs of an interleave cycle of len(Interleaving) ADUs are sent,
+	// a permutation of the indices of the cycle, up to 256; the ADUs of a cycle are buffered until the cycle is complete,
+	// and the call completing it returns the packets of all of them.  Packetize can't stamp these with the timestamps
+	// of their ADUs, so interleaved packets are to be timestamped by the caller, each ADU lasting a frame
+	Interleaving []uint8
+
+	encoder mpa_codec.ADUEncoder
+	cycle   [][]byte
+	count   uint8
+	err     error
+}
+
+// ClockRate returns the RTP clock rate of MPEG audio, 90 kHz
+func (p *MP3ADUPayloader) ClockRate() uint32 {
+	return mpa.ClockRate
+}
+
+// Err returns the error of the last call to Payload, such as more than one frame
+func (p *MP3ADUPayloader) Err() error {
+	return p.err
+}
+
+// Payload fragments an ADU across one or more byte arrays
+// Payloads of more than one frame result in no packets, Err returns why.
+// A frame whose main data starts in frames not given to the payloader yet is dropped
+func (p *MP3ADUPayloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	p.err = nil
+	if payload == nil || mtu <= 2 {
+		return out
+	}
+	frames, _ := mpa_codec.SplitFrames(payload)
+	if len(frames) != 1 {
+		p.err = fmt.Errorf("payload of %d frames is not a single frame", len(frames))
+		return out
+	}
+	adu, err := p.encoder.Encode(frames[0])
+	if err != nil {
+		p.err = err
+		return out
+	}
+	if adu == nil {
+		return out
+	}
+
+	adus := [][]byte{adu}
+	if len(p.Interleaving) > 0 {
+		p.cycle = append(p.cycle, adu)
+		if len(p.cycle) < len(p.Interleaving) {
+			return out
+		}
+		adus = nil
+		for _, index := range p.Interleaving {
+			if int(index) >= len(p.cycle) {
+				continue
+			}
+			adu := p.cycle[index]
+			if err := mpa.SetInterleaving(adu, index, p.count); err == nil {
+				adus = append(adus, adu)
+			}
+		}
+		p.cycle = nil
+		p.count = (p.count + 1) % (mpa.MaxInterleaveCycleCount + 1)
+	}
+
+	for _, adu := range adus {
+		d, err := mpa.ADUDescriptor{Size: len(adu)}.Marshal()
+		if err != nil {
+			p.err = err
+			continue
+		}
+		if len(d)+len(adu) <= mtu {
+			out = append(out, append(d, adu...))
+			continue
+		}
+		// fragments, with the 2-byte descriptor, are alone in their packet
+		for offset := 0; offset < len(adu); {
+			d, _ := mpa.ADUDescriptor{C: offset > 0, T: true, Size: len(adu)}.Marshal()
+			n := min(mtu-len(d), len(adu)-offset)
+			out = append(out, append(d, adu[offset:offset+n]...))
+			offset += n
+		}
+	}
+	return out
+}
+
+// Samples returns the duration of the frames of the payload, at the given clock rate
+func (p *MP3ADUPayloader) Samples(clockRate uint32, payload []byte) uint32 {
+	return mpaSamples(clockRate, payload)
+}
+
+// MP3ADUPacket represents the ADUs that are stored in the payload of an RTP Packet
+type MP3ADUPacket struct {
+	// Interleaved makes Unmarshal deinterleave the ADUs
+	Interleaved bool
+
+	// the descriptor of the last ADU of the last packet
+	mpa.ADUDescriptor
+
+	// ADUs holds the ADUs completed by the last packet, in decoding order
+	ADUs [][]byte
+
+	Payload []byte
+
+	fragment []byte
+	decoder  mpa_codec.ADUDecoder
+	cycle    []interleavedADU
+	count    int
+}
+
+type interleavedADU struct {
+	index uint8
+	adu   []byte
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the MP3ADUPacket this method is called upon
+// It returns the MPEG audio frames rebuilt from the ADUs; a frame is returned once the following ADU is received,
+// which may put main data in it, and Flush returns the last ones.  Fragmented ADUs are buffered until complete,
+// and the ADUs of an interleave cycle until the next cycle starts
+func (p *MP3ADUPacket) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) == 0 {
+		return nil, fmt.Errorf("Payload is not large enough")
+	}
+	p.ADUs = nil
+	p.Payload = nil
+
+	var adus [][]byte
+	for data := packet; len(data) > 0; {
+		n, err := (&p.ADUDescriptor).Unmarshal(data)
+		if err != nil {
+			return nil, err
+		}
+		data = data[n:]
+		if p.C {
+			fragment := p.fragment
+			p.fragment = nil
+			if fragment == nil {
+				// the start of the ADU was lost
+				break
+			}
+			fragment = append(fragment, data...)
+			if len(fragment) < p.Size {
+				p.fragment = fragment
+			} else {
+				adus = append(adus, fragment[:p.Size])
+			}
+			break
+		}
+		p.fragment = nil
+		if p.Size > len(data) {
+			p.fragment = append([]byte(nil), data...)
+			break
+		}
+		adus = append(adus, append([]byte(nil), data[:p.Size]...))
+		data = data[p.Size:]
+	}
+
+	for _, adu := range adus {
+		if !p.Interleaved {
+			p.ADUs = append(p.ADUs, adu)
+			continue
+		}
+		index, count, err := mpa.Interleaving(adu)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.cycle) > 0 && int(count) != p.count {
+			p.ADUs = append(p.ADUs, p.deinterleave()...)
+		}
+		p.count = int(count)
+		p.cycle = append(p.cycle, interleavedADU{index: index, adu: adu})
+	}
+	return p.decode(p.ADUs)
+}
+
+// deinterleave returns the ADUs of the interleave cycle in decoding order
+func (p *MP3ADUPacket) deinterleave() [][]byte {
+	sort.SliceStable(p.cycle, func(i, j int) bool {
+		return p.cycle[i].index < p.cycle[j].index
+	})
+	var adus [][]byte
+	for _, a := range p.cycle {
+		adus = append(adus, a.adu)
+	}
+	p.cycle = nil
+	return adus
+}
+
+func (p *MP3ADUPacket) decode(adus [][]byte) ([]byte, error) {
+	var frames [][]byte
+	var err error
+	for _, adu := range adus {
+		f, e := p.decoder.Decode(adu)
+		if e != nil && err == nil {
+			err = e
+		}
+		frames = append(frames, f...)
+	}
+	if len(frames) == 0 {
+		return nil, err
+	}
+	p.Payload = bytes.Join(frames, nil)
+	return p.Payload, err
+}
+
+// Flush returns the frames of the ADUs buffered, of the current interleave cycle included
+func (p *MP3ADUPacket) Flush() ([]byte, error) {
+	p.ADUs = p.deinterleave()
+	out, err := p.decode(p.ADUs)
+	if frames := p.decoder.Flush(); len(frames) > 0 {
+		out = append(out, bytes.Join(frames, nil)...)
+		p.Payload = out
+	}
+	return out, err
+}
@@ -0,0 +1,145 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/searKing/rtp/codecs/bitstream"
+	mpa_codec "github.com/searKing/rtp/codecs/mpa"
+	"github.com/searKing/rtp/format/mpa"
+)
+
+// testMP3Frames builds MPEG-1 Layer III mono frames of 417 bytes, whose main data sizes are given,
+// spread over the bit reservoir
+func testMP3Frames(t *testing.T, sizes ...int) [][]byte {
+	var decoder mpa_codec.ADUDecoder
+	var frames [][]byte
+	for i, size := range sizes {
+		w := bitstream.NewWriter()
+		w.WriteBytes([]byte{0xff, 0xfb, 0x90, 0xc0})
+		w.WriteBits(0, 9+5+4)
+		for gr := 0; gr < 2; gr++ {
+			w.WriteBits(uint64(size*8/2), 12)
+			w.WriteBits(0, 47)
+		}
+		w.ByteAlign()
+		f, err := decoder.Decode(append(w.Bytes(), bytes.Repeat([]byte{byte(i + 1)}, size)...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, f...)
+	}
+	return append(frames, decoder.Flush()...)
+}
+
+func TestMPAPayloader_Payload(t *testing.T) {
+	// MPEG-1 Layer II frames of 576 bytes, 1152 samples at 48 kHz
+	frame := make([]byte, 576)
+	copy(frame, []byte{0xff, 0xfd, 0xa4, 0x00})
+	frame[575] = 0x55
+	payload := append(append([]byte(nil), frame...), frame...)
+
+	pck := MPAPayloader{}
+	if res := pck.Payload(4, payload); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+	if pck.Samples(90000, payload) != 2*2160 {
+		t.Fatal("Samples should be 2160 per frame at 90 kHz")
+	}
+
+	// two frames in a packet
+	res := pck.Payload(1200, payload)
+	if len(res) != 1 || !bytes.Equal(res[0], append([]byte{0, 0, 0, 0}, payload...)) {
+		t.Fatalf("Generated payload should aggregate the frames, got %d packets", len(res))
+	}
+
+	// whole frames would share the timestamp of the call
+	if res := pck.Payload(1000, payload); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty if the frames do not fit a packet")
+	}
+	// fragments of a frame
+	res = pck.Payload(304, frame)
+	if len(res) != 2 || !bytes.Equal(res[1][:4], []byte{0x00, 0x00, 0x01, 0x2c}) {
+		t.Fatalf("Generated payload should fragment the frame, got %d packets", len(res))
+	}
+	res = append(res, pck.Payload(304, frame)...)
+
+	depacketizer := MPAPacket{}
+	if _, err := depacketizer.Unmarshal(nil); err == nil {
+		t.Fatal("Unmarshal should reject a nil packet")
+	}
+	var out []byte
+	for i, r := range res {
+		raw, err := depacketizer.Unmarshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (raw != nil) != (i%2 == 1) {
+			t.Fatalf("Packet %d should complete a frame: %v", i, raw != nil)
+		}
+		out = append(out, raw...)
+	}
+	if !bytes.Equal(out, payload) || depacketizer.FrameHeader.Layer != mpa_codec.Layer2 {
+		t.Fatal("Unmarshal should reassemble the frames")
+	}
+
+	// a lost fragment drops the frame
+	res = pck.Payload(204, frame)
+	if _, err := depacketizer.Unmarshal(res[0]); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := depacketizer.Unmarshal(res[2]); raw != nil {
+		t.Fatal("Unmarshal should drop a frame with a lost fragment")
+	}
+}
+
+func TestMP3ADUPayloader_Payload(t *testing.T) {
+	frames := testMP3Frames(t, 100, 600, 400, 50, 396, 0, 300, 200)
+	payload := bytes.Join(frames, nil)
+
+	for _, interleaving := range [][]uint8{nil, {1, 3, 0, 2}} {
+		pck := MP3ADUPayloader{Interleaving: interleaving}
+		if res := pck.Payload(500, payload); len(res) != 0 || pck.Err() == nil {
+			t.Fatal("Generated payload should be empty for more than one frame")
+		}
+		var res [][]byte
+		for i, frame := range frames {
+			packets := pck.Payload(500, frame)
+			if pck.Err() != nil {
+				t.Fatal(pck.Err())
+			}
+			// a frame per call, its ADU alone in its packets
+			if interleaving == nil && (len(packets) == 0 || len(packets) > 1 && i != 1) {
+				t.Fatalf("Generated payload of frame %d should be an ADU, got %d packets", i, len(packets))
+			}
+			res = append(res, packets...)
+		}
+		for _, r := range res {
+			if len(r) > 500 {
+				t.Fatal("Packets should fit the MTU")
+			}
+		}
+		// the 600 bytes of main data of the second frame are fragmented
+		if d, _ := (&mpa.ADUDescriptor{}).Unmarshal(res[1]); d != 2 {
+			t.Fatal("Fragments should use the 2-byte descriptor")
+		}
+
+		depacketizer := MP3ADUPacket{Interleaved: interleaving != nil}
+		var out []byte
+		for _, r := range res {
+			raw, err := depacketizer.Unmarshal(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, raw...)
+		}
+		raw, err := depacketizer.Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, raw...)
+		if !bytes.Equal(out, payload) {
+			t.Fatalf("Depacketized frames should be rebuilt, interleaving %v", interleaving)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/searKing/rtp/codecs/aac"
+	"github.com/searKing/rtp/codecs/bitstream"
+)
+
+const (
+	// rfc3640#section-3.3.6: AAC-hbr mode
+	mpeg4GenericDefaultSizeLength       = 13
+	mpeg4GenericDefaultIndexLength      = 3
+	mpeg4GenericDefaultIndexDeltaLength = 3
+
+	// AU-headers-length, in bits
+	mpeg4GenericAUHeadersLengthSize = 2
+)
+
+// MPEG4GenericPayloader payloads MPEG-4 audio access units, such as AAC frames,
+// with the AU-header section of rfc3640#section-3.2.1.
+// The zero value uses the AAC-hbr mode: sizeLength=13, indexLength=3, indexDeltaLength=3
+type MPEG4GenericPayloader struct {
+	SizeLength       int
+	IndexLength      int
+	IndexDeltaLength int
+
+	// Config is the stream configuration, used to derive the number of samples per access unit
+	Config *aac.AudioSpecificConfig
+
+	err error
+}
+
+// Err returns the error of the last call to Payload, such as access units that do not fit a packet
+func (p *MPEG4GenericPayloader) Err() error {
+	return p.err
+}
+
+// UnmarshalFmtp configures the payloader from the parameters of an SDP fmtp line, such as
+// "streamtype=5;profile-level-id=15;mode=AAC-hbr;config=1210;sizelength=13;indexlength=3;indexdeltalength=3"
+func (p *MPEG4GenericPayloader) UnmarshalFmtp(fmtp string) error {
+	sizeLength, indexLength, indexDeltaLength, config, err := parseMPEG4GenericFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.SizeLength, p.IndexLength, p.IndexDeltaLength, p.Config = sizeLength, indexLength, indexDeltaLength, config
+	return nil
+}
+
+// ClockRate returns the RTP clock rate, the sample rate of the configuration
+func (p *MPEG4GenericPayloader) ClockRate() uint32 {
+	if p.Config == nil {
+		return 0
+	}
+	return uint32(p.Config.SampleRate)
+}
+
+// Channels returns the number of channels of the configuration
+func (p *MPEG4GenericPayloader) Channels() int {
+	if p.Config == nil {
+		return 0
+	}
+	return p.Config.Channels()
+}
+
+func mpeg4GenericLengths(sizeLength, indexLength, indexDeltaLength int) (int, int, int) {
+	if sizeLength == 0 {
+		return mpeg4GenericDefaultSizeLength, mpeg4GenericDefaultIndexLength, mpeg4GenericDefaultIndexDeltaLength
+	}
+	return sizeLength, indexLength, indexDeltaLength
+}
+
+// Payload fragments access units across one or more byte arrays
+// The payload is either a stream of ADTS frames, whose headers are stripped, or a single raw access unit.
+// Packetize stamps every packet of a payload with the same timestamp, so each call takes either access units
+// that are aggregated into one packet, or a single one, fragmented if larger than the MTU, see rfc3640#section-3.2.3.
+// Access units that do not fit a packet, or whose size the AU-header cannot hold, result in no packets,
+// Err returns why
+func (p *MPEG4GenericPayloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	p.err = nil
+	if payload == nil || mtu <= 0 {
+		return out
+	}
+	aus := [][]byte{payload}
+	if aac.IsADTS(payload) {
+		var err error
+		if aus, _, err = aac.SplitADTS(payload); err != nil {
+			p.err = err
+			return out
+		}
+	}
+
+	sizeLength, indexLength, indexDeltaLength := mpeg4GenericLengths(p.SizeLength, p.IndexLength, p.IndexDeltaLength)
+	maxAUSize := 1<<uint(sizeLength) - 1
+
+	// size of the AU-header section for a number of AU-headers
+	headersSize := func(count int) int {
+		bits := sizeLength + indexLength + (count-1)*(sizeLength+indexDeltaLength)
+		return mpeg4GenericAUHeadersLengthSize + (bits+7)/8
+	}
+	marshal := func(sizes []int, data ...[]byte) []byte {
+		w := bitstream.NewWriter()
+		bits := sizeLength + indexLength + (len(sizes)-1)*(sizeLength+indexDeltaLength)
+		w.WriteBits(uint64(bits), 16)
+		for i, size := range sizes {
+			w.WriteBits(uint64(size), sizeLength)
+			if i == 0 {
+				// AU-Index, no interleaving
+				w.WriteBits(0, indexLength)
+			} else {
+				// AU-Index-delta, consecutive access units
+				w.WriteBits(0, indexDeltaLength)
+			}
+		}
+		w.ByteAlign()
+		for _, d := range data {
+			w.WriteBytes(d)
+		}
+		return w.Bytes()
+	}
+
+	sizes := make([]int, 0, len(aus))
+	size := headersSize(len(aus))
+	for _, au := range aus {
+		if len(au) == 0 || len(au) > maxAUSize {
+			p.err = fmt.Errorf("access unit of %d bytes is not in [1, %d]", len(au), maxAUSize)
+			return out
+		}
+		sizes = append(sizes, len(au))
+		size += len(au)
+	}
+	if size <= mtu {
+		return append(out, marshal(sizes, aus...))
+	}
+	if len(aus) > 1 {
+		p.err = fmt.Errorf("%d access units of %d bytes do not fit the MTU %d", len(aus), size, mtu)
+		return out
+	}
+
+	// Fragment: each fragment carries a single AU-header, with the size of the whole access unit
+	au := aus[0]
+	maxFragmentSize := mtu - headersSize(1)
+	if maxFragmentSize <= 0 {
+		p.err = fmt.Errorf("MTU %d is too small for an AU-header", mtu)
+		return out
+	}
+	for data := au; len(data) > 0; {
+		n := min(maxFragmentSize, len(data))
+		out = append(out, marshal([]int{len(au)}, data[:n]))
+		data = data[n:]
+	}
+	return out
+}
+
+// Samples returns the number of samples in the access units of the payload, at the given clock rate
+func (p *MPEG4GenericPayloader) Samples(clockRate uint32, payload []byte) uint32 {
+	count := 1
+	if aac.IsADTS(payload) {
+		aus, _, err := aac.SplitADTS(payload)
+		if err != nil {
+			return 0
+		}
+		count = len(aus)
+	}
+	samples := aac.SamplesPerFrame
+	if p.Config != nil {
+		samples = p.Config.SamplesPerFrame()
+		if p.Config.SampleRate > 0 && clockRate > 0 && uint32(p.Config.SampleRate) != clockRate {
+			samples = samples * int(clockRate) / p.Config.SampleRate
+		}
+	}
+	return uint32(count * samples)
+}
+
+// MPEG4GenericAUHeader represents an AU-header of rfc3640#section-3.2.1.1
+type MPEG4GenericAUHeader struct {
+	Size int
+	// Index is the AU-Index of the first header, the AU-Index-delta of the following ones
+	Index int
+}
+
+// MPEG4GenericPacket represents the mpeg4-generic payload that is stored in the payload of an RTP Packet
+// The zero value uses the AAC-hbr mode: sizeLength=13, indexLength=3, indexDeltaLength=3
+type MPEG4GenericPacket struct {
+	SizeLength       int
+	IndexLength      int
+	IndexDeltaLength int
+	// Interleaved, as the maxDisplacement fmtp parameter, makes the access units go through a de-interleaving
+	// buffer, released in the order of the serial numbers of their AU-Index and AU-Index-delta,
+	// see rfc3640#section-3.2.1.1; without it, AU-Index and AU-Index-delta are to be 0
+	Interleaved bool
+
+	// Marker is the RTP marker bit of the packet of the next call to Unmarshal, set by the caller:
+	// it ends a fragmented access unit, see rfc3640#section-3.2.3
+	Marker bool
+
+	AUHeaders []MPEG4GenericAUHeader
+	// AUs holds the access units completed by the last packet, in decoding order
+	AUs [][]byte
+
+	Payload []byte
+
+	fragment      []byte
+	fragmentSize  int
+	fragmentIndex int
+
+	buffer []indexedAU
+	// the serial number of the next access unit to release, that of the first one received once started
+	next    int
+	started bool
+}
+
+// indexedAU is an access unit with its serial number
+type indexedAU struct {
+	index int
+	au    []byte
+}
+
+// UnmarshalFmtp configures the depacketizer from the parameters of an SDP fmtp line
+func (p *MPEG4GenericPacket) UnmarshalFmtp(fmtp string) error {
+	sizeLength, indexLength, indexDeltaLength, _, err := parseMPEG4GenericFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.SizeLength, p.IndexLength, p.IndexDeltaLength = sizeLength, indexLength, indexDeltaLength
+	_, p.Interleaved = parseFmtp(fmtp)["maxdisplacement"]
+	return nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the MPEG4GenericPacket this method is called upon
+// It returns the access units completed by this packet, concatenated.  The fragments of an access unit
+// are buffered until the packet with the RTP marker bit set, and nothing is returned until then;
+// an access unit whose fragments do not add up to its size is dropped
+func (p *MPEG4GenericPacket) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) < mpeg4GenericAUHeadersLengthSize {
+		return nil, fmt.Errorf("Payload is not large enough to container header")
+	}
+	sizeLength, indexLength, indexDeltaLength := mpeg4GenericLengths(p.SizeLength, p.IndexLength, p.IndexDeltaLength)
+
+	r := bitstream.NewReader(packet)
+	headersLength := int(r.ReadBits(16))
+	headersSize := (headersLength + 7) / 8
+	if len(packet) < mpeg4GenericAUHeadersLengthSize+headersSize {
+		return nil, fmt.Errorf("Payload is not large enough to container AU headers")
+	}
+	p.AUHeaders = p.AUHeaders[:0]
+	for bits := 0; bits < headersLength; {
+		var h MPEG4GenericAUHeader
+		h.Size = int(r.ReadBits(sizeLength))
+		if len(p.AUHeaders) == 0 {
+			h.Index = int(r.ReadBits(indexLength))
+			bits += sizeLength + indexLength
+		} else {
+			h.Index = int(r.ReadBits(indexDeltaLength))
+			bits += sizeLength + indexDeltaLength
+		}
+		if bits > headersLength {
+			return nil, fmt.Errorf("AU headers length %d is not a whole number of AU headers", headersLength)
+		}
+		if !p.Interleaved && h.Index != 0 {
+			return nil, fmt.Errorf("AU-Index or AU-Index-delta %d without interleaving", h.Index)
+		}
+		p.AUHeaders = append(p.AUHeaders, h)
+	}
+	data := packet[mpeg4GenericAUHeadersLengthSize+headersSize:]
+	p.AUs = nil
+	p.Payload = nil
+
+	// a fragment of an access unit, see rfc3640#section-3.2.3
+	if len(p.AUHeaders) == 1 && p.AUHeaders[0].Size > len(data) {
+		h := p.AUHeaders[0]
+		if p.fragment != nil && (p.fragmentSize != h.Size || p.fragmentIndex != h.Index) {
+			// the previous access unit was not completed, drop it
+			p.fragment = nil
+		}
+		fragment := append(p.fragment, data...)
+		p.fragment = nil
+		if len(fragment) > h.Size {
+			return nil, fmt.Errorf("fragments exceed AU size %d", h.Size)
+		}
+		if !p.Marker {
+			p.fragment, p.fragmentSize, p.fragmentIndex = fragment, h.Size, h.Index
+			return nil, nil
+		}
+		if len(fragment) != h.Size {
+			return nil, fmt.Errorf("fragments of %d bytes do not make AU size %d", len(fragment), h.Size)
+		}
+		p.deinterleave(h.Index, fragment, indexLength)
+	} else {
+		p.fragment = nil
+		var aus []indexedAU
+		index := 0
+		for i, h := range p.AUHeaders {
+			if h.Size > len(data) {
+				return nil, fmt.Errorf("AU size %d exceeds remaining %d bytes", h.Size, len(data))
+			}
+			if i == 0 {
+				index = h.Index
+			} else {
+				index += h.Index + 1
+			}
+			aus = append(aus, indexedAU{index: index, au: data[:h.Size]})
+			data = data[h.Size:]
+		}
+		for _, au := range aus {
+			p.deinterleave(au.index, au.au, indexLength)
+		}
+	}
+	if len(p.AUs) == 0 {
+		return nil, nil
+	}
+	p.Payload = bytes.Join(p.AUs, nil)
+	return p.Payload, nil
+}
+
+// Flush releases the access units left in the de-interleaving buffer, in decoding order, concatenated
+func (p *MPEG4GenericPacket) Flush() []byte {
+	p.AUs = nil
+	p.Payload = nil
+	_, indexLength, _ := mpeg4GenericLengths(p.SizeLength, p.IndexLength, p.IndexDeltaLength)
+	for len(p.buffer) > 0 {
+		p.release(1 << uint(indexLength))
+	}
+	if len(p.AUs) == 0 {
+		return nil
+	}
+	p.Payload = bytes.Join(p.AUs, nil)
+	return p.Payload
+}
+
+// deinterleave buffers the access unit of a serial number, modulo 2^indexLength, and releases the ones
+// following the last released in decoding order.  Once half the serial numbers are buffered, the access unit
+// awaited is considered lost and skipped; a late one is dropped
+func (p *MPEG4GenericPacket) deinterleave(index int, au []byte, indexLength int) {
+	if !p.Interleaved || indexLength == 0 {
+		p.AUs = append(p.AUs, au)
+		return
+	}
+	serials := 1 << uint(indexLength)
+	index %= serials
+	if !p.started {
+		p.next, p.started = index, true
+	}
+	if (index-p.next+serials)%serials >= serials/2 {
+		// late, its serial number already released
+		return
+	}
+	p.buffer = append(p.buffer, indexedAU{index: index, au: append([]byte(nil), au...)})
+	for len(p.buffer) > 0 {
+		if p.buffer[p.first(serials)].index != p.next && len(p.buffer) < serials/2 {
+			break
+		}
+		p.release(serials)
+	}
+}
+
+// first returns the position in the buffer of the access unit following the last released in decoding order
+func (p *MPEG4GenericPacket) first(serials int) int {
+	first := 0
+	for i := range p.buffer {
+		if (p.buffer[i].index-p.next+serials)%serials < (p.buffer[first].index-p.next+serials)%serials {
+			first = i
+		}
+	}
+	return first
+}
+
+// release moves the access unit following the last released in decoding order to AUs
+func (p *MPEG4GenericPacket) release(serials int) {
+	first := p.first(serials)
+	p.AUs = append(p.AUs, p.buffer[first].au)
+	p.next = (p.buffer[first].index + 1) % serials
+	p.buffer = append(p.buffer[:first], p.buffer[first+1:]...)
+}
+
+func parseMPEG4GenericFmtp(fmtp string) (sizeLength, indexLength, indexDeltaLength int, config *aac.AudioSpecificConfig, err error) {
+	params := parseFmtp(fmtp)
+	for name, v := range map[string]*int{
+		"sizelength":       &sizeLength,
+		"indexlength":      &indexLength,
+		"indexdeltalength": &indexDeltaLength,
+	} {
+		s, ok := params[name]
+		if !ok {
+			continue
+		}
+		if *v, err = strconv.Atoi(s); err != nil || *v < 0 || *v > 32 {
+			return 0, 0, 0, nil, fmt.Errorf("invalid fmtp parameter %s=%s", name, s)
+		}
+	}
+	if s, ok := params["config"]; ok {
+		c, err := aac.ParseConfig(s)
+		if err != nil {
+			return 0, 0, 0, nil, err
+		}
+		config = &c
+	}
+	return sizeLength, indexLength, indexDeltaLength, config, nil
+}
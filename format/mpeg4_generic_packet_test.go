@@ -0,0 +1,161 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/searKing/rtp/codecs/aac"
+)
+
+func adtsStream(t *testing.T, sizes ...int) ([]byte, [][]byte) {
+	h := aac.ADTSHeader{ProtectionAbsent: true, ObjectType: aac.ObjectTypeAACLC, SampleRate: 44100, ChannelConfiguration: 2, RawDataBlocks: 1}
+	var stream []byte
+	var aus [][]byte
+	for i, size := range sizes {
+		h.FrameLength = aac.ADTSHeaderSize + size
+		raw, err := h.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		au := bytes.Repeat([]byte{byte(i + 1)}, size)
+		stream = append(stream, raw...)
+		stream = append(stream, au...)
+		aus = append(aus, au)
+	}
+	return stream, aus
+}
+
+func TestMPEG4GenericPayloader_Payload(t *testing.T) {
+	var pck MPEG4GenericPayloader
+	if err := pck.UnmarshalFmtp("streamtype=5; profile-level-id=15; mode=AAC-hbr; config=1210; SizeLength=13; IndexLength=3; IndexDeltaLength=3"); err != nil {
+		t.Fatal(err)
+	}
+	if pck.ClockRate() != 44100 || pck.Channels() != 2 {
+		t.Fatalf("Clock rate and channels should be derived from config, got %d %d", pck.ClockRate(), pck.Channels())
+	}
+
+	// Nil payload
+	if res := pck.Payload(100, nil); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+
+	// 3 access units aggregated into one packet
+	stream, aus := adtsStream(t, 10, 20, 30)
+	res := pck.Payload(1500, stream)
+	if len(res) != 1 {
+		t.Fatalf("Generated %d payloads instead of 1", len(res))
+	}
+	// AU-headers-length 48 bits, then 3 AU-headers of 16 bits
+	if !bytes.Equal(res[0][:8], []byte{0x00, 0x30, 0x00, 0x50, 0x00, 0xa0, 0x00, 0xf0}) {
+		t.Fatalf("AU header section is packed incorrectly: %x", res[0][:8])
+	}
+	if samples := pck.Samples(44100, stream); samples != 3*1024 {
+		t.Fatalf("Samples should be 3072, got %d", samples)
+	}
+
+	var depack MPEG4GenericPacket
+	raw, err := depack.Unmarshal(res[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(depack.AUs) != 3 || !bytes.Equal(raw, bytes.Join(aus, nil)) {
+		t.Fatal("Access units are depacketized incorrectly")
+	}
+
+	// aggregated access units would share the timestamp of the call
+	if res = pck.Payload(40, stream); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty if the access units do not fit a packet")
+	}
+
+	// a large access unit is fragmented
+	stream, aus = adtsStream(t, 3000)
+	res = pck.Payload(1000, stream)
+	if len(res) != 4 {
+		t.Fatalf("Generated %d payloads instead of 4", len(res))
+	}
+	depack = MPEG4GenericPacket{}
+	for i, payload := range res {
+		if len(payload) > 1000 {
+			t.Fatalf("Payload %d exceeds the MTU", i)
+		}
+		depack.Marker = i == len(res)-1
+		raw, err = depack.Unmarshal(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < len(res)-1 && raw != nil {
+			t.Fatal("Incomplete access unit should not be returned")
+		}
+	}
+	if !bytes.Equal(raw, aus[0]) {
+		t.Fatal("Fragmented access unit is reassembled incorrectly")
+	}
+
+	// a lost fragment drops the access unit
+	depack = MPEG4GenericPacket{}
+	_, _ = depack.Unmarshal(res[0])
+	depack.Marker = true
+	if raw, err = depack.Unmarshal(res[len(res)-1]); raw != nil || err == nil {
+		t.Fatal("Access unit with a lost fragment should not be returned")
+	}
+}
+
+func TestMPEG4GenericPacket_Unmarshal(t *testing.T) {
+	// AAC-lbr: sizeLength=6, indexLength=2, indexDeltaLength=2
+	pck := MPEG4GenericPacket{SizeLength: 6, IndexLength: 2, IndexDeltaLength: 2}
+
+	if _, err := pck.Unmarshal(nil); err == nil {
+		t.Fatal("Nil packet should fail")
+	}
+	if _, err := pck.Unmarshal([]byte{0x00}); err == nil {
+		t.Fatal("Packet smaller than the header should fail")
+	}
+
+	// 2 AU-headers of 8 bits: sizes 2 and 1
+	raw, err := pck.Unmarshal([]byte{0x00, 0x10, 0x08, 0x04, 0xaa, 0xbb, 0xcc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pck.AUs) != 2 || !bytes.Equal(raw, []byte{0xaa, 0xbb, 0xcc}) {
+		t.Fatal("Access units are parsed incorrectly")
+	}
+
+	// AU size exceeds the packet, as a second AU-header can't be a fragment
+	if _, err = pck.Unmarshal([]byte{0x00, 0x10, 0x08, 0x08, 0xaa, 0xbb, 0xcc}); err == nil {
+		t.Fatal("Truncated access unit should fail")
+	}
+}
+
+func TestMPEG4GenericPacket_Interleaved(t *testing.T) {
+	// AAC-hbr, 2 AU-headers of 16 bits: sizes 1 and 1, AU-Index and AU-Index-delta
+	packet := func(index, delta uint8, a, b byte) []byte {
+		return []byte{0x00, 0x20, 0x00, 0x08 | index, 0x00, 0x08 | delta, a, b}
+	}
+	pck := MPEG4GenericPacket{}
+	if _, err := pck.Unmarshal(packet(1, 0, 0xaa, 0xbb)); err == nil {
+		t.Fatal("AU-Index should be 0 without interleaving")
+	}
+
+	if err := pck.UnmarshalFmtp("mode=AAC-hbr;sizelength=13;indexlength=3;indexdeltalength=3;maxdisplacement=5000"); err != nil {
+		t.Fatal(err)
+	}
+	if !pck.Interleaved {
+		t.Fatal("maxDisplacement should select interleaving")
+	}
+	// access units 0, 2, then 1, 3, then 4, 6 with 5 lost
+	raw, err := pck.Unmarshal(packet(0, 1, 0x00, 0x02))
+	if err != nil || !bytes.Equal(raw, []byte{0x00}) {
+		t.Fatalf("Unmarshal should release the first access unit, got %x", raw)
+	}
+	raw, err = pck.Unmarshal(packet(1, 1, 0x01, 0x03))
+	if err != nil || !bytes.Equal(raw, []byte{0x01, 0x02, 0x03}) {
+		t.Fatalf("Unmarshal should release the access units in decoding order, got %x", raw)
+	}
+	raw, err = pck.Unmarshal(packet(4, 1, 0x04, 0x06))
+	if err != nil || !bytes.Equal(raw, []byte{0x04}) {
+		t.Fatalf("Unmarshal should wait for the lost access unit, got %x", raw)
+	}
+	if raw = pck.Flush(); !bytes.Equal(raw, []byte{0x06}) {
+		t.Fatalf("Flush should release the buffered access units, got %x", raw)
+	}
+}
@@ -2,15 +2,21 @@ package format
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/binary"
+	"fmt"
 	h264_codec "github.com/searKing/rtp/codecs/h264"
 	hevc_codec "github.com/searKing/rtp/codecs/hevc"
 	"github.com/searKing/rtp/format/h264"
 	"github.com/searKing/rtp/format/hevc"
 	"math"
+	"strings"
 )
 
-func naluPacket(maxPayloadSize int, nals [][]byte, skipAggregate bool, h264NotHevc bool) [][]byte {
+// naluPacket packs the NAL units in single NAL unit packets, aggregation packets of up to maxAggregatedNalus
+// NAL units if not 0, and fragmentation units; parameter sets are not aggregated with the other NAL units
+// if separateParameterSets
+func naluPacket(maxPayloadSize int, nals [][]byte, skipAggregate bool, maxAggregatedNalus int, separateParameterSets bool, h264NotHevc bool) [][]byte {
 	var packetedNals [][]byte
 	payloadHeaderSize := func() int {
 		if h264NotHevc {
@@ -21,6 +27,8 @@ func naluPacket(maxPayloadSize int, nals [][]byte, skipAggregate bool, h264NotHe
 
 	var nalbuffersSize int
 	var nalbuffers [][]byte
+	// whether the buffered NAL units are parameter sets
+	var nalbuffersParameterSets bool
 
 	flushBufferedNals := func() {
 		defer func() {
@@ -31,7 +39,7 @@ func naluPacket(maxPayloadSize int, nals [][]byte, skipAggregate bool, h264NotHe
 		if len(nalbuffers) == 0 {
 			return
 		}
-		// Fragment
+		// Single NAL unit
 		if len(nalbuffers) == 1 {
 			packetedNals = append(packetedNals, tryFragmentNaluIfNecessary(maxPayloadSize, nalbuffers[0], h264NotHevc)...)
 			return
@@ -47,41 +55,46 @@ func naluPacket(maxPayloadSize int, nals [][]byte, skipAggregate bool, h264NotHe
 			continue
 		}
 
-		if len(nal) <= maxPayloadSize {
-			// If the NAL unit fits including the
-			// framing (2 bytes length, plus 1/2 bytes for the STAP-A/AP marker),
-			// write the unit to the buffer as a STAP-A/AP packet, otherwise flush
-			// and send as single NAL.
-
-			//	 0                   1                   2                   3
-			//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
-			//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
-			//	|F|NRI|  Type   |        NAL unit size          |
-			//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
-
-			//	 0                   1                   2                   3
-			//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
-			//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
-			//	|   PayloadHdr (Type=48)        |          NALU 1 Size          |
-			//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
-			//	|            NALU 1 HDR         |
-			//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
-			payloadSize := payloadHeaderSize + 2 + len(nal)
-			if nalbuffersSize+payloadSize <= maxPayloadSize && !skipAggregate {
+		// If the NAL unit fits including the
+		// framing (2 bytes length, plus 1/2 bytes for the STAP-A/AP marker),
+		// write the unit to the buffer as a STAP-A/AP packet, otherwise flush
+		// and send as single NAL.
+
+		//	 0                   1                   2                   3
+		//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+		//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		//	|F|NRI|  Type   |        NAL unit size          |
+		//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+		//	 0                   1                   2                   3
+		//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+		//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		//	|   PayloadHdr (Type=48)        |          NALU 1 Size          |
+		//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		//	|            NALU 1 HDR         |
+		//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+		if !skipAggregate && maxAggregatedNalus != 1 {
+			parameterSet := separateParameterSets && parameterSetIndex(nal, h264NotHevc) >= 0
+			if len(nalbuffers) > 0 && nalbuffersSize+2+len(nal) <= maxPayloadSize &&
+				(maxAggregatedNalus <= 0 || len(nalbuffers) < maxAggregatedNalus) &&
+				parameterSet == nalbuffersParameterSets {
 				// aggregate
 				nalbuffers = append(nalbuffers, nal)
-				nalbuffersSize += len(nal)
+				nalbuffersSize += 2 + len(nal)
 				continue
 			}
-		}
-
-		flushBufferedNals()
-		nalbuffers = append(nalbuffers, nal)
-		nalbuffersSize += len(nal)
-		// fragment this nalu
-		if len(nal) > maxPayloadSize {
 			flushBufferedNals()
+			if payloadHeaderSize+2+len(nal) <= maxPayloadSize {
+				// may be aggregated with the next ones
+				nalbuffers = append(nalbuffers, nal)
+				nalbuffersSize = payloadHeaderSize + 2 + len(nal)
+				nalbuffersParameterSets = parameterSet
+				continue
+			}
 		}
+
+		// single NAL unit, or fragment this nalu
+		packetedNals = append(packetedNals, tryFragmentNaluIfNecessary(maxPayloadSize, nal, h264NotHevc)...)
 	}
 	flushBufferedNals()
 
@@ -90,6 +103,19 @@ func naluPacket(maxPayloadSize int, nals [][]byte, skipAggregate bool, h264NotHe
 }
 
 func tryFragmentNaluIfNecessary(maxPayloadSize int, nalu []byte, h264NotHevc bool) [][]byte {
+	var fragmentedNals [][]byte
+	// Single NALU
+	if len(nalu) <= maxPayloadSize {
+		out := make([]byte, len(nalu))
+		copy(out, nalu)
+		fragmentedNals = append(fragmentedNals, out)
+		return fragmentedNals
+	}
+	return fragmentNalu(maxPayloadSize, nalu, h264NotHevc)
+}
+
+// fragmentNalu fragments the NAL unit in FU-A or FU, whatever its size
+func fragmentNalu(maxPayloadSize int, nalu []byte, h264NotHevc bool) [][]byte {
 	var fragmentedNals [][]byte
 	headerSize := func() int {
 		if h264NotHevc {
@@ -97,17 +123,19 @@ func tryFragmentNaluIfNecessary(maxPayloadSize int, nalu []byte, h264NotHevc boo
 		}
 		return hevc.RTPPacketTypeFu.PayloadHeaderSize()
 	}()
-
-	// Single NALU
-	if len(nalu) <= maxPayloadSize {
-		out := make([]byte, len(nalu))
-		copy(out, nalu)
-		fragmentedNals = append(fragmentedNals, out)
+	// the size of the NAL unit header, not sent in the fragments
+	naluHeaderSize := func() int {
+		if h264NotHevc {
+			return 1
+		}
+		return 2
+	}()
+	if len(nalu) <= naluHeaderSize {
 		return fragmentedNals
 	}
 
 	// FU-A
-	maxFragmentSize := maxPayloadSize - headerSize // nalu without nal header(1Byte)
+	maxFragmentSize := maxPayloadSize - headerSize
 
 	// The FU payload consists of fragments of the payload of the fragmented
 	// NAL unit so that if the fragmentation unit payloads of consecutive
@@ -120,16 +148,11 @@ func tryFragmentNaluIfNecessary(maxPayloadSize int, nalu []byte, h264NotHevc boo
 	// the FU header.  An FU payload MAY have any number of octets and MAY
 	// be empty.
 
-	naluData := nalu
-	// According to the RFC, the first octet is skipped due to redundant information
-	naluDataIndex := 1
-	naluDataLength := len(nalu) - naluDataIndex
-	naluDataRemaining := naluDataLength
-
-	if min(maxFragmentSize, naluDataRemaining) <= 0 {
+	// According to the RFC, the NAL unit header is skipped due to redundant information
+	naluData := nalu[naluHeaderSize:]
+	if maxFragmentSize <= 0 || len(naluData) == 0 {
 		return fragmentedNals
 	}
-	currentNalDataFragmentSize := min(maxFragmentSize, naluDataRemaining)
 
 	var h264FuIndicator h264.FuIndicator
 	var h264FuHeader h264.FuHeader
@@ -142,27 +165,14 @@ func tryFragmentNaluIfNecessary(maxPayloadSize int, nalu []byte, h264NotHevc boo
 		hevcPayloadHdr, hevcFuHeader = initNaluHEVCFu(nalu)
 	}
 
-	for naluDataRemaining > 0 {
-		if naluDataRemaining == naluDataLength {
-			// Set start bit
-			h264FuHeader.StartBit = true
-			h264FuHeader.EndBit = false
-			hevcFuHeader.StartBit = true
-			hevcFuHeader.EndBit = false
-		} else if naluDataRemaining-currentNalDataFragmentSize == 0 {
-			// Set end bit
-			h264FuHeader.StartBit = false
-			h264FuHeader.EndBit = true
-			hevcFuHeader.StartBit = false
-			hevcFuHeader.EndBit = true
-		} else {
-			h264FuHeader.StartBit = false
-			h264FuHeader.EndBit = false
-			hevcFuHeader.StartBit = false
-			hevcFuHeader.EndBit = false
-		}
+	for naluDataIndex := 0; naluDataIndex < len(naluData); {
+		currentNalDataFragmentSize := min(maxFragmentSize, len(naluData)-naluDataIndex)
+		h264FuHeader.StartBit = naluDataIndex == 0
+		h264FuHeader.EndBit = naluDataIndex+currentNalDataFragmentSize == len(naluData)
+		hevcFuHeader.StartBit = h264FuHeader.StartBit
+		hevcFuHeader.EndBit = h264FuHeader.EndBit
 
-		w := bytes.NewBuffer(make([]byte, headerSize+currentNalDataFragmentSize))
+		w := bytes.NewBuffer(make([]byte, 0, headerSize+currentNalDataFragmentSize))
 		if h264NotHevc {
 			w.WriteByte(h264FuIndicator.Byte())
 			w.WriteByte(h264FuHeader.Byte())
@@ -173,35 +183,49 @@ func tryFragmentNaluIfNecessary(maxPayloadSize int, nalu []byte, h264NotHevc boo
 		w.Write(naluData[naluDataIndex : naluDataIndex+currentNalDataFragmentSize])
 		fragmentedNals = append(fragmentedNals, w.Bytes())
 
-		naluDataRemaining -= currentNalDataFragmentSize
 		naluDataIndex += currentNalDataFragmentSize
 	}
 	return fragmentedNals
 }
-func tryAggregateNalus(nalbuffers [][]byte, h264NotHevc bool) [][]byte {
-	var aggregatedNals [][]byte
 
+// tryAggregateNalus aggregates NAL units into a single STAP-A or AP
+func tryAggregateNalus(nalbuffers [][]byte, h264NotHevc bool) [][]byte {
+	w := bytes.NewBuffer(nil)
+	if h264NotHevc {
+		fuIndicator := h264.FuIndicator{}
+		fuIndicator.NalUnitType = h264.RTPPacketTypeStapA.NalUnitType()
+		header := fuIndicator.Byte()
+		for _, nal := range nalbuffers {
+			header = h264AggregationHeader(header, nal)
+		}
+		w.WriteByte(header)
+	} else {
+		payloadHdr := hevc.PayloadHdr{}
+		payloadHdr.NalUnitType = hevc.RTPPacketTypeAp.NalUnitType()
+		payloadHdr.NalTemporalId = 1
+		w.Write(payloadHdr.Bytes())
+	}
+	var word = make([]byte, 2)
 	for _, nal := range nalbuffers {
 		//aggregate buffered nalus
-		w := bytes.NewBuffer(nil)
-		if h264NotHevc {
-			fuIndicator := h264.FuIndicator{}
-			fuIndicator.NalUnitType = h264.RTPPacketTypeStapA.NalUnitType()
-			w.WriteByte(fuIndicator.Byte())
-		} else {
-			payloadHdr := hevc.PayloadHdr{}
-			payloadHdr.NalUnitType = hevc.RTPPacketTypeAp.NalUnitType()
-			payloadHdr.NalTemporalId = 1
-			w.Write(payloadHdr.Bytes())
-		}
-		var word = make([]byte, 4)
 		binary.BigEndian.PutUint16(word, uint16(len(nal)))
-		w.Write(word[:2])
+		w.Write(word)
 		w.Write(nal)
-		aggregatedNals = append(aggregatedNals, w.Bytes())
 	}
-	return aggregatedNals
+	return [][]byte{w.Bytes()}
 }
+
+// h264AggregationHeader raises the F and NRI of the header of an aggregation packet to the ones of the NAL unit:
+// F is set if any aggregated NAL unit has it set, NRI is the maximum of the aggregated NAL units, see rfc6184#section-5.7
+func h264AggregationHeader(header byte, nalu []byte) byte {
+	f := (header | nalu[0]) & h264_codec.ForbiddenZeroBitMask
+	nri := header & h264_codec.NalRefIdcMask
+	if nalu[0]&h264_codec.NalRefIdcMask > nri {
+		nri = nalu[0] & h264_codec.NalRefIdcMask
+	}
+	return header&h264_codec.NalUnitTypeMask | f | nri
+}
+
 func initNaluH264Fu(nalu []byte) (h264.FuIndicator, h264.FuHeader) {
 	naluHeader := h264_codec.ParseNalHeader(nalu)
 	// +---------------+
@@ -242,3 +266,111 @@ func initNaluHEVCFu(nalu []byte) (hevc.PayloadHdr, hevc.FuHeader) {
 	}
 	return payloadHdr, fuHeader
 }
+
+// parameterSets caches the most recent VPS, SPS and PPS NAL units of a stream,
+// to be repeated before the keyframes lacking them
+type parameterSets struct {
+	nalus [3][][]byte // VPS, SPS, PPS; no VPS for H.264
+}
+
+// parameterSetIndex returns the index of the NAL unit in parameterSets, -1 if not a parameter set
+func parameterSetIndex(nalu []byte, h264NotHevc bool) int {
+	if h264NotHevc {
+		if len(nalu) == 0 {
+			return -1
+		}
+		switch h264_codec.ParseNalUnitType(nalu) {
+		case h264_codec.NalUnitTypeSps:
+			return 1
+		case h264_codec.NalUnitTypePps:
+			return 2
+		}
+		return -1
+	}
+	if len(nalu) < 2 {
+		return -1
+	}
+	switch hevc_codec.ParseNalUnitType(nalu) {
+	case hevc_codec.NalUnitTypeVpsNut:
+		return 0
+	case hevc_codec.NalUnitTypeSpsNut:
+		return 1
+	case hevc_codec.NalUnitTypePpsNut:
+		return 2
+	}
+	return -1
+}
+
+// keyframe reports whether the NAL unit is a slice of an IDR picture, or of an IRAP picture for HEVC
+func keyframe(nalu []byte, h264NotHevc bool) bool {
+	if h264NotHevc {
+		return len(nalu) > 0 && h264_codec.ParseNalUnitType(nalu) == h264_codec.NalUnitTypeIdrSlice
+	}
+	return len(nalu) >= 2 && hevc_codec.ParseNalUnitType(nalu).Irap()
+}
+
+// update replaces the cached parameter sets by the ones of nalus, per kind
+func (c *parameterSets) update(nalus [][]byte, h264NotHevc bool) {
+	var seen [3]bool
+	for _, nalu := range nalus {
+		i := parameterSetIndex(nalu, h264NotHevc)
+		if i < 0 {
+			continue
+		}
+		if !seen[i] {
+			seen[i] = true
+			c.nalus[i] = nil
+		}
+		c.nalus[i] = append(c.nalus[i], append([]byte(nil), nalu...))
+	}
+}
+
+// inject updates the cache with the parameter sets of the access unit nalus, and inserts the cached
+// parameter sets of the kinds missing before its first keyframe slice
+func (c *parameterSets) inject(nalus [][]byte, h264NotHevc bool) [][]byte {
+	c.update(nalus, h264NotHevc)
+
+	var present [3]bool
+	for i, nalu := range nalus {
+		if keyframe(nalu, h264NotHevc) {
+			var missing [][]byte
+			for j := range c.nalus {
+				if !present[j] {
+					missing = append(missing, c.nalus[j]...)
+				}
+			}
+			if len(missing) == 0 {
+				return nalus
+			}
+			out := make([][]byte, 0, len(nalus)+len(missing))
+			out = append(out, nalus[:i]...)
+			out = append(out, missing...)
+			return append(out, nalus[i:]...)
+		}
+		if j := parameterSetIndex(nalu, h264NotHevc); j >= 0 {
+			present[j] = true
+		}
+	}
+	return nalus
+}
+
+// unmarshalSprop updates the cache with the comma-separated base64 NAL units of a sprop parameter
+// of an SDP fmtp line, see rfc6184#section-8.1 and rfc7798#section-7.1
+func (c *parameterSets) unmarshalSprop(sprop string, h264NotHevc bool) error {
+	var nalus [][]byte
+	for _, s := range strings.Split(sprop, ",") {
+		if s == "" {
+			continue
+		}
+		nalu, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("malformed sprop parameter set %q: %s", s, err)
+		}
+		if parameterSetIndex(nalu, h264NotHevc) < 0 {
+			return fmt.Errorf("sprop %q is not a parameter set", s)
+		}
+		nalus = append(nalus, nalu)
+	}
+	c.update(nalus, h264NotHevc)
+	return nil
+}
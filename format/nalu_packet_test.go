@@ -0,0 +1,85 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestH265Payloader_Fragment(t *testing.T) {
+	// IDR_W_RADL slice segment, TID 1
+	nalu := append([]byte{0x26, 0x01}, bytes.Repeat([]byte{0xaa, 0xbb, 0xcc}, 100)...)
+
+	pck := H265Payloader{}
+	res := pck.Payload(100, nalu)
+	if len(res) != 4 {
+		t.Fatalf("Payload should fragment the NAL unit into 4 FUs, got %d", len(res))
+	}
+	var data []byte
+	for i, fu := range res {
+		if len(fu) > 100 || fu[0]>>1&0x3f != 49 || fu[2]&0x3f != 19 ||
+			(fu[2]&0x80 != 0) != (i == 0) || (fu[2]&0x40 != 0) != (i == len(res)-1) {
+			t.Fatalf("FU %d is packed incorrectly: % x", i, fu[:3])
+		}
+		data = append(data, fu[3:]...)
+	}
+	// the 2-byte NAL unit header is conveyed by the payload and FU headers only
+	if !bytes.Equal(data, nalu[2:]) {
+		t.Fatal("FU payloads should reassemble into the NAL unit payload")
+	}
+}
+
+func TestH265Payloader_Aggregate(t *testing.T) {
+	vps := []byte{0x40, 0x01, 0x0c}
+	sps := []byte{0x42, 0x01, 0x01}
+	pps := []byte{0x44, 0x01, 0xc1}
+	slice := append([]byte{0x26, 0x01}, bytes.Repeat([]byte{0xaa}, 8)...)
+	au := []byte{0x00, 0x00, 0x00, 0x01}
+	au = append(au, vps...)
+	au = append(au, 0x00, 0x00, 0x01)
+	au = append(au, sps...)
+	au = append(au, 0x00, 0x00, 0x01)
+	au = append(au, pps...)
+	au = append(au, 0x00, 0x00, 0x01)
+	au = append(au, slice...)
+
+	// the AP counts its 2-byte payload header and the 2-byte size of each NAL unit
+	pck := H265Payloader{}
+	res := pck.Payload(17, au)
+	ap := []byte{0x60, 0x01, 0x00, 0x03, 0x40, 0x01, 0x0c, 0x00, 0x03, 0x42, 0x01, 0x01, 0x00, 0x03, 0x44, 0x01, 0xc1}
+	if len(res) != 2 || !bytes.Equal(res[0], ap) || !bytes.Equal(res[1], slice) {
+		t.Fatalf("Payload should aggregate the parameter sets in a single AP, got % x", res)
+	}
+	if res = pck.Payload(16, au); len(res) != 3 || len(res[0]) != 12 || !bytes.Equal(res[1], pps) {
+		t.Fatalf("Payload should aggregate the NAL units fitting in the MTU only, got % x", res)
+	}
+	pck.SkipAggregate = true
+	if res = pck.Payload(1200, au); len(res) != 4 {
+		t.Fatalf("Payload shouldn't aggregate NAL units when skipped, got %d packets", len(res))
+	}
+}
+
+func TestH264Payloader_FragmentAggregate(t *testing.T) {
+	// FU-A fragments reassemble into the IDR slice
+	slice := append([]byte{0x65}, bytes.Repeat([]byte{0xaa, 0xbb, 0xcc}, 100)...)
+	pck := H264Payloader{}
+	res := pck.Payload(100, slice)
+	if len(res) != 4 {
+		t.Fatalf("Payload should fragment the slice into 4 FU-A, got %d", len(res))
+	}
+	nalu := []byte{res[0][0]&0xe0 | res[0][1]&0x1f}
+	for i, fu := range res {
+		if len(fu) > 100 || fu[0]&0x1f != 28 || (fu[1]&0x80 != 0) != (i == 0) || (fu[1]&0x40 != 0) != (i == len(res)-1) {
+			t.Fatalf("FU-A %d is packed incorrectly", i)
+		}
+		nalu = append(nalu, fu[2:]...)
+	}
+	if !bytes.Equal(nalu, slice) {
+		t.Fatal("FU-A fragments should reassemble into the slice")
+	}
+
+	// STAP-A
+	au := []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0x10, 0x00, 0x00, 0x01, 0x0c, 0xff, 0x80}
+	if res = pck.Payload(1200, au); len(res) != 1 || !bytes.Equal(res[0], []byte{0x18, 0x00, 0x02, 0x09, 0x10, 0x00, 0x03, 0x0c, 0xff, 0x80}) {
+		t.Fatalf("Payload should aggregate the NAL units in a STAP-A, got % x", res)
+	}
+}
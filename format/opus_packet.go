@@ -2,27 +2,119 @@ package format
 
 import (
 	"fmt"
+
+	"github.com/searKing/rtp/codecs/opus"
 )
 
 // OpusPayloader payloads Opus packets
-type OpusPayloader struct{}
+type OpusPayloader struct {
+	// ChannelMapping, if set, makes the payloader expect Opus multistream packets,
+	// with ChannelMapping.StreamCount streams in each
+	ChannelMapping *opus.ChannelMapping
+
+	err error
+}
+
+// Err returns the error of the last call to Payload, the invalid framing of a dropped packet
+func (p *OpusPayloader) Err() error {
+	return p.err
+}
+
+// UnmarshalFmtp configures the payloader from the parameters of an SDP fmtp line, the channel mapping of
+// a multiopus one, such as "num_streams=4;coupled_streams=2;channel_mapping=0,4,1,2,3,5";
+// the parameters of rfc7587#section-6.1, such as "minptime=10;useinbandfec=1", leave it unset
+func (p *OpusPayloader) UnmarshalFmtp(fmtp string) error {
+	m, err := parseOpusFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.ChannelMapping = m
+	return nil
+}
+
+// parseOpusFmtp returns the channel mapping of a multiopus fmtp line, nil without its parameters
+func parseOpusFmtp(fmtp string) (*opus.ChannelMapping, error) {
+	params := parseFmtp(fmtp)
+	for _, name := range []string{"num_streams", "coupled_streams", "channel_mapping"} {
+		if _, ok := params[name]; ok {
+			var m opus.ChannelMapping
+			if err := m.UnmarshalFmtp(fmtp); err != nil {
+				return nil, err
+			}
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
 
 // Payload fragments an Opus packet across one or more byte arrays
+// Opus packets are never fragmented, see rfc7587#section-4.2;
+// packets whose framing is invalid, as defined in rfc6716#section-3.4, are dropped, Err returns why
 func (p *OpusPayloader) Payload(mtu int, payload []byte) [][]byte {
+	p.err = nil
 	if payload == nil {
 		return [][]byte{}
 	}
+	if _, err := p.parse(payload); err != nil {
+		p.err = err
+		return [][]byte{}
+	}
 
 	out := make([]byte, len(payload))
 	copy(out, payload)
 	return [][]byte{out}
 }
 
-// OpusPacket represents the VP8 header that is stored in the payload of an RTP Packet
+// Samples returns the number of samples in the Opus packet, at the given clock rate
+func (p *OpusPayloader) Samples(clockRate uint32, payload []byte) uint32 {
+	pkt, err := p.parse(payload)
+	if err != nil {
+		return 0
+	}
+	return uint32(pkt.Samples(int(clockRate)))
+}
+
+func (p *OpusPayloader) parse(payload []byte) (opus.Packet, error) {
+	if p.ChannelMapping == nil {
+		return opus.ParsePacket(payload)
+	}
+	pkt, err := opus.ParseMultistreamPacket(payload, p.ChannelMapping.StreamCount)
+	if err != nil {
+		return opus.Packet{}, err
+	}
+	return pkt.Streams[0], nil
+}
+
+// OpusPacket represents the Opus packet that is stored in the payload of an RTP Packet
 type OpusPacket struct {
+	// ChannelMapping, if set, makes Unmarshal expect Opus multistream packets,
+	// with ChannelMapping.StreamCount streams in each
+	ChannelMapping *opus.ChannelMapping
+
+	// the first, or only, stream of the packet
+	opus.Packet
+	// every stream of a multistream packet
+	Streams []opus.Packet
+
+	// FEC is set if the packet carries LBRR frames, the in-band FEC of
+	// rfc6716#section-2.1.7: when the previous packet is lost, a jitter buffer
+	// can decode this one with FEC enabled to recover the lost audio,
+	// of the same duration, instead of concealing it; FECFrames returns them
+	FEC bool
+
 	Payload []byte
 }
 
+// UnmarshalFmtp configures the depacketizer from the parameters of an SDP fmtp line, as OpusPayloader does
+func (p *OpusPacket) UnmarshalFmtp(fmtp string) error {
+	m, err := parseOpusFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.ChannelMapping = m
+	return nil
+}
+
 // Unmarshal parses the passed byte slice and stores the result in the OpusPacket this method is called upon
 func (p *OpusPacket) Unmarshal(packet []byte) ([]byte, error) {
 	if packet == nil {
@@ -31,6 +123,31 @@ func (p *OpusPacket) Unmarshal(packet []byte) ([]byte, error) {
 	if len(packet) == 0 {
 		return nil, fmt.Errorf("Payload is not large enough")
 	}
+	if p.ChannelMapping != nil {
+		ms, err := opus.ParseMultistreamPacket(packet, p.ChannelMapping.StreamCount)
+		if err != nil {
+			return nil, err
+		}
+		p.Packet = ms.Streams[0]
+		p.Streams = ms.Streams
+		p.FEC = ms.HasLBRR()
+	} else {
+		if err := (&p.Packet).Unmarshal(packet); err != nil {
+			return nil, err
+		}
+		p.Streams = []opus.Packet{p.Packet}
+		p.FEC = p.Packet.HasLBRR()
+	}
 	p.Payload = packet
 	return packet, nil
 }
+
+// FECFrames returns the LBRR frames of the streams of the last packet, indexed as Streams,
+// the zero value for the streams without
+func (p *OpusPacket) FECFrames() []opus.LBRR {
+	frames := make([]opus.LBRR, len(p.Streams))
+	for i, stream := range p.Streams {
+		frames[i], _ = stream.LBRR()
+	}
+	return frames
+}
@@ -1,8 +1,12 @@
 package format
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/searKing/rtp/codecs/opus"
 )
 
 func TestOpusPacket_Unmarshal(t *testing.T) {
@@ -68,3 +72,143 @@ func TestOpusPayloader_Payload(t *testing.T) {
 	}
 
 }
+
+func TestOpusPacket_Unmarshal_TOC(t *testing.T) {
+	pck := OpusPacket{}
+
+	// config 12 (Hybrid SWB 10ms), stereo, code 1: two frames of 2 bytes
+	raw, err := pck.Unmarshal([]byte{0x65, 0x01, 0x02, 0x03, 0x04})
+	if err != nil {
+		t.Fatal("Error should be nil in case of success", err)
+	}
+	if len(raw) != 5 {
+		t.Fatal("Payload should be the whole packet")
+	}
+	if pck.TOC.Configuration.Mode() != opus.ModeHybrid {
+		t.Fatalf("Mode should be Hybrid, got %s", pck.TOC.Configuration.Mode())
+	}
+	if pck.TOC.Configuration.Bandwidth() != opus.BandwidthSuperwideband {
+		t.Fatalf("Bandwidth should be SWB, got %s", pck.TOC.Configuration.Bandwidth())
+	}
+	if !pck.TOC.Stereo {
+		t.Fatal("Packet should be stereo")
+	}
+	if pck.FrameCount() != 2 {
+		t.Fatalf("Frame count should be 2, got %d", pck.FrameCount())
+	}
+	if pck.Duration() != 20*time.Millisecond {
+		t.Fatalf("Duration should be 20ms, got %v", pck.Duration())
+	}
+
+	// code 1 with 3 bytes of frame data after the TOC, which two equal frames cannot split
+	if _, err = pck.Unmarshal([]byte{0x01, 0x01, 0x02, 0x03}); err == nil {
+		t.Fatal("Code 1 packet with odd frame data should fail")
+	}
+
+	// code 2, first frame length exceeds the packet
+	if _, err = pck.Unmarshal([]byte{0x02, 0x05, 0x01}); err == nil {
+		t.Fatal("Code 2 packet with truncated frame should fail")
+	}
+
+	// config 31 (CELT FB 20ms), code 3 VBR with padding, 3 frames of 1, 2 and 1 bytes, 2 bytes of padding
+	raw, err = pck.Unmarshal([]byte{0xfb, 0xc3, 0x02, 0x01, 0x02, 0xaa, 0xbb, 0xbb, 0xcc, 0x00, 0x00})
+	if err != nil {
+		t.Fatal("Error should be nil in case of success", err)
+	}
+	if pck.TOC.Configuration.Mode() != opus.ModeCELT || pck.TOC.Configuration.Bandwidth() != opus.BandwidthFullband {
+		t.Fatal("Configuration should be CELT-only FB")
+	}
+	if !bytes.Equal(bytes.Join(pck.Frames, nil), []byte{0xaa, 0xbb, 0xbb, 0xcc}) || len(pck.Frames[1]) != 2 {
+		t.Fatal("Frames are parsed incorrectly")
+	}
+	if pck.Duration() != 60*time.Millisecond {
+		t.Fatalf("Duration should be 60ms, got %v", pck.Duration())
+	}
+
+	// code 3 CBR, 7 frames of 20ms exceed 120ms
+	if _, err = pck.Unmarshal([]byte{0xfb, 0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}); err == nil {
+		t.Fatal("Code 3 packet longer than 120ms should fail")
+	}
+
+	// code 3 CBR, 3 bytes cannot be split in 2 frames
+	if _, err = pck.Unmarshal([]byte{0xfb, 0x02, 0x00, 0x00, 0x00}); err == nil {
+		t.Fatal("CBR code 3 packet with non integral frame size should fail")
+	}
+}
+
+func TestOpusPayloader_Samples(t *testing.T) {
+	pck := OpusPayloader{}
+
+	// config 1 (SILK NB 20ms), code 0
+	if samples := pck.Samples(48000, []byte{0x08, 0x00}); samples != 960 {
+		t.Fatalf("Samples should be 960, got %d", samples)
+	}
+	// config 16 (CELT NB 2.5ms), code 1
+	if samples := pck.Samples(48000, []byte{0x81, 0x00, 0x00}); samples != 240 {
+		t.Fatalf("Samples should be 240, got %d", samples)
+	}
+	// invalid framing
+	if samples := pck.Samples(48000, []byte{0x01, 0x00}); samples != 0 {
+		t.Fatalf("Samples should be 0, got %d", samples)
+	}
+	if res := pck.Payload(1500, []byte{0x01, 0x00}); len(res) != 0 || pck.Err() == nil {
+		t.Fatal("Generated payload should be empty for invalid framing")
+	}
+}
+
+func TestOpusPacket_Multistream(t *testing.T) {
+	mapping, err := opus.NewChannelMapping(opus.ChannelMappingFamilyVorbis, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stereo stream, self-delimited with one frame of 2 bytes, then a mono stream with LBRR
+	payload := []byte{0x0c, 0x02, 0x80, 0x00, 0x08, 0x40, 0x00}
+
+	pck := OpusPacket{ChannelMapping: mapping}
+	if _, err = pck.Unmarshal(payload); err != nil {
+		t.Fatal("Error should be nil in case of success", err)
+	}
+	if len(pck.Streams) != 2 || !pck.TOC.Stereo || pck.Streams[1].TOC.Stereo {
+		t.Fatal("Streams are parsed incorrectly")
+	}
+	if !pck.FEC {
+		t.Fatal("Packet should carry FEC")
+	}
+	if fec := pck.FECFrames(); len(fec) != 2 || fec[0].Frame != nil || !fec[1].Mid ||
+		!bytes.Equal(fec[1].Frame, []byte{0x40, 0x00}) || fec[1].Duration != 20*time.Millisecond {
+		t.Fatalf("FECFrames should return the LBRR frames of the mono stream, got %+v", fec)
+	}
+
+	payloader := OpusPayloader{ChannelMapping: mapping}
+	if res := payloader.Payload(1500, payload); len(res) != 1 {
+		t.Fatal("Generated payload should be 1")
+	}
+	if samples := payloader.Samples(48000, payload); samples != 960 {
+		t.Fatalf("Samples should be 960, got %d", samples)
+	}
+	if res := payloader.Payload(1500, payload[:4]); len(res) != 0 {
+		t.Fatal("Generated payload should be empty for a missing stream")
+	}
+}
+
+func TestOpusPayloader_UnmarshalFmtp(t *testing.T) {
+	pck := OpusPayloader{}
+	if err := pck.UnmarshalFmtp("minptime=10;useinbandfec=1"); err != nil || pck.ChannelMapping != nil {
+		t.Fatal("UnmarshalFmtp should leave the channel mapping unset")
+	}
+	if err := pck.UnmarshalFmtp("num_streams=4;coupled_streams=2;channel_mapping=0,4,1,2,3,5"); err != nil {
+		t.Fatal(err)
+	}
+	if m := pck.ChannelMapping; m == nil || m.StreamCount != 4 || m.CoupledCount != 2 || len(m.Mapping) != 6 {
+		t.Fatal("UnmarshalFmtp should set the channel mapping")
+	}
+	if err := pck.UnmarshalFmtp("num_streams=4"); err == nil {
+		t.Fatal("UnmarshalFmtp accepted an incomplete channel mapping")
+	}
+
+	depacketizer := OpusPacket{}
+	if err := depacketizer.UnmarshalFmtp("num_streams=1;coupled_streams=1;channel_mapping=0,1"); err != nil ||
+		depacketizer.ChannelMapping == nil || depacketizer.ChannelMapping.StreamCount != 1 {
+		t.Fatal("UnmarshalFmtp should set the channel mapping")
+	}
+}
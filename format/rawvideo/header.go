@@ -0,0 +1,180 @@
+package rawvideo
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The payload header, 4.1 in rfc4175: the extended sequence number, then a line segment header per line segment
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|   Extended Sequence Number    |            Length             |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|F|          Line No            |C|           Offset            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|            Length             |F|          Line No            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|C|           Offset            |                               .
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+                               .
+const (
+	ExtendedSequenceNumberSize = 2
+	LineHeaderSize             = 6
+
+	LineHeaderLengthByteIndex     = 0
+	LineHeaderLineNumberByteIndex = 2
+	LineHeaderOffsetByteIndex     = 4
+
+	LineHeaderFMask          = 0x8000
+	LineHeaderLineNumberMask = 0x7fff
+	LineHeaderCMask          = 0x8000
+	LineHeaderOffsetMask     = 0x7fff
+)
+
+// LineHeader represents the header of a line segment
+type LineHeader struct {
+	// Length is the number of bytes of the line segment
+	Length uint16
+	// F identifies the second field of interlaced video
+	F bool
+	// LineNumber is the line of the line segment
+	LineNumber uint16
+	// C is set when another line segment header follows
+	C bool
+	// Offset is the position of the first pixel of the line segment in the line, in pixels
+	Offset uint16
+}
+
+// Marshal serializes the header into bytes.
+func (h LineHeader) Marshal() ([]byte, error) {
+	if h.LineNumber > LineHeaderLineNumberMask || h.Offset > LineHeaderOffsetMask {
+		return nil, fmt.Errorf("line number %d or offset %d exceeds 15 bits", h.LineNumber, h.Offset)
+	}
+	buf := make([]byte, LineHeaderSize)
+	binary.BigEndian.PutUint16(buf[LineHeaderLengthByteIndex:], h.Length)
+	line := h.LineNumber
+	if h.F {
+		line |= LineHeaderFMask
+	}
+	binary.BigEndian.PutUint16(buf[LineHeaderLineNumberByteIndex:], line)
+	offset := h.Offset
+	if h.C {
+		offset |= LineHeaderCMask
+	}
+	binary.BigEndian.PutUint16(buf[LineHeaderOffsetByteIndex:], offset)
+	return buf, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the LineHeader this method is called upon
+func (h *LineHeader) Unmarshal(buf []byte) error {
+	if len(buf) < LineHeaderSize {
+		return fmt.Errorf("buf is not large enough to container line header")
+	}
+	h.Length = binary.BigEndian.Uint16(buf[LineHeaderLengthByteIndex:])
+	line := binary.BigEndian.Uint16(buf[LineHeaderLineNumberByteIndex:])
+	h.F = line&LineHeaderFMask != 0
+	h.LineNumber = line & LineHeaderLineNumberMask
+	offset := binary.BigEndian.Uint16(buf[LineHeaderOffsetByteIndex:])
+	h.C = offset&LineHeaderCMask != 0
+	h.Offset = offset & LineHeaderOffsetMask
+	return nil
+}
+
+// String helps with debugging by printing LineHeader information in a readable way
+func (h LineHeader) String() string {
+	out := "RawVideo LineHeader:\n"
+
+	out += fmt.Sprintf("\tLength: %d\n", h.Length)
+	out += fmt.Sprintf("\tF: %v\n", h.F)
+	out += fmt.Sprintf("\tLineNumber: %d\n", h.LineNumber)
+	out += fmt.Sprintf("\tC: %v\n", h.C)
+	out += fmt.Sprintf("\tOffset: %d\n", h.Offset)
+
+	return out
+}
+
+// Header represents the payload header that starts the payload of an RTP Packet
+type Header struct {
+	// ExtendedSequenceNumber holds the high-order 16 bits of the 32-bit sequence number,
+	// whose low-order 16 bits are the RTP sequence number
+	ExtendedSequenceNumber uint16
+	Lines                  []LineHeader
+}
+
+// SequenceNumber returns the 32-bit sequence number of the packet of the RTP sequence number
+func (h Header) SequenceNumber(rtpSequenceNumber uint16) uint32 {
+	return uint32(h.ExtendedSequenceNumber)<<16 | uint32(rtpSequenceNumber)
+}
+
+// MarshalSize returns the size of the header once serialized
+func (h Header) MarshalSize() int {
+	return ExtendedSequenceNumberSize + LineHeaderSize*len(h.Lines)
+}
+
+// DataSize returns the number of bytes of the line segments
+func (h Header) DataSize() int {
+	var size int
+	for _, l := range h.Lines {
+		size += int(l.Length)
+	}
+	return size
+}
+
+// Marshal serializes the header into bytes, the C bit of each line segment header is derived from its position
+func (h Header) Marshal() ([]byte, error) {
+	if len(h.Lines) == 0 {
+		return nil, fmt.Errorf("header without line segment")
+	}
+	buf := make([]byte, ExtendedSequenceNumberSize, h.MarshalSize())
+	binary.BigEndian.PutUint16(buf, h.ExtendedSequenceNumber)
+	for i, l := range h.Lines {
+		l.C = i != len(h.Lines)-1
+		b, err := l.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the Header this method is called upon,
+// it returns the number of bytes read
+func (h *Header) Unmarshal(buf []byte) (int, error) {
+	if len(buf) < ExtendedSequenceNumberSize+LineHeaderSize {
+		return 0, fmt.Errorf("buf is not large enough to container header")
+	}
+	h.ExtendedSequenceNumber = binary.BigEndian.Uint16(buf)
+	h.Lines = nil
+	n := ExtendedSequenceNumberSize
+	for {
+		var l LineHeader
+		if err := (&l).Unmarshal(buf[n:]); err != nil {
+			return 0, err
+		}
+		h.Lines = append(h.Lines, l)
+		n += LineHeaderSize
+		if !l.C {
+			return n, nil
+		}
+	}
+}
+
+// String helps with debugging by printing Header information in a readable way
+func (h Header) String() string {
+	out := "RawVideo Header:\n"
+
+	out += fmt.Sprintf("\tExtendedSequenceNumber: %d\n", h.ExtendedSequenceNumber)
+	for _, l := range h.Lines {
+		out += fmt.Sprintf("\tLine: %d F: %v Offset: %d Length: %d\n", l.LineNumber, l.F, l.Offset, l.Length)
+	}
+
+	return out
+}
+
+func ParseHeader(rtpPayload []byte) (Header, int, error) {
+	var h Header
+	n, err := (&h).Unmarshal(rtpPayload)
+	return h, n, err
+}
@@ -0,0 +1,41 @@
+package rawvideo
+
+import "fmt"
+
+// ClockRate is the RTP clock rate of uncompressed video, 90 kHz, see rfc4175#section-6.1
+const ClockRate = 90000
+
+// Sampling is the color subsampling of the video, the sampling media type parameter of rfc4175#section-6.1
+type Sampling string
+
+// Samplings supported, section 4.3 in rfc4175
+const (
+	SamplingYCbCr422 Sampling = "YCbCr-4:2:2"
+)
+
+// PixelGroup is the smallest unit of pixels ending on an octet boundary, rfc4175#section-4.1:
+// Size bytes carry Pixels pixels; lines are only split at pixel group boundaries
+type PixelGroup struct {
+	Size   int
+	Pixels int
+}
+
+// NewPixelGroup returns the pixel group of a sampling and bit depth
+func NewPixelGroup(sampling Sampling, depth int) (PixelGroup, error) {
+	switch sampling {
+	case SamplingYCbCr422:
+		// C'B Y'0 C'R Y'1, 4 samples for 2 pixels
+		switch depth {
+		case 8, 10, 12, 16:
+			return PixelGroup{Size: 4 * depth / 8, Pixels: 2}, nil
+		}
+	default:
+		return PixelGroup{}, fmt.Errorf("unsupported sampling %q", sampling)
+	}
+	return PixelGroup{}, fmt.Errorf("unsupported depth %d for sampling %q", depth, sampling)
+}
+
+// LineSize returns the number of bytes of a line of width pixels, which must be a multiple of the pixel group
+func (g PixelGroup) LineSize(width int) int {
+	return width / g.Pixels * g.Size
+}
@@ -0,0 +1,246 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/searKing/rtp/format/rawvideo"
+)
+
+// RawVideoPayloader payloads uncompressed video, see rfc4175 and SMPTE ST 2110-20
+// Each call to Payload takes a frame, or a field of interlaced video, so that the marker bit
+// Packetize sets on the last packet ends the frame or field.  Lines are split at pixel group
+// boundaries, and a packet carries as many line segments as the MTU allows
+type RawVideoPayloader struct {
+	// Width and Height are the dimensions of the frame in pixels,
+	// the width a multiple of the pixels of the pixel group
+	Width  int
+	Height int
+	// Sampling and Depth select the pixel group, as the sampling and depth media type parameters
+	Sampling rawvideo.Sampling
+	Depth    int
+
+	// Interlaced makes each call to Payload take a field, whose lines are numbered from 0 in the field;
+	// the first field holds the even lines of the frame
+	Interlaced bool
+	// F is the field of the next call, toggled after each call with Interlaced
+	F bool
+
+	// SequenceNumber is the 32-bit sequence number of the next packet, incremented per packet,
+	// whose low-order 16 bits are to match the RTP sequence number
+	SequenceNumber uint32
+}
+
+// UnmarshalFmtp configures the payloader from the parameters of an SDP fmtp line, such as
+// "sampling=YCbCr-4:2:2;width=1280;height=720;depth=10;colorimetry=BT709-2;interlace"
+func (p *RawVideoPayloader) UnmarshalFmtp(fmtp string) error {
+	f, err := parseRawVideoFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.Width, p.Height, p.Sampling, p.Depth, p.Interlaced = f.Width, f.Height, f.Sampling, f.Depth, f.Interlaced
+	return nil
+}
+
+// ClockRate returns the RTP clock rate of uncompressed video, 90 kHz
+func (p *RawVideoPayloader) ClockRate() uint32 {
+	return rawvideo.ClockRate
+}
+
+// lines returns the number of lines of the next call
+func (p *RawVideoPayloader) lines() int {
+	switch {
+	case !p.Interlaced:
+		return p.Height
+	case p.F:
+		return p.Height / 2
+	default:
+		return (p.Height + 1) / 2
+	}
+}
+
+// Payload fragments a frame, or field, across one or more byte arrays
+// Payloads whose size does not match the lines of the dimensions result in no packets
+func (p *RawVideoPayloader) Payload(mtu int, payload []byte) [][]byte {
+	var out [][]byte
+	if payload == nil || mtu <= 0 {
+		return out
+	}
+	pg, err := rawvideo.NewPixelGroup(p.Sampling, p.Depth)
+	if err != nil || p.Width <= 0 || p.Width%pg.Pixels != 0 || p.Width > rawvideo.LineHeaderOffsetMask {
+		return out
+	}
+	lineSize := pg.LineSize(p.Width)
+	lines := p.lines()
+	if lines > rawvideo.LineHeaderLineNumberMask+1 || len(payload) != lines*lineSize {
+		return out
+	}
+	if mtu < rawvideo.ExtendedSequenceNumberSize+rawvideo.LineHeaderSize+pg.Size {
+		return out
+	}
+
+	var line, offset int
+	for line < lines {
+		h := rawvideo.Header{ExtendedSequenceNumber: uint16(p.SequenceNumber >> 16)}
+		var data []byte
+		for room := mtu - rawvideo.ExtendedSequenceNumberSize; line < lines && room >= rawvideo.LineHeaderSize+pg.Size; {
+			size := min(lineSize-offset, (room-rawvideo.LineHeaderSize)/pg.Size*pg.Size)
+			h.Lines = append(h.Lines, rawvideo.LineHeader{
+				Length:     uint16(size),
+				F:          p.Interlaced && p.F,
+				LineNumber: uint16(line),
+				Offset:     uint16(offset / pg.Size * pg.Pixels),
+			})
+			data = append(data, payload[line*lineSize+offset:line*lineSize+offset+size]...)
+			room -= rawvideo.LineHeaderSize + size
+			offset += size
+			if offset == lineSize {
+				line, offset = line+1, 0
+			}
+		}
+		o, err := h.Marshal()
+		if err != nil {
+			return nil
+		}
+		out = append(out, append(o, data...))
+		p.SequenceNumber++
+	}
+	if p.Interlaced {
+		p.F = !p.F
+	}
+	return out
+}
+
+// RawVideoPacket represents the payload header that is stored in the payload of an RTP Packet
+// With the dimensions, sampling and depth set, the line segments are written into Frame
+type RawVideoPacket struct {
+	// the header of the last packet
+	rawvideo.Header
+
+	Width      int
+	Height     int
+	Sampling   rawvideo.Sampling
+	Depth      int
+	Interlaced bool
+
+	// Frame is the frame buffer, Height lines of pixel groups allocated on first use;
+	// the lines of the fields of interlaced video are interleaved.  It is complete when
+	// the RTP marker bit is set, the caller then reads it and may replace it
+	Frame []byte
+
+	// Payload holds the line segments of the last packet, concatenated
+	Payload []byte
+}
+
+// UnmarshalFmtp configures the depacketizer from the parameters of an SDP fmtp line
+func (p *RawVideoPacket) UnmarshalFmtp(fmtp string) error {
+	f, err := parseRawVideoFmtp(fmtp)
+	if err != nil {
+		return err
+	}
+	p.Width, p.Height, p.Sampling, p.Depth, p.Interlaced = f.Width, f.Height, f.Sampling, f.Depth, f.Interlaced
+	return nil
+}
+
+// rawVideoFormat holds the media type parameters of rfc4175#section-6.1 the packets depend on
+type rawVideoFormat struct {
+	Width      int
+	Height     int
+	Sampling   rawvideo.Sampling
+	Depth      int
+	Interlaced bool
+}
+
+// parseRawVideoFmtp parses the required sampling, width, height and depth parameters,
+// and the interlace one, which has no value
+func parseRawVideoFmtp(fmtp string) (rawVideoFormat, error) {
+	var f rawVideoFormat
+	params := parseFmtp(fmtp)
+	for _, name := range []string{"sampling", "width", "height", "depth"} {
+		if _, ok := params[name]; !ok {
+			return f, fmt.Errorf("fmtp requires %s", name)
+		}
+	}
+	f.Sampling = rawvideo.Sampling(params["sampling"])
+	for _, v := range []struct {
+		name string
+		p    *int
+	}{{"width", &f.Width}, {"height", &f.Height}, {"depth", &f.Depth}} {
+		n, err := strconv.Atoi(params[v.name])
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("invalid %s %q", v.name, params[v.name])
+		}
+		*v.p = n
+	}
+	pg, err := rawvideo.NewPixelGroup(f.Sampling, f.Depth)
+	if err != nil {
+		return f, err
+	}
+	if f.Width%pg.Pixels != 0 {
+		return f, fmt.Errorf("width %d is not a multiple of the pixel group", f.Width)
+	}
+	for _, param := range strings.Split(fmtp, ";") {
+		if strings.ToLower(strings.TrimSpace(param)) == "interlace" {
+			f.Interlaced = true
+		}
+	}
+	return f, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the RawVideoPacket this method is called upon
+// It returns the line segments of the packet concatenated, and writes them into Frame if the dimensions are set
+func (p *RawVideoPacket) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	p.Payload = nil
+	n, err := (&p.Header).Unmarshal(packet)
+	if err != nil {
+		return nil, err
+	}
+	data := packet[n:]
+	if len(data) < p.DataSize() {
+		return nil, fmt.Errorf("Payload is not large enough")
+	}
+	data = data[:p.DataSize()]
+
+	if p.Width > 0 && p.Height > 0 {
+		if err := p.write(data); err != nil {
+			return nil, err
+		}
+	}
+	p.Payload = data
+	return data, nil
+}
+
+// write writes the line segments into Frame
+func (p *RawVideoPacket) write(data []byte) error {
+	pg, err := rawvideo.NewPixelGroup(p.Sampling, p.Depth)
+	if err != nil {
+		return err
+	}
+	lineSize := pg.LineSize(p.Width)
+	if len(p.Frame) != lineSize*p.Height {
+		p.Frame = make([]byte, lineSize*p.Height)
+	}
+	for _, l := range p.Lines {
+		row := int(l.LineNumber)
+		if p.Interlaced {
+			row *= 2
+			if l.F {
+				row++
+			}
+		}
+		if int(l.Offset)%pg.Pixels != 0 || int(l.Length)%pg.Size != 0 {
+			return fmt.Errorf("line %d segment at %d of %d bytes is not aligned on pixel groups", l.LineNumber, l.Offset, l.Length)
+		}
+		start := int(l.Offset) / pg.Pixels * pg.Size
+		if row >= p.Height || start+int(l.Length) > lineSize {
+			return fmt.Errorf("line %d segment at %d of %d bytes exceeds the frame", l.LineNumber, l.Offset, l.Length)
+		}
+		copy(p.Frame[row*lineSize+start:], data[:l.Length])
+		data = data[l.Length:]
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/searKing/rtp/format/rawvideo"
+)
+
+func TestRawVideoPayloader_Payload(t *testing.T) {
+	// 4x2 pixels, 10-bit 4:2:2: pixel groups of 5 bytes for 2 pixels, lines of 10 bytes
+	frame := []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19,
+	}
+	pck := RawVideoPayloader{Width: 4, Height: 2, Sampling: rawvideo.SamplingYCbCr422, Depth: 10, SequenceNumber: 0x1ffff}
+
+	if res := pck.Payload(100, frame[:15]); len(res) != 0 {
+		t.Fatal("Generated payload should be empty for a partial frame")
+	}
+	// MTU too small for a pixel group
+	if res := pck.Payload(12, frame); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+
+	// both lines in a packet
+	res := pck.Payload(100, frame)
+	expected := append([]byte{0x00, 0x01, 0x00, 0x0a, 0x00, 0x00, 0x80, 0x00, 0x00, 0x0a, 0x00, 0x01, 0x00, 0x00}, frame...)
+	if len(res) != 1 || !bytes.Equal(res[0], expected) {
+		t.Fatalf("Generated payload should carry two line segments, got %x", res)
+	}
+
+	// lines split at pixel groups: the first line and a pixel group of the second, then the rest of the second
+	res = pck.Payload(30, frame)
+	if len(res) != 2 {
+		t.Fatalf("Generated payload should be 2 packets, got %d", len(res))
+	}
+	var lines []rawvideo.LineHeader
+	for i, r := range res {
+		h, _, err := rawvideo.ParseHeader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r) > 30 {
+			t.Fatalf("Packet %d exceeds the MTU", i)
+		}
+		if h.SequenceNumber(uint16(i)) != 0x20000+uint32(i) {
+			t.Fatalf("Packet %d has extended sequence number %d", i, h.ExtendedSequenceNumber)
+		}
+		lines = append(lines, h.Lines...)
+	}
+	for i, l := range []rawvideo.LineHeader{{Length: 10}, {Length: 5, LineNumber: 1}, {Length: 5, LineNumber: 1, Offset: 2}} {
+		if lines[i].Length != l.Length || lines[i].LineNumber != l.LineNumber || lines[i].Offset != l.Offset {
+			t.Fatalf("Line segment %d should be %+v, got %+v", i, l, lines[i])
+		}
+	}
+	if pck.SequenceNumber != 0x20002 {
+		t.Fatal("SequenceNumber should increment per packet")
+	}
+}
+
+func TestRawVideoPacket_Unmarshal(t *testing.T) {
+	frame := make([]byte, 8*4*2)
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+	pck := RawVideoPayloader{Width: 8, Height: 4, Sampling: rawvideo.SamplingYCbCr422, Depth: 8, Interlaced: true}
+	var packets [][]byte
+	// the first field holds the even lines
+	for field := 0; field < 2; field++ {
+		var lines []byte
+		for line := field; line < 4; line += 2 {
+			lines = append(lines, frame[line*16:line*16+16]...)
+		}
+		packets = append(packets, pck.Payload(2+6*2+12, lines)...)
+	}
+
+	depacketizer := RawVideoPacket{Width: 8, Height: 4, Sampling: rawvideo.SamplingYCbCr422, Depth: 8, Interlaced: true}
+	if _, err := depacketizer.Unmarshal(nil); err == nil {
+		t.Fatal("Unmarshal should reject a nil packet")
+	}
+	for _, p := range packets {
+		if _, err := depacketizer.Unmarshal(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !depacketizer.Lines[0].F {
+		t.Fatal("Last packet should belong to the second field")
+	}
+	if !bytes.Equal(depacketizer.Frame, frame) {
+		t.Fatalf("Frame should be rebuilt, got %x", depacketizer.Frame)
+	}
+
+	// segment beyond the frame
+	bad := []byte{0x00, 0x00, 0x00, 0x04, 0x00, 0x08, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04}
+	if _, err := depacketizer.Unmarshal(bad); err == nil {
+		t.Fatal("Unmarshal should reject a line beyond the frame")
+	}
+	// truncated data
+	if _, err := depacketizer.Unmarshal(bad[:10]); err == nil {
+		t.Fatal("Unmarshal should reject truncated line segments")
+	}
+}
+
+func TestRawVideoPayloader_UnmarshalFmtp(t *testing.T) {
+	pck := RawVideoPayloader{}
+	if err := pck.UnmarshalFmtp("sampling=YCbCr-4:2:2; width=1280; height=720; depth=10; colorimetry=BT709-2; interlace"); err != nil {
+		t.Fatal(err)
+	}
+	if pck.Width != 1280 || pck.Height != 720 || pck.Sampling != rawvideo.SamplingYCbCr422 || pck.Depth != 10 || !pck.Interlaced {
+		t.Fatalf("UnmarshalFmtp should configure the format, got %+v", pck)
+	}
+	for _, fmtp := range []string{
+		"sampling=YCbCr-4:2:2;width=1280;height=720",
+		"sampling=RGB;width=1280;height=720;depth=8",
+		"sampling=YCbCr-4:2:2;width=1281;height=720;depth=8",
+		"sampling=YCbCr-4:2:2;width=1280;height=-1;depth=8",
+	} {
+		if err := pck.UnmarshalFmtp(fmtp); err == nil {
+			t.Fatalf("UnmarshalFmtp accepted %q", fmtp)
+		}
+	}
+
+	depacketizer := RawVideoPacket{}
+	if err := depacketizer.UnmarshalFmtp("sampling=YCbCr-4:2:2;width=4;height=2;depth=8"); err != nil ||
+		depacketizer.Width != 4 || depacketizer.Height != 2 || depacketizer.Depth != 8 || depacketizer.Interlaced {
+		t.Fatal("UnmarshalFmtp should configure the format")
+	}
+}
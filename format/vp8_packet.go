@@ -2,15 +2,107 @@ package format
 
 import (
 	"fmt"
+
+	"github.com/searKing/rtp/codecs/vp8"
 )
 
 // VP8Payloader payloads VP8 packets
-type VP8Payloader struct{}
+// The zero value writes the bare descriptor: S=1 and PID=0 on the first packet of each frame.
+// The fields below enable the optional descriptor fields of rfc7741#section-4.2;
+// the per-frame ones are set before each call to Payload
+type VP8Payloader struct {
+	// PictureIDBits selects the length of the PictureID: 0 omits it, 7 or 15 writes it on 1 or 2 bytes
+	PictureIDBits int
+	// PictureID is the PictureID of the next frame, incremented after every frame,
+	// wrapping around at the PictureIDBits
+	PictureID uint16
+
+	// TemporalScalability makes the payloader write TL0PICIDX, TID and Y
+	TemporalScalability bool
+	// TL0PICIDX is the index of the last frame of temporal layer 0,
+	// incremented before every following frame with TID 0
+	TL0PICIDX uint8
+	// TID is the temporal layer index of the next frame
+	TID uint8
+	// Y is the layer sync bit of the next frame: it only depends on frames of temporal layer 0
+	Y bool
+
+	// KeyIdx makes the payloader write KEYIDX
+	KeyIdx bool
+	// KEYIDX is the temporal key frame index, incremented by the caller when a frame
+	// updates the references the following frames depend on
+	KEYIDX uint8
+
+	// NonReference sets the N bit of the next frame: no other frame depends on it
+	NonReference bool
+
+	// Partitions holds the sizes of the partitions of the next frame, the first partition
+	// and the DCT token partitions.  When they add up to the payload size, each packet
+	// carries the index of the partition its first byte belongs to, and S=1 when that byte
+	// starts the partition, see rfc7741#section-4.4; otherwise the frame is one partition
+	Partitions []int
+
+	started bool
+}
 
 const (
-	vp8HeaderSize = 1
+	// the PID field is 3 bits, the partitions past the 8th share the last index
+	vp8MaxPartitionIndex = 7
 )
 
+// descriptor returns the payload descriptor of a packet starting partition pid, or continuing it
+func (p *VP8Payloader) descriptor(start bool, pid int) []byte {
+	if pid > vp8MaxPartitionIndex {
+		pid = vp8MaxPartitionIndex
+	}
+	b := byte(pid)
+	if p.NonReference {
+		b |= 0x20
+	}
+	if start {
+		b |= 0x10
+	}
+	out := []byte{b}
+
+	var x byte
+	if p.PictureIDBits > 0 {
+		x |= 0x80
+	}
+	if p.TemporalScalability {
+		x |= 0x40 | 0x20
+	}
+	if p.KeyIdx {
+		x |= 0x10
+	}
+	if x == 0 {
+		return out
+	}
+	out[0] |= 0x80
+	out = append(out, x)
+	if p.PictureIDBits > 7 {
+		out = append(out, 0x80|byte(p.PictureID>>8)&0x7f, byte(p.PictureID))
+	} else if p.PictureIDBits > 0 {
+		out = append(out, byte(p.PictureID)&0x7f)
+	}
+	if p.TemporalScalability {
+		out = append(out, p.TL0PICIDX)
+	}
+	if p.TemporalScalability || p.KeyIdx {
+		var tk byte
+		if p.TemporalScalability {
+			tk |= p.TID << 6
+			if p.Y {
+				tk |= 0x20
+			}
+		}
+		if p.KeyIdx {
+			tk |= p.KEYIDX & 0x1f
+		}
+		out = append(out, tk)
+	}
+	return out
+}
+
 // Payload fragments a VP8 packet across one or more byte arrays
 func (p *VP8Payloader) Payload(mtu int, payload []byte) [][]byte {
 
@@ -35,32 +127,52 @@ func (p *VP8Payloader) Payload(mtu int, payload []byte) [][]byte {
 	 *     first packet of each encoded frame.
 	 */
 
-	maxFragmentSize := mtu - vp8HeaderSize
-
-	payloadData := payload
-	payloadDataRemaining := len(payload)
-
-	payloadDataIndex := 0
 	var payloads [][]byte
+	if len(payload) == 0 {
+		return payloads
+	}
+	maxFragmentSize := mtu - len(p.descriptor(true, 0))
 
 	// Make sure the fragment/payload size is correct
-	if min(maxFragmentSize, payloadDataRemaining) <= 0 {
+	if maxFragmentSize <= 0 {
 		return payloads
 	}
-	for payloadDataRemaining > 0 {
-		currentFragmentSize := min(maxFragmentSize, payloadDataRemaining)
-		out := make([]byte, vp8HeaderSize+currentFragmentSize)
-		if payloadDataRemaining == len(payload) {
-			out[0] = 0x10
+	if p.TemporalScalability && p.TID == 0 && p.started {
+		p.TL0PICIDX++
+	}
+
+	partitions := []int{len(payload)}
+	if len(p.Partitions) > 0 {
+		sum := 0
+		for _, size := range p.Partitions {
+			sum += size
 		}
+		if sum == len(payload) {
+			partitions = p.Partitions
+		}
+	}
 
-		copy(out[vp8HeaderSize:], payloadData[payloadDataIndex:payloadDataIndex+currentFragmentSize])
+	pid, partitionStart := 0, 0
+	for payloadDataIndex := 0; payloadDataIndex < len(payload); {
+		// skip to the partition of the first byte of the packet
+		for pid < len(partitions)-1 && payloadDataIndex >= partitionStart+partitions[pid] {
+			partitionStart += partitions[pid]
+			pid++
+		}
+		currentFragmentSize := min(maxFragmentSize, len(payload)-payloadDataIndex)
+		out := p.descriptor(payloadDataIndex == partitionStart, pid)
+		out = append(out, payload[payloadDataIndex:payloadDataIndex+currentFragmentSize]...)
 		payloads = append(payloads, out)
 
-		payloadDataRemaining -= currentFragmentSize
 		payloadDataIndex += currentFragmentSize
 	}
 
+	p.started = true
+	if p.PictureIDBits > 7 {
+		p.PictureID = (p.PictureID + 1) & 0x7fff
+	} else if p.PictureIDBits > 0 {
+		p.PictureID = (p.PictureID + 1) & 0x7f
+	}
 	return payloads
 }
 
@@ -77,8 +189,15 @@ type VP8Packet struct {
 	L         uint8  /* 1 if TL0PICIDX is present */
 	T         uint8  /* 1 if TID is present */
 	K         uint8  /* 1 if KEYIDX is present */
-	PictureID uint16 /* 8 or 16 bits, picture ID */
+	M         uint8  /* 1 if PictureID is 15 bits */
+	PictureID uint16 /* 7 or 15 bits, picture ID */
 	TL0PICIDX uint8  /* 8 bits temporal level zero index */
+	TID       uint8  /* 2 bits temporal layer index */
+	Y         uint8  /* 1 if the frame only depends on the base temporal layer */
+	KEYIDX    uint8  /* 5 bits temporal key frame index */
+
+	// FrameHeader is the VP8 frame header, decoded from the first packet of a frame: S=1 and PID=0
+	FrameHeader *vp8.FrameHeader
 
 	Payload []byte
 }
@@ -91,10 +210,11 @@ func (p *VP8Packet) Unmarshal(payload []byte) ([]byte, error) {
 
 	payloadLen := len(payload)
 
-	if payloadLen < 4 {
+	if payloadLen < 1 {
 		return nil, fmt.Errorf("Payload is not large enough to container header")
 	}
 
+	*p = VP8Packet{}
 	payloadIndex := 0
 
 	p.X = (payload[payloadIndex] & 0x80) >> 7
@@ -105,6 +225,9 @@ func (p *VP8Packet) Unmarshal(payload []byte) ([]byte, error) {
 	payloadIndex++
 
 	if p.X == 1 {
+		if payloadIndex >= payloadLen {
+			return nil, fmt.Errorf("Payload is not large enough")
+		}
 		p.I = (payload[payloadIndex] & 0x80) >> 7
 		p.L = (payload[payloadIndex] & 0x40) >> 6
 		p.T = (payload[payloadIndex] & 0x20) >> 5
@@ -113,18 +236,41 @@ func (p *VP8Packet) Unmarshal(payload []byte) ([]byte, error) {
 	}
 
 	if p.I == 1 { // PID present?
-		if payload[payloadIndex]&0x80 > 0 { // M == 1, PID is 16bit
+		if payloadIndex >= payloadLen {
+			return nil, fmt.Errorf("Payload is not large enough")
+		}
+		p.M = (payload[payloadIndex] & 0x80) >> 7
+		if p.M == 1 { // M == 1, PID is 16bit
+			if payloadIndex+1 >= payloadLen {
+				return nil, fmt.Errorf("Payload is not large enough")
+			}
+			p.PictureID = uint16(payload[payloadIndex]&0x7f)<<8 | uint16(payload[payloadIndex+1])
 			payloadIndex += 2
 		} else {
+			p.PictureID = uint16(payload[payloadIndex])
 			payloadIndex++
 		}
 	}
 
 	if p.L == 1 {
+		if payloadIndex >= payloadLen {
+			return nil, fmt.Errorf("Payload is not large enough")
+		}
+		p.TL0PICIDX = payload[payloadIndex]
 		payloadIndex++
 	}
 
 	if p.T == 1 || p.K == 1 {
+		if payloadIndex >= payloadLen {
+			return nil, fmt.Errorf("Payload is not large enough")
+		}
+		if p.T == 1 {
+			p.TID = payload[payloadIndex] >> 6
+			p.Y = (payload[payloadIndex] & 0x20) >> 5
+		}
+		if p.K == 1 {
+			p.KEYIDX = payload[payloadIndex] & 0x1f
+		}
 		payloadIndex++
 	}
 
@@ -132,5 +278,17 @@ func (p *VP8Packet) Unmarshal(payload []byte) ([]byte, error) {
 		return nil, fmt.Errorf("Payload is not large enough")
 	}
 	p.Payload = payload[payloadIndex:]
+
+	if p.S == 1 && p.PID == 0 {
+		// the frame header may be missing from a packet that is too small, or invalid
+		if h, err := vp8.ParseFrameHeader(p.Payload); err == nil {
+			p.FrameHeader = &h
+		}
+	}
 	return p.Payload, nil
 }
+
+// IsKeyFrame reports whether the packet starts a key frame
+func (p *VP8Packet) IsKeyFrame() bool {
+	return p.FrameHeader != nil && p.FrameHeader.KeyFrame
+}
@@ -1,6 +1,7 @@
 package format
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 )
@@ -30,13 +31,22 @@ func TestVP8Packet_Unmarshal(t *testing.T) {
 		t.Fatal("Error should be:", errSmallerThanHeaderLen)
 	}
 
-	// Payload smaller than header size
-	raw, err = pck.Unmarshal([]byte{0x00, 0x11, 0x22})
+	// Payload of a single byte, after the required header
+	raw, err = pck.Unmarshal([]byte{0x00, 0x11})
+	if !bytes.Equal(raw, []byte{0x11}) {
+		t.Fatal("Result should be the byte after the header")
+	}
+	if err != nil {
+		t.Fatal("Error should be nil in case of success")
+	}
+
+	// Header only
+	raw, err = pck.Unmarshal([]byte{0x00})
 	if raw != nil {
 		t.Fatal("Result should be nil in case of error")
 	}
-	if err == nil || err.Error() != errSmallerThanHeaderLen.Error() {
-		t.Fatal("Error should be:", errSmallerThanHeaderLen)
+	if err == nil || err.Error() != errPayloadTooSmall.Error() {
+		t.Fatal("Error should be:", errPayloadTooSmall)
 	}
 
 	// Normal payload
@@ -112,6 +122,54 @@ func TestVP8Packet_Unmarshal(t *testing.T) {
 	}
 }
 
+func TestVP8Packet_Fields(t *testing.T) {
+	pck := VP8Packet{}
+
+	// all fields, 15-bit PictureID
+	raw, err := pck.Unmarshal([]byte{0xa3, 0xf0, 0x92, 0x34, 0x05, 0x63, 0x90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, []byte{0x90}) {
+		t.Fatal("Result should be the byte after the descriptor")
+	}
+	if pck.X != 1 || pck.N != 1 || pck.S != 0 || pck.PID != 3 || pck.M != 1 || pck.PictureID != 0x1234 ||
+		pck.TL0PICIDX != 5 || pck.TID != 1 || pck.Y != 1 || pck.KEYIDX != 3 {
+		t.Fatalf("Unmarshal should populate every descriptor field, got %+v", pck)
+	}
+	if pck.FrameHeader != nil {
+		t.Fatal("FrameHeader should only be decoded from the start of a frame")
+	}
+
+	// the fields of a previous packet are cleared
+	if _, err = pck.Unmarshal([]byte{0x80, 0x80, 0x7f, 0x90}); err != nil {
+		t.Fatal(err)
+	}
+	if pck.M != 0 || pck.PictureID != 0x7f || pck.L != 0 || pck.TL0PICIDX != 0 || pck.KEYIDX != 0 {
+		t.Fatalf("Unmarshal should parse a 7-bit PictureID, got %+v", pck)
+	}
+
+	// key frame, 640x480 with a horizontal scale of 1
+	if _, err = pck.Unmarshal([]byte{0x10, 0x50, 0x2d, 0x01, 0x9d, 0x01, 0x2a, 0x80, 0x42, 0xe0, 0x01, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if !pck.IsKeyFrame() {
+		t.Fatal("Packet should start a key frame")
+	}
+	if h := pck.FrameHeader; h.Version != 0 || !h.ShowFrame || h.FirstPartitionSize != 0x96a ||
+		h.Width != 640 || h.HorizontalScale != 1 || h.Height != 480 || h.VerticalScale != 0 {
+		t.Fatalf("Unmarshal should decode the key frame header, got %s", h)
+	}
+
+	// inter frame
+	if _, err = pck.Unmarshal([]byte{0x10, 0x31, 0x01, 0x00, 0x00}); err != nil {
+		t.Fatal(err)
+	}
+	if pck.IsKeyFrame() || pck.FrameHeader == nil || pck.FrameHeader.FirstPartitionSize != 9 {
+		t.Fatal("Unmarshal should decode the inter frame header")
+	}
+}
+
 func TestVP8Payloader_Payload(t *testing.T) {
 	pck := VP8Payloader{}
 	payload := []byte{0x90, 0x90, 0x90}
@@ -141,3 +199,82 @@ func TestVP8Payloader_Payload(t *testing.T) {
 		t.Fatal("Generated payload should be the same size as original payload size")
 	}
 }
+
+func TestVP8Payloader_PictureID(t *testing.T) {
+	payload := []byte{0x90, 0x90, 0x90}
+
+	// 7-bit PictureID, wrapping around
+	pck := VP8Payloader{PictureIDBits: 7, PictureID: 0x7f}
+	res := pck.Payload(5, payload)
+	if len(res) != 2 || !bytes.Equal(res[0], []byte{0x90, 0x80, 0x7f, 0x90, 0x90}) || !bytes.Equal(res[1], []byte{0x80, 0x80, 0x7f, 0x90}) {
+		t.Fatalf("Generated payload should carry a 7-bit PictureID, got %x", res)
+	}
+	res = pck.Payload(100, payload)
+	if len(res) != 1 || !bytes.Equal(res[0], []byte{0x90, 0x80, 0x00, 0x90, 0x90, 0x90}) {
+		t.Fatalf("PictureID should increment per frame, got %x", res)
+	}
+
+	// 15-bit PictureID, non-reference frame
+	pck = VP8Payloader{PictureIDBits: 15, PictureID: 0x1234, NonReference: true}
+	res = pck.Payload(100, payload)
+	if len(res) != 1 || !bytes.Equal(res[0], []byte{0xb0, 0x80, 0x92, 0x34, 0x90, 0x90, 0x90}) {
+		t.Fatalf("Generated payload should carry a 15-bit PictureID and the N bit, got %x", res)
+	}
+	if pck.PictureID != 0x1235 {
+		t.Fatal("PictureID should increment per frame")
+	}
+
+	// MTU too small for the descriptor
+	if res = pck.Payload(4, payload); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+}
+
+func TestVP8Payloader_TemporalScalability(t *testing.T) {
+	payload := []byte{0x90}
+	pck := VP8Payloader{TemporalScalability: true, KeyIdx: true, TL0PICIDX: 5, KEYIDX: 3}
+
+	// the first TID 0 frame keeps TL0PICIDX
+	res := pck.Payload(100, payload)
+	if len(res) != 1 || !bytes.Equal(res[0], []byte{0x90, 0x70, 0x05, 0x03, 0x90}) {
+		t.Fatalf("Generated payload should carry TL0PICIDX, TID and KEYIDX, got %x", res)
+	}
+	pck.TID, pck.Y = 1, true
+	res = pck.Payload(100, payload)
+	if len(res) != 1 || !bytes.Equal(res[0], []byte{0x90, 0x70, 0x05, 0x63, 0x90}) {
+		t.Fatalf("TL0PICIDX should not increment for TID 1, got %x", res)
+	}
+	pck.TID, pck.Y = 0, false
+	res = pck.Payload(100, payload)
+	if len(res) != 1 || !bytes.Equal(res[0], []byte{0x90, 0x70, 0x06, 0x03, 0x90}) {
+		t.Fatalf("TL0PICIDX should increment for TID 0, got %x", res)
+	}
+}
+
+func TestVP8Payloader_Partitions(t *testing.T) {
+	payload := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	pck := VP8Payloader{Partitions: []int{2, 2, 3}}
+
+	res := pck.Payload(3, payload)
+	expected := [][]byte{
+		{0x10, 0x00, 0x01},
+		{0x11, 0x02, 0x03},
+		{0x12, 0x04, 0x05},
+		{0x02, 0x06},
+	}
+	if len(res) != len(expected) {
+		t.Fatalf("Generated payload should be %d packets, got %d", len(expected), len(res))
+	}
+	for i := range expected {
+		if !bytes.Equal(res[i], expected[i]) {
+			t.Fatalf("Packet %d should be %x, got %x", i, expected[i], res[i])
+		}
+	}
+
+	// Partitions not matching the payload are ignored
+	pck.Partitions = []int{3}
+	res = pck.Payload(100, payload)
+	if len(res) != 1 || res[0][0] != 0x10 {
+		t.Fatal("Generated payload should be a single partition")
+	}
+}
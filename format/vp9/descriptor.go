@@ -0,0 +1,287 @@
+package vp9
+
+import (
+	"fmt"
+)
+
+// The VP9 payload descriptor, rfc9628#section-4.2
+//
+//	      0 1 2 3 4 5 6 7
+//	     +-+-+-+-+-+-+-+-+
+//	     |I|P|L|F|B|E|V|Z| (REQUIRED)
+//	     +-+-+-+-+-+-+-+-+
+//	I:   |M| PICTURE ID  | (REQUIRED)
+//	     +-+-+-+-+-+-+-+-+
+//	M:   | EXTENDED PID  | (RECOMMENDED)
+//	     +-+-+-+-+-+-+-+-+
+//	L:   |  TID  |U| SID |D| (Conditionally RECOMMENDED)
+//	     +-+-+-+-+-+-+-+-+
+//	     |   TL0PICIDX   | (Conditionally REQUIRED, non-flexible mode)
+//	     +-+-+-+-+-+-+-+-+
+//	P,F: | P_DIFF      |N| (Conditionally REQUIRED, flexible mode)  - up to 3 times
+//	     +-+-+-+-+-+-+-+-+
+//	V:   | SS            |
+//	     | ..            |
+//	     +-+-+-+-+-+-+-+-+
+const (
+	PictureIDPresentMask      = 0x80
+	InterPicturePredictedMask = 0x40
+	LayerIndicesPresentMask   = 0x20
+	FlexibleModeMask          = 0x10
+	StartOfFrameMask          = 0x08
+	EndOfFrameMask            = 0x04
+	ScalabilityStructureMask  = 0x02
+	NotReferenceMask          = 0x01
+
+	ExtendedPictureIDMask = 0x80
+
+	TIDMask   = 0xe0
+	TIDOffset = 5
+	UMask     = 0x10
+	SIDMask   = 0x0e
+	SIDOffset = 1
+	DMask     = 0x01
+
+	PDiffMask   = 0xfe
+	PDiffOffset = 1
+	PDiffNMask  = 0x01
+
+	// 4.2: a layer frame references at most 3 others
+	MaxPDiffCount = 3
+	// 4.2: the number of spatial layers, N_S + 1, is at most 8
+	MaxSpatialLayers = 8
+
+	MaxPictureID7  = 0x7f
+	MaxPictureID15 = 0x7fff
+)
+
+// Descriptor represents the VP9 payload descriptor that starts the payload of an RTP Packet
+type Descriptor struct {
+	I bool // picture ID present
+	P bool // inter-picture predicted layer frame
+	L bool // layer indices present
+	F bool // flexible mode
+	B bool // start of a layer frame
+	E bool // end of a layer frame
+	V bool // scalability structure present
+	Z bool // not a reference for upper spatial layers
+
+	M         bool   // 15-bit picture ID
+	PictureID uint16 // 7 or 15 bits
+
+	TID uint8 // temporal layer ID
+	U   bool  // switching up point
+	SID uint8 // spatial layer ID
+	D   bool  // inter-layer dependency used
+
+	TL0PICIDX uint8 // temporal layer zero index, non-flexible mode
+
+	// PDiffs holds the reference indices, in flexible mode: the picture ID differences
+	// to the pictures the layer frame references
+	PDiffs []uint8
+
+	SS *ScalabilityStructure
+}
+
+// MarshalSize returns the size of the marshaled descriptor
+func (d Descriptor) MarshalSize() int {
+	size := 1
+	if d.I {
+		size++
+		if d.M {
+			size++
+		}
+	}
+	if d.L {
+		size++
+		if !d.F {
+			size++
+		}
+	}
+	if d.F && d.P {
+		size += len(d.PDiffs)
+	}
+	if d.V && d.SS != nil {
+		size += d.SS.MarshalSize()
+	}
+	return size
+}
+
+// Marshal serializes the descriptor into bytes.
+func (d Descriptor) Marshal() ([]byte, error) {
+	buf := make([]byte, 0, d.MarshalSize())
+	var b byte
+	for _, f := range []struct {
+		set  bool
+		mask byte
+	}{
+		{d.I, PictureIDPresentMask},
+		{d.P, InterPicturePredictedMask},
+		{d.L, LayerIndicesPresentMask},
+		{d.F, FlexibleModeMask},
+		{d.B, StartOfFrameMask},
+		{d.E, EndOfFrameMask},
+		{d.V, ScalabilityStructureMask},
+		{d.Z, NotReferenceMask},
+	} {
+		if f.set {
+			b |= f.mask
+		}
+	}
+	buf = append(buf, b)
+
+	if d.I {
+		if d.M {
+			buf = append(buf, ExtendedPictureIDMask|byte(d.PictureID>>8)&0x7f, byte(d.PictureID))
+		} else {
+			buf = append(buf, byte(d.PictureID)&0x7f)
+		}
+	}
+	if d.L {
+		b := d.TID<<TIDOffset&TIDMask | d.SID<<SIDOffset&SIDMask
+		if d.U {
+			b |= UMask
+		}
+		if d.D {
+			b |= DMask
+		}
+		buf = append(buf, b)
+		if !d.F {
+			buf = append(buf, d.TL0PICIDX)
+		}
+	}
+	if d.F && d.P {
+		if len(d.PDiffs) == 0 || len(d.PDiffs) > MaxPDiffCount {
+			return nil, fmt.Errorf("flexible mode requires 1 to %d P_DIFF, got %d", MaxPDiffCount, len(d.PDiffs))
+		}
+		for i, pdiff := range d.PDiffs {
+			b := pdiff << PDiffOffset & PDiffMask
+			if i < len(d.PDiffs)-1 {
+				b |= PDiffNMask
+			}
+			buf = append(buf, b)
+		}
+	}
+	if d.V {
+		if d.SS == nil {
+			return nil, fmt.Errorf("V bit set without scalability structure")
+		}
+		ss, err := d.SS.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, ss...)
+	}
+	return buf, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the Descriptor this method is called upon
+// It returns the size of the descriptor, the offset of the VP9 payload
+func (d *Descriptor) Unmarshal(buf []byte) (int, error) {
+	if len(buf) < 1 {
+		return 0, fmt.Errorf("buf is not large enough to container descriptor")
+	}
+	*d = Descriptor{
+		I: buf[0]&PictureIDPresentMask != 0,
+		P: buf[0]&InterPicturePredictedMask != 0,
+		L: buf[0]&LayerIndicesPresentMask != 0,
+		F: buf[0]&FlexibleModeMask != 0,
+		B: buf[0]&StartOfFrameMask != 0,
+		E: buf[0]&EndOfFrameMask != 0,
+		V: buf[0]&ScalabilityStructureMask != 0,
+		Z: buf[0]&NotReferenceMask != 0,
+	}
+	index := 1
+	next := func() (byte, error) {
+		if index >= len(buf) {
+			return 0, fmt.Errorf("buf is not large enough to container descriptor")
+		}
+		index++
+		return buf[index-1], nil
+	}
+
+	if d.I {
+		b, err := next()
+		if err != nil {
+			return 0, err
+		}
+		d.M = b&ExtendedPictureIDMask != 0
+		d.PictureID = uint16(b & 0x7f)
+		if d.M {
+			b, err := next()
+			if err != nil {
+				return 0, err
+			}
+			d.PictureID = d.PictureID<<8 | uint16(b)
+		}
+	}
+	if d.L {
+		b, err := next()
+		if err != nil {
+			return 0, err
+		}
+		d.TID = (b & TIDMask) >> TIDOffset
+		d.U = b&UMask != 0
+		d.SID = (b & SIDMask) >> SIDOffset
+		d.D = b&DMask != 0
+		if !d.F {
+			if d.TL0PICIDX, err = next(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if d.F && d.P {
+		for {
+			b, err := next()
+			if err != nil {
+				return 0, err
+			}
+			if len(d.PDiffs) == MaxPDiffCount {
+				return 0, fmt.Errorf("more than %d P_DIFF", MaxPDiffCount)
+			}
+			d.PDiffs = append(d.PDiffs, (b&PDiffMask)>>PDiffOffset)
+			if b&PDiffNMask == 0 {
+				break
+			}
+		}
+	}
+	if d.V {
+		d.SS = &ScalabilityStructure{}
+		n, err := d.SS.Unmarshal(buf[index:])
+		if err != nil {
+			return 0, err
+		}
+		index += n
+	}
+	return index, nil
+}
+
+// String helps with debugging by printing descriptor information in a readable way
+func (d Descriptor) String() string {
+	out := "VP9 Payload Descriptor:\n"
+
+	out += fmt.Sprintf("\tI: %v P: %v L: %v F: %v B: %v E: %v V: %v Z: %v\n", d.I, d.P, d.L, d.F, d.B, d.E, d.V, d.Z)
+	if d.I {
+		out += fmt.Sprintf("\tPictureID: %d\n", d.PictureID)
+	}
+	if d.L {
+		out += fmt.Sprintf("\tTID: %d U: %v SID: %d D: %v\n", d.TID, d.U, d.SID, d.D)
+		if !d.F {
+			out += fmt.Sprintf("\tTL0PICIDX: %d\n", d.TL0PICIDX)
+		}
+	}
+	if d.F && d.P {
+		out += fmt.Sprintf("\tPDiffs: %v\n", d.PDiffs)
+	}
+	if d.V && d.SS != nil {
+		out += d.SS.String()
+	}
+
+	return out
+}
+
+func ParseDescriptor(buf []byte) (Descriptor, int, error) {
+	var d Descriptor
+	n, err := (&d).Unmarshal(buf)
+	return d, n, err
+}
@@ -0,0 +1,178 @@
+package vp9
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The scalability structure, rfc9628#section-4.2.1
+//
+//	     +-+-+-+-+-+-+-+-+
+//	V:   | N_S |Y|G|-|-|-|
+//	     +-+-+-+-+-+-+-+-+              -\
+//	Y:   |     WIDTH     | (OPTIONAL)    .
+//	     +               +               .
+//	     |               | (OPTIONAL)    .
+//	     +-+-+-+-+-+-+-+-+               . - N_S + 1 times
+//	     |     HEIGHT    | (OPTIONAL)    .
+//	     +               +               .
+//	     |               | (OPTIONAL)    .
+//	     +-+-+-+-+-+-+-+-+              -/
+//	G:   |      N_G      | (OPTIONAL)
+//	     +-+-+-+-+-+-+-+-+                           -\
+//	N_G: |  TID  |U| R |-|-| (OPTIONAL)                 .
+//	     +-+-+-+-+-+-+-+-+              -\              . - N_G times
+//	     |    P_DIFF     | (OPTIONAL)    . - R times    .
+//	     +-+-+-+-+-+-+-+-+              -/             -/
+const (
+	SpatialLayersMask           = 0xe0
+	SpatialLayersOffset         = 5
+	ResolutionsPresentMask      = 0x10
+	PictureGroupsPresentMask    = 0x08
+	PictureGroupTIDMask         = 0xe0
+	PictureGroupTIDOffset       = 5
+	PictureGroupUMask           = 0x10
+	PictureGroupReferencesMask  = 0x0c
+	PictureGroupReferenceOffset = 2
+)
+
+// Resolution is the size of the frames of a spatial layer
+type Resolution struct {
+	Width  uint16
+	Height uint16
+}
+
+// PictureGroup describes a picture of the group of pictures, in non-flexible mode
+type PictureGroup struct {
+	TID uint8
+	U   bool
+	// PDiffs holds the picture ID differences to the pictures it references, at most 3
+	PDiffs []uint8
+}
+
+// ScalabilityStructure represents the SS, which describes the spatial layers of a stream
+// and, in non-flexible mode, the structure of its group of pictures
+type ScalabilityStructure struct {
+	// SpatialLayers is the number of spatial layers, N_S + 1
+	SpatialLayers int
+	// Resolutions holds the resolution of each spatial layer, or is empty (Y=0)
+	Resolutions []Resolution
+	// PictureGroups describes the pictures of the group of pictures, or is empty (G=0)
+	PictureGroups []PictureGroup
+}
+
+// MarshalSize returns the size of the marshaled scalability structure
+func (s ScalabilityStructure) MarshalSize() int {
+	size := 1 + 4*len(s.Resolutions)
+	if len(s.PictureGroups) > 0 {
+		size++
+		for _, g := range s.PictureGroups {
+			size += 1 + len(g.PDiffs)
+		}
+	}
+	return size
+}
+
+// Marshal serializes the scalability structure into bytes.
+func (s ScalabilityStructure) Marshal() ([]byte, error) {
+	if s.SpatialLayers < 1 || s.SpatialLayers > MaxSpatialLayers {
+		return nil, fmt.Errorf("invalid spatial layer count %d", s.SpatialLayers)
+	}
+	if len(s.Resolutions) > 0 && len(s.Resolutions) != s.SpatialLayers {
+		return nil, fmt.Errorf("%d resolutions for %d spatial layers", len(s.Resolutions), s.SpatialLayers)
+	}
+	if len(s.PictureGroups) > 0xff {
+		return nil, fmt.Errorf("picture group count %d exceeds 255", len(s.PictureGroups))
+	}
+	buf := make([]byte, 1, s.MarshalSize())
+	buf[0] = byte(s.SpatialLayers-1) << SpatialLayersOffset
+	if len(s.Resolutions) > 0 {
+		buf[0] |= ResolutionsPresentMask
+	}
+	if len(s.PictureGroups) > 0 {
+		buf[0] |= PictureGroupsPresentMask
+	}
+	for _, r := range s.Resolutions {
+		buf = append(buf, byte(r.Width>>8), byte(r.Width), byte(r.Height>>8), byte(r.Height))
+	}
+	if len(s.PictureGroups) > 0 {
+		buf = append(buf, byte(len(s.PictureGroups)))
+	}
+	for _, g := range s.PictureGroups {
+		if len(g.PDiffs) > MaxPDiffCount {
+			return nil, fmt.Errorf("picture group with %d P_DIFF exceeds %d", len(g.PDiffs), MaxPDiffCount)
+		}
+		b := g.TID<<PictureGroupTIDOffset&PictureGroupTIDMask | byte(len(g.PDiffs))<<PictureGroupReferenceOffset
+		if g.U {
+			b |= PictureGroupUMask
+		}
+		buf = append(buf, b)
+		buf = append(buf, g.PDiffs...)
+	}
+	return buf, nil
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the ScalabilityStructure this method is called upon
+// It returns the size of the scalability structure
+func (s *ScalabilityStructure) Unmarshal(buf []byte) (int, error) {
+	errShort := fmt.Errorf("buf is not large enough to container scalability structure")
+	if len(buf) < 1 {
+		return 0, errShort
+	}
+	*s = ScalabilityStructure{SpatialLayers: int(buf[0]>>SpatialLayersOffset) + 1}
+	y := buf[0]&ResolutionsPresentMask != 0
+	g := buf[0]&PictureGroupsPresentMask != 0
+	index := 1
+	if y {
+		if len(buf) < index+4*s.SpatialLayers {
+			return 0, errShort
+		}
+		for i := 0; i < s.SpatialLayers; i++ {
+			s.Resolutions = append(s.Resolutions, Resolution{
+				Width:  binary.BigEndian.Uint16(buf[index:]),
+				Height: binary.BigEndian.Uint16(buf[index+2:]),
+			})
+			index += 4
+		}
+	}
+	if g {
+		if len(buf) < index+1 {
+			return 0, errShort
+		}
+		count := int(buf[index])
+		index++
+		for i := 0; i < count; i++ {
+			if len(buf) < index+1 {
+				return 0, errShort
+			}
+			b := buf[index]
+			index++
+			refs := int(b&PictureGroupReferencesMask) >> PictureGroupReferenceOffset
+			if len(buf) < index+refs {
+				return 0, errShort
+			}
+			s.PictureGroups = append(s.PictureGroups, PictureGroup{
+				TID:    (b & PictureGroupTIDMask) >> PictureGroupTIDOffset,
+				U:      b&PictureGroupUMask != 0,
+				PDiffs: append([]uint8(nil), buf[index:index+refs]...),
+			})
+			index += refs
+		}
+	}
+	return index, nil
+}
+
+// String helps with debugging by printing scalability structure information in a readable way
+func (s ScalabilityStructure) String() string {
+	out := "VP9 Scalability Structure:\n"
+
+	out += fmt.Sprintf("\tSpatialLayers: %d\n", s.SpatialLayers)
+	for i, r := range s.Resolutions {
+		out += fmt.Sprintf("\tLayer %d: %dx%d\n", i, r.Width, r.Height)
+	}
+	for i, g := range s.PictureGroups {
+		out += fmt.Sprintf("\tPicture %d: TID: %d U: %v PDiffs: %v\n", i, g.TID, g.U, g.PDiffs)
+	}
+
+	return out
+}
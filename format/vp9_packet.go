@@ -0,0 +1,190 @@
+package format
+
+import (
+	"fmt"
+
+	vp9_codec "github.com/searKing/rtp/codecs/vp9"
+	"github.com/searKing/rtp/format/vp9"
+)
+
+// VP9Payloader payloads VP9 layer frames, see rfc9628#section-4
+// The zero value writes the bare descriptor of the non-flexible mode, with B and E bits.
+// The fields below enable the optional descriptor fields; the per-frame ones are set
+// before each call to Payload
+type VP9Payloader struct {
+	// FlexibleMode makes the payloader write the references of each layer frame, PDiffs,
+	// instead of the TL0PICIDX and the picture groups of the scalability structure
+	FlexibleMode bool
+
+	// PictureIDBits selects the length of the PictureID: 7 or 15 bits; 0 omits it in non-flexible mode,
+	// and means 15 bits in flexible mode, where it is required
+	PictureIDBits int
+	// PictureID is the PictureID of the next picture, incremented at the start of every following picture,
+	// the layer frame with SID 0, wrapping around at the PictureIDBits
+	PictureID uint16
+
+	// Layers makes the payloader write the layer indices: TID, U, SID and D, and TL0PICIDX in non-flexible mode
+	Layers bool
+	TID    uint8
+	U      bool
+	SID    uint8
+	D      bool
+	// TL0PICIDX is the index of the last picture of temporal layer 0,
+	// incremented at the start of every following picture with TID 0
+	TL0PICIDX uint8
+
+	// PDiffs holds the references of the next layer frame, in flexible mode; none means an intra frame
+	PDiffs []uint8
+	// Z is set when the next layer frame is not used by the upper spatial layers
+	Z bool
+
+	// ScalabilityStructure is written in the first packet of every key frame
+	ScalabilityStructure *vp9.ScalabilityStructure
+
+	started bool
+}
+
+// Payload fragments a VP9 layer frame across one or more byte arrays
+// Outside of the flexible mode, P is derived from the frame header: unset for key frames and intra-only frames
+func (p *VP9Payloader) Payload(mtu int, payload []byte) [][]byte {
+	var payloads [][]byte
+	if len(payload) == 0 || mtu <= 0 {
+		return payloads
+	}
+
+	header, err := vp9_codec.ParseFrameHeader(payload)
+	keyFrame := err == nil && header.KeyFrame
+	d := vp9.Descriptor{
+		F: p.FlexibleMode,
+		L: p.Layers,
+		Z: p.Z,
+	}
+	if p.FlexibleMode {
+		d.P = len(p.PDiffs) > 0
+		d.PDiffs = p.PDiffs
+	} else {
+		d.P = err != nil || !(header.KeyFrame || header.IntraOnly)
+	}
+
+	switch {
+	case p.PictureIDBits == 7:
+		d.I = true
+	case p.PictureIDBits > 0 || p.FlexibleMode:
+		d.I, d.M = true, true
+	}
+	// the descriptor of the first packet carries B, and the scalability structure of key frames
+	start := func(d vp9.Descriptor) vp9.Descriptor {
+		d.B = true
+		if keyFrame && p.ScalabilityStructure != nil && (!p.Layers || p.SID == 0) {
+			d.V, d.SS = true, p.ScalabilityStructure
+		}
+		return d
+	}
+	// Make sure the fragment/payload size is correct
+	if mtu-start(d).MarshalSize() <= 0 || mtu-d.MarshalSize() <= 0 {
+		return payloads
+	}
+
+	// the start of a new picture
+	if p.started && (!p.Layers || p.SID == 0) {
+		p.PictureID++
+		if p.Layers && !p.FlexibleMode && p.TID == 0 {
+			p.TL0PICIDX++
+		}
+	}
+	if d.M {
+		p.PictureID &= vp9.MaxPictureID15
+	} else {
+		p.PictureID &= vp9.MaxPictureID7
+	}
+	d.PictureID = p.PictureID
+	if p.Layers {
+		d.TID, d.U, d.SID, d.D, d.TL0PICIDX = p.TID, p.U, p.SID, p.D, p.TL0PICIDX
+	}
+
+	for index := 0; index < len(payload); {
+		desc := d
+		if index == 0 {
+			desc = start(d)
+		}
+		n := min(mtu-desc.MarshalSize(), len(payload)-index)
+		desc.E = index+n == len(payload)
+		out, err := desc.Marshal()
+		if err != nil {
+			return [][]byte{}
+		}
+		out = append(out, payload[index:index+n]...)
+		payloads = append(payloads, out)
+		index += n
+	}
+
+	p.started = true
+	return payloads
+}
+
+// VP9Packet represents the VP9 payload descriptor that is stored in the payload of an RTP Packet,
+// and reassembles the layer frames fragmented across packets
+type VP9Packet struct {
+	// the descriptor of the last packet
+	vp9.Descriptor
+
+	// ScalabilityStructure is the last scalability structure received, which describes the
+	// spatial layers and, in non-flexible mode, the group of pictures
+	ScalabilityStructure *vp9.ScalabilityStructure
+
+	// FrameHeader is the VP9 frame header of the last layer frame
+	FrameHeader *vp9_codec.FrameHeader
+
+	Payload []byte
+
+	frame   []byte
+	inFrame bool
+}
+
+// Unmarshal parses the passed byte slice and stores the result in the VP9Packet this method is called upon
+// It returns the layer frame completed by this packet, the one with the E bit;
+// the packets before it are buffered and nothing is returned until then.
+// A layer frame whose first packet, the one with the B bit, was not received is dropped
+func (p *VP9Packet) Unmarshal(packet []byte) ([]byte, error) {
+	if packet == nil {
+		return nil, fmt.Errorf("invalid nil packet")
+	}
+	if len(packet) == 0 {
+		return nil, fmt.Errorf("Payload is not large enough to container header")
+	}
+
+	p.Payload = nil
+	n, err := (&p.Descriptor).Unmarshal(packet)
+	if err != nil {
+		return nil, err
+	}
+	if n >= len(packet) {
+		return nil, fmt.Errorf("Payload is not large enough")
+	}
+	if p.SS != nil {
+		p.ScalabilityStructure = p.SS
+	}
+	data := packet[n:]
+
+	if p.B {
+		p.frame = append(p.frame[:0], data...)
+		p.inFrame = true
+	} else if p.inFrame {
+		p.frame = append(p.frame, data...)
+	}
+	if !p.inFrame || !p.E {
+		return nil, nil
+	}
+	p.inFrame = false
+	p.Payload = append([]byte(nil), p.frame...)
+	p.FrameHeader = nil
+	if h, err := vp9_codec.ParseFrameHeader(p.Payload); err == nil {
+		p.FrameHeader = &h
+	}
+	return p.Payload, nil
+}
+
+// IsKeyFrame reports whether the last layer frame is a key frame
+func (p *VP9Packet) IsKeyFrame() bool {
+	return p.FrameHeader != nil && p.FrameHeader.KeyFrame
+}
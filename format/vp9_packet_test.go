@@ -0,0 +1,192 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/searKing/rtp/format/vp9"
+)
+
+var (
+	// profile 0 key frame, 640x480
+	vp9KeyFrame = []byte{0x82, 0x49, 0x83, 0x42, 0x00, 0x27, 0xf0, 0x1d, 0xf0}
+	// profile 0 inter frame
+	vp9InterFrame = []byte{0x86, 0x00, 0x00}
+)
+
+func TestVP9Descriptor_Marshal(t *testing.T) {
+	ss := &vp9.ScalabilityStructure{
+		SpatialLayers: 2,
+		Resolutions:   []vp9.Resolution{{Width: 320, Height: 240}, {Width: 640, Height: 480}},
+		PictureGroups: []vp9.PictureGroup{{TID: 0, PDiffs: []uint8{4}}, {TID: 1, U: true, PDiffs: []uint8{1}}, {TID: 2, PDiffs: []uint8{1, 2}}},
+	}
+	tests := []vp9.Descriptor{
+		{B: true, E: true},
+		{I: true, PictureID: 0x7f, P: true, E: true},
+		{I: true, M: true, PictureID: 0x1234, L: true, TID: 2, U: true, SID: 1, D: true, TL0PICIDX: 9, B: true, Z: true},
+		{I: true, M: true, PictureID: 0x7fff, F: true, P: true, L: true, TID: 1, SID: 3, PDiffs: []uint8{1, 2, 127}},
+		{I: true, M: true, L: true, B: true, V: true, SS: ss},
+		{B: true, V: true, SS: &vp9.ScalabilityStructure{SpatialLayers: 1}},
+	}
+
+	for i, want := range tests {
+		raw, err := want.Marshal()
+		if err != nil {
+			t.Fatalf("#%d: Marshal failed: %v", i, err)
+		}
+		if len(raw) != want.MarshalSize() {
+			t.Fatalf("#%d: MarshalSize %d, Marshal %d", i, want.MarshalSize(), len(raw))
+		}
+		got, n, err := vp9.ParseDescriptor(append(raw, 0xff))
+		if err != nil {
+			t.Fatalf("#%d: Unmarshal failed: %v", i, err)
+		}
+		if n != len(raw) {
+			t.Fatalf("#%d: Unmarshal read %d bytes, want %d", i, n, len(raw))
+		}
+		if got.String() != want.String() {
+			t.Fatalf("#%d: got %s, want %s", i, got, want)
+		}
+		// truncated descriptors
+		for size := 0; size < len(raw); size++ {
+			if _, _, err := vp9.ParseDescriptor(raw[:size]); err == nil {
+				t.Fatalf("#%d: Unmarshal accepted %d of %d bytes", i, size, len(raw))
+			}
+		}
+	}
+
+	if _, err := (vp9.Descriptor{F: true, P: true}).Marshal(); err == nil {
+		t.Fatal("Marshal accepted flexible mode without references")
+	}
+	if _, _, err := vp9.ParseDescriptor([]byte{0x50, 0x03, 0x05, 0x07, 0x08}); err == nil {
+		t.Fatal("Unmarshal accepted more than 3 references")
+	}
+}
+
+func TestVP9Payloader_Payload(t *testing.T) {
+	pck := VP9Payloader{}
+
+	// Nil payload
+	if res := pck.Payload(100, nil); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+	// MTU too small for the descriptor
+	if res := pck.Payload(1, vp9InterFrame); len(res) != 0 {
+		t.Fatal("Generated payload should be empty")
+	}
+
+	// Fragmented inter frame, bare descriptor
+	res := pck.Payload(2, vp9InterFrame)
+	expected := [][]byte{{0x48, 0x86}, {0x40, 0x00}, {0x44, 0x00}}
+	if len(res) != len(expected) {
+		t.Fatalf("Generated payload should be %d packets, got %d", len(expected), len(res))
+	}
+	for i := range expected {
+		if !bytes.Equal(res[i], expected[i]) {
+			t.Fatalf("Packet %d should be %x, got %x", i, expected[i], res[i])
+		}
+	}
+
+	// Key frame with the scalability structure, 15-bit PictureID and layer indices
+	ss := &vp9.ScalabilityStructure{SpatialLayers: 1, Resolutions: []vp9.Resolution{{Width: 640, Height: 480}}}
+	pck = VP9Payloader{PictureIDBits: 15, PictureID: 0x7fff, Layers: true, TL0PICIDX: 3, ScalabilityStructure: ss}
+	res = pck.Payload(11, vp9KeyFrame)
+	if len(res) != 3 {
+		t.Fatalf("Generated payload should be 3 packets, got %d", len(res))
+	}
+	var frame []byte
+	for i, raw := range res {
+		d, n, err := vp9.ParseDescriptor(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.P || d.B != (i == 0) || d.E != (i == len(res)-1) || d.V != (i == 0) ||
+			!d.I || d.PictureID != 0x7fff || !d.L || d.TL0PICIDX != 3 {
+			t.Fatalf("Packet %d has an invalid descriptor %s", i, d)
+		}
+		frame = append(frame, raw[n:]...)
+	}
+	if !bytes.Equal(frame, vp9KeyFrame) {
+		t.Fatal("Generated payload should carry the key frame")
+	}
+
+	// the next picture increments PictureID, wrapping around, and TL0PICIDX
+	res = pck.Payload(100, vp9InterFrame)
+	d, _, err := vp9.ParseDescriptor(res[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.P || d.V || d.PictureID != 0 || d.TL0PICIDX != 4 {
+		t.Fatalf("Inter frame has an invalid descriptor %s", d)
+	}
+	// an upper spatial layer of the same picture
+	pck.SID, pck.D = 1, true
+	res = pck.Payload(100, vp9InterFrame)
+	if d, _, err = vp9.ParseDescriptor(res[0]); err != nil {
+		t.Fatal(err)
+	}
+	if d.PictureID != 0 || d.TL0PICIDX != 4 || d.SID != 1 || !d.D {
+		t.Fatalf("Upper spatial layer has an invalid descriptor %s", d)
+	}
+
+	// Flexible mode
+	pck = VP9Payloader{FlexibleMode: true, Layers: true, TID: 1, PDiffs: []uint8{1, 2}}
+	res = pck.Payload(100, vp9InterFrame)
+	if d, _, err = vp9.ParseDescriptor(res[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !d.F || !d.P || !d.I || !d.M || d.TID != 1 || len(d.PDiffs) != 2 || d.PDiffs[1] != 2 {
+		t.Fatalf("Flexible mode has an invalid descriptor %s", d)
+	}
+}
+
+func TestVP9Packet_Unmarshal(t *testing.T) {
+	pck := VP9Packet{}
+
+	// Nil packet
+	if raw, err := pck.Unmarshal(nil); raw != nil || err == nil {
+		t.Fatal("Unmarshal did not fail on nil payload")
+	}
+	// Descriptor only
+	if raw, err := pck.Unmarshal([]byte{0x0c}); raw != nil || err == nil {
+		t.Fatal("Unmarshal did not fail on empty VP9 payload")
+	}
+
+	ss := &vp9.ScalabilityStructure{SpatialLayers: 1, Resolutions: []vp9.Resolution{{Width: 640, Height: 480}}}
+	payloader := VP9Payloader{PictureIDBits: 7, Layers: true, ScalabilityStructure: ss}
+	packets := payloader.Payload(12, vp9KeyFrame)
+	if len(packets) < 2 {
+		t.Fatal("Key frame should be fragmented")
+	}
+
+	// the layer frame is returned with its last packet
+	for i, packet := range packets {
+		raw, err := pck.Unmarshal(packet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < len(packets)-1 && raw != nil {
+			t.Fatal("Unmarshal should buffer the layer frame")
+		}
+		if i == len(packets)-1 && !bytes.Equal(raw, vp9KeyFrame) {
+			t.Fatal("Unmarshal should return the layer frame")
+		}
+	}
+	if !pck.IsKeyFrame() || pck.FrameHeader.Width != 640 || pck.FrameHeader.Height != 480 {
+		t.Fatal("Unmarshal should decode the key frame header")
+	}
+	if pck.ScalabilityStructure == nil || pck.ScalabilityStructure.Resolutions[0].Width != 640 {
+		t.Fatal("Unmarshal should keep the scalability structure")
+	}
+
+	// a layer frame missing its first packet is dropped
+	packets = payloader.Payload(6, vp9InterFrame)
+	for _, packet := range packets[1:] {
+		if raw, err := pck.Unmarshal(packet); raw != nil || err != nil {
+			t.Fatal("Unmarshal should drop the layer frame")
+		}
+	}
+	if pck.PictureID != 1 || !pck.P || pck.ScalabilityStructure == nil {
+		t.Fatal("Unmarshal should expose the descriptor of the last packet")
+	}
+}
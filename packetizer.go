@@ -11,6 +11,14 @@ type Payloader interface {
 	Payload(mtu int, payload []byte) [][]byte
 }
 
+// SamplesPayloader is a Payloader which knows how many samples a payload carries,
+// so that Packetize can advance the timestamp on its own
+type SamplesPayloader interface {
+	Payloader
+	// Samples returns the duration of the payload, in units of the clock rate
+	Samples(clockRate uint32, payload []byte) uint32
+}
+
 // Packetizer packetizes a payload
 type Packetizer interface {
 	Packetize(payload []byte, samples uint32) []*Packet
@@ -68,12 +76,19 @@ func toNtpTime(t time.Time) uint64 {
 }
 
 // Packetize packetizes the payload of an RTP packet and returns one or more RTP packets
+// If samples is 0 and the Payloader is a SamplesPayloader, the timestamp increment is derived from the payload.
+// A payload the Payloader drops, such as an Opus packet of invalid framing, returns no packets;
+// Payloaders with an Err method, such as OpusPayloader, report why
 func (p *packetizer) Packetize(payload []byte, samples uint32) []*Packet {
 	// Guard against an empty payload
 	if len(payload) == 0 {
 		return nil
 	}
 
+	if sp, ok := p.Payloader.(SamplesPayloader); ok && samples == 0 {
+		samples = sp.Samples(p.ClockRate, payload)
+	}
+
 	payloads := p.Payloader.Payload(p.MTU-12, payload)
 	packets := make([]*Packet, len(payloads))
 
@@ -42,3 +42,18 @@ func TestPacketizer(t *testing.T) {
 	}
 
 }
+
+func TestPacketizer_Samples(t *testing.T) {
+	//config 1 (SILK NB 20ms), code 3 CBR with 2 frames
+	payload := []byte{0x0b, 0x02, 0x00, 0x00}
+	packetizer := NewPacketizer(100, 111, 0x1234ABCD, &format.OpusPayloader{}, NewRandomSequencer(), 48000)
+	first := packetizer.Packetize(payload, 0)
+	second := packetizer.Packetize(payload, 0)
+
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatal("Opus packets should not be fragmented")
+	}
+	if diff := second[0].Header.Timestamp - first[0].Header.Timestamp; diff != 1920 {
+		t.Fatalf("Timestamp should advance by 1920, got %d", diff)
+	}
+}